@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+func loadCmd(service client.Service) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "load",
+		Short: "Send the read-only test cases from a discovery model at a target rate",
+		Long:  "Sends the GET test cases generated from a discovery model in turn, at --rps requests a second for --duration, and reports throughput, error rate and latency percentiles. Requests that create or change resources are never sent.",
+		RunE:  load(service),
+		// a failed load run is not a usage error
+		SilenceUsage: true,
+	}
+	cmd.Flags().StringP("filename", "f", "", "Discovery filename")
+	cmd.Flags().StringP("config", "c", "", "Config filename")
+	cmd.Flags().Float64("rps", 1, "Requests started each second, at most 1000")
+	cmd.Flags().String("duration", "1m", "How long to send requests for, such as 30s or 5m")
+	cmd.Flags().String("tests", "", "Regular expression matching the ids of the test cases to send, all read-only test cases when empty")
+	return cmd
+}
+
+func load(service client.Service) func(cmd *cobra.Command, _ []string) error {
+	return func(cmd *cobra.Command, _ []string) error {
+		filenameFlag, err := cmd.Flags().GetString("filename")
+		if err != nil || filenameFlag == "" {
+			return errors.New("you need to provide a discovery filename")
+		}
+		configFlag, err := cmd.Flags().GetString("config")
+		if err != nil || configFlag == "" {
+			return errors.New("you need to provide a config filename")
+		}
+
+		options := client.LoadOptions{}
+		if options.RPS, err = cmd.Flags().GetFloat64("rps"); err != nil {
+			return err
+		}
+		if options.Duration, err = cmd.Flags().GetString("duration"); err != nil {
+			return err
+		}
+		if options.TestCases, err = cmd.Flags().GetString("tests"); err != nil {
+			return err
+		}
+
+		report, err := service.Load(filenameFlag, configFlag, options)
+		if err != nil {
+			return err
+		}
+		client.LoadWriter(os.Stdout, report)
+		return nil
+	}
+}
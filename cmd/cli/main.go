@@ -24,6 +24,9 @@ func main() {
 		fmt.Println(err.Error())
 		os.Exit(1)
 	}
+	if token := os.Getenv("FCS_TOKEN"); token != "" {
+		insecureConn.SetToken(token)
+	}
 	service := client.NewService(
 		os2.GetEnvOrDefault("FCS_HOST", defaultHostServer),
 		os2.GetEnvOrDefault("FCS_WEBSOCKET_HOST", defaultWebsocketHostServer),
@@ -45,5 +48,8 @@ func newRootCommand(service client.Service) *cobra.Command {
 	}
 	rootCmd.AddCommand(runCmd(service))
 	rootCmd.AddCommand(versionCmd(service))
+	rootCmd.AddCommand(preflightCmd(service))
+	rootCmd.AddCommand(runsCmd(service))
+	rootCmd.AddCommand(loadCmd(service))
 	return rootCmd
 }
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+func preflightCmd(service client.Service) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "preflight",
+		Short: "Check the certificates in a config before running tests",
+		Long:  "Checks the signing and transport certificates, the signing kid and the mTLS handshake to the token endpoint. Exits non zero when a check fails.",
+		RunE:  preflight(service),
+		// a failed check is not a usage error
+		SilenceUsage: true,
+	}
+	cmd.Flags().StringP("config", "c", "", "Config filename")
+	return cmd
+}
+
+func preflight(service client.Service) func(cmd *cobra.Command, _ []string) error {
+	return func(cmd *cobra.Command, _ []string) error {
+		configFlag, err := cmd.Flags().GetString("config")
+		if err != nil || configFlag == "" {
+			return errors.New("you need to provide a config filename")
+		}
+
+		report, err := service.Preflight(configFlag)
+		if err != nil {
+			return err
+		}
+
+		client.PreflightWriter(os.Stdout, report)
+		if !report.Pass {
+			return errors.New("pre-flight checks failed")
+		}
+		return nil
+	}
+}
@@ -17,6 +17,7 @@ func runCmd(service client.Service) *cobra.Command {
 	generatorCmd.Flags().StringP("filename", "f", "", "Discovery filename")
 	generatorCmd.Flags().StringP("config", "c", "", "Config filename")
 	generatorCmd.Flags().StringP("export", "e", "", "Export config filename")
+	generatorCmd.Flags().String("resume", "", "ID of a run in the server's run history to continue instead of starting a new run")
 	return generatorCmd
 }
 
@@ -41,7 +42,18 @@ func run(service client.Service) func(cmd *cobra.Command, _ []string) {
 			return
 		}
 
-		results, err := service.Run(filenameFlag, configFlag, exportFlag)
+		resumeFlag, err := cmd.Flags().GetString("resume")
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+
+		var results []client.TestCase
+		if resumeFlag != "" {
+			results, err = service.Resume(filenameFlag, configFlag, exportFlag, resumeFlag)
+		} else {
+			results, err = service.Run(filenameFlag, configFlag, exportFlag)
+		}
 		if err != nil {
 			fmt.Printf("Error running tests: %s\n", err.Error())
 			return
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+func runsCmd(service client.Service) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "runs",
+		Short: "List, show or delete runs in the server's run history, or print their events",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List runs, most recent first",
+		Args:  cobra.NoArgs,
+		RunE:  listRuns(service),
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "get <run id>",
+		Short: "Print a run as JSON, including its results and evidence",
+		Args:  cobra.ExactArgs(1),
+		RunE:  getRun(service),
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "events <run id>",
+		Short: "Print the events of a run as NDJSON, one event per line",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runEvents(service),
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "delete <run id>",
+		Short: "Remove a run from the history",
+		Args:  cobra.ExactArgs(1),
+		RunE:  deleteRun(service),
+	})
+	return cmd
+}
+
+func listRuns(service client.Service) func(_ *cobra.Command, _ []string) error {
+	return func(_ *cobra.Command, _ []string) error {
+		runs, err := service.ListRuns()
+		if err != nil {
+			return err
+		}
+		client.RunsWriter(os.Stdout, runs)
+		return nil
+	}
+}
+
+func getRun(service client.Service) func(_ *cobra.Command, args []string) error {
+	return func(_ *cobra.Command, args []string) error {
+		run, err := service.GetRun(args[0])
+		if err != nil {
+			return err
+		}
+		indented := &bytes.Buffer{}
+		if err := json.Indent(indented, run, "", "  "); err != nil {
+			return err
+		}
+		fmt.Println(indented.String())
+		return nil
+	}
+}
+
+func runEvents(service client.Service) func(_ *cobra.Command, args []string) error {
+	return func(_ *cobra.Command, args []string) error {
+		return service.RunEvents(args[0], os.Stdout)
+	}
+}
+
+func deleteRun(service client.Service) func(_ *cobra.Command, args []string) error {
+	return func(_ *cobra.Command, args []string) error {
+		return service.DeleteRun(args[0])
+	}
+}
@@ -1,19 +1,29 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/OpenBankingUK/conformance-suite/pkg/access"
+	"github.com/OpenBankingUK/conformance-suite/pkg/authentication"
 	"github.com/OpenBankingUK/conformance-suite/pkg/discovery"
 	"github.com/OpenBankingUK/conformance-suite/pkg/generation"
 	"github.com/OpenBankingUK/conformance-suite/pkg/manifest"
 
 	"github.com/OpenBankingUK/conformance-suite/pkg/model"
+	"github.com/OpenBankingUK/conformance-suite/pkg/redact"
+	"github.com/OpenBankingUK/conformance-suite/pkg/runevents"
+	"github.com/OpenBankingUK/conformance-suite/pkg/runstore"
+	"github.com/OpenBankingUK/conformance-suite/pkg/schema"
 	"github.com/OpenBankingUK/conformance-suite/pkg/server"
 	"github.com/OpenBankingUK/conformance-suite/pkg/tracer"
+	"github.com/OpenBankingUK/conformance-suite/pkg/tracing"
 	"github.com/OpenBankingUK/conformance-suite/pkg/version"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -29,8 +39,10 @@ const (
 )
 
 var (
-	logger  = logrus.StandardLogger()
-	rootCmd = &cobra.Command{
+	logger = logrus.StandardLogger()
+	// httpLog - where the requests sent to the ASPSP are logged
+	httpLog io.Writer = redact.NewWriter(os.Stderr)
+	rootCmd           = &cobra.Command{
 		Use:   "fcs_server",
 		Short: "Functional Conformance Suite Server",
 		Long: `A Fast and Flexible tool that enables implementers to test
@@ -44,19 +56,116 @@ Complete documentation is available at https://github.com/OpenBankingUK/conforma
 
 			printVersionInfo(ver, logger)
 
+			if endpoint := viper.GetString("otlp_endpoint"); endpoint != "" {
+				shutdown, err := tracing.Setup(tracing.Config{
+					Endpoint:       endpoint,
+					Insecure:       viper.GetBool("otlp_insecure"),
+					Propagate:      viper.GetBool("trace_propagation"),
+					ServiceVersion: version.FullVersion,
+				})
+				if err != nil {
+					return errors.Wrap(err, "setting up tracing")
+				}
+				defer func() {
+					if err := shutdown(context.Background()); err != nil {
+						logger.WithError(err).Error("flushing traces")
+					}
+				}()
+			}
+
 			validatorEngine := discovery.NewFuncValidator(model.NewConditionalityChecker())
 			testGenerator := generation.NewGenerator()
 			tlsValidator := discovery.NewStdTLSValidator(tls.VersionTLS11)
-			journey := server.NewJourney(logger, testGenerator, validatorEngine, tlsValidator, viper.GetBool("dynres"))
+			runs, err := openRunStore(viper.GetString("run_store"))
+			if err != nil {
+				return err
+			}
+			eventLog, err := openEventLog(viper.GetString("event_log_dir"))
+			if err != nil {
+				return err
+			}
+			newJourney := func(id string) server.Journey {
+				journey := server.NewJourney(logger, testGenerator, validatorEngine, tlsValidator, viper.GetBool("dynres"))
+				journey.SetID(id)
+				journey.SetHTTPClient(newHTTPClient())
+				if viper.GetBool("disable_jws") {
+					journey.DisableJWS()
+				}
+				if runs != nil {
+					journey.SetRunStore(runs)
+				}
+				if eventLog != nil {
+					journey.SetEventLog(eventLog)
+				}
+				return journey
+			}
+			journeys := server.NewJourneys(newJourney, viper.GetDuration("journey_idle_timeout"), logger)
 
-			echoServer := server.NewServer(journey, logger, ver)
+			echoServer := server.NewServer(journeys, runs, logger, ver)
+			if eventLog != nil {
+				echoServer.ServeRunEventLogs(eventLog)
+			}
+			if viper.GetBool("metrics") {
+				echoServer.ServeMetrics()
+			}
+			clientCAs, err := requireAccess(echoServer, viper.GetString("access_config"), logger)
+			if err != nil {
+				return err
+			}
 			address := fmt.Sprintf("%s:%d", server.ListenHost, viper.GetInt("port"))
 			logger.Infof("listening on https://%s", address)
+			if clientCAs != nil {
+				return echoServer.StartTLSWithClientCAs(address, certFile, keyFile, clientCAs)
+			}
 			return echoServer.StartTLS(address, certFile, keyFile)
 		},
 	}
 )
 
+// newHTTPClient - a client of its own for a journey's requests to the ASPSP, logged like those
+// sent with resty's default client
+func newHTTPClient() *resty.Client {
+	return resty.New().
+		SetLogger(httpLog).
+		SetDebug(viper.GetBool("log_http_trace")).
+		SetRedirectPolicy(resty.FlexibleRedirectPolicy(15))
+}
+
+// openRunStore - the run history kept at `path`, nil if `path` is empty
+func openRunStore(path string) (runstore.RunStore, error) {
+	if path == "" {
+		return nil, nil
+	}
+	return runstore.NewBoltStore(path)
+}
+
+// openEventLog - the run event logs kept in `dir`, nil if `dir` is empty
+func openEventLog(dir string) (runevents.Log, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	return runevents.NewFileLog(dir)
+}
+
+// requireAccess - authenticates the users of `echoServer` as configured in `filename`, returning
+// the CAs of the client certificates to ask for, if any. Anyone may use the API if `filename` is empty.
+func requireAccess(echoServer *server.Server, filename string, logger *logrus.Entry) (*x509.CertPool, error) {
+	if filename == "" {
+		logger.Warn("access_config not set, anyone who can reach the server can use the API")
+		return nil, nil
+	}
+	config, err := access.LoadConfig(filename)
+	if err != nil {
+		return nil, err
+	}
+	a, err := access.New(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "setting up access control")
+	}
+	echoServer.RequireAccess(a)
+	return a.ClientCAs, nil
+}
+
 func printVersionInfo(ver version.GitHub, logger *logrus.Entry) {
 	v, err := ver.VersionFormatter(version.FullVersion)
 	if err != nil {
@@ -89,6 +198,21 @@ func init() {
 	rootCmd.PersistentFlags().Bool("dumpcontexts", false, "Dump contexts when trace enabled")
 	rootCmd.PersistentFlags().Bool("tlscheck", true, "enable tls version checking - default enabled")
 	rootCmd.PersistentFlags().Bool("export_testcases", false, "Dump all testcases to console in CSV format")
+	rootCmd.PersistentFlags().String("trust_anchors", "", "Trust anchor and JWKS resolution config file - default uses the OB directory and known ASPSP trust anchors")
+	rootCmd.PersistentFlags().String("pkcs11_module", "", "PKCS#11 module used for pkcs11: private keys without a module-path, e.g. /usr/lib/softhsm/libsofthsm2.so")
+	rootCmd.PersistentFlags().Duration("journey_idle_timeout", 4*time.Hour, "Remove journeys created with POST /api/journeys after this long without a request, 0 keeps them")
+	rootCmd.PersistentFlags().String("pkcs11_pin", "", "PKCS#11 user PIN used for pkcs11: private keys without a pin-value")
+	rootCmd.PersistentFlags().String("run_store", "data/runs.db", "File keeping the history of test runs, empty disables the history")
+	rootCmd.PersistentFlags().String("event_log_dir", "data/events", "Directory keeping an NDJSON log of the events of each test run, empty disables the logs")
+	rootCmd.PersistentFlags().Bool("metrics", true, "Serve Prometheus metrics at /metrics")
+	rootCmd.PersistentFlags().String("otlp_endpoint", "", "host:port of an OTLP/HTTP collector to export traces to, e.g. localhost:4318, empty disables tracing")
+	rootCmd.PersistentFlags().Bool("otlp_insecure", true, "Export traces over plain HTTP rather than HTTPS")
+	rootCmd.PersistentFlags().Bool("trace_propagation", false, "Send the W3C traceparent header to the ASPSP, so its traces join the suite's")
+	rootCmd.PersistentFlags().String("access_config", "", "Access control config file setting out API tokens, OIDC login, client certificates and the audit log - empty lets anyone use the API")
+	rootCmd.PersistentFlags().StringSlice("redact_keys", nil, "JSON fields, form values and context keys redacted from logs, events and reports, added to the defaults")
+	rootCmd.PersistentFlags().StringSlice("redact_headers", nil, "HTTP headers redacted from logs, events and reports, added to the defaults")
+	rootCmd.PersistentFlags().String("spec_dir", "", "Directory of vendored API specs, <spec_dir>/<version>/<file>, used in place of the bundled ones - empty uses the bundled specs")
+	rootCmd.PersistentFlags().Duration("idempotency_key_expiry", 0, "How long the ASPSP keeps x-idempotency-keys, e.g. 24h - when set, payment tests check keys expire after this long, 0 skips the expiry tests")
 
 	if err := viper.BindPFlags(rootCmd.PersistentFlags()); err != nil {
 		fmt.Fprint(os.Stderr, err)
@@ -122,6 +246,13 @@ func initConfig() {
 	}
 	logger.SetLevel(level)
 
+	redactConfig := redact.DefaultConfig()
+	redactConfig.Keys = append(redactConfig.Keys, viper.GetStringSlice("redact_keys")...)
+	redactConfig.Headers = append(redactConfig.Headers, viper.GetStringSlice("redact_headers")...)
+	redact.SetDefault(redact.New(redactConfig))
+	logger.AddHook(redact.NewHook())
+	resty.SetLogger(httpLog)
+
 	if viper.GetBool("export_testcases") {
 		manifest.GenerateTestCaseListCSV()
 		os.Exit(0)
@@ -151,40 +282,82 @@ func initConfig() {
 		if err != nil {
 			logrus.Warn("cannot set http trace file")
 		} else {
-			resty.SetLogger(httpLogFile)
+			httpLog = redact.NewWriter(httpLogFile)
+			resty.SetLogger(httpLog)
 		}
 	}
 
-	if viper.GetBool("disable_jws") {
-		model.DisableJWS()
-	}
-
 	if viper.GetBool("dumpcontexts") {
 		model.EnableContextDumps()
 	}
 
+	schema.SetSpecDir(viper.GetString("spec_dir"))
+	model.SetIdempotencyKeyExpiry(viper.GetDuration("idempotency_key_expiry"))
+
 	if viper.GetBool("tlscheck") == false {
 		server.EnableTLSCheck(false)
 	}
 
+	if trustAnchors := viper.GetString("trust_anchors"); trustAnchors != "" {
+		if err := setTrustAnchorResolver(trustAnchors); err != nil {
+			fmt.Fprint(os.Stderr, err)
+			fmt.Fprint(os.Stderr, "\n")
+			os.Exit(1)
+		}
+	}
+
+	authentication.SetKeyProviders(
+		authentication.NewPKCS11KeyProvider(viper.GetString("pkcs11_module"), viper.GetString("pkcs11_pin")),
+		authentication.PEMKeyProvider(),
+	)
+
 	resty.SetDebug(viper.GetBool("log_http_trace"))
 	resty.SetRedirectPolicy(resty.FlexibleRedirectPolicy(15))
 	printConfigurationFlags()
 }
 
+// setTrustAnchorResolver - replaces the default trust anchor resolver with the one configured in `filename`
+func setTrustAnchorResolver(filename string) error {
+	config, err := authentication.LoadTrustAnchorConfig(filename)
+	if err != nil {
+		return err
+	}
+	resolver, err := authentication.NewTrustAnchorResolver(config)
+	if err != nil {
+		return err
+	}
+	authentication.SetTrustAnchorResolver(resolver)
+	return nil
+}
+
 func printConfigurationFlags() {
 	logger.WithFields(logrus.Fields{
-		"log_level":        viper.GetString("log_level"),
-		"log_tracer":       viper.GetBool("log_tracer"),
-		"log_http_trace":   viper.GetBool("log_http_trace"),
-		"log_http_file":    viper.GetBool("log_http_file"),
-		"log_to_file":      viper.GetBool("log_to_file"),
-		"port":             viper.GetInt("port"),
-		"tracer.Silent":    tracer.Silent,
-		"disable_jws":      viper.GetBool("disable_jws"),
-		"dynres":           viper.GetBool("dynres"),
-		"dumpcontexts":     viper.GetBool("dumpcontexts"),
-		"tlscheck":         viper.GetBool("tlscheck"),
-		"export_testcases": viper.GetString("export_testcases"),
+		"log_level":              viper.GetString("log_level"),
+		"log_tracer":             viper.GetBool("log_tracer"),
+		"log_http_trace":         viper.GetBool("log_http_trace"),
+		"log_http_file":          viper.GetBool("log_http_file"),
+		"log_to_file":            viper.GetBool("log_to_file"),
+		"port":                   viper.GetInt("port"),
+		"tracer.Silent":          tracer.Silent,
+		"disable_jws":            viper.GetBool("disable_jws"),
+		"dynres":                 viper.GetBool("dynres"),
+		"dumpcontexts":           viper.GetBool("dumpcontexts"),
+		"tlscheck":               viper.GetBool("tlscheck"),
+		"export_testcases":       viper.GetString("export_testcases"),
+		"trust_anchors":          viper.GetString("trust_anchors"),
+		"journey_idle_timeout":   viper.GetDuration("journey_idle_timeout"),
+		"pkcs11_module":          viper.GetString("pkcs11_module"),
+		"pkcs11_pin_set":         viper.GetString("pkcs11_pin") != "",
+		"run_store":              viper.GetString("run_store"),
+		"event_log_dir":          viper.GetString("event_log_dir"),
+		"metrics":                viper.GetBool("metrics"),
+		"otlp_endpoint":          viper.GetString("otlp_endpoint"),
+		"otlp_insecure":          viper.GetBool("otlp_insecure"),
+		"trace_propagation":      viper.GetBool("trace_propagation"),
+		"access_config":          viper.GetString("access_config"),
+		"redact_keys":            viper.GetStringSlice("redact_keys"),
+		"redact_headers":         viper.GetStringSlice("redact_headers"),
+		"spec_dir":               viper.GetString("spec_dir"),
+		"idempotency_key_expiry": viper.GetDuration("idempotency_key_expiry"),
 	}).Info("configuration flags")
 }
@@ -0,0 +1,110 @@
+// Package access authenticates the users of the fcs_server API and decides what they may do. Users
+// are identified by static API tokens, by an OpenID Connect login against the organisation's
+// identity provider or by TLS client certificates, and hold one of three roles. What they do is
+// kept in an audit log.
+package access
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Role - what a user may do, each role may do everything the roles before it may
+type Role int
+
+// Roles
+const (
+	// Viewer - sees journeys, test cases, results and the run history
+	Viewer Role = iota + 1
+	// Operator - sets discovery models, runs, stops and resumes tests and collects consent
+	Operator
+	// Admin - sets the global configuration, with its certificates and private keys, and deletes runs
+	Admin
+)
+
+var roleNames = map[Role]string{
+	Viewer:   "viewer",
+	Operator: "operator",
+	Admin:    "admin",
+}
+
+func (r Role) String() string {
+	if name, ok := roleNames[r]; ok {
+		return name
+	}
+	return "none"
+}
+
+// Allows - whether a user with role `r` may do what needs `required`
+func (r Role) Allows(required Role) bool {
+	return r >= required
+}
+
+// MarshalText - roles are written by name
+func (r Role) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText - reads a role by name
+func (r *Role) UnmarshalText(text []byte) error {
+	role, err := ParseRole(string(text))
+	if err != nil {
+		return err
+	}
+	*r = role
+	return nil
+}
+
+// ParseRole - the role called `name`
+func ParseRole(name string) (Role, error) {
+	for role, roleName := range roleNames {
+		if roleName == name {
+			return role, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown role %q, must be viewer, operator or admin", name)
+}
+
+// Methods of authentication
+const (
+	MethodToken       = "token"
+	MethodOIDC        = "oidc"
+	MethodCertificate = "certificate"
+)
+
+// Principal - an authenticated user
+type Principal struct {
+	Name   string
+	Role   Role
+	Method string // how the user was authenticated
+}
+
+var (
+	// ErrNoCredentials - the request has no credentials the Authenticator understands
+	ErrNoCredentials = errors.New("no credentials")
+	// ErrInvalidCredentials - the request has credentials the Authenticator does not accept
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Authenticator - identifies the user making a request
+type Authenticator interface {
+	// Authenticate - the user making `r`. ErrNoCredentials when `r` has no credentials this
+	// Authenticator understands, so that the next one can try.
+	Authenticate(r *http.Request) (Principal, error)
+}
+
+// Authenticators - tries each Authenticator in turn
+type Authenticators []Authenticator
+
+// Authenticate - the user identified by the first Authenticator understanding the credentials in `r`
+func (a Authenticators) Authenticate(r *http.Request) (Principal, error) {
+	for _, authenticator := range a {
+		principal, err := authenticator.Authenticate(r)
+		if err == ErrNoCredentials {
+			continue
+		}
+		return principal, err
+	}
+	return Principal{}, ErrNoCredentials
+}
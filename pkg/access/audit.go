@@ -0,0 +1,78 @@
+package access
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Outcomes of a request
+const (
+	OutcomeAllowed         = "allowed"
+	OutcomeUnauthenticated = "unauthenticated"
+	OutcomeForbidden       = "forbidden"
+	OutcomeLogin           = "login"
+	OutcomeLoginFailed     = "login_failed"
+	OutcomeLogout          = "logout"
+)
+
+// AuditEntry - what a user did
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	User     string    `json:"user,omitempty"`
+	Role     string    `json:"role,omitempty"`
+	Method   string    `json:"auth_method,omitempty"`
+	Action   string    `json:"action"` // HTTP method and route, e.g. POST /api/config/global
+	Path     string    `json:"path"`   // the path requested
+	Journey  string    `json:"journey,omitempty"`
+	Status   int       `json:"status,omitempty"` // HTTP status of the response
+	Outcome  string    `json:"outcome"`
+	RemoteIP string    `json:"remote_ip"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// AuditLog - keeps a record of who did what
+type AuditLog interface {
+	Record(entry AuditEntry) error
+}
+
+type fileAuditLog struct {
+	filename string
+	lock     sync.Mutex
+}
+
+// NewFileAuditLog - an AuditLog appending entries to `filename` as NDJSON, one entry per line
+func NewFileAuditLog(filename string) (AuditLog, error) {
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+	return &fileAuditLog{filename: filename}, nil
+}
+
+func (l *fileAuditLog) Record(entry AuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	file, err := os.OpenFile(l.filename, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,51 @@
+package access
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// ClientCertificates - TLS client certificates accepted in place of a token or login
+type ClientCertificates struct {
+	// CAFile - PEM bundle of the CAs issuing client certificates
+	CAFile string `json:"ca_file"`
+	// CommonNames - the role of each user, by the common name of their certificate
+	CommonNames map[string]Role `json:"common_names"`
+}
+
+// Pool - the CAs in CAFile, for the server's TLS configuration
+func (c ClientCertificates) Pool() (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(c.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading client certificate CAs: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates in %s", c.CAFile)
+	}
+	return pool, nil
+}
+
+type certificateAuthenticator struct {
+	commonNames map[string]Role
+}
+
+// NewCertificateAuthenticator - authenticates requests made with a client certificate verified by
+// the TLS server, by the certificate's common name
+func NewCertificateAuthenticator(commonNames map[string]Role) Authenticator {
+	return certificateAuthenticator{commonNames: commonNames}
+}
+
+func (a certificateAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return Principal{}, ErrNoCredentials
+	}
+	name := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	role, ok := a.commonNames[name]
+	if !ok {
+		return Principal{}, ErrInvalidCredentials
+	}
+	return Principal{Name: name, Role: role, Method: MethodCertificate}, nil
+}
@@ -0,0 +1,82 @@
+package access
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// Config - how users are authenticated and where what they do is recorded
+type Config struct {
+	Tokens             []Token             `json:"tokens,omitempty"`
+	OIDC               *OIDCConfig         `json:"oidc,omitempty"`
+	ClientCertificates *ClientCertificates `json:"client_certificates,omitempty"`
+	// AuditLog - NDJSON file recording who did what, required
+	AuditLog string `json:"audit_log"`
+}
+
+// LoadConfig - reads a Config from a JSON file
+func LoadConfig(filename string) (Config, error) {
+	config := Config{}
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return config, fmt.Errorf("access.LoadConfig: %w", err)
+	}
+	if err := json.Unmarshal(content, &config); err != nil {
+		return config, fmt.Errorf("access.LoadConfig: invalid config %s: %w", filename, err)
+	}
+	return config, nil
+}
+
+// Access - what the server needs to authenticate users and record what they do
+type Access struct {
+	Authenticator Authenticators
+	// OIDC - serves logins, nil unless configured
+	OIDC *OIDC
+	// ClientCAs - CAs of the client certificates the TLS server asks for, nil unless configured
+	ClientCAs *x509.CertPool
+	Audit     AuditLog
+}
+
+// New - sets up the authentication methods in `config`, tokens first, then OIDC, then client certificates
+func New(config Config) (Access, error) {
+	a := Access{}
+	if config.AuditLog == "" {
+		return a, fmt.Errorf("access: audit_log is required")
+	}
+	if len(config.Tokens) == 0 && config.OIDC == nil && config.ClientCertificates == nil {
+		return a, fmt.Errorf("access: configure at least one of tokens, oidc and client_certificates")
+	}
+
+	if len(config.Tokens) > 0 {
+		tokens, err := NewTokenAuthenticator(config.Tokens)
+		if err != nil {
+			return a, err
+		}
+		a.Authenticator = append(a.Authenticator, tokens)
+	}
+	if config.OIDC != nil {
+		oidc, err := NewOIDC(*config.OIDC)
+		if err != nil {
+			return a, err
+		}
+		a.OIDC = oidc
+		a.Authenticator = append(a.Authenticator, oidc)
+	}
+	if config.ClientCertificates != nil {
+		pool, err := config.ClientCertificates.Pool()
+		if err != nil {
+			return a, err
+		}
+		a.ClientCAs = pool
+		a.Authenticator = append(a.Authenticator, NewCertificateAuthenticator(config.ClientCertificates.CommonNames))
+	}
+
+	audit, err := NewFileAuditLog(config.AuditLog)
+	if err != nil {
+		return a, err
+	}
+	a.Audit = audit
+	return a, nil
+}
@@ -0,0 +1,506 @@
+package access
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/authentication"
+)
+
+const (
+	// SessionCookie - holds the session of a user logged in with OIDC
+	SessionCookie = "fcs_session"
+	// loginCookie - holds the state of a login until the identity provider redirects back
+	loginCookie = "fcs_login"
+
+	defaultSessionTTL    = 8 * time.Hour
+	defaultUsernameClaim = "preferred_username"
+	defaultRolesClaim    = "roles"
+	loginTimeout         = 10 * time.Minute
+	jwksRefreshInterval  = time.Minute
+	idpTimeout           = 25 * time.Second
+)
+
+// OIDCConfig - login against an OpenID Connect identity provider with the authorization code flow
+type OIDCConfig struct {
+	Issuer       string `json:"issuer"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	// RedirectURL - where the identity provider sends the user back to, https://<host>:8443/auth/callback
+	RedirectURL string `json:"redirect_url"`
+	// CAFile - PEM bundle of the CAs issuing the identity provider's certificate, when they are not trusted by the host
+	CAFile string `json:"ca_file,omitempty"`
+	// UsernameClaim - the ID token claim naming the user, preferred_username when empty
+	UsernameClaim string `json:"username_claim,omitempty"`
+	// RolesClaim - the ID token claim listing the user's groups or roles, a dotted path such as
+	// realm_access.roles for a nested claim, roles when empty
+	RolesClaim string `json:"roles_claim,omitempty"`
+	// Roles - the suite role of each group or role in RolesClaim. Users with several get the highest.
+	Roles map[string]Role `json:"roles"`
+	// SessionTTL - how long a login lasts, 8h when empty
+	SessionTTL string `json:"session_ttl,omitempty"`
+}
+
+// OIDC - logs users in with an OpenID Connect identity provider and authenticates requests by the
+// session cookie set on login, or by an ID token from the identity provider as a bearer token
+type OIDC struct {
+	config     OIDCConfig
+	provider   authentication.OpenIDConfiguration
+	client     *http.Client
+	sessionKey []byte
+	sessionTTL time.Duration
+	now        func() time.Time
+
+	keysLock    sync.Mutex
+	keys        map[string]*rsa.PublicKey
+	keysFetched time.Time
+}
+
+// NewOIDC - reads the identity provider's discovery document and keys. Sessions are signed with a
+// key made at start up, so users log in again after the server restarts.
+func NewOIDC(config OIDCConfig) (*OIDC, error) {
+	if config.Issuer == "" || config.ClientID == "" || config.RedirectURL == "" {
+		return nil, fmt.Errorf("oidc: issuer, client_id and redirect_url are required")
+	}
+	sessionTTL := defaultSessionTTL
+	if config.SessionTTL != "" {
+		var err error
+		if sessionTTL, err = time.ParseDuration(config.SessionTTL); err != nil {
+			return nil, fmt.Errorf("oidc: invalid session_ttl: %w", err)
+		}
+	}
+	if config.UsernameClaim == "" {
+		config.UsernameClaim = defaultUsernameClaim
+	}
+	if config.RolesClaim == "" {
+		config.RolesClaim = defaultRolesClaim
+	}
+	client, err := idpClient(config.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	sessionKey := make([]byte, 32)
+	if _, err := rand.Read(sessionKey); err != nil {
+		return nil, err
+	}
+
+	o := &OIDC{
+		config:     config,
+		client:     client,
+		sessionKey: sessionKey,
+		sessionTTL: sessionTTL,
+		now:        time.Now,
+	}
+	wellKnown := strings.TrimSuffix(config.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := o.getJSON(wellKnown, &o.provider); err != nil {
+		return nil, err
+	}
+	if o.provider.Issuer != config.Issuer {
+		return nil, fmt.Errorf("oidc: discovery document issuer %q does not match %q", o.provider.Issuer, config.Issuer)
+	}
+	if err := o.refreshKeys(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func idpClient(caFile string) (*http.Client, error) {
+	if caFile == "" {
+		return &http.Client{Timeout: idpTimeout}, nil
+	}
+	pem, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: reading ca_file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("oidc: no certificates in %s", caFile)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	return &http.Client{Timeout: idpTimeout, Transport: transport}, nil
+}
+
+// Authenticate - the user of the session cookie in `r`, or of the ID token in its Authorization header
+func (o *OIDC) Authenticate(r *http.Request) (Principal, error) {
+	if bearer, ok := bearerToken(r); ok && isJWT(bearer) {
+		principal, err := o.verifyIDToken(bearer, "")
+		if err != nil {
+			return Principal{}, ErrInvalidCredentials
+		}
+		return principal, nil
+	}
+	cookie, err := r.Cookie(SessionCookie)
+	if err != nil {
+		return Principal{}, ErrNoCredentials
+	}
+	session := session{}
+	if err := o.open(cookie.Value, &session); err != nil || o.now().Unix() > session.Expires {
+		return Principal{}, ErrInvalidCredentials
+	}
+	return Principal{Name: session.Name, Role: session.Role, Method: MethodOIDC}, nil
+}
+
+// session - the contents of the session cookie
+type session struct {
+	Name    string `json:"name"`
+	Role    Role   `json:"role"`
+	Expires int64  `json:"exp"`
+}
+
+// login - the contents of the login cookie
+type login struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"` // PKCE code verifier
+	Redirect string `json:"redirect"`
+	Expires  int64  `json:"exp"`
+}
+
+// Login - starts a login, returning the identity provider's authorization URL to redirect the user
+// to. The user comes back to `redirect`, a path on this server, once logged in.
+func (o *OIDC) Login(w http.ResponseWriter, redirect string) (string, error) {
+	if !isLocalPath(redirect) {
+		redirect = "/"
+	}
+	l := login{
+		State:    randomString(),
+		Nonce:    randomString(),
+		Verifier: randomString(),
+		Redirect: redirect,
+		Expires:  o.now().Add(loginTimeout).Unix(),
+	}
+	value, err := o.seal(l)
+	if err != nil {
+		return "", err
+	}
+	http.SetCookie(w, o.cookie(loginCookie, value, loginTimeout))
+
+	challenge := sha256.Sum256([]byte(l.Verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {o.config.ClientID},
+		"redirect_uri":          {o.config.RedirectURL},
+		"scope":                 {"openid profile"},
+		"state":                 {l.State},
+		"nonce":                 {l.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(o.provider.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return o.provider.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// isLocalPath - whether `redirect` is a path on this server. Browsers read `\` as `/`, so
+// `/\evil.example` would send the user to another host, as `//evil.example` would.
+func isLocalPath(redirect string) bool {
+	if !strings.HasPrefix(redirect, "/") || strings.HasPrefix(redirect, "//") || strings.ContainsAny(redirect, "\\\r\n\t") {
+		return false
+	}
+	parsed, err := url.Parse(redirect)
+	return err == nil && parsed.Scheme == "" && parsed.Host == ""
+}
+
+// Callback - completes the login the identity provider redirected `r` back from, setting the
+// session cookie. Returns the user and the path they asked to come back to.
+func (o *OIDC) Callback(w http.ResponseWriter, r *http.Request) (Principal, string, error) {
+	cookie, err := r.Cookie(loginCookie)
+	if err != nil {
+		return Principal{}, "", fmt.Errorf("no login in progress")
+	}
+	http.SetCookie(w, o.cookie(loginCookie, "", -1))
+	l := login{}
+	if err := o.open(cookie.Value, &l); err != nil || o.now().Unix() > l.Expires {
+		return Principal{}, "", fmt.Errorf("login expired")
+	}
+	query := r.URL.Query()
+	if query.Get("state") != l.State {
+		return Principal{}, "", fmt.Errorf("state does not match")
+	}
+	if idpError := query.Get("error"); idpError != "" {
+		return Principal{}, "", fmt.Errorf("identity provider: %s %s", idpError, query.Get("error_description"))
+	}
+
+	idToken, err := o.exchange(query.Get("code"), l.Verifier)
+	if err != nil {
+		return Principal{}, "", err
+	}
+	principal, err := o.verifyIDToken(idToken, l.Nonce)
+	if err != nil {
+		return Principal{}, "", err
+	}
+	value, err := o.seal(session{Name: principal.Name, Role: principal.Role, Expires: o.now().Add(o.sessionTTL).Unix()})
+	if err != nil {
+		return Principal{}, "", err
+	}
+	http.SetCookie(w, o.cookie(SessionCookie, value, o.sessionTTL))
+	return principal, l.Redirect, nil
+}
+
+// Logout - ends the session
+func (o *OIDC) Logout(w http.ResponseWriter) {
+	http.SetCookie(w, o.cookie(SessionCookie, "", -1))
+}
+
+// exchange - the ID token issued for authorization code `code`
+func (o *OIDC) exchange(code, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {o.config.RedirectURL},
+		"code_verifier": {verifier},
+		"client_id":     {o.config.ClientID},
+	}
+	request, err := http.NewRequest(http.MethodPost, o.provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if o.config.ClientSecret != "" {
+		request.SetBasicAuth(url.QueryEscape(o.config.ClientID), url.QueryEscape(o.config.ClientSecret))
+	}
+	response, err := o.client.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("oidc: token request: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("oidc: token request: status %d", response.StatusCode)
+	}
+	token := struct {
+		IDToken string `json:"id_token"`
+	}{}
+	if err := json.NewDecoder(response.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("oidc: token response: %w", err)
+	}
+	if token.IDToken == "" {
+		return "", fmt.Errorf("oidc: token response has no id_token")
+	}
+	return token.IDToken, nil
+}
+
+// verifyIDToken - the user of ID token `idToken`, checking its signature, issuer, audience,
+// expiry and, when not empty, `nonce`
+func (o *OIDC) verifyIDToken(idToken, nonce string) (Principal, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			if _, ok := token.Method.(*jwt.SigningMethodRSAPSS); !ok {
+				return nil, fmt.Errorf("unsupported alg %v", token.Header["alg"])
+			}
+		}
+		kid, _ := token.Header["kid"].(string)
+		return o.key(kid)
+	})
+	if err != nil {
+		return Principal{}, fmt.Errorf("oidc: invalid id token: %w", err)
+	}
+	if issuer, _ := claims["iss"].(string); issuer != o.config.Issuer {
+		return Principal{}, fmt.Errorf("oidc: id token issuer %q is not %q", issuer, o.config.Issuer)
+	}
+	if !hasAudience(claims["aud"], o.config.ClientID) {
+		return Principal{}, fmt.Errorf("oidc: id token not issued to %q", o.config.ClientID)
+	}
+	if _, ok := claims["exp"]; !ok {
+		return Principal{}, fmt.Errorf("oidc: id token has no exp")
+	}
+	if nonce != "" && claims["nonce"] != nonce {
+		return Principal{}, fmt.Errorf("oidc: id token nonce does not match")
+	}
+
+	name, _ := claims[o.config.UsernameClaim].(string)
+	if name == "" {
+		name, _ = claims["sub"].(string)
+	}
+	role := Role(0)
+	for _, group := range stringsAt(claims, o.config.RolesClaim) {
+		if groupRole := o.config.Roles[group]; groupRole > role {
+			role = groupRole
+		}
+	}
+	if role == 0 {
+		return Principal{}, fmt.Errorf("oidc: %s has none of the configured roles", name)
+	}
+	return Principal{Name: name, Role: role, Method: MethodOIDC}, nil
+}
+
+func hasAudience(aud interface{}, clientID string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == clientID
+	case []interface{}:
+		for _, a := range aud {
+			if a == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// stringsAt - the strings at dotted `path` in `claims`, a single string or a list
+func stringsAt(claims map[string]interface{}, path string) []string {
+	var value interface{} = claims
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+	switch value := value.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		values := []string{}
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// key - the identity provider's key `kid`, refetching its keys if the key is new
+func (o *OIDC) key(kid string) (*rsa.PublicKey, error) {
+	o.keysLock.Lock()
+	key, ok := o.keys[kid]
+	stale := o.now().Sub(o.keysFetched) > jwksRefreshInterval
+	o.keysLock.Unlock()
+	if ok {
+		return key, nil
+	}
+	if stale {
+		if err := o.refreshKeys(); err != nil {
+			return nil, err
+		}
+		o.keysLock.Lock()
+		key, ok = o.keys[kid]
+		o.keysLock.Unlock()
+		if ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown kid %q", kid)
+}
+
+func (o *OIDC) refreshKeys() error {
+	jwks := authentication.JWKS{}
+	if err := o.getJSON(o.provider.JwksURI, &jwks); err != nil {
+		return err
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	o.keysLock.Lock()
+	defer o.keysLock.Unlock()
+	o.keys = keys
+	o.keysFetched = o.now()
+	return nil
+}
+
+func (o *OIDC) getJSON(url string, v interface{}) error {
+	response, err := o.client.Get(url)
+	if err != nil {
+		return fmt.Errorf("oidc: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: GET %s: status %d", url, response.StatusCode)
+	}
+	if err := json.NewDecoder(response.Body).Decode(v); err != nil {
+		return fmt.Errorf("oidc: GET %s: %w", url, err)
+	}
+	return nil
+}
+
+func (o *OIDC) cookie(name, value string, maxAge time.Duration) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   int(maxAge.Seconds()),
+	}
+	if maxAge < 0 {
+		cookie.MaxAge = -1
+	}
+	return cookie
+}
+
+// seal - `v` as JSON with an HMAC, so that it cannot be changed by the browser
+func (o *OIDC) seal(v interface{}) (string, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(o.mac(encoded)), nil
+}
+
+// open - reads `value` made by seal into `v`
+func (o *OIDC) open(value string, v interface{}) error {
+	parts := strings.Split(value, ".")
+	if len(parts) != 2 {
+		return ErrInvalidCredentials
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(mac, o.mac(parts[0])) {
+		return ErrInvalidCredentials
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return ErrInvalidCredentials
+	}
+	return json.Unmarshal(payload, v)
+}
+
+func (o *OIDC) mac(encoded string) []byte {
+	mac := hmac.New(sha256.New, o.sessionKey)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
+
+func randomString() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,212 @@
+package access
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/test"
+)
+
+// identityProvider - a fake OpenID Connect identity provider issuing ID tokens with `claims`
+type identityProvider struct {
+	*httptest.Server
+	key    *rsa.PrivateKey
+	claims jwt.MapClaims
+	code   string
+}
+
+func newIdentityProvider(t *testing.T) *identityProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	test.NewRequire(t).NoError(err)
+	idp := &identityProvider{key: key, code: "auth-code"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/authorize",
+			"token_endpoint":         idp.URL + "/token",
+			"jwks_uri":               idp.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "idp-key",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != idp.code || r.FormValue("code_verifier") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": idp.idToken(idp.claims)})
+	})
+	idp.Server = httptest.NewServer(mux)
+	return idp
+}
+
+func (idp *identityProvider) idToken(claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "idp-key"
+	signed, err := token.SignedString(idp.key)
+	if err != nil {
+		panic(err)
+	}
+	return signed
+}
+
+func (idp *identityProvider) config() OIDCConfig {
+	return OIDCConfig{
+		Issuer:      idp.URL,
+		ClientID:    "fcs",
+		RedirectURL: "https://fcs.example.com/auth/callback",
+		RolesClaim:  "realm_access.roles",
+		Roles:       map[string]Role{"fcs-viewers": Viewer, "fcs-admins": Admin},
+	}
+}
+
+func (idp *identityProvider) validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":                idp.URL,
+		"aud":                []string{"fcs", "account"},
+		"sub":                "f7c3",
+		"preferred_username": "alice",
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"realm_access":       map[string]interface{}{"roles": []string{"fcs-viewers", "fcs-admins", "offline_access"}},
+	}
+}
+
+func TestOIDCLogin(t *testing.T) {
+	require := test.NewRequire(t)
+	idp := newIdentityProvider(t)
+	defer idp.Close()
+	oidc, err := NewOIDC(idp.config())
+	require.NoError(err)
+
+	recorder := httptest.NewRecorder()
+	authorizationURL, err := oidc.Login(recorder, "/runs")
+	require.NoError(err)
+	authorization, err := url.Parse(authorizationURL)
+	require.NoError(err)
+	query := authorization.Query()
+	require.Equal(idp.URL+"/authorize", authorization.Scheme+"://"+authorization.Host+authorization.Path)
+	require.Equal("code", query.Get("response_type"))
+	require.Equal("S256", query.Get("code_challenge_method"))
+
+	idp.claims = idp.validClaims()
+	idp.claims["nonce"] = query.Get("nonce")
+	callback := httptest.NewRequest(http.MethodGet, "/auth/callback?code=auth-code&state="+query.Get("state"), nil)
+	for _, cookie := range recorder.Result().Cookies() {
+		callback.AddCookie(cookie)
+	}
+	recorder = httptest.NewRecorder()
+	principal, redirect, err := oidc.Callback(recorder, callback)
+	require.NoError(err)
+	require.Equal(Principal{Name: "alice", Role: Admin, Method: MethodOIDC}, principal)
+	require.Equal("/runs", redirect)
+
+	request := httptest.NewRequest(http.MethodGet, "/api/runs", nil)
+	for _, cookie := range recorder.Result().Cookies() {
+		if cookie.Name == SessionCookie {
+			request.AddCookie(cookie)
+		}
+	}
+	principal, err = oidc.Authenticate(request)
+	require.NoError(err)
+	require.Equal(Principal{Name: "alice", Role: Admin, Method: MethodOIDC}, principal)
+}
+
+func TestIsLocalPath(t *testing.T) {
+	require := test.NewRequire(t)
+
+	require.True(isLocalPath("/runs"))
+	require.True(isLocalPath("/runs?status=failed"))
+	require.False(isLocalPath("https://evil.example.com"))
+	require.False(isLocalPath("//evil.example.com"))
+	require.False(isLocalPath("/\\evil.example.com"))
+	require.False(isLocalPath("/\t/evil.example.com"))
+	require.False(isLocalPath("runs"))
+}
+
+func TestOIDCCallbackRejectsWrongState(t *testing.T) {
+	require := test.NewRequire(t)
+	idp := newIdentityProvider(t)
+	defer idp.Close()
+	oidc, err := NewOIDC(idp.config())
+	require.NoError(err)
+
+	recorder := httptest.NewRecorder()
+	_, err = oidc.Login(recorder, "https://evil.example.com")
+	require.NoError(err)
+	callback := httptest.NewRequest(http.MethodGet, "/auth/callback?code=auth-code&state=forged", nil)
+	for _, cookie := range recorder.Result().Cookies() {
+		callback.AddCookie(cookie)
+	}
+	_, _, err = oidc.Callback(httptest.NewRecorder(), callback)
+	require.EqualError(err, "state does not match")
+}
+
+func TestOIDCBearerIDToken(t *testing.T) {
+	require := test.NewRequire(t)
+	idp := newIdentityProvider(t)
+	defer idp.Close()
+	oidc, err := NewOIDC(idp.config())
+	require.NoError(err)
+
+	request := httptest.NewRequest(http.MethodGet, "/api/runs", nil)
+	request.Header.Set("Authorization", "Bearer "+idp.idToken(idp.validClaims()))
+	principal, err := oidc.Authenticate(request)
+	require.NoError(err)
+	require.Equal(Admin, principal.Role)
+
+	claims := idp.validClaims()
+	claims["aud"] = "another-client"
+	request.Header.Set("Authorization", "Bearer "+idp.idToken(claims))
+	_, err = oidc.Authenticate(request)
+	require.Equal(ErrInvalidCredentials, err)
+
+	claims = idp.validClaims()
+	claims["exp"] = time.Now().Add(-time.Minute).Unix()
+	request.Header.Set("Authorization", "Bearer "+idp.idToken(claims))
+	_, err = oidc.Authenticate(request)
+	require.Equal(ErrInvalidCredentials, err)
+
+	claims = idp.validClaims()
+	claims["realm_access"] = map[string]interface{}{"roles": []string{"offline_access"}}
+	_, err = oidc.verifyIDToken(idp.idToken(claims), "")
+	require.EqualError(err, "oidc: alice has none of the configured roles")
+}
+
+func TestOIDCRejectsTamperedSession(t *testing.T) {
+	require := test.NewRequire(t)
+	idp := newIdentityProvider(t)
+	defer idp.Close()
+	oidc, err := NewOIDC(idp.config())
+	require.NoError(err)
+
+	value, err := oidc.seal(session{Name: "bob", Role: Viewer, Expires: time.Now().Add(time.Hour).Unix()})
+	require.NoError(err)
+	forged, err := oidc.seal(session{Name: "bob", Role: Admin, Expires: time.Now().Add(time.Hour).Unix()})
+	require.NoError(err)
+	// the admin session's payload with the viewer session's MAC
+	tampered := strings.Split(forged, ".")[0] + "." + strings.Split(value, ".")[1]
+
+	request := httptest.NewRequest(http.MethodGet, "/api/runs", nil)
+	request.AddCookie(&http.Cookie{Name: SessionCookie, Value: tampered})
+	_, err = oidc.Authenticate(request)
+	require.Equal(ErrInvalidCredentials, err)
+}
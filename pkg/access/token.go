@@ -0,0 +1,71 @@
+package access
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Token - a static API token, for pipelines and the fcs CLI
+type Token struct {
+	Name string `json:"name"`
+	Role Role   `json:"role"`
+	// SHA256 - hex SHA-256 of the token, so that the configuration does not hold the token itself
+	SHA256 string `json:"sha256"`
+}
+
+type tokenAuthenticator struct {
+	tokens []Token
+	hashes [][]byte
+}
+
+// NewTokenAuthenticator - authenticates requests with one of `tokens` as a bearer token
+func NewTokenAuthenticator(tokens []Token) (Authenticator, error) {
+	hashes := make([][]byte, len(tokens))
+	for i, token := range tokens {
+		hash, err := hex.DecodeString(token.SHA256)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("token %q: sha256 must be 64 hex digits", token.Name)
+		}
+		if token.Role == 0 {
+			return nil, fmt.Errorf("token %q has no role", token.Name)
+		}
+		hashes[i] = hash
+	}
+	return tokenAuthenticator{tokens: tokens, hashes: hashes}, nil
+}
+
+func (a tokenAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	bearer, ok := bearerToken(r)
+	if !ok {
+		return Principal{}, ErrNoCredentials
+	}
+	hash := sha256.Sum256([]byte(bearer))
+	for i, token := range a.tokens {
+		if subtle.ConstantTimeCompare(hash[:], a.hashes[i]) == 1 {
+			return Principal{Name: token.Name, Role: token.Role, Method: MethodToken}, nil
+		}
+	}
+	if isJWT(bearer) {
+		// leave it to the OIDC authenticator
+		return Principal{}, ErrNoCredentials
+	}
+	return Principal{}, ErrInvalidCredentials
+}
+
+// bearerToken - the token in the `Authorization: Bearer` header of `r`
+func bearerToken(r *http.Request) (string, bool) {
+	const prefix = "bearer "
+	header := r.Header.Get("Authorization")
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(header[len(prefix):]), true
+}
+
+func isJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
@@ -19,15 +19,17 @@ import (
 type Certificate interface {
 	PublicKey() *rsa.PublicKey
 	PrivateKey() *rsa.PrivateKey
+	Signer() crypto.Signer
 	TLSCert() tls.Certificate
 	DN() (string, string, string, error)
 	SignatureIssuer(bool) (string, error)
+	X509() (*x509.Certificate, error)
 }
 
 // certificate implements Certificate
 type certificate struct {
 	publicKey     *rsa.PublicKey
-	privateKey    *rsa.PrivateKey
+	signer        crypto.Signer
 	tlsCert       tls.Certificate
 	publicCertPem []byte
 }
@@ -36,38 +38,62 @@ type certificate struct {
 //
 // Parameters:
 // * publicKeyPem=PEM encoded public key.
-// * privateKeyPem=PEM encoded private key.
+// * privateKey=PEM encoded private key, or a reference to a key loaded by a KeyProvider.
 //
 // Returns Certificate, or nil with error set if something is invalid.
-func NewCertificate(publicKeyPem, privateKeyPem string) (Certificate, error) {
+func NewCertificate(publicKeyPem, privateKey string) (Certificate, error) {
 	publicKey, err := jwt.ParseRSAPublicKeyFromPEM([]byte(publicKeyPem))
 	if err != nil {
 		return nil, fmt.Errorf("error with public key: %w", err)
 	}
 	publicPem := []byte(publicKeyPem)
 
-	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(privateKeyPem))
+	signer, err := signerFor(privateKey, publicKey)
 	if err != nil {
 		return nil, fmt.Errorf("error with private key: %w", err)
 	}
 
-	tlsCert, err := tls.X509KeyPair([]byte(publicKeyPem), []byte(privateKeyPem))
+	tlsCert, err := tlsCertificate(publicPem, signer)
 	if err != nil {
-		logrus.StandardLogger().Warnln("tls.X509KeyPair, err=", err)
+		logrus.StandardLogger().Warnln("tlsCertificate, err=", err)
 	}
 
-	if err := validateKeys(publicKey, privateKey); err != nil {
+	if err := validateKeys(publicKey, signer); err != nil {
 		return nil, err
 	}
 
 	return &certificate{
 		publicKey:     publicKey,
-		privateKey:    privateKey,
+		signer:        signer,
 		tlsCert:       tlsCert,
 		publicCertPem: publicPem,
 	}, nil
 }
 
+// tlsCertificate - the client certificate for mTLS, with the private key used through `signer`
+func tlsCertificate(publicPem []byte, signer crypto.Signer) (tls.Certificate, error) {
+	cert := tls.Certificate{PrivateKey: signer}
+	for block, rest := pem.Decode(publicPem); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type == "CERTIFICATE" {
+			cert.Certificate = append(cert.Certificate, block.Bytes)
+		}
+	}
+	if len(cert.Certificate) == 0 {
+		return tls.Certificate{}, errors.New("no CERTIFICATE found in public key PEM")
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	leafKey, _ := leaf.PublicKey.(*rsa.PublicKey)
+	signerKey, _ := signer.Public().(*rsa.PublicKey)
+	if !publicKeysEqual(leafKey, signerKey) {
+		return tls.Certificate{}, errors.New("private key does not match public key")
+	}
+	cert.Leaf = leaf
+	return cert, nil
+}
+
 // creates a certificate from only the public key, in the case of the aspsp public cert to validate signatures
 func NewPublicCertificate(publicKeyPem string) (Certificate, error) {
 	publicKey, err := jwt.ParseRSAPublicKeyFromPEM([]byte(publicKeyPem))
@@ -86,15 +112,22 @@ func (c certificate) PublicKey() *rsa.PublicKey {
 	return c.publicKey
 }
 
+// PrivateKey - the private key when it was configured as PEM, nil for keys held by other providers.
+// Use Signer to sign.
 func (c certificate) PrivateKey() *rsa.PrivateKey {
-	return c.privateKey
+	key, _ := c.signer.(*rsa.PrivateKey)
+	return key
+}
+
+func (c certificate) Signer() crypto.Signer {
+	return c.signer
 }
 
 func (c certificate) TLSCert() tls.Certificate {
 	return c.tlsCert
 }
 
-func validateKeys(publicKey *rsa.PublicKey, privateKey *rsa.PrivateKey) error {
+func validateKeys(publicKey *rsa.PublicKey, signer crypto.Signer) error {
 	// validate public and private key pair
 	// see:
 	// * https://stackoverflow.com/questions/20655702/signing-and-decoding-with-rsa-sha-in-go
@@ -102,7 +135,7 @@ func validateKeys(publicKey *rsa.PublicKey, privateKey *rsa.PrivateKey) error {
 	plaintext := []byte(`date: Thu, 05 Jan 2012 21:31:40 GMT`)
 
 	hashed := sha256.Sum256(plaintext)
-	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, hashed[:])
+	signature, err := signer.Sign(rand.Reader, hashed[:], crypto.SHA256)
 	if err != nil {
 		return fmt.Errorf("error signing: %w", err)
 	}
@@ -147,9 +180,17 @@ func (c certificate) SignatureIssuer(tpp bool) (string, error) {
 
 }
 
-func (c certificate) nameComponents() (string, string, string, string, error) {
+// X509 - the parsed public certificate, which is not available when only a public key was provided
+func (c certificate) X509() (*x509.Certificate, error) {
 	cpb, _ := pem.Decode(c.publicCertPem)
-	crt, err := x509.ParseCertificate(cpb.Bytes)
+	if cpb == nil {
+		return nil, errors.New("public certificate is not PEM encoded")
+	}
+	return x509.ParseCertificate(cpb.Bytes)
+}
+
+func (c certificate) nameComponents() (string, string, string, string, error) {
+	crt, err := c.X509()
 	if err != nil {
 		logrus.Errorf("cannot parse cert %s", err.Error())
 		return "", "", "", "", err
@@ -12,4 +12,5 @@ const (
 const (
 	GrantType                  = "grant_type"
 	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
 )
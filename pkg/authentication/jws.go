@@ -26,14 +26,14 @@ var SigningMethodPS256 = &jwt.SigningMethodRSAPSS{
 	},
 }
 
-var b64Status bool // for report export
-
+// GetSigningAlg - the signing method for `alg`. The methods sign with a Certificate's Signer,
+// so keys held by any KeyProvider can be used.
 func GetSigningAlg(alg string) (jwt.SigningMethod, error) {
 	switch strings.ToUpper(alg) {
 	case "PS256":
-		return SigningMethodPS256, nil
+		return signerMethod{SigningMethod: SigningMethodPS256, opts: SigningMethodPS256.Options}, nil
 	case "RS256":
-		return jwt.SigningMethodRS256, nil
+		return signerMethod{SigningMethod: jwt.SigningMethodRS256, opts: crypto.SHA256}, nil
 	case "NONE":
 		fallthrough
 	default:
@@ -205,8 +205,7 @@ func ModifyJWSHeaders(jws string, ctx ContextInterface, opts ...JWSHeaderOpt) (s
 		return "", fmt.Errorf("failed to modify JWS: %w", err)
 	}
 
-	privKey := cert.PrivateKey()
-	tokenString, err := CreateSignature(token, privKey, string(body), b64Encoded)
+	tokenString, err := CreateSignature(token, cert.Signer(), string(body), b64Encoded)
 	if err != nil {
 		return "", fmt.Errorf("failed to modify JWS: %w", err)
 	}
@@ -258,7 +257,7 @@ func NewJWSSignature(requestBody string, ctx ContextInterface, alg jwt.SigningMe
 		return "", fmt.Errorf("NewJWSSignature: cannot GetB64Encoding: %w", err)
 	}
 
-	return buildSignature(b64encoding, tppSignatureKID, tppSignatureIssuer, tppSignatureTAN, minifiedBody, alg, cert.PrivateKey())
+	return buildSignature(b64encoding, tppSignatureKID, tppSignatureIssuer, tppSignatureTAN, minifiedBody, alg, cert.Signer())
 }
 
 func legacyIssuerFromCert(cert Certificate) (string, error) {
@@ -302,7 +301,6 @@ func getB64Encoding(paymentVersion string) (bool, error) {
 	case "v3.1.5":
 		fallthrough
 	case "v3.1.4":
-		setB64Status(true) // record setting for report
 		return true, nil
 	case "v3.1.3":
 		fallthrough
@@ -379,11 +377,3 @@ func after(value string, a string) string {
 	}
 	return value[adjustedPos:]
 }
-
-func setB64Status(status bool) {
-	b64Status = status
-}
-
-func GetB64Status() bool {
-	return b64Status
-}
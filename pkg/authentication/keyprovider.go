@@ -0,0 +1,92 @@
+package authentication
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// KeyProvider - loads the private key that goes with a certificate. The configured private key
+// value (`signing_private`, `transport_private`) selects the provider: a PEM key, or a reference
+// to a key held elsewhere such as a PKCS#11 URI. Keys that cannot leave a token are only ever
+// used through crypto.Signer.
+type KeyProvider interface {
+	// Supports returns true if `privateKey` is a value this provider can load
+	Supports(privateKey string) bool
+	// Signer returns the key for `privateKey`, which must be the private half of `publicKey`
+	Signer(privateKey string, publicKey *rsa.PublicKey) (crypto.Signer, error)
+}
+
+var errNoKeyProvider = errors.New("no key provider supports the private key, expected a PEM encoded key or a pkcs11: URI")
+
+var (
+	keyProvidersLock sync.RWMutex
+	keyProviders     = []KeyProvider{NewPKCS11KeyProvider("", ""), PEMKeyProvider()}
+)
+
+// SetKeyProviders - replaces the key providers used by NewCertificate, first match wins
+func SetKeyProviders(providers ...KeyProvider) {
+	keyProvidersLock.Lock()
+	defer keyProvidersLock.Unlock()
+	keyProviders = providers
+}
+
+func signerFor(privateKey string, publicKey *rsa.PublicKey) (crypto.Signer, error) {
+	keyProvidersLock.RLock()
+	providers := keyProviders
+	keyProvidersLock.RUnlock()
+
+	for _, provider := range providers {
+		if provider.Supports(privateKey) {
+			return provider.Signer(privateKey, publicKey)
+		}
+	}
+	return nil, errNoKeyProvider
+}
+
+type pemKeyProvider struct{}
+
+// PEMKeyProvider - keys pasted into the configuration as PEM. It accepts any value so that
+// parse errors are reported against the PEM, and goes last in the list of providers.
+func PEMKeyProvider() KeyProvider {
+	return pemKeyProvider{}
+}
+
+func (pemKeyProvider) Supports(string) bool {
+	return true
+}
+
+func (pemKeyProvider) Signer(privateKey string, _ *rsa.PublicKey) (crypto.Signer, error) {
+	return jwt.ParseRSAPrivateKeyFromPEM([]byte(privateKey))
+}
+
+// signerMethod - a jwt.SigningMethod that signs with any crypto.Signer, as jwt-go only accepts
+// *rsa.PrivateKey. Verification is left to the wrapped method.
+type signerMethod struct {
+	jwt.SigningMethod
+	opts crypto.SignerOpts
+}
+
+func (m signerMethod) Sign(signingString string, key interface{}) (string, error) {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+
+	hasher := m.opts.HashFunc().New()
+	hasher.Write([]byte(signingString))
+	signature, err := signer.Sign(rand.Reader, hasher.Sum(nil), m.opts)
+	if err != nil {
+		return "", fmt.Errorf("signerMethod: %s signing failed: %w", m.Alg(), err)
+	}
+	return jwt.EncodeSegment(signature), nil
+}
+
+func publicKeysEqual(a, b *rsa.PublicKey) bool {
+	return a != nil && b != nil && a.E == b.E && a.N.Cmp(b.N) == 0
+}
@@ -0,0 +1,178 @@
+package authentication
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const pkcs11Scheme = "pkcs11:"
+
+// pkcs11URI - the parts of an RFC 7512 PKCS#11 URI used to find a key, e.g.
+// pkcs11:token=fcs;object=signing?module-path=/usr/lib/softhsm/libsofthsm2.so&pin-value=1234
+type pkcs11URI struct {
+	Module string
+	Token  string
+	Slot   *int
+	Object string
+	ID     []byte
+	PIN    string
+}
+
+func parsePKCS11URI(ref string) (pkcs11URI, error) {
+	if !strings.HasPrefix(ref, pkcs11Scheme) {
+		return pkcs11URI{}, fmt.Errorf("pkcs11: %q is not a pkcs11: URI", ref)
+	}
+	path, query := strings.TrimPrefix(ref, pkcs11Scheme), ""
+	if i := strings.Index(path, "?"); i >= 0 {
+		path, query = path[:i], path[i+1:]
+	}
+
+	uri := pkcs11URI{}
+	for _, attribute := range strings.Split(path, ";") {
+		if attribute == "" {
+			continue
+		}
+		parts := strings.SplitN(attribute, "=", 2)
+		if len(parts) != 2 {
+			return pkcs11URI{}, fmt.Errorf("pkcs11: invalid attribute %q", attribute)
+		}
+		value, err := url.PathUnescape(parts[1])
+		if err != nil {
+			return pkcs11URI{}, fmt.Errorf("pkcs11: invalid %s: %w", parts[0], err)
+		}
+		switch parts[0] {
+		case "token":
+			uri.Token = value
+		case "object":
+			uri.Object = value
+		case "id":
+			uri.ID = []byte(value)
+		case "slot-id":
+			slot, err := strconv.Atoi(value)
+			if err != nil {
+				return pkcs11URI{}, fmt.Errorf("pkcs11: invalid slot-id %q", value)
+			}
+			uri.Slot = &slot
+		}
+	}
+
+	for _, attribute := range strings.Split(query, "&") {
+		parts := strings.SplitN(attribute, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value, err := url.QueryUnescape(parts[1])
+		if err != nil {
+			return pkcs11URI{}, fmt.Errorf("pkcs11: invalid %s: %w", parts[0], err)
+		}
+		switch parts[0] {
+		case "module-path":
+			uri.Module = value
+		case "pin-value":
+			uri.PIN = value
+		}
+	}
+
+	if uri.Token == "" && uri.Slot == nil {
+		return pkcs11URI{}, fmt.Errorf("pkcs11: %q needs a token or slot-id", ref)
+	}
+	if uri.Object == "" && len(uri.ID) == 0 {
+		return pkcs11URI{}, fmt.Errorf("pkcs11: %q needs an object or id", ref)
+	}
+	return uri, nil
+}
+
+// pkcs11Session - an open token, see openPKCS11Session
+type pkcs11Session interface {
+	FindSigner(id, label []byte) (crypto.Signer, error)
+}
+
+type pkcs11KeyProvider struct {
+	module string
+	pin    string
+
+	lock     sync.Mutex
+	sessions map[string]pkcs11Session
+	open     func(uri pkcs11URI) (pkcs11Session, error)
+}
+
+// NewPKCS11KeyProvider - keys held on a PKCS#11 token such as an HSM, or SoftHSM for local testing.
+// The private key is configured as a pkcs11: URI. `module` and `pin` are used when the URI does not
+// have a module-path or pin-value, so the PIN does not need to be part of the configuration.
+func NewPKCS11KeyProvider(module, pin string) KeyProvider {
+	return &pkcs11KeyProvider{
+		module:   module,
+		pin:      pin,
+		sessions: map[string]pkcs11Session{},
+		open:     openPKCS11Session,
+	}
+}
+
+func (p *pkcs11KeyProvider) Supports(privateKey string) bool {
+	return strings.HasPrefix(strings.TrimSpace(privateKey), pkcs11Scheme)
+}
+
+func (p *pkcs11KeyProvider) Signer(privateKey string, publicKey *rsa.PublicKey) (crypto.Signer, error) {
+	uri, err := parsePKCS11URI(strings.TrimSpace(privateKey))
+	if err != nil {
+		return nil, err
+	}
+	if uri.Module == "" {
+		uri.Module = p.module
+	}
+	if uri.PIN == "" {
+		uri.PIN = p.pin
+	}
+	if uri.Module == "" {
+		return nil, fmt.Errorf("pkcs11: no module configured, set module-path in the URI or start the server with --pkcs11_module")
+	}
+
+	session, err := p.session(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	var label []byte
+	if uri.Object != "" {
+		label = []byte(uri.Object)
+	}
+	signer, err := session.FindSigner(uri.ID, label)
+	if err != nil {
+		return nil, fmt.Errorf("pkcs11: finding key: %w", err)
+	}
+	if signer == nil {
+		return nil, fmt.Errorf("pkcs11: no key found for object %q id %q", uri.Object, uri.ID)
+	}
+	public, ok := signer.Public().(*rsa.PublicKey)
+	if !ok || !publicKeysEqual(public, publicKey) {
+		return nil, fmt.Errorf("pkcs11: key for object %q id %q does not match the certificate", uri.Object, uri.ID)
+	}
+	return signer, nil
+}
+
+// session - tokens are opened once and shared, as loading a module is expensive and
+// certificates are created from the configuration for each request that is signed
+func (p *pkcs11KeyProvider) session(uri pkcs11URI) (pkcs11Session, error) {
+	slot := ""
+	if uri.Slot != nil {
+		slot = strconv.Itoa(*uri.Slot)
+	}
+	key := strings.Join([]string{uri.Module, uri.Token, slot, uri.PIN}, "|")
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if session, ok := p.sessions[key]; ok {
+		return session, nil
+	}
+	session, err := p.open(uri)
+	if err != nil {
+		return nil, fmt.Errorf("pkcs11: opening token: %w", err)
+	}
+	p.sessions[key] = session
+	return session, nil
+}
@@ -0,0 +1,171 @@
+package authentication
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePKCS11Session struct {
+	keys map[string]*rsa.PrivateKey
+}
+
+func (s fakePKCS11Session) FindSigner(id, label []byte) (crypto.Signer, error) {
+	if key, ok := s.keys[string(label)]; ok {
+		return key, nil
+	}
+	return nil, nil
+}
+
+func newFakePKCS11Provider(keys map[string]*rsa.PrivateKey) (*pkcs11KeyProvider, *[]pkcs11URI) {
+	opened := []pkcs11URI{}
+	provider := NewPKCS11KeyProvider("/usr/lib/softhsm/libsofthsm2.so", "1234").(*pkcs11KeyProvider)
+	provider.open = func(uri pkcs11URI) (pkcs11Session, error) {
+		opened = append(opened, uri)
+		return fakePKCS11Session{keys: keys}, nil
+	}
+	return provider, &opened
+}
+
+func selfSignedCertificate(t *testing.T, key *rsa.PrivateKey) string {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "pkcs11"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestParsePKCS11URI(t *testing.T) {
+	uri, err := parsePKCS11URI("pkcs11:token=fcs;object=signing%20key?module-path=/usr/lib/softhsm/libsofthsm2.so&pin-value=1234")
+	require.NoError(t, err)
+	assert.Equal(t, pkcs11URI{
+		Module: "/usr/lib/softhsm/libsofthsm2.so",
+		Token:  "fcs",
+		Object: "signing key",
+		PIN:    "1234",
+	}, uri)
+
+	uri, err = parsePKCS11URI("pkcs11:slot-id=2;id=%01%02")
+	require.NoError(t, err)
+	require.NotNil(t, uri.Slot)
+	assert.Equal(t, 2, *uri.Slot)
+	assert.Equal(t, []byte{1, 2}, uri.ID)
+
+	for _, invalid := range []string{
+		"token=fcs;object=signing",
+		"pkcs11:object=signing",
+		"pkcs11:token=fcs",
+		"pkcs11:token=fcs;object",
+		"pkcs11:slot-id=one;object=signing",
+	} {
+		_, err := parsePKCS11URI(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestPKCS11KeyProviderSigner(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	provider, opened := newFakePKCS11Provider(map[string]*rsa.PrivateKey{"signing": key})
+
+	assert.True(t, provider.Supports(" pkcs11:token=fcs;object=signing"))
+	assert.False(t, provider.Supports(privateCertValid))
+
+	signer, err := provider.Signer("pkcs11:token=fcs;object=signing", &key.PublicKey)
+	require.NoError(t, err)
+	assert.Equal(t, key, signer)
+	_, err = provider.Signer("pkcs11:token=fcs;object=signing", &key.PublicKey)
+	require.NoError(t, err)
+
+	require.Len(t, *opened, 1, "the token is opened once")
+	assert.Equal(t, "/usr/lib/softhsm/libsofthsm2.so", (*opened)[0].Module)
+	assert.Equal(t, "1234", (*opened)[0].PIN)
+
+	_, err = provider.Signer("pkcs11:token=fcs;object=missing", &key.PublicKey)
+	assert.EqualError(t, err, `pkcs11: no key found for object "missing" id ""`)
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, err = provider.Signer("pkcs11:token=fcs;object=signing", &other.PublicKey)
+	assert.EqualError(t, err, `pkcs11: key for object "signing" id "" does not match the certificate`)
+}
+
+func TestPKCS11KeyProviderNeedsModule(t *testing.T) {
+	provider := NewPKCS11KeyProvider("", "")
+
+	_, err := provider.Signer("pkcs11:token=fcs;object=signing", nil)
+
+	assert.EqualError(t, err, "pkcs11: no module configured, set module-path in the URI or start the server with --pkcs11_module")
+}
+
+func TestPKCS11KeyProviderOpenError(t *testing.T) {
+	provider := NewPKCS11KeyProvider("/usr/lib/softhsm/libsofthsm2.so", "").(*pkcs11KeyProvider)
+	provider.open = func(pkcs11URI) (pkcs11Session, error) {
+		return nil, errors.New("CKR_PIN_INCORRECT")
+	}
+
+	_, err := provider.Signer("pkcs11:token=fcs;object=signing", nil)
+
+	assert.EqualError(t, err, "pkcs11: opening token: CKR_PIN_INCORRECT")
+}
+
+func TestNewCertificateWithPKCS11Key(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	provider, _ := newFakePKCS11Provider(map[string]*rsa.PrivateKey{"signing": key})
+	SetKeyProviders(provider, PEMKeyProvider())
+	defer SetKeyProviders(NewPKCS11KeyProvider("", ""), PEMKeyProvider())
+
+	cert, err := NewCertificate(selfSignedCertificate(t, key), "pkcs11:token=fcs;object=signing")
+	require.NoError(t, err)
+
+	assert.Equal(t, key, cert.Signer())
+	assert.Equal(t, key, cert.TLSCert().PrivateKey)
+	require.NotNil(t, cert.TLSCert().Leaf)
+	assert.Equal(t, "pkcs11", cert.TLSCert().Leaf.Subject.CommonName)
+}
+
+func TestNewCertificateWithoutKeyProvider(t *testing.T) {
+	SetKeyProviders(NewPKCS11KeyProvider("", ""))
+	defer SetKeyProviders(NewPKCS11KeyProvider("", ""), PEMKeyProvider())
+
+	_, err := NewCertificate(publicCertValid, privateCertValid)
+
+	assert.EqualError(t, err, "error with private key: "+errNoKeyProvider.Error())
+}
+
+func TestSignerMethodVerifiesWithJWTMethod(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	for _, alg := range []string{"PS256", "RS256"} {
+		method, err := GetSigningAlg(alg)
+		require.NoError(t, err)
+
+		token := jwt.NewWithClaims(method, jwt.MapClaims{"iss": "fcs"})
+		signed, err := token.SignedString(crypto.Signer(key))
+		require.NoError(t, err, alg)
+
+		parsed, err := jwt.Parse(signed, func(*jwt.Token) (interface{}, error) {
+			return &key.PublicKey, nil
+		})
+		require.NoError(t, err, alg)
+		assert.True(t, parsed.Valid, alg)
+		assert.Equal(t, alg, parsed.Method.Alg())
+	}
+}
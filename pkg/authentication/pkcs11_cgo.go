@@ -0,0 +1,40 @@
+//go:build cgo
+// +build cgo
+
+package authentication
+
+import (
+	"crypto"
+
+	"github.com/ThalesIgnite/crypto11"
+)
+
+type crypto11Session struct {
+	context *crypto11.Context
+}
+
+func openPKCS11Session(uri pkcs11URI) (pkcs11Session, error) {
+	config := &crypto11.Config{
+		Path: uri.Module,
+		Pin:  uri.PIN,
+	}
+	// crypto11 selects a token by exactly one of slot or label
+	if uri.Slot != nil {
+		config.SlotNumber = uri.Slot
+	} else {
+		config.TokenLabel = uri.Token
+	}
+	context, err := crypto11.Configure(config)
+	if err != nil {
+		return nil, err
+	}
+	return crypto11Session{context: context}, nil
+}
+
+func (s crypto11Session) FindSigner(id, label []byte) (crypto.Signer, error) {
+	signer, err := s.context.FindKeyPair(id, label)
+	if err != nil || signer == nil {
+		return nil, err
+	}
+	return signer, nil
+}
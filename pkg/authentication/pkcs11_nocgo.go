@@ -0,0 +1,10 @@
+//go:build !cgo
+// +build !cgo
+
+package authentication
+
+import "errors"
+
+func openPKCS11Session(pkcs11URI) (pkcs11Session, error) {
+	return nil, errors.New("PKCS#11 keys need a binary built with CGO_ENABLED=1")
+}
@@ -1,7 +1,7 @@
 package authentication
 
 import (
-	"crypto/rsa"
+	"crypto"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -45,39 +45,11 @@ type JWK struct {
 }
 
 // ValidateSignature takes the signature JWT
-// and extracts the kid to lookup the public key in the JWKS
+// and extracts the kid to lookup the public key in the JWKS of the trust anchor,
+// which is located by the configured TrustAnchorResolver
 func ValidateSignature(jwtToken, body, jwksURI string, b64 bool) (bool, error) {
-	err := ValidateSignatureHeader(jwtToken, b64)
-	if err != nil {
-		return false, err
-	}
-
-	kid, err := getKidFromToken(jwtToken)
-	if err != nil {
-		return false, err
-	}
-
-	cert, err := getCertForKid(kid, jwksURI)
-	if err != nil {
-		return false, err
-	}
-
-	signature, err := insertBodyIntoJWT(jwtToken, body, b64) // b64claim
-	if err != nil {
-		logrus.Errorf("failed to insert body into signature message: %v", err)
-		return false, err
-	}
-	logrus.Trace("Signature with payload: " + signature)
-
-	verified, err := JWSVerify(signature, jwa.PS256, cert.PublicKey, b64)
-	if err != nil {
-		logrus.Errorf("failed to verify message: %v", err)
-		return false, err
-	}
-
-	logrus.Tracef("signed message verified! -> %s", verified)
-
-	return true, nil
+	report, err := VerifySignature(jwtToken, body, jwksURI, b64)
+	return report.Valid, err
 }
 
 // insertBodyB64False
@@ -120,7 +92,7 @@ func getKidFromToken(token string) (string, error) {
 
 // buildSignature - takes all the token parameters and assembles a detached header signed token string which is returned
 // Handles api versions v3.1.4 and above, v3.1.3 and prior, plus v3.0 which has a slightly different JWT header
-func buildSignature(b64 bool, kid, issuer, trustAnchor, body string, alg jwt.SigningMethod, privKey *rsa.PrivateKey) (string, error) {
+func buildSignature(b64 bool, kid, issuer, trustAnchor, body string, alg jwt.SigningMethod, privKey crypto.Signer) (string, error) {
 	var token jwt.Token
 
 	if b64 {
@@ -226,6 +198,19 @@ type signatureHeader struct {
 // ValidateSignatureHeader takes a token and performs the header validation
 // taking the b64 parameter value in consideration.
 func ValidateSignatureHeader(token string, b64 bool) error {
+	tokenHeader, err := decodeSignatureHeader(token)
+	if err != nil {
+		return err
+	}
+
+	dumpJSON(tokenHeader)
+
+	err = tokenHeader.validateSignatureHeader(b64) // validate header depent on b64 setting for api true=3.1.4, false=3.1.3
+
+	return err
+}
+
+func decodeSignatureHeader(token string) (signatureHeader, error) {
 	var tokenHeader signatureHeader
 
 	segments := strings.Split(token, ".")
@@ -235,14 +220,9 @@ func ValidateSignatureHeader(token string, b64 bool) error {
 
 	err := json.Unmarshal(decodedPayload, &tokenHeader)
 	if err != nil {
-		return fmt.Errorf("ValidateSignatureHeader: cannot convert header into JSON: " + err.Error())
+		return signatureHeader{}, fmt.Errorf("ValidateSignatureHeader: cannot convert header into JSON: " + err.Error())
 	}
-
-	dumpJSON(tokenHeader)
-
-	err = tokenHeader.validateSignatureHeader(b64) // validate header depent on b64 setting for api true=3.1.4, false=3.1.3
-
-	return err
+	return tokenHeader, nil
 }
 
 // Utility to Dump Json
@@ -258,6 +238,20 @@ func dumpJSON(i interface{}) {
 func (s signatureHeader) validateSignatureHeader(b64 bool) error {
 	dumpJSON(s)
 
+	if err := s.validateJOSEClaims(); err != nil {
+		return err
+	}
+	if err := s.validateCritical(b64); err != nil {
+		return err
+	}
+	if err := s.validateIssuedAt(); err != nil {
+		return err
+	}
+	return s.validateIssuer()
+}
+
+// validateJOSEClaims - the registered JOSE header parameters
+func (s signatureHeader) validateJOSEClaims() error {
 	if s.Type != "" { // Optional must be "JOSE" if present
 		if s.Type != "JOSE" {
 			return errInvalidSignatureClaim("typ", s.Type, "must equal 'JOSE' if present")
@@ -277,7 +271,11 @@ func (s signatureHeader) validateSignatureHeader(b64 bool) error {
 			return errInvalidSignatureClaim("cty", s.Ctype, "'json' or 'application/json'")
 		}
 	}
+	return nil
+}
 
+// validateCritical - the b64 and crit claims, which depend on the API version
+func (s signatureHeader) validateCritical(b64 bool) error {
 	if b64 { // version 3.1.4 and newer
 		if s.B64 != nil {
 			return fmt.Errorf("%w: b64 claim is set - must not be present for v3.1.4 and newer APIs", ErrInvalidSignatureHeader)
@@ -307,11 +305,19 @@ func (s signatureHeader) validateSignatureHeader(b64 bool) error {
 			return errInvalidSignatureClaim("crit", s.Critical, requiredElements)
 		}
 	}
+	return nil
+}
 
+func (s signatureHeader) validateIssuedAt() error {
 	if s.IssuedAt == decimal.Zero {
 		return errInvalidSignatureClaim("http://openbanking.org.uk/iat", s.IssuedAt.String(), "a JSON number representing time")
 	}
-	if s.TrustAnchor != "openbanking.org.uk" && !isHSBCTrustAnchor(s.TrustAnchor) { // allow trust anchors from OBIE HSBC
+	return nil
+}
+
+// validateIssuer - the trust anchor and the issuer it vouches for
+func (s signatureHeader) validateIssuer() error {
+	if !currentTrustAnchorResolver().Supports(s.TrustAnchor) { // OBIE, or an ASPSP specific trust anchor known to the resolver
 		return errInvalidSignatureClaim("http://openbanking.org.uk/tan", s.TrustAnchor, "openbanking.org.uk or ASPSP specific value")
 	}
 
@@ -319,7 +325,7 @@ func (s signatureHeader) validateSignatureHeader(b64 bool) error {
 		return errInvalidSignatureClaim("http://openbanking.org.uk/iss", s.Issuer, "non empty value")
 	}
 
-	if s.TrustAnchor == "openbanking.org.uk" { // only check when trust anchor is OBIE
+	if s.TrustAnchor == TrustAnchorOB { // only check when trust anchor is OBIE
 		if !checkSignatureIssuerASPSP(s.Issuer) {
 			return errInvalidSignatureClaim("http://openbanking.org.uk/iss", s.Issuer, "only the ORG-ID")
 		}
@@ -0,0 +1,229 @@
+package authentication
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/sirupsen/logrus"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/authentication/certificates"
+)
+
+// signatureIatSkewTolerance - how far `http://openbanking.org.uk/iat` can be from the time a response
+// is verified before the skew is reported
+const signatureIatSkewTolerance = 5 * time.Minute
+
+// Names of the steps in a SignatureReport
+const (
+	SignatureCheckHeader      = "protected header"
+	SignatureCheckCritical    = "b64/crit"
+	SignatureCheckIssuedAt    = "iat"
+	SignatureCheckTrustAnchor = "trust anchor"
+	SignatureCheckKid         = "kid lookup"
+	SignatureCheckChain       = "certificate chain"
+	SignatureCheckSignature   = "signature"
+)
+
+// SignatureCheck - the outcome of one step of x-jws-signature verification
+type SignatureCheck struct {
+	Name     string `json:"name"`
+	Pass     bool   `json:"pass"`
+	Advisory bool   `json:"advisory,omitempty"` // reported only, does not invalidate the signature
+	Detail   string `json:"detail,omitempty"`
+}
+
+// SignatureCertificate - summary of a certificate in the x5c chain of the signing key
+type SignatureCertificate struct {
+	Subject      string    `json:"subject"`
+	Issuer       string    `json:"issuer"`
+	SerialNumber string    `json:"serialNumber"`
+	NotBefore    time.Time `json:"notBefore"`
+	NotAfter     time.Time `json:"notAfter"`
+}
+
+// SignatureReport - what was checked when verifying an x-jws-signature, and what was found.
+// SignedPayload is the payload exactly as it was put into the JWS signing input, base64url encoded
+// when b64 is true and the raw response body otherwise.
+type SignatureReport struct {
+	Valid            bool                   `json:"valid"`
+	ProtectedHeader  map[string]interface{} `json:"protectedHeader,omitempty"`
+	B64              bool                   `json:"b64"`
+	Kid              string                 `json:"kid,omitempty"`
+	TrustAnchor      string                 `json:"trustAnchor,omitempty"`
+	Issuer           string                 `json:"issuer,omitempty"`
+	JWKSURI          string                 `json:"jwksUri,omitempty"`
+	IssuedAt         int64                  `json:"iat,omitempty"`
+	IatSkew          string                 `json:"iatSkew,omitempty"`
+	SignedPayload    string                 `json:"signedPayload,omitempty"`
+	CertificateChain []SignatureCertificate `json:"certificateChain,omitempty"`
+	Checks           []SignatureCheck       `json:"checks"`
+}
+
+func (r *SignatureReport) check(name string, err error) error {
+	r.Checks = append(r.Checks, newSignatureCheck(name, false, err))
+	return err
+}
+
+func (r *SignatureReport) advise(name string, err error) {
+	r.Checks = append(r.Checks, newSignatureCheck(name, true, err))
+}
+
+func newSignatureCheck(name string, advisory bool, err error) SignatureCheck {
+	check := SignatureCheck{Name: name, Pass: err == nil, Advisory: advisory}
+	if err != nil {
+		check.Detail = err.Error()
+	}
+	return check
+}
+
+// VerifySignature verifies a detached x-jws-signature over body and reports every step.
+// All header checks are made, so a report lists each problem with the header rather than the first.
+// The returned error is the first required check that failed, and is nil when the report is valid.
+func VerifySignature(jwtToken, body, jwksURI string, b64 bool) (SignatureReport, error) {
+	report := SignatureReport{B64: b64, JWKSURI: jwksURI}
+	now := time.Now()
+
+	header, err := decodeSignatureHeader(jwtToken)
+	if err != nil {
+		return report, report.check(SignatureCheckHeader, err)
+	}
+	report.ProtectedHeader = decodeProtectedHeader(jwtToken)
+	report.Kid = header.Kid
+	report.TrustAnchor = header.TrustAnchor
+	report.Issuer = header.Issuer
+	report.IssuedAt = header.IssuedAt.IntPart()
+	dumpJSON(header)
+
+	var firstErr error
+	required := func(name string, err error) {
+		if report.check(name, err) != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	required(SignatureCheckHeader, header.validateJOSEClaims())
+	required(SignatureCheckCritical, header.validateCritical(b64))
+	if err := header.validateIssuedAt(); err != nil {
+		required(SignatureCheckIssuedAt, err)
+	} else {
+		skew := now.Sub(time.Unix(report.IssuedAt, 0)).Round(time.Second)
+		report.IatSkew = skew.String()
+		report.advise(SignatureCheckIssuedAt, checkIatSkew(skew))
+	}
+	required(SignatureCheckTrustAnchor, header.validateIssuer())
+
+	if header.Kid == "" {
+		required(SignatureCheckKid, errors.New("kid missing"))
+		return report, firstErr
+	}
+
+	resolver := currentTrustAnchorResolver()
+	chain, err := getCertChainForKid(resolver, KeyRequest{
+		TrustAnchor: header.TrustAnchor,
+		Issuer:      header.Issuer,
+		Kid:         header.Kid,
+		JWKSURI:     jwksURI,
+	})
+	required(SignatureCheckKid, err)
+	if err != nil {
+		return report, firstErr
+	}
+	for _, cert := range chain {
+		report.CertificateChain = append(report.CertificateChain, SignatureCertificate{
+			Subject:      cert.Subject.String(),
+			Issuer:       cert.Issuer.String(),
+			SerialNumber: cert.SerialNumber.String(),
+			NotBefore:    cert.NotBefore,
+			NotAfter:     cert.NotAfter,
+		})
+	}
+	report.advise(SignatureCheckChain, verifyCertificateChain(chain, trustAnchorRoots(resolver, header.TrustAnchor), now))
+
+	signature, err := insertBodyIntoJWT(jwtToken, body, b64) // b64claim
+	if err != nil {
+		logrus.Errorf("failed to insert body into signature message: %v", err)
+		required(SignatureCheckSignature, err)
+		return report, firstErr
+	}
+	_, report.SignedPayload, _ = payloadSplit(signature)
+	logrus.Trace("Signature with payload: " + signature)
+
+	verified, err := JWSVerify(signature, jwa.PS256, chain[0].PublicKey, b64)
+	if err != nil {
+		logrus.Errorf("failed to verify message: %v", err)
+	} else {
+		logrus.Tracef("signed message verified! -> %s", verified)
+	}
+	required(SignatureCheckSignature, err)
+
+	report.Valid = firstErr == nil
+	return report, firstErr
+}
+
+// decodeProtectedHeader - the protected header as sent, including claims signatureHeader does not know about
+func decodeProtectedHeader(token string) map[string]interface{} {
+	header := map[string]interface{}{}
+	segments := strings.Split(token, ".")
+	decoded, err := base64.RawURLEncoding.DecodeString(segments[0])
+	if err != nil {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(decoded))
+	decoder.UseNumber()
+	if err := decoder.Decode(&header); err != nil {
+		return nil
+	}
+	return header
+}
+
+func checkIatSkew(skew time.Duration) error {
+	if skew > signatureIatSkewTolerance || skew < -signatureIatSkewTolerance {
+		return fmt.Errorf("iat is %s from the time the response was verified, more than %s", skew, signatureIatSkewTolerance)
+	}
+	return nil
+}
+
+// trustAnchorRoots - the CAs `resolver` is configured with for `trustAnchor`, the OB directory
+// CAs when it has none
+func trustAnchorRoots(resolver TrustAnchorResolver, trustAnchor string) *x509.CertPool {
+	if configured, ok := resolver.(TrustAnchorRoots); ok {
+		if roots := configured.Roots(trustAnchor); roots != nil {
+			return roots
+		}
+	}
+	roots := x509.NewCertPool()
+	for _, ca := range [][]byte{
+		certificates.OpenBankingSandBoxIssuingCA(),
+		certificates.OpenBankingSandBoxRootCA(),
+		certificates.OpenBankingIssuingCA(),
+		certificates.OpenBankingRootCA(),
+	} {
+		roots.AppendCertsFromPEM(ca)
+	}
+	return roots
+}
+
+// verifyCertificateChain checks the signing certificate chains to one of `roots`, the CAs of
+// the trust anchor, and that every certificate is in date. The rest of the x5c is only used as
+// intermediates, so a root the ASPSP includes itself is not trusted.
+func verifyCertificateChain(chain []*x509.Certificate, roots *x509.CertPool, now time.Time) error {
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err
+}
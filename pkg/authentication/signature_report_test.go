@@ -0,0 +1,217 @@
+package authentication
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const reportBody = `{"Data":{"ConsentId":"58923","Status":"AwaitingAuthorisation"}}`
+
+// signedResponse - signs reportBody with a self-signed certificate published for `kid` under the OB trust anchor
+func signedResponse(t *testing.T, b64 bool) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(58923),
+		Subject:      pkix.Name{Organization: []string{"OpenBanking"}, CommonName: "0015800001041RHAAY"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	previous := currentTrustAnchorResolver()
+	SetTrustAnchorResolver(staticResolver{keySets: map[string]JWKS{
+		TrustAnchorOB: {Keys: []JWK{{Kid: "kid-1", Kty: "RSA", X5c: []string{base64.StdEncoding.EncodeToString(der)}}}},
+	}})
+	t.Cleanup(func() { SetTrustAnchorResolver(previous) })
+
+	signature, err := buildSignature(b64, "kid-1", "0015800001041RHAAY", TrustAnchorOB, reportBody, SigningMethodPS256, key)
+	require.NoError(t, err)
+	return signature
+}
+
+func reportCheck(report SignatureReport, name string) SignatureCheck {
+	for _, check := range report.Checks {
+		if check.Name == name {
+			return check
+		}
+	}
+	return SignatureCheck{}
+}
+
+func TestVerifySignatureReportsEveryStep(t *testing.T) {
+	signature := signedResponse(t, true)
+
+	report, err := VerifySignature(signature, reportBody, "https://aspsp.example.com/jwks", true)
+	require.NoError(t, err)
+
+	assert.True(t, report.Valid)
+	assert.Equal(t, "kid-1", report.Kid)
+	assert.Equal(t, TrustAnchorOB, report.TrustAnchor)
+	assert.Equal(t, "PS256", report.ProtectedHeader["alg"])
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString([]byte(reportBody)), report.SignedPayload)
+	assert.NotEmpty(t, report.IatSkew)
+	require.Len(t, report.CertificateChain, 1)
+	assert.Equal(t, "58923", report.CertificateChain[0].SerialNumber)
+
+	names := []string{}
+	for _, check := range report.Checks {
+		names = append(names, check.Name)
+		if check.Name != SignatureCheckChain {
+			assert.Truef(t, check.Pass, "%s: %s", check.Name, check.Detail)
+		}
+	}
+	assert.Equal(t, []string{
+		SignatureCheckHeader,
+		SignatureCheckCritical,
+		SignatureCheckIssuedAt,
+		SignatureCheckTrustAnchor,
+		SignatureCheckKid,
+		SignatureCheckChain,
+		SignatureCheckSignature,
+	}, names)
+	// a self-signed leaf is not anchored in the OB directory, which is reported but not fatal
+	assert.False(t, reportCheck(report, SignatureCheckChain).Pass)
+	assert.True(t, reportCheck(report, SignatureCheckChain).Advisory)
+}
+
+func TestVerifySignatureB64False(t *testing.T) {
+	signature := signedResponse(t, false)
+
+	report, err := VerifySignature(signature, reportBody, "", false)
+	require.NoError(t, err)
+	assert.True(t, report.Valid)
+	assert.Equal(t, reportBody, report.SignedPayload)
+}
+
+func TestVerifySignatureReportsB64Mismatch(t *testing.T) {
+	signature := signedResponse(t, false)
+
+	report, err := VerifySignature(signature, reportBody, "", true)
+	assert.True(t, errors.Is(err, ErrInvalidSignatureHeader))
+	assert.False(t, report.Valid)
+	assert.False(t, reportCheck(report, SignatureCheckCritical).Pass)
+	assert.True(t, reportCheck(report, SignatureCheckHeader).Pass)
+	// the key is still looked up so the report shows how far verification would get
+	assert.True(t, reportCheck(report, SignatureCheckKid).Pass)
+}
+
+func TestVerifySignatureReportsTamperedBody(t *testing.T) {
+	signature := signedResponse(t, true)
+
+	report, err := VerifySignature(signature, `{"Data":{}}`, "", true)
+	assert.Error(t, err)
+	assert.False(t, report.Valid)
+	assert.True(t, reportCheck(report, SignatureCheckKid).Pass)
+	assert.False(t, reportCheck(report, SignatureCheckSignature).Pass)
+}
+
+func TestVerifySignatureReportsUnknownKid(t *testing.T) {
+	signature := signedResponse(t, true)
+	SetTrustAnchorResolver(staticResolver{keySets: map[string]JWKS{TrustAnchorOB: {}}})
+
+	report, err := VerifySignature(signature, reportBody, "", true)
+	assert.True(t, errors.Is(err, ErrSignatureCert))
+	assert.False(t, reportCheck(report, SignatureCheckKid).Pass)
+	assert.Empty(t, report.CertificateChain)
+}
+
+func TestVerifySignatureReportsMissingKid(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	signature, err := buildSignature(true, "", "0015800001041RHAAY", TrustAnchorOB, reportBody, SigningMethodPS256, key)
+	require.NoError(t, err)
+
+	report, err := VerifySignature(signature, reportBody, "", true)
+	assert.Error(t, err)
+	assert.False(t, report.Valid)
+	assert.False(t, reportCheck(report, SignatureCheckKid).Pass)
+	assert.Equal(t, "kid missing", reportCheck(report, SignatureCheckKid).Detail)
+}
+
+func TestVerifyCertificateChainRejectsExpiredCertificate(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "root"},
+		NotBefore:             time.Now().Add(-2 * time.Hour),
+		NotAfter:              time.Now().Add(-time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	assert.Error(t, verifyCertificateChain([]*x509.Certificate{cert}, roots, time.Now()))
+	assert.NoError(t, verifyCertificateChain([]*x509.Certificate{cert}, roots, time.Now().Add(-90*time.Minute)))
+}
+
+// testChain - a signing certificate issued by a self-signed CA, and the CA
+func testChain(t *testing.T) (leaf, ca *x509.Certificate) {
+	caKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ASPSP Root CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err = x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "0015800001041RHAAY"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err = x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	require.NoError(t, err)
+	leaf, err = x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return leaf, ca
+}
+
+func TestVerifyCertificateChainDoesNotTrustRootInX5c(t *testing.T) {
+	leaf, ca := testChain(t)
+	chain := []*x509.Certificate{leaf, ca}
+
+	err := verifyCertificateChain(chain, trustAnchorRoots(NewDirectoryResolver(DefaultTrustAnchors()), TrustAnchorOB), time.Now())
+	assert.Error(t, err, "a root the ASPSP sends in its own x5c proves nothing")
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	assert.NoError(t, verifyCertificateChain(chain, roots, time.Now()))
+}
+
+func TestCheckIatSkew(t *testing.T) {
+	assert.NoError(t, checkIatSkew(-time.Minute))
+	assert.Error(t, checkIatSkew(10*time.Minute))
+	assert.Error(t, checkIatSkew(-10*time.Minute))
+}
@@ -7,91 +7,259 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
-var jwkCache = make(map[string]JWK)
+// TrustAnchorOB - the `http://openbanking.org.uk/tan` value of the OBIE directory
+const TrustAnchorOB = "openbanking.org.uk"
 
-var hsbcTanList = []string{
-	"https://ob.hsbc.co.uk/jwks/public.jwks",
-	"https://ob.firstdirect.com/jwks/public.jwks",
-	"https://ob.mandsbank.com/jwks/public.jwks",
-	"https://ob.business.hsbc.co.uk/jwks/public.jwks",
-	"https://ob.hsbckinetic.co.uk/jwks/public.jwks",
-	"https://ob.hsbcnet.com/jwks/public.jwks",
-	"ob.hsbc.co.uk",
-	"ob.firstdirect.com",
-	"ob.mandsbank.com",
-	"ob.business.hsbc.co.uk",
-	"ob.hsbckinetic.co.uk",
-	"ob.hsbcnet.com",
+// defaultJWKSCacheTTL - how long a downloaded JWKS is trusted before being fetched again
+const defaultJWKSCacheTTL = time.Hour
+
+// minJWKSRefreshInterval - how long a cached JWKS is kept before a kid missing from it triggers
+// another download, so signatures with unknown kids cannot have the suite download a JWKS each
+const minJWKSRefreshInterval = time.Minute
+
+// KeyRequest - identifies the key used to sign a JWS
+type KeyRequest struct {
+	TrustAnchor string // value of the `http://openbanking.org.uk/tan` header
+	Issuer      string // value of the `http://openbanking.org.uk/iss` header
+	Kid         string
+	JWKSURI     string // jwks_uri of the ASPSP, taken from the well-known endpoint
 }
 
-func isHSBCTrustAnchor(tan string) bool {
-	for _, v := range hsbcTanList {
-		if tan == v {
-			return true
-		}
+// TrustAnchorResolver - locates the JWKS holding the signing keys of a trust anchor
+type TrustAnchorResolver interface {
+	// Supports reports whether `trustAnchor` is an accepted `http://openbanking.org.uk/tan` value
+	Supports(trustAnchor string) bool
+	// KeySet returns the JWKS which should contain `req.Kid`
+	KeySet(req KeyRequest) (JWKS, error)
+}
+
+// TrustAnchorRoots - optional, implemented by a TrustAnchorResolver configured with the CAs that
+// issue the signing certificates of its trust anchors
+type TrustAnchorRoots interface {
+	// Roots returns the CAs of `trustAnchor`, nil when none were configured
+	Roots(trustAnchor string) *x509.CertPool
+}
+
+// TrustAnchor - a trust anchor and the locations of its JWKS.
+//
+// JWKSURLs are templates tried in order until one returns a JWKS containing the kid. The
+// placeholders `{jwks_uri}`, `{tan}`, `{iss}`, `{org_id}`, `{software_id}` and `{kid}` are
+// replaced with values from the signature being validated.
+type TrustAnchor struct {
+	Names    []string `json:"names"`
+	JWKSURLs []string `json:"jwks_urls"`
+}
+
+// TrustAnchorConfig - selects and configures a TrustAnchorResolver, usually loaded from a JSON file
+type TrustAnchorConfig struct {
+	Mode         string            `json:"mode"`          // "directory" (default) or "static"
+	TrustAnchors []TrustAnchor     `json:"trust_anchors"` // directory mode, defaults to DefaultTrustAnchors
+	StaticJWKS   map[string]string `json:"static_jwks"`   // static mode, trust anchor name to JWKS file
+	CacheTTL     string            `json:"cache_ttl"`     // e.g. "30m", "0" disables caching
+	// RootCAs - trust anchor name to a PEM file of the CAs issuing its signing certificates, the
+	// OB directory CAs are used for trust anchors not listed
+	RootCAs map[string]string `json:"root_cas"`
+}
+
+// DefaultTrustAnchors - the OBIE directory, and ASPSPs known to sign with their own trust anchor
+func DefaultTrustAnchors() []TrustAnchor {
+	return []TrustAnchor{
+		{
+			Names:    []string{TrustAnchorOB},
+			JWKSURLs: []string{"{jwks_uri}"},
+		},
+		{
+			Names: []string{
+				"https://ob.hsbc.co.uk/jwks/public.jwks",
+				"https://ob.firstdirect.com/jwks/public.jwks",
+				"https://ob.mandsbank.com/jwks/public.jwks",
+				"https://ob.business.hsbc.co.uk/jwks/public.jwks",
+				"https://ob.hsbckinetic.co.uk/jwks/public.jwks",
+				"https://ob.hsbcnet.com/jwks/public.jwks",
+				"ob.hsbc.co.uk",
+				"ob.firstdirect.com",
+				"ob.mandsbank.com",
+				"ob.business.hsbc.co.uk",
+				"ob.hsbckinetic.co.uk",
+				"ob.hsbcnet.com",
+			},
+			JWKSURLs: []string{"{jwks_uri}"},
+		},
 	}
-	return false
 }
 
-// getCertForKid
-// Given a Kid - return the public cert from the JWKS keystore of the TrustAnchor
-func getCertForKid(kid, jwks_uri string) (*x509.Certificate, error) {
-	jwk, err := getJwkFromJwks(kid, jwks_uri)
+var (
+	trustAnchorResolver     TrustAnchorResolver = NewCachedResolver(NewDirectoryResolver(DefaultTrustAnchors()), defaultJWKSCacheTTL)
+	trustAnchorResolverLock sync.RWMutex
+)
+
+// SetTrustAnchorResolver - sets the resolver used by ValidateSignature
+func SetTrustAnchorResolver(resolver TrustAnchorResolver) {
+	trustAnchorResolverLock.Lock()
+	defer trustAnchorResolverLock.Unlock()
+	trustAnchorResolver = resolver
+}
+
+func currentTrustAnchorResolver() TrustAnchorResolver {
+	trustAnchorResolverLock.RLock()
+	defer trustAnchorResolverLock.RUnlock()
+	return trustAnchorResolver
+}
+
+// LoadTrustAnchorConfig - reads a TrustAnchorConfig from a JSON file
+func LoadTrustAnchorConfig(filename string) (TrustAnchorConfig, error) {
+	config := TrustAnchorConfig{}
+	content, err := ioutil.ReadFile(filename)
 	if err != nil {
-		return nil, err
+		return config, fmt.Errorf("authentication.LoadTrustAnchorConfig: %w", err)
+	}
+	if err := json.Unmarshal(content, &config); err != nil {
+		return config, fmt.Errorf("authentication.LoadTrustAnchorConfig: invalid config %s: %w", filename, err)
 	}
+	return config, nil
+}
 
-	if len(jwk.X5c) == 0 {
-		return nil, errors.New(fmt.Sprintf("No X5c certificate chain found for kid %s", kid))
+// NewTrustAnchorResolver - creates the resolver described by `config`
+func NewTrustAnchorResolver(config TrustAnchorConfig) (TrustAnchorResolver, error) {
+	ttl := defaultJWKSCacheTTL
+	if config.CacheTTL != "" {
+		var err error
+		ttl, err = time.ParseDuration(config.CacheTTL)
+		if err != nil {
+			return nil, fmt.Errorf("authentication.NewTrustAnchorResolver: invalid cache_ttl: %w", err)
+		}
 	}
 
-	certs, err := parseCertificateChain(jwk.X5c)
-	if err != nil {
-		return nil, err
+	var resolver TrustAnchorResolver
+	switch config.Mode {
+	case "", "directory":
+		anchors := config.TrustAnchors
+		if len(anchors) == 0 {
+			anchors = DefaultTrustAnchors()
+		}
+		resolver = NewDirectoryResolver(anchors)
+	case "static":
+		static, err := NewStaticFileResolver(config.StaticJWKS)
+		if err != nil {
+			return nil, err
+		}
+		resolver = static
+		ttl = 0 // local files do not change during a run
+	default:
+		return nil, fmt.Errorf("authentication.NewTrustAnchorResolver: unknown mode %q", config.Mode)
 	}
 
-	cert := certs[0] // assumes a single certificate in chain which is the style used by the OB directory
+	if len(config.RootCAs) > 0 {
+		roots, err := loadRootCAs(config.RootCAs)
+		if err != nil {
+			return nil, err
+		}
+		resolver = rootsResolver{TrustAnchorResolver: resolver, roots: roots}
+	}
+	if ttl <= 0 {
+		return resolver, nil
+	}
+	return NewCachedResolver(resolver, ttl), nil
+}
 
-	return cert, nil
+// rootsResolver adds the CAs configured for trust anchors to another resolver
+type rootsResolver struct {
+	TrustAnchorResolver
+	roots map[string]*x509.CertPool
+}
+
+func (r rootsResolver) Roots(trustAnchor string) *x509.CertPool {
+	return r.roots[trustAnchor]
 }
 
-// getJwkFromJwks
-// Retieve the jwk representing a single public key from the jwks keystore
-func getJwkFromJwks(kid, jwks string) (JWK, error) {
-	if jwk, ok := jwkCache[kid]; !ok {
-		logrus.Traceln("Retrieving JWKS url: " + jwks)
-		jwks, err := getJwks(jwks)
+// loadRootCAs - the CAs in each PEM file of `files`, keyed by trust anchor name
+func loadRootCAs(files map[string]string) (map[string]*x509.CertPool, error) {
+	roots := map[string]*x509.CertPool{}
+	for trustAnchor, filename := range files {
+		content, err := ioutil.ReadFile(filename)
 		if err != nil {
-			return JWK{}, fmt.Errorf("GetJwkFromJwks: errors: %v", err)
+			return nil, fmt.Errorf("authentication.NewTrustAnchorResolver: cannot read root CAs for %s: %w", trustAnchor, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(content) {
+			return nil, fmt.Errorf("authentication.NewTrustAnchorResolver: no certificates in root CAs %s", filename)
+		}
+		roots[trustAnchor] = pool
+	}
+	return roots, nil
+}
+
+// directoryResolver downloads JWKS from URL templates configured per trust anchor
+type directoryResolver struct {
+	anchors map[string]TrustAnchor
+	client  *http.Client
+}
+
+// NewDirectoryResolver - resolves keys by downloading the JWKS of each trust anchor
+func NewDirectoryResolver(anchors []TrustAnchor) TrustAnchorResolver {
+	byName := map[string]TrustAnchor{}
+	for _, anchor := range anchors {
+		for _, name := range anchor.Names {
+			byName[name] = anchor
+		}
+	}
+	return directoryResolver{
+		anchors: byName,
+		client: &http.Client{Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}},
+	}
+}
+
+func (r directoryResolver) Supports(trustAnchor string) bool {
+	_, ok := r.anchors[trustAnchor]
+	return ok
+}
+
+func (r directoryResolver) KeySet(req KeyRequest) (JWKS, error) {
+	anchor, ok := r.anchors[req.TrustAnchor]
+	if !ok {
+		return JWKS{}, fmt.Errorf("directoryResolver: unknown trust anchor %q", req.TrustAnchor)
+	}
+
+	var (
+		jwks    JWKS
+		lastErr error
+	)
+	for _, template := range anchor.JWKSURLs {
+		url := expandJWKSURL(template, req)
+		if url == "" {
+			continue
 		}
-		for _, k := range jwks.Keys {
-			if k.Kid == kid {
-				jwkCache[kid] = k
-				return k, nil
-			}
+		logrus.Traceln("Retrieving JWKS url: " + url)
+		jwks, lastErr = r.getJwks(url)
+		if lastErr != nil {
+			continue
 		}
-	} else {
-		logrus.Traceln("Using cached jwk")
-		return jwk, nil
+		if _, found := jwks.Key(req.Kid); found {
+			return jwks, nil
+		}
+	}
+	if lastErr != nil {
+		return JWKS{}, lastErr
 	}
-	logrus.Traceln("no matching key found")
-	return JWK{}, nil
+	return jwks, nil
 }
 
 // getJwks
 // download the JWKS key store from the given url
-func getJwks(url string) (JWKS, error) {
-	tr := &http.Transport{
-		Proxy:           http.ProxyFromEnvironment,
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-	client := &http.Client{Transport: tr}
-	resp, err := client.Get(url)
+func (r directoryResolver) getJwks(url string) (JWKS, error) {
+	resp, err := r.client.Get(url)
 	if err != nil {
 		return JWKS{}, fmt.Errorf("GetJwkss error retrieving url: %s, %v", url, err)
 	}
@@ -105,6 +273,173 @@ func getJwks(url string) (JWKS, error) {
 	return jwks, nil
 }
 
+func expandJWKSURL(template string, req KeyRequest) string {
+	orgID, softwareID := req.Issuer, ""
+	if idx := strings.Index(req.Issuer, "/"); idx != -1 {
+		orgID, softwareID = req.Issuer[:idx], req.Issuer[idx+1:]
+	}
+	return strings.NewReplacer(
+		"{jwks_uri}", req.JWKSURI,
+		"{tan}", req.TrustAnchor,
+		"{iss}", req.Issuer,
+		"{org_id}", orgID,
+		"{software_id}", softwareID,
+		"{kid}", req.Kid,
+	).Replace(template)
+}
+
+// staticResolver serves JWKS bundles read from local files, for offline runs
+type staticResolver struct {
+	keySets map[string]JWKS
+}
+
+// NewStaticFileResolver - resolves keys from local JWKS files, keyed by trust anchor name
+func NewStaticFileResolver(files map[string]string) (TrustAnchorResolver, error) {
+	keySets := map[string]JWKS{}
+	for trustAnchor, filename := range files {
+		content, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("staticResolver: cannot read JWKS for %s: %w", trustAnchor, err)
+		}
+		jwks := JWKS{}
+		if err := json.Unmarshal(content, &jwks); err != nil {
+			return nil, fmt.Errorf("staticResolver: invalid JWKS %s: %w", filename, err)
+		}
+		keySets[trustAnchor] = jwks
+	}
+	return staticResolver{keySets: keySets}, nil
+}
+
+func (r staticResolver) Supports(trustAnchor string) bool {
+	_, ok := r.keySets[trustAnchor]
+	return ok
+}
+
+func (r staticResolver) KeySet(req KeyRequest) (JWKS, error) {
+	jwks, ok := r.keySets[req.TrustAnchor]
+	if !ok {
+		return JWKS{}, fmt.Errorf("staticResolver: no JWKS for trust anchor %q", req.TrustAnchor)
+	}
+	return jwks, nil
+}
+
+// cachedResolver keeps JWKS returned by another resolver for a time to live. A kid that is
+// not in a cached JWKS triggers a refresh, as ASPSPs rotate keys without notice, at most once
+// every minRefresh for each JWKS. Until then the kid is reported as not found.
+type cachedResolver struct {
+	resolver   TrustAnchorResolver
+	ttl        time.Duration
+	minRefresh time.Duration
+	now        func() time.Time
+	lock       *sync.Mutex
+	entries    map[string]cachedKeySet
+}
+
+type cachedKeySet struct {
+	jwks      JWKS
+	fetched   time.Time
+	refreshed time.Time // last download started, including one for a missing kid still going on
+}
+
+// NewCachedResolver - caches the JWKS returned by `resolver` for `ttl`
+func NewCachedResolver(resolver TrustAnchorResolver, ttl time.Duration) TrustAnchorResolver {
+	return &cachedResolver{
+		resolver:   resolver,
+		ttl:        ttl,
+		minRefresh: minJWKSRefreshInterval,
+		now:        time.Now,
+		lock:       &sync.Mutex{},
+		entries:    map[string]cachedKeySet{},
+	}
+}
+
+func (r *cachedResolver) Supports(trustAnchor string) bool {
+	return r.resolver.Supports(trustAnchor)
+}
+
+func (r *cachedResolver) Roots(trustAnchor string) *x509.CertPool {
+	if configured, ok := r.resolver.(TrustAnchorRoots); ok {
+		return configured.Roots(trustAnchor)
+	}
+	return nil
+}
+
+func (r *cachedResolver) KeySet(req KeyRequest) (JWKS, error) {
+	cacheKey := strings.Join([]string{req.TrustAnchor, req.Issuer, req.JWKSURI}, "|")
+
+	now := r.now()
+	r.lock.Lock()
+	entry, ok := r.entries[cacheKey]
+	if ok && now.Sub(entry.fetched) < r.ttl {
+		if _, found := entry.jwks.Key(req.Kid); found {
+			r.lock.Unlock()
+			logrus.Traceln("Using cached jwks")
+			return entry.jwks, nil
+		}
+		if now.Sub(entry.refreshed) < r.minRefresh {
+			r.lock.Unlock()
+			logrus.Tracef("kid %s not in cached jwks, refreshed less than %s ago", req.Kid, r.minRefresh)
+			return entry.jwks, nil
+		}
+		logrus.Tracef("kid %s not in cached jwks, refreshing", req.Kid)
+		entry.refreshed = now
+		r.entries[cacheKey] = entry
+	}
+	r.lock.Unlock()
+
+	jwks, err := r.resolver.KeySet(req)
+	if err != nil {
+		return JWKS{}, err
+	}
+
+	r.lock.Lock()
+	r.entries[cacheKey] = cachedKeySet{jwks: jwks, fetched: r.now(), refreshed: now}
+	r.lock.Unlock()
+	return jwks, nil
+}
+
+// Key - the key with ID `kid`, if the set has one
+func (j JWKS) Key(kid string) (JWK, bool) {
+	for _, k := range j.Keys {
+		if k.Kid == kid {
+			return k, true
+		}
+	}
+	return JWK{}, false
+}
+
+// getCertForKid
+// Given a key request - return the public cert from the JWKS keystore of the TrustAnchor
+func getCertForKid(resolver TrustAnchorResolver, req KeyRequest) (*x509.Certificate, error) {
+	certs, err := getCertChainForKid(resolver, req)
+	if err != nil {
+		return nil, err
+	}
+
+	cert := certs[0] // assumes a single certificate in chain which is the style used by the OB directory
+
+	return cert, nil
+}
+
+// getCertChainForKid - returns the x5c chain of the key, signing certificate first
+func getCertChainForKid(resolver TrustAnchorResolver, req KeyRequest) ([]*x509.Certificate, error) {
+	jwks, err := resolver.KeySet(req)
+	if err != nil {
+		return nil, fmt.Errorf("GetJwkFromJwks: errors: %v", err)
+	}
+
+	jwk, ok := jwks.Key(req.Kid)
+	if !ok {
+		return nil, fmt.Errorf("%w: no key found for kid %s", ErrSignatureCert, req.Kid)
+	}
+
+	if len(jwk.X5c) == 0 {
+		return nil, errors.New(fmt.Sprintf("No X5c certificate chain found for kid %s", req.Kid))
+	}
+
+	return parseCertificateChain(jwk.X5c)
+}
+
 // parseCertificateChain
 // takes a JWKS x5c claim containing a set of certs as strings - x509 cert objects in an array
 func parseCertificateChain(chain []string) ([]*x509.Certificate, error) {
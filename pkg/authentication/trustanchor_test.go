@@ -1,14 +1,24 @@
 package authentication
 
 import (
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/lestrrat-go/jwx/jwa"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var hsbcResponseSignature = "eyJodHRwOlwvXC9vcGVuYmFua2luZy5vcmcudWtcL2lhdCI6MTYwMTkyMjI5OSwiaHR0cDpcL1wvb3BlbmJhbmtpbmcub3JnLnVrXC90YW4iOiJzMy1ldS13ZXN0LTEuYW1hem9uYXdzLmNvbSIsImNyaXQiOlsiaHR0cDpcL1wvb3BlbmJhbmtpbmcub3JnLnVrXC9pYXQiLCJodHRwOlwvXC9vcGVuYmFua2luZy5vcmcudWtcL3RhbiIsImh0dHA6XC9cL29wZW5iYW5raW5nLm9yZy51a1wvaXNzIl0sImtpZCI6ImV4dGVybmFsXzIiLCJ0eXAiOiJKT1NFIiwiaHR0cDpcL1wvb3BlbmJhbmtpbmcub3JnLnVrXC9pc3MiOiJQb3N0YWxDb2RlPUIxIDFIUSwyLjUuNC45Nz1QU0RHQi1GQ0EtNzY1MTEyLENOPUhTQkMsU1RSRUVUPUJpcm1pbmdoYW0sTD1CaXJtaW5naGFtLE9VPTEgQ2VudGVuYXJ5IFNxdWFyZSxPPUhTQkMgVUssQz1VSyIsImFsZyI6IlBTMjU2In0..g3jvSLnCLo2x8E7LEsjLKjv6BVwctNBc3voHk6EhJ6v2gIuL5CYSIh4F0cJLGNEkz7jXNkXTilcSCeAYSaCkdumk6CosK-tdNj_AQXe0Ma1gQURJi5wfeNA_7uLAnSXW4nFzSe1wGjH4vUEf8nd72K5R-XGr3EOB41aYj37ON521c496IVQCDzsJ2aiS7KG4l-6-_IOIVto1utIaZfTJis2t1PDNHusFEOKq9tFCwVGz_cSEyhlBSl-blc6wik6Nket59UP3itUop1xNdaUecCA3-_CaqjWynvoA6ZH26h0tXtxczgk9BqKxweSn3VO7PEPRWD6_-GnBb6wSCev6VA"
@@ -49,7 +59,8 @@ func TestHSBCTrustAnchor(t *testing.T) {
 }
 
 func verifyHSBCSig(t *testing.T, signingMethod jwt.SigningMethod, signingString, signature string, b64 bool) bool {
-	cert, err := getCertForKid("external_2", hsbc_jwks_uri)
+	resolver := NewDirectoryResolver(DefaultTrustAnchors())
+	cert, err := getCertForKid(resolver, KeyRequest{TrustAnchor: "ob.business.hsbc.co.uk", Kid: "external_2", JWKSURI: hsbc_jwks_uri})
 	assert.Nil(t, err)
 	verified, err := JWSVerify(signingString+signature, jwa.PS256, cert.PublicKey, b64)
 	if err != nil {
@@ -70,18 +81,205 @@ var hsbcTanTestList = []struct {
 	{"https://ob.mandsbank.com/jwks/public.jwks", true},
 	{"https://ob.business.hsbc.co.uk/jwks/public.jwks", true},
 	{"https://ob.hsbckinetic.co.uk/jwks/public.jwks", true},
-	{"openbanking.org.uk", false},
+	{"ob.hsbcnet.com", true},
+	{"openbanking.org.uk", true},
 	{"hsbc.co.uk", false},
 	{"", false},
 	{":", false},
 }
 
-func TestIsHSBCTrustAnchor(t *testing.T) {
+func TestDefaultTrustAnchorsSupported(t *testing.T) {
+	resolver := NewDirectoryResolver(DefaultTrustAnchors())
 	for _, tt := range hsbcTanTestList {
-		actual := isHSBCTrustAnchor(tt.tan)
+		actual := resolver.Supports(tt.tan)
 		if actual != tt.expected {
-			t.Errorf("isHSBCTrustAnchor(%s): expected %t, actual %t", tt.tan, tt.expected, actual)
+			t.Errorf("Supports(%s): expected %t, actual %t", tt.tan, tt.expected, actual)
 		}
 	}
+}
+
+func TestDirectoryResolverTriesTemplatesUntilKidFound(t *testing.T) {
+	requested := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		if r.URL.Path == "/0015800001041RHAAY/software-10001.jwks" {
+			fmt.Fprint(w, `{"keys":[{"kid":"kid-2"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"keys":[{"kid":"kid-1"}]}`)
+	}))
+	defer srv.Close()
+
+	resolver := NewDirectoryResolver([]TrustAnchor{{
+		Names:    []string{"aspsp.example.com"},
+		JWKSURLs: []string{"{jwks_uri}", srv.URL + "/{org_id}/{software_id}.jwks"},
+	}})
+	require.True(t, resolver.Supports("aspsp.example.com"))
+	require.False(t, resolver.Supports(TrustAnchorOB))
+
+	jwks, err := resolver.KeySet(KeyRequest{
+		TrustAnchor: "aspsp.example.com",
+		Issuer:      "0015800001041RHAAY/software-10001",
+		Kid:         "kid-2",
+		JWKSURI:     srv.URL + "/jwks",
+	})
+	require.NoError(t, err)
+	_, found := jwks.Key("kid-2")
+	assert.True(t, found)
+	assert.Equal(t, []string{"/jwks", "/0015800001041RHAAY/software-10001.jwks"}, requested)
+}
+
+func TestStaticFileResolver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwks")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "ob.jwks")
+	require.NoError(t, ioutil.WriteFile(filename, []byte(`{"keys":[{"kid":"kid-1","kty":"RSA"}]}`), 0600))
+
+	resolver, err := NewTrustAnchorResolver(TrustAnchorConfig{Mode: "static", StaticJWKS: map[string]string{TrustAnchorOB: filename}})
+	require.NoError(t, err)
+	assert.True(t, resolver.Supports(TrustAnchorOB))
+	assert.False(t, resolver.Supports("ob.hsbc.co.uk"))
+
+	jwks, err := resolver.KeySet(KeyRequest{TrustAnchor: TrustAnchorOB, Kid: "kid-1"})
+	require.NoError(t, err)
+	jwk, found := jwks.Key("kid-1")
+	assert.True(t, found)
+	assert.Equal(t, "RSA", jwk.Kty)
+
+	_, err = NewTrustAnchorResolver(TrustAnchorConfig{Mode: "static", StaticJWKS: map[string]string{TrustAnchorOB: filepath.Join(dir, "missing.jwks")}})
+	assert.Error(t, err)
+}
 
+type countingResolver struct {
+	calls int
+	kids  []string
+}
+
+func (r *countingResolver) Supports(string) bool { return true }
+
+func (r *countingResolver) KeySet(KeyRequest) (JWKS, error) {
+	r.calls++
+	jwks := JWKS{}
+	for _, kid := range r.kids {
+		jwks.Keys = append(jwks.Keys, JWK{Kid: kid})
+	}
+	return jwks, nil
+}
+
+func TestCachedResolverRefreshesOnTTLAndKidMiss(t *testing.T) {
+	inner := &countingResolver{kids: []string{"kid-1"}}
+	now := time.Now()
+	resolver := NewCachedResolver(inner, time.Hour).(*cachedResolver)
+	resolver.now = func() time.Time { return now }
+
+	req := KeyRequest{TrustAnchor: TrustAnchorOB, Kid: "kid-1", JWKSURI: "https://aspsp.example.com/jwks"}
+	_, err := resolver.KeySet(req)
+	require.NoError(t, err)
+	_, err = resolver.KeySet(req)
+	require.NoError(t, err)
+	assert.Equal(t, 1, inner.calls, "second lookup should be served from the cache")
+
+	// key rotated, the unknown kid forces a refresh
+	now = now.Add(minJWKSRefreshInterval)
+	inner.kids = append(inner.kids, "kid-2")
+	req.Kid = "kid-2"
+	jwks, err := resolver.KeySet(req)
+	require.NoError(t, err)
+	assert.Equal(t, 2, inner.calls)
+	_, found := jwks.Key("kid-2")
+	assert.True(t, found)
+
+	now = now.Add(2 * time.Hour)
+	_, err = resolver.KeySet(req)
+	require.NoError(t, err)
+	assert.Equal(t, 3, inner.calls, "expired entry should be fetched again")
+}
+
+func TestCachedResolverLimitsRefreshesOnKidMiss(t *testing.T) {
+	inner := &countingResolver{kids: []string{"kid-1"}}
+	now := time.Now()
+	resolver := NewCachedResolver(inner, time.Hour).(*cachedResolver)
+	resolver.now = func() time.Time { return now }
+
+	req := KeyRequest{TrustAnchor: TrustAnchorOB, Kid: "kid-1", JWKSURI: "https://aspsp.example.com/jwks"}
+	_, err := resolver.KeySet(req)
+	require.NoError(t, err)
+
+	// unknown kids within the refresh interval are answered from the cache, without the kid
+	for _, kid := range []string{"kid-2", "kid-3", "kid-2"} {
+		req.Kid = kid
+		jwks, err := resolver.KeySet(req)
+		require.NoError(t, err)
+		_, found := jwks.Key(kid)
+		assert.False(t, found)
+	}
+	assert.Equal(t, 1, inner.calls)
+	_, err = getCertChainForKid(resolver, req)
+	assert.True(t, errors.Is(err, ErrSignatureCert))
+
+	// once the interval has passed one refresh is made, the miss is then cached again
+	now = now.Add(minJWKSRefreshInterval)
+	_, err = resolver.KeySet(req)
+	require.NoError(t, err)
+	_, err = resolver.KeySet(req)
+	require.NoError(t, err)
+	assert.Equal(t, 2, inner.calls)
+
+	// a different JWKS has a refresh interval of its own
+	req.JWKSURI = "https://aspsp.example.com/other/jwks"
+	_, err = resolver.KeySet(req)
+	require.NoError(t, err)
+	assert.Equal(t, 3, inner.calls)
+}
+
+func TestNewTrustAnchorResolverRejectsUnknownMode(t *testing.T) {
+	_, err := NewTrustAnchorResolver(TrustAnchorConfig{Mode: "ldap"})
+	assert.Error(t, err)
+
+	_, err = NewTrustAnchorResolver(TrustAnchorConfig{CacheTTL: "soon"})
+	assert.Error(t, err)
+
+	resolver, err := NewTrustAnchorResolver(TrustAnchorConfig{CacheTTL: "0"})
+	require.NoError(t, err)
+	assert.IsType(t, directoryResolver{}, resolver)
+}
+
+func TestNewTrustAnchorResolverRootCAs(t *testing.T) {
+	leaf, ca := testChain(t)
+	dir, err := ioutil.TempDir("", "roots")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "hsbc.pem")
+	require.NoError(t, ioutil.WriteFile(filename, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}), 0600))
+	jwks := filepath.Join(dir, "ob.jwks")
+	require.NoError(t, ioutil.WriteFile(jwks, []byte(`{"keys":[]}`), 0600))
+
+	for _, config := range []TrustAnchorConfig{
+		{RootCAs: map[string]string{"ob.hsbc.co.uk": filename}},
+		{Mode: "static", StaticJWKS: map[string]string{TrustAnchorOB: jwks}, RootCAs: map[string]string{"ob.hsbc.co.uk": filename}},
+	} {
+		resolver, err := NewTrustAnchorResolver(config)
+		require.NoError(t, err)
+
+		chain := []*x509.Certificate{leaf, ca}
+		assert.NoError(t, verifyCertificateChain(chain, trustAnchorRoots(resolver, "ob.hsbc.co.uk"), time.Now()))
+		assert.Error(t, verifyCertificateChain(chain, trustAnchorRoots(resolver, TrustAnchorOB), time.Now()), "the OB directory CAs for trust anchors without root CAs")
+	}
+
+	_, err = NewTrustAnchorResolver(TrustAnchorConfig{RootCAs: map[string]string{"ob.hsbc.co.uk": jwks}})
+	assert.EqualError(t, err, "authentication.NewTrustAnchorResolver: no certificates in root CAs "+jwks)
+	_, err = NewTrustAnchorResolver(TrustAnchorConfig{RootCAs: map[string]string{"ob.hsbc.co.uk": filepath.Join(dir, "missing.pem")}})
+	assert.Error(t, err)
+}
+
+// getJwkFromJwks - downloads `jwksURL` and returns the key for `kid`
+func getJwkFromJwks(kid, jwksURL string) (JWK, error) {
+	resolver := NewDirectoryResolver([]TrustAnchor{{Names: []string{TrustAnchorOB}, JWKSURLs: []string{"{jwks_uri}"}}})
+	jwks, err := resolver.KeySet(KeyRequest{TrustAnchor: TrustAnchorOB, Kid: kid, JWKSURI: jwksURL})
+	if err != nil {
+		return JWK{}, err
+	}
+	jwk, _ := jwks.Key(kid)
+	return jwk, nil
 }
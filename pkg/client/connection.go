@@ -9,6 +9,7 @@ import (
 
 type Connection struct {
 	*http.Client
+	token string
 }
 
 var ErrInsecure = errors.New("this client connection is insecure")
@@ -19,9 +20,39 @@ func NewConnection() (*Connection, error) {
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 	}
 	return &Connection{
-		&http.Client{
+		Client: &http.Client{
 			Transport: tr,
 			Timeout:   5 * time.Minute,
 		},
 	}, ErrInsecure
 }
+
+// SetToken - sends `token` as a bearer token with every request, for servers requiring an API token
+func (c *Connection) SetToken(token string) {
+	c.token = token
+	transport := c.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	c.Transport = bearerTransport{token: token, next: transport}
+}
+
+// header - the headers to dial websockets with
+func (c *Connection) header() http.Header {
+	if c.token == "" {
+		return nil
+	}
+	return http.Header{"Authorization": []string{"Bearer " + c.token}}
+}
+
+// bearerTransport - adds an `Authorization: Bearer` header to requests
+type bearerTransport struct {
+	token string
+	next  http.RoundTripper
+}
+
+func (t bearerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+t.token)
+	return t.next.RoundTrip(r)
+}
@@ -3,6 +3,9 @@ package client
 import (
 	"fmt"
 	"io"
+	"sort"
+	"strings"
+	"time"
 )
 
 // ResultWriter writes testcase results to a writer
@@ -15,3 +18,45 @@ func ResultWriter(w io.Writer, results []TestCase) {
 		}
 	}
 }
+
+// PreflightWriter writes pre-flight check results to a writer
+func PreflightWriter(w io.Writer, report PreflightReport) {
+	for _, check := range report.Checks {
+		fmt.Fprintf(w, "=== %s: %s\n", strings.ToUpper(check.Status), check.Name)
+		if check.Detail != "" {
+			fmt.Fprintf(w, "\t %s\n", check.Detail)
+		}
+	}
+}
+
+// RunsWriter writes the run history to a writer, one run per line
+func RunsWriter(w io.Writer, runs []RunSummary) {
+	for _, run := range runs {
+		fmt.Fprintf(w, "%s  %s  %-9s  %d passed, %d failed  %s\n",
+			run.ID, run.Started.Format(time.RFC3339), run.Status, run.Passed, run.Failed, run.Name)
+	}
+}
+
+// LoadWriter writes the throughput, error rate and latency percentiles of a load run to a writer
+func LoadWriter(w io.Writer, report LoadReport) {
+	fmt.Fprintf(w, "requests: %d in %.1fs, %.2f/s (target %.2f/s)\n", report.Requests, report.Elapsed, report.Throughput, report.TargetRPS)
+	fmt.Fprintf(w, "errors: %d (%.2f%%), rate limited: %d, dropped: %d\n", report.Errors, report.ErrorRate*100, report.RateLimited, report.Dropped)
+	codes := make([]string, 0, len(report.StatusCodes))
+	for code := range report.StatusCodes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		fmt.Fprintf(w, "\t %s: %d\n", code, report.StatusCodes[code])
+	}
+	fmt.Fprintf(w, "latency: %s\n", formatLatency(report.Latency))
+	for _, tc := range report.TestCases {
+		fmt.Fprintf(w, "=== %s: %s\n", tc.ID, tc.Endpoint)
+		fmt.Fprintf(w, "\t %d requests, %d errors, %s\n", tc.Requests, tc.Errors, formatLatency(tc.Latency))
+	}
+}
+
+func formatLatency(latency LoadLatency) string {
+	return fmt.Sprintf("p50 %.1fms, p90 %.1fms, p95 %.1fms, p99 %.1fms, max %.1fms",
+		latency.P50, latency.P90, latency.P95, latency.P99, latency.Max)
+}
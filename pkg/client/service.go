@@ -1,12 +1,15 @@
 package client
 
 import (
+	"bytes"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -17,16 +20,32 @@ import (
 type Service interface {
 	Version() (VersionResponse, error)
 	Run(discoveryFile, configFile, exportConfig string) ([]TestCase, error)
+	Resume(discoveryFile, configFile, exportConfig, runID string) ([]TestCase, error)
+	Preflight(configFile string) (PreflightReport, error)
+	ListRuns() ([]RunSummary, error)
+	GetRun(id string) (json.RawMessage, error)
+	DeleteRun(id string) error
+	RunEvents(id string, w io.Writer) error
+	Load(discoveryFile, configFile string, options LoadOptions) (LoadReport, error)
 }
 
 const (
-	setDiscoveryModelPath = "/api/discovery-model"
-	setConfigPath         = "/api/config/global"
-	exportReport          = "/api/export"
-	generateTestCases     = "/api/test-cases"
-	runTestCases          = "/api/run"
-	runTestCasesResultsWS = "/api/run/ws"
-	versionPath           = "/api/version"
+	apiPath               = "/api"
+	journeysPath          = "/api/journeys"
+	setDiscoveryModelPath = "/discovery-model"
+	setConfigPath         = "/config/global"
+	preflightPath         = "/config/preflight"
+	exportReport          = "/export"
+	generateTestCases     = "/test-cases"
+	runTestCases          = "/run"
+	runTestCasesResultsWS = "/run/ws"
+	resumeRunPath         = "/run/resume"
+	versionPath           = "/version"
+	runsPath              = "/api/runs"
+	loadPath              = "/load"
+
+	// loadPollInterval - how often the report of a load run is fetched while it runs
+	loadPollInterval = time.Second
 )
 
 // service is the implementation using HTTP client for consuming FCS services
@@ -34,6 +53,8 @@ type service struct {
 	conn   *Connection
 	host   string
 	wsHost string
+	// api - path the journey routes are under, `/api/journeys/{id}` once a journey is created
+	api string
 }
 
 func NewService(host, wsHost string, conn *Connection) service {
@@ -41,9 +62,14 @@ func NewService(host, wsHost string, conn *Connection) service {
 		conn:   conn,
 		host:   host,
 		wsHost: wsHost,
+		api:    apiPath,
 	}
 }
 
+type journeyResponse struct {
+	ID string `json:"id"`
+}
+
 type VersionResponse struct {
 	Version string `json:"version"`
 	Message string `json:"message"`
@@ -51,7 +77,13 @@ type VersionResponse struct {
 }
 
 func (s service) Run(discovery, config, report string) ([]TestCase, error) {
-	err := s.setDiscoveryModel(discovery)
+	s, err := s.newJourney()
+	if err != nil {
+		return nil, err
+	}
+	defer s.deleteJourney()
+
+	err = s.setDiscoveryModel(discovery)
 	if err != nil {
 		return nil, err
 	}
@@ -87,6 +119,86 @@ func (s service) Run(discovery, config, report string) ([]TestCase, error) {
 	return results, nil
 }
 
+// Resume - continues run `runID` from the server's run history, skipping the test cases it already ran
+func (s service) Resume(discovery, config, report, runID string) ([]TestCase, error) {
+	s, err := s.newJourney()
+	if err != nil {
+		return nil, err
+	}
+	defer s.deleteJourney()
+
+	err = s.setDiscoveryModel(discovery)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.setConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsChan := make(chan TestCase)
+	endedChan := make(chan struct{})
+
+	err = s.handleResults(resultsChan, endedChan)
+	if err != nil {
+		return nil, errors.Wrap(err, "resuming run")
+	}
+
+	err = s.resumeRun(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := aggregateResults(resultsChan, endedChan)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.exportReport(report)
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+type resumeResponse struct {
+	Tokens []struct {
+		Name       string `json:"name"`
+		ConsentURL string `json:"consent_url"`
+	} `json:"tokens"`
+}
+
+func (s service) resumeRun(runID string) error {
+	body, err := json.Marshal(map[string]string{"run_id": runID})
+	if err != nil {
+		return errors.Wrap(err, "resuming run")
+	}
+	response, err := s.conn.Post(s.host+s.api+resumeRunPath, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "resuming run")
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusCreated:
+		return nil
+	case http.StatusAccepted:
+		resume := resumeResponse{}
+		if err := json.NewDecoder(response.Body).Decode(&resume); err != nil {
+			return errors.Wrap(err, "decoding resume response")
+		}
+		names := []string{}
+		for _, token := range resume.Tokens {
+			names = append(names, token.Name)
+		}
+		return fmt.Errorf("tokens %s have expired and need PSU consent, which the CLI cannot collect - see docs/run-history.md", strings.Join(names, ", "))
+	default:
+		return unexpectedStatus("resuming run", response)
+	}
+}
+
 func aggregateResults(resultChan chan TestCase, endedChan chan struct{}) ([]TestCase, error) {
 	var results []TestCase
 	const timeoutRunningTests = 5 * time.Minute
@@ -107,8 +219,50 @@ func aggregateResults(resultChan chan TestCase, endedChan chan struct{}) ([]Test
 	}
 }
 
+// newJourney - a copy of the service that uses its own journey, so runs sharing a server do not
+// overwrite each other. Servers that do not support journeys are used as before.
+func (s service) newJourney() (service, error) {
+	response, err := s.conn.Post(s.host+journeysPath, "application/json", nil)
+	if err != nil {
+		return s, errors.Wrap(err, "creating journey")
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound || response.StatusCode == http.StatusMethodNotAllowed {
+		return s, nil
+	}
+	if response.StatusCode != http.StatusCreated {
+		return s, fmt.Errorf("unexpected status code creating journey %d", response.StatusCode)
+	}
+
+	journey := journeyResponse{}
+	if err := json.NewDecoder(response.Body).Decode(&journey); err != nil {
+		return s, errors.Wrap(err, "decoding journey")
+	}
+	s.api = journeysPath + "/" + journey.ID
+	return s, nil
+}
+
+// deleteJourney - removes the journey created by newJourney, the server expires it anyway if this fails
+func (s service) deleteJourney() {
+	if s.api == apiPath {
+		return
+	}
+	request, err := http.NewRequest(http.MethodDelete, s.host+s.api, nil)
+	if err != nil {
+		fmt.Printf("error deleting journey: %v\n", err)
+		return
+	}
+	response, err := s.conn.Do(request)
+	if err != nil {
+		fmt.Printf("error deleting journey: %v\n", err)
+		return
+	}
+	response.Body.Close()
+}
+
 func (s service) Version() (VersionResponse, error) {
-	response, err := s.conn.Get(s.host + versionPath)
+	response, err := s.conn.Get(s.host + apiPath + versionPath)
 	if err != nil {
 		return VersionResponse{}, errors.Wrap(err, "getting version")
 	}
@@ -126,13 +280,261 @@ func (s service) Version() (VersionResponse, error) {
 	return versionResponse, nil
 }
 
+// PreflightReport - result of the certificate pre-flight checks
+type PreflightReport struct {
+	Pass   bool             `json:"pass"`
+	Checks []PreflightCheck `json:"checks"`
+}
+
+// PreflightCheck - one pre-flight check, status is one of pass, warn, fail or skip
+type PreflightCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func (s service) Preflight(configFile string) (PreflightReport, error) {
+	file, err := os.Open(configFile)
+	if err != nil {
+		return PreflightReport{}, errors.Wrap(err, "pre-flight check")
+	}
+
+	response, err := s.conn.Post(s.host+s.api+preflightPath, "application/json", file)
+	if err != nil {
+		return PreflightReport{}, errors.Wrap(err, "pre-flight check")
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		responseBody, err := ioutil.ReadAll(response.Body)
+		if err != nil {
+			return PreflightReport{}, errors.Wrap(err, "reading error response from pre-flight check")
+		}
+		return PreflightReport{}, fmt.Errorf("unexpected status code from pre-flight check %d, %s", response.StatusCode, string(responseBody))
+	}
+
+	report := PreflightReport{}
+	err = json.NewDecoder(response.Body).Decode(&report)
+	if err != nil {
+		return PreflightReport{}, errors.Wrap(err, "decoding pre-flight report")
+	}
+
+	return report, nil
+}
+
+// RunSummary - a run kept in the server's run history
+type RunSummary struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Status   string    `json:"status"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Duration string    `json:"duration"`
+	Tests    int       `json:"tests"`
+	Passed   int       `json:"passed"`
+	Failed   int       `json:"failed"`
+}
+
+func (s service) ListRuns() ([]RunSummary, error) {
+	response, err := s.conn.Get(s.host + runsPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing runs")
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, unexpectedStatus("listing runs", response)
+	}
+
+	runs := []RunSummary{}
+	if err := json.NewDecoder(response.Body).Decode(&runs); err != nil {
+		return nil, errors.Wrap(err, "decoding runs")
+	}
+	return runs, nil
+}
+
+func (s service) GetRun(id string) (json.RawMessage, error) {
+	response, err := s.conn.Get(s.host + runsPath + "/" + id)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting run")
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, unexpectedStatus("getting run", response)
+	}
+
+	run, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading run")
+	}
+	return run, nil
+}
+
+func (s service) DeleteRun(id string) error {
+	request, err := http.NewRequest(http.MethodDelete, s.host+runsPath+"/"+id, nil)
+	if err != nil {
+		return errors.Wrap(err, "deleting run")
+	}
+	response, err := s.conn.Do(request)
+	if err != nil {
+		return errors.Wrap(err, "deleting run")
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusNoContent {
+		return unexpectedStatus("deleting run", response)
+	}
+	return nil
+}
+
+// RunEvents - writes the event log of run `id` to `w` as NDJSON
+func (s service) RunEvents(id string, w io.Writer) error {
+	response, err := s.conn.Get(s.host + runsPath + "/" + id + "/events")
+	if err != nil {
+		return errors.Wrap(err, "getting run events")
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return unexpectedStatus("getting run events", response)
+	}
+
+	_, err = io.Copy(w, response.Body)
+	return errors.Wrap(err, "reading run events")
+}
+
+// LoadOptions - the read-only test cases a load run sends, how fast and for how long
+type LoadOptions struct {
+	TestCases string  `json:"test_cases"` // regular expression matching test case ids, all when empty
+	RPS       float64 `json:"rps"`
+	Duration  string  `json:"duration"`
+}
+
+// LoadReport - what a load run measured. Latencies are in milliseconds.
+type LoadReport struct {
+	Running     bool               `json:"running"`
+	Started     time.Time          `json:"started"`
+	Elapsed     float64            `json:"elapsed"`
+	TargetRPS   float64            `json:"target_rps"`
+	Throughput  float64            `json:"throughput"`
+	Requests    int                `json:"requests"`
+	Errors      int                `json:"errors"`
+	ErrorRate   float64            `json:"error_rate"`
+	RateLimited int                `json:"rate_limited"`
+	Dropped     int                `json:"dropped"`
+	StatusCodes map[string]int     `json:"status_codes"`
+	Latency     LoadLatency        `json:"latency"`
+	TestCases   []LoadTestCaseStat `json:"test_cases"`
+}
+
+// LoadLatency - response time percentiles of a load run, in milliseconds
+type LoadLatency struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+// LoadTestCaseStat - the requests a load run sent for one test case
+type LoadTestCaseStat struct {
+	ID       string      `json:"id"`
+	Endpoint string      `json:"endpoint"`
+	Requests int         `json:"requests"`
+	Errors   int         `json:"errors"`
+	Latency  LoadLatency `json:"latency"`
+}
+
+// Load - sends the read-only test cases generated from the discovery model selected by `options`,
+// and waits for the load run to finish
+func (s service) Load(discovery, config string, options LoadOptions) (LoadReport, error) {
+	s, err := s.newJourney()
+	if err != nil {
+		return LoadReport{}, err
+	}
+	defer s.deleteJourney()
+
+	err = s.setDiscoveryModel(discovery)
+	if err != nil {
+		return LoadReport{}, err
+	}
+
+	err = s.setConfig(config)
+	if err != nil {
+		return LoadReport{}, err
+	}
+
+	err = s.TestCases()
+	if err != nil {
+		return LoadReport{}, err
+	}
+
+	err = s.startLoad(options)
+	if err != nil {
+		return LoadReport{}, err
+	}
+
+	for {
+		report, err := s.loadReport()
+		if err != nil || !report.Running {
+			return report, err
+		}
+		time.Sleep(loadPollInterval)
+	}
+}
+
+func (s service) startLoad(options LoadOptions) error {
+	body, err := json.Marshal(options)
+	if err != nil {
+		return errors.Wrap(err, "starting load run")
+	}
+	response, err := s.conn.Post(s.host+s.api+loadPath, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "starting load run")
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusCreated {
+		return unexpectedStatus("starting load run", response)
+	}
+	return nil
+}
+
+func (s service) loadReport() (LoadReport, error) {
+	response, err := s.conn.Get(s.host + s.api + loadPath)
+	if err != nil {
+		return LoadReport{}, errors.Wrap(err, "getting load report")
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return LoadReport{}, unexpectedStatus("getting load report", response)
+	}
+
+	report := LoadReport{}
+	if err := json.NewDecoder(response.Body).Decode(&report); err != nil {
+		return LoadReport{}, errors.Wrap(err, "decoding load report")
+	}
+	return report, nil
+}
+
+// unexpectedStatus - an error with the status code and body of `response`
+func unexpectedStatus(action string, response *http.Response) error {
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return errors.Wrapf(err, "reading error response from %s", action)
+	}
+	return fmt.Errorf("unexpected status code %s %d, %s", action, response.StatusCode, string(responseBody))
+}
+
 func (s service) setDiscoveryModel(filename string) error {
 	file, err := os.Open(filename)
 	if err != nil {
 		return errors.Wrap(err, "setting discovery model")
 	}
 
-	response, err := s.conn.Post(s.host+setDiscoveryModelPath, "application/json", file)
+	response, err := s.conn.Post(s.host+s.api+setDiscoveryModelPath, "application/json", file)
 	if err != nil {
 		return errors.Wrap(err, "setting discovery model")
 	}
@@ -150,7 +552,7 @@ func (s service) setConfig(filename string) error {
 		return errors.Wrap(err, "setting config")
 	}
 
-	response, err := s.conn.Post(s.host+setConfigPath, "application/json", file)
+	response, err := s.conn.Post(s.host+s.api+setConfigPath, "application/json", file)
 	if err != nil {
 		return errors.Wrap(err, "setting config")
 	}
@@ -169,7 +571,7 @@ func (s service) setConfig(filename string) error {
 }
 
 func (s service) TestCases() error {
-	response, err := s.conn.Get(s.host + generateTestCases)
+	response, err := s.conn.Get(s.host + s.api + generateTestCases)
 	if err != nil {
 		return errors.Wrap(err, "generating test cases")
 	}
@@ -192,7 +594,7 @@ func (s service) runTests(resultChan chan<- TestCase, endedChan chan<- struct{})
 		return errors.Wrap(err, "running test cases")
 	}
 
-	response, err := s.conn.Post(s.host+runTestCases, "application/test", nil)
+	response, err := s.conn.Post(s.host+s.api+runTestCases, "application/test", nil)
 	if err != nil {
 		return errors.Wrap(err, "running test cases")
 	}
@@ -216,7 +618,7 @@ func (s service) exportReport(reportConfig string) error {
 		return errors.Wrap(err, "export report")
 	}
 
-	response, err := s.conn.Post(s.host+exportReport, "application/json", file)
+	response, err := s.conn.Post(s.host+s.api+exportReport, "application/json", file)
 	if err != nil {
 		return errors.Wrap(err, "export config")
 	}
@@ -267,6 +669,6 @@ func (s service) wsDialer() (*websocket.Conn, error) {
 		HandshakeTimeout: 5 * time.Second,
 		TLSClientConfig:  &tls.Config{InsecureSkipVerify: true},
 	}
-	c, _, err := dialer.Dial(s.wsHost+runTestCasesResultsWS, nil)
+	c, _, err := dialer.Dial(s.wsHost+s.api+runTestCasesResultsWS, s.conn.header())
 	return c, err
 }
@@ -1,9 +1,12 @@
 package client
 
 import (
+	"bytes"
+
 	"github.com/OpenBankingUK/conformance-suite/pkg/test"
 	"github.com/stretchr/testify/assert"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -52,3 +55,136 @@ func TestTestCases(t *testing.T) {
 
 	assert.NoError(t, err)
 }
+
+func TestPreflight(t *testing.T) {
+	response := `{"pass": false, "checks": [{"name": "signing expiry", "status": "fail", "detail": "expired on 2017-09-25T00:47:17Z"}]}`
+	server, url := test.HTTPServer(http.StatusOK, response, nil)
+	defer server.Close()
+	conn := &Connection{Client: &http.Client{}}
+	service := NewService(url, url, conn)
+
+	report, err := service.Preflight("testdata/sample.json")
+
+	assert.NoError(t, err)
+	assert.False(t, report.Pass)
+	assert.Equal(t, []PreflightCheck{{Name: "signing expiry", Status: "fail", Detail: "expired on 2017-09-25T00:47:17Z"}}, report.Checks)
+}
+
+func TestStartLoad(t *testing.T) {
+	server, url := test.HTTPServer(http.StatusConflict, `{"error": "error load run already running"}`, nil)
+	defer server.Close()
+	conn := &Connection{Client: &http.Client{}}
+	service := NewService(url, url, conn)
+
+	err := service.startLoad(LoadOptions{RPS: 5, Duration: "1m"})
+
+	assert.EqualError(t, err, `unexpected status code starting load run 409, {"error": "error load run already running"}`)
+}
+
+func TestLoadReport(t *testing.T) {
+	response := `{"running": false, "requests": 10, "errors": 1, "error_rate": 0.1, "status_codes": {"200": 9, "500": 1}, "latency": {"p50": 12.5, "max": 40}}`
+	server, url := test.HTTPServer(http.StatusOK, response, nil)
+	defer server.Close()
+	conn := &Connection{Client: &http.Client{}}
+	service := NewService(url, url, conn)
+
+	report, err := service.loadReport()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 10, report.Requests)
+	assert.Equal(t, 0.1, report.ErrorRate)
+	assert.Equal(t, map[string]int{"200": 9, "500": 1}, report.StatusCodes)
+	assert.Equal(t, LoadLatency{P50: 12.5, Max: 40}, report.Latency)
+}
+
+func TestNewJourney(t *testing.T) {
+	server, url := test.HTTPServer(http.StatusCreated, `{"id": "6c3b4ed5"}`, nil)
+	defer server.Close()
+	conn := &Connection{Client: &http.Client{}}
+	service := NewService(url, url, conn)
+
+	journeyService, err := service.newJourney()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/api/journeys/6c3b4ed5", journeyService.api)
+	assert.Equal(t, "/api", service.api)
+}
+
+func TestNewJourneyNotSupported(t *testing.T) {
+	server, url := test.HTTPServer(http.StatusNotFound, `{"message": "Not Found"}`, nil)
+	defer server.Close()
+	conn := &Connection{Client: &http.Client{}}
+	service := NewService(url, url, conn)
+
+	journeyService, err := service.newJourney()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/api", journeyService.api)
+}
+
+func TestListRuns(t *testing.T) {
+	response := `[{"id": "run-1", "name": "ob-v3.1-ozone", "status": "completed", "tests": 2, "passed": 1, "failed": 1}]`
+	server, url := test.HTTPServer(http.StatusOK, response, nil)
+	defer server.Close()
+	conn := &Connection{Client: &http.Client{}}
+	service := NewService(url, url, conn)
+
+	runs, err := service.ListRuns()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []RunSummary{{ID: "run-1", Name: "ob-v3.1-ozone", Status: "completed", Tests: 2, Passed: 1, Failed: 1}}, runs)
+}
+
+func TestGetRunNotFound(t *testing.T) {
+	server, url := test.HTTPServer(http.StatusNotFound, `{"error": "run not found"}`, nil)
+	defer server.Close()
+	conn := &Connection{Client: &http.Client{}}
+	service := NewService(url, url, conn)
+
+	_, err := service.GetRun("run-1")
+
+	assert.EqualError(t, err, `unexpected status code getting run 404, {"error": "run not found"}`)
+}
+
+func TestRunEvents(t *testing.T) {
+	response := `{"version":1,"seq":1,"type":"run_started","run_id":"run-1","data":{"tests":2,"resumed":false}}` + "\n"
+	server, url := test.HTTPServer(http.StatusOK, response, nil)
+	defer server.Close()
+	conn := &Connection{Client: &http.Client{}}
+	service := NewService(url, url, conn)
+	out := &bytes.Buffer{}
+
+	err := service.RunEvents("run-1", out)
+
+	assert.NoError(t, err)
+	assert.Equal(t, response, out.String())
+}
+
+func TestResumeRunNeedsConsent(t *testing.T) {
+	response := `{"run_id": "run-1", "tokens": [{"name": "Token001", "consent_url": "https://aspsp.example.com/auth"}]}`
+	server, url := test.HTTPServer(http.StatusAccepted, response, nil)
+	defer server.Close()
+	conn := &Connection{Client: &http.Client{}}
+	service := NewService(url, url, conn)
+
+	err := service.resumeRun("run-1")
+
+	assert.EqualError(t, err, "tokens Token001 have expired and need PSU consent, which the CLI cannot collect - see docs/run-history.md")
+}
+
+func TestServiceSendsToken(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.Write([]byte(`{"version": "v1.6.0"}`))
+	}))
+	defer server.Close()
+	conn := &Connection{Client: &http.Client{}}
+	conn.SetToken("s3cr3t")
+	service := NewService(server.URL, server.URL, conn)
+
+	_, err := service.Version()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer s3cr3t", authorization)
+}
@@ -0,0 +1,100 @@
+package dcr
+
+import (
+	"crypto"
+	"fmt"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/authentication"
+)
+
+// requestLifetime - how long a registration request is valid for
+const requestLifetime = 5 * time.Minute
+
+// RegistrationClaims - claims of an OBClientRegistration1 registration request
+type RegistrationClaims struct {
+	jwt.StandardClaims
+	RedirectURIs                []string `json:"redirect_uris"`
+	TokenEndpointAuthMethod     string   `json:"token_endpoint_auth_method"`
+	TokenEndpointAuthSigningAlg string   `json:"token_endpoint_auth_signing_alg,omitempty"`
+	GrantTypes                  []string `json:"grant_types"`
+	ResponseTypes               []string `json:"response_types"`
+	SoftwareID                  string   `json:"software_id,omitempty"`
+	Scope                       string   `json:"scope"`
+	SoftwareStatement           string   `json:"software_statement"`
+	ApplicationType             string   `json:"application_type"`
+	IDTokenSignedResponseAlg    string   `json:"id_token_signed_response_alg"`
+	RequestObjectSigningAlg     string   `json:"request_object_signing_alg"`
+	TLSClientAuthSubjectDN      string   `json:"tls_client_auth_subject_dn,omitempty"`
+}
+
+// NewRegistrationClaims - builds the claims of a registration request for `ssa` issued at `now`
+func NewRegistrationClaims(config Config, ssa string, now time.Time) (RegistrationClaims, error) {
+	softwareID, err := softwareIDFromSSA(ssa)
+	if err != nil {
+		return RegistrationClaims{}, err
+	}
+
+	claims := RegistrationClaims{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    softwareID,
+			Audience:  config.Issuer,
+			Id:        uuid.New().String(),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(requestLifetime).Unix(),
+		},
+		RedirectURIs:             config.RedirectURIs,
+		TokenEndpointAuthMethod:  config.TokenEndpointAuthMethod,
+		GrantTypes:               []string{"authorization_code", "client_credentials", "refresh_token"},
+		ResponseTypes:            []string{"code id_token"},
+		SoftwareID:               softwareID,
+		Scope:                    config.Scope,
+		SoftwareStatement:        ssa,
+		ApplicationType:          "web",
+		IDTokenSignedResponseAlg: config.SigningAlg,
+		RequestObjectSigningAlg:  config.SigningAlg,
+	}
+
+	switch config.TokenEndpointAuthMethod {
+	case authentication.PrivateKeyJwt:
+		claims.TokenEndpointAuthSigningAlg = config.SigningAlg
+	case authentication.TlsClientAuth:
+		if config.TransportCert != nil {
+			dn, _, _, err := config.TransportCert.DN()
+			if err != nil {
+				return RegistrationClaims{}, fmt.Errorf("dcr: cannot read transport certificate DN: %w", err)
+			}
+			claims.TLSClientAuthSubjectDN = dn
+		}
+	}
+
+	return claims, nil
+}
+
+// Sign - signs the registration request with `key`, identified by `kid`
+func (c RegistrationClaims) Sign(alg, kid string, key crypto.Signer) (string, error) {
+	method, err := authentication.GetSigningAlg(alg)
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(method, c)
+	token.Header["kid"] = kid
+	return token.SignedString(key)
+}
+
+// softwareIDFromSSA - reads the `software_id` claim of the SSA, the signature is left
+// for the ASPSP to verify
+func softwareIDFromSSA(ssa string) (string, error) {
+	claims := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(ssa, claims); err != nil {
+		return "", fmt.Errorf("dcr: cannot parse software statement: %w", err)
+	}
+	softwareID, ok := claims["software_id"].(string)
+	if !ok || softwareID == "" {
+		return "", fmt.Errorf("dcr: software statement has no software_id claim")
+	}
+	return softwareID, nil
+}
@@ -0,0 +1,165 @@
+package dcr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"gopkg.in/resty.v1"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/authentication"
+	"github.com/OpenBankingUK/conformance-suite/pkg/tracing"
+)
+
+const contentTypeJWT = "application/jwt"
+
+// Registration - subset of the OBClientRegistration1 response needed to drive the tests
+type Registration struct {
+	ClientID                string `json:"client_id"`
+	ClientSecret            string `json:"client_secret,omitempty"`
+	RegistrationAccessToken string `json:"registration_access_token,omitempty"`
+	TokenEndpointAuthMethod string `json:"token_endpoint_auth_method"`
+}
+
+// Client - calls the ASPSP registration endpoint. Each request is traced under the span in `traceCtx`.
+type Client interface {
+	Register(traceCtx context.Context, requestJWT string) (*resty.Response, error)
+	Retrieve(traceCtx context.Context, clientID, accessToken string) (*resty.Response, error)
+	Update(traceCtx context.Context, clientID, accessToken, requestJWT string) (*resty.Response, error)
+	Delete(traceCtx context.Context, clientID, accessToken string) (*resty.Response, error)
+	AccessToken(traceCtx context.Context, registration Registration) (string, error)
+}
+
+type client struct {
+	httpClient *resty.Client
+	config     Config
+}
+
+// NewClient - creates a Client sending its requests with `httpClient`, which carries the transport certificates
+func NewClient(httpClient *resty.Client, config Config) Client {
+	return client{httpClient: httpClient, config: config}
+}
+
+// Register - POST /register
+func (c client) Register(traceCtx context.Context, requestJWT string) (*resty.Response, error) {
+	req := c.httpClient.R().
+		SetHeader("Content-Type", contentTypeJWT).
+		SetHeader("Accept", "application/json").
+		SetBody(requestJWT)
+	return send(traceCtx, req, http.MethodPost, c.config.RegistrationEndpoint)
+}
+
+// Retrieve - GET /register/{ClientId}
+func (c client) Retrieve(traceCtx context.Context, clientID, accessToken string) (*resty.Response, error) {
+	req := c.httpClient.R().
+		SetHeader("Accept", "application/json").
+		SetAuthToken(accessToken)
+	return send(traceCtx, req, http.MethodGet, clientURL(c.config.RegistrationEndpoint, clientID))
+}
+
+// Update - PUT /register/{ClientId}
+func (c client) Update(traceCtx context.Context, clientID, accessToken, requestJWT string) (*resty.Response, error) {
+	req := c.httpClient.R().
+		SetHeader("Content-Type", contentTypeJWT).
+		SetHeader("Accept", "application/json").
+		SetAuthToken(accessToken).
+		SetBody(requestJWT)
+	return send(traceCtx, req, http.MethodPut, clientURL(c.config.RegistrationEndpoint, clientID))
+}
+
+// Delete - DELETE /register/{ClientId}
+func (c client) Delete(traceCtx context.Context, clientID, accessToken string) (*resty.Response, error) {
+	req := c.httpClient.R().
+		SetAuthToken(accessToken)
+	return send(traceCtx, req, http.MethodDelete, clientURL(c.config.RegistrationEndpoint, clientID))
+}
+
+// AccessToken - returns the token used to manage `registration`. The registration access token
+// is preferred when the ASPSP issues one, otherwise a client credentials grant is made with the
+// newly registered client.
+func (c client) AccessToken(traceCtx context.Context, registration Registration) (string, error) {
+	if registration.RegistrationAccessToken != "" {
+		return registration.RegistrationAccessToken, nil
+	}
+
+	authMethod := registration.TokenEndpointAuthMethod
+	if authMethod == "" {
+		authMethod = c.config.TokenEndpointAuthMethod
+	}
+
+	req := c.httpClient.R().SetFormData(map[string]string{
+		"grant_type": "client_credentials",
+		"scope":      c.config.Scope,
+	})
+	switch authMethod {
+	case authentication.ClientSecretBasic:
+		req.SetBasicAuth(registration.ClientID, registration.ClientSecret)
+	case authentication.TlsClientAuth:
+		req.SetFormData(map[string]string{"client_id": registration.ClientID})
+	case authentication.PrivateKeyJwt:
+		assertion, err := c.clientAssertion(registration.ClientID)
+		if err != nil {
+			return "", err
+		}
+		req.SetFormData(map[string]string{
+			authentication.ClientAssertionType: authentication.ClientAssertionTypeValue,
+			authentication.ClientAssertion:     assertion,
+		})
+	default:
+		return "", fmt.Errorf("dcr: unsupported token_endpoint_auth_method %q", authMethod)
+	}
+
+	resp, err := send(traceCtx, req, http.MethodPost, c.config.TokenEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("dcr: client credentials grant failed: %w", err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return "", fmt.Errorf("dcr: client credentials grant failed: status %d, body %s", resp.StatusCode(), resp.String())
+	}
+
+	token := struct {
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.Unmarshal(resp.Body(), &token); err != nil {
+		return "", fmt.Errorf("dcr: cannot decode token response: %w", err)
+	}
+	return token.AccessToken, nil
+}
+
+func (c client) clientAssertion(clientID string) (string, error) {
+	now := time.Now()
+	claims := jwt.StandardClaims{
+		Issuer:    clientID,
+		Subject:   clientID,
+		Audience:  c.config.TokenEndpoint,
+		Id:        uuid.New().String(),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(requestLifetime).Unix(),
+	}
+	method, err := authentication.GetSigningAlg(c.config.SigningAlg)
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = c.config.KID
+	return token.SignedString(c.config.SigningCert.Signer())
+}
+
+// send - sends `req` to `url` in a span of its own
+func send(traceCtx context.Context, req *resty.Request, method, url string) (*resty.Response, error) {
+	req.Method = method
+	req.URL = url
+	span := tracing.StartHTTP(traceCtx, req)
+	resp, err := req.Execute(method, url)
+	tracing.EndHTTP(span, resp, err)
+	return resp, err
+}
+
+func clientURL(registrationEndpoint, clientID string) string {
+	return strings.TrimSuffix(registrationEndpoint, "/") + "/" + clientID
+}
@@ -0,0 +1,86 @@
+// Package dcr provides conformance tests for the OBIE Dynamic Client Registration API.
+//
+// The tests register a new client at the ASPSP registration endpoint using a
+// registration request signed with the TPP signing certificate and backed by a
+// software statement assertion (SSA), then retrieve, update and delete that
+// client. Negative cases check that invalid registration requests are rejected.
+package dcr
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/authentication"
+)
+
+const (
+	// APIName - name used to group DCR results, and to locate the DCR OpenAPI specification
+	APIName = "Dynamic Client Registration API"
+	// APIVersion - version of the DCR specification tested
+	APIVersion = "v3.3"
+
+	specRefURI = "https://openbankinguk.github.io/dcr-docs-pub/v3.3/dynamic-client-registration.html"
+
+	defaultScope                 = "openid accounts payments"
+	defaultUnsupportedAuthMethod = "client_secret_jwt"
+)
+
+var (
+	errNoRegistrationEndpoint = errors.New("dcr: registration endpoint not set")
+	errNoSoftwareStatement    = errors.New("dcr: software statement not set")
+	errNoSigningCert          = errors.New("dcr: signing certificate not set")
+
+	errNoExpiredSoftwareStatement = errors.New("dcr: not run, expired_software_statement not set")
+)
+
+// Config - parameters required to run the DCR test cases
+type Config struct {
+	RegistrationEndpoint     string
+	SoftwareStatement        string
+	ExpiredSoftwareStatement string // optional, SSA issued by the directory that has since expired
+	Issuer                   string // audience of the registration request, i.e. the ASPSP issuer
+	RedirectURIs             []string
+	Scope                    string
+	TokenEndpoint            string
+	TokenEndpointAuthMethod  string
+	UnsupportedAuthMethod    string // auth method the ASPSP is expected to reject
+	SigningAlg               string
+	KID                      string
+	SigningCert              authentication.Certificate
+	TransportCert            authentication.Certificate
+}
+
+// Validate - checks the mandatory parameters are present and applies defaults
+func (c *Config) Validate() error {
+	if c.RegistrationEndpoint == "" {
+		return errNoRegistrationEndpoint
+	}
+	if _, err := url.Parse(c.RegistrationEndpoint); err != nil {
+		return err
+	}
+	if c.SoftwareStatement == "" {
+		return errNoSoftwareStatement
+	}
+	if c.SigningCert == nil {
+		return errNoSigningCert
+	}
+	if c.Scope == "" {
+		c.Scope = defaultScope
+	}
+	if c.UnsupportedAuthMethod == "" {
+		c.UnsupportedAuthMethod = defaultUnsupportedAuthMethod
+	}
+	if c.SigningAlg == "" {
+		c.SigningAlg = "PS256"
+	}
+	if c.KID == "" {
+		modulus := base64.RawURLEncoding.EncodeToString(c.SigningCert.PublicKey().N.Bytes())
+		kid, err := authentication.CalcKid(modulus)
+		if err != nil {
+			return err
+		}
+		c.KID = kid
+	}
+	return nil
+}
@@ -0,0 +1,334 @@
+package dcr
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gopkg.in/resty.v1"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors/results"
+	"github.com/OpenBankingUK/conformance-suite/pkg/schema"
+	"github.com/OpenBankingUK/conformance-suite/pkg/tracing"
+)
+
+// ResultSink - receives the DCR test results, satisfied by executors.DaemonController
+type ResultSink interface {
+	AddResult(result results.TestCase)
+	ShouldStop() bool
+}
+
+// Runner - runs the DCR test cases against an ASPSP
+type Runner struct {
+	config    Config
+	client    Client
+	validator schema.Validator
+	logger    *logrus.Entry
+	now       func() time.Time
+}
+
+// NewRunner - creates a Runner validating responses against the bundled DCR OpenAPI specification
+func NewRunner(logger *logrus.Entry, httpClient *resty.Client, config Config) (*Runner, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	validator, err := schema.NewOpenAPI3Validator(APIName, APIVersion)
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{
+		config:    config,
+		client:    NewClient(httpClient, config),
+		validator: validator,
+		logger:    logger.WithField("module", "dcr.Runner"),
+		now:       time.Now,
+	}, nil
+}
+
+// testCase - static description of a DCR test case
+type testCase struct {
+	id       string
+	detail   string
+	method   string
+	path     string
+	endpoint string
+	expected int
+}
+
+// Run - executes the DCR test cases, adding each result to `sink`. Each test case is traced in a
+// span of its own, a child of the span in `traceCtx`.
+func (r *Runner) Run(traceCtx context.Context, sink ResultSink) {
+	registration, ok := r.runPositive(traceCtx, sink)
+	if ok && !sink.ShouldStop() {
+		r.runManagement(traceCtx, sink, registration)
+	}
+	if sink.ShouldStop() {
+		return
+	}
+	r.runNegative(traceCtx, sink)
+}
+
+func (r *Runner) runPositive(traceCtx context.Context, sink ResultSink) (Registration, bool) {
+	tc := testCase{
+		id:       "DCR-001",
+		detail:   "Register a client using a valid registration request",
+		method:   http.MethodPost,
+		path:     "/register",
+		endpoint: r.config.RegistrationEndpoint,
+		expected: http.StatusCreated,
+	}
+	testCtx, span := startTest(traceCtx, tc)
+
+	requestJWT, err := r.signedRequest(r.config.SoftwareStatement, r.now(), nil)
+	if err != nil {
+		addResult(sink, span, r.fail(tc, nil, err))
+		return Registration{}, false
+	}
+
+	resp, err := r.client.Register(testCtx, requestJWT)
+	result := r.check(tc, resp, err)
+	addResult(sink, span, result)
+	if !result.Pass {
+		return Registration{}, false
+	}
+
+	registration := Registration{}
+	if err := json.Unmarshal(resp.Body(), &registration); err != nil || registration.ClientID == "" {
+		return Registration{}, false
+	}
+	return registration, true
+}
+
+func (r *Runner) runManagement(traceCtx context.Context, sink ResultSink, registration Registration) {
+	path := "/register/" + registration.ClientID
+	endpoint := clientURL(r.config.RegistrationEndpoint, registration.ClientID)
+
+	retrieve := testCase{id: "DCR-002", detail: "Retrieve the registered client", method: http.MethodGet, path: path, endpoint: endpoint, expected: http.StatusOK}
+	update := testCase{id: "DCR-003", detail: "Update the registered client", method: http.MethodPut, path: path, endpoint: endpoint, expected: http.StatusOK}
+	remove := testCase{id: "DCR-004", detail: "Delete the registered client", method: http.MethodDelete, path: path, endpoint: endpoint, expected: http.StatusNoContent}
+
+	accessToken, err := r.client.AccessToken(traceCtx, registration)
+	if err != nil {
+		for _, tc := range []testCase{retrieve, update, remove} {
+			_, span := startTest(traceCtx, tc)
+			addResult(sink, span, r.fail(tc, nil, err))
+		}
+		return
+	}
+
+	testCtx, span := startTest(traceCtx, retrieve)
+	resp, err := r.client.Retrieve(testCtx, registration.ClientID, accessToken)
+	result := r.check(retrieve, resp, err)
+	if result.Pass {
+		if errs := sameClient(resp, registration.ClientID); len(errs) > 0 {
+			result = r.fail(retrieve, resp, errs...)
+		}
+	}
+	addResult(sink, span, result)
+	if sink.ShouldStop() {
+		return
+	}
+
+	testCtx, span = startTest(traceCtx, update)
+	requestJWT, err := r.signedRequest(r.config.SoftwareStatement, r.now(), nil)
+	if err != nil {
+		addResult(sink, span, r.fail(update, nil, err))
+	} else {
+		resp, err = r.client.Update(testCtx, registration.ClientID, accessToken, requestJWT)
+		addResult(sink, span, r.check(update, resp, err))
+	}
+	if sink.ShouldStop() {
+		return
+	}
+
+	testCtx, span = startTest(traceCtx, remove)
+	resp, err = r.client.Delete(testCtx, registration.ClientID, accessToken)
+	addResult(sink, span, r.check(remove, resp, err))
+}
+
+func (r *Runner) runNegative(traceCtx context.Context, sink ResultSink) {
+	type negativeCase struct {
+		testCase
+		ssa    string
+		issued time.Time
+		modify func(*RegistrationClaims)
+		key    func() (crypto.Signer, error)
+		notRun error // why the test case cannot be sent, reported as its failure
+	}
+
+	configuredKey := func() (crypto.Signer, error) {
+		return r.config.SigningCert.Signer(), nil
+	}
+	wrongKey := func() (crypto.Signer, error) {
+		return rsa.GenerateKey(rand.Reader, 2048)
+	}
+
+	cases := []negativeCase{
+		{
+			testCase: testCase{id: "DCR-005", detail: "Reject a registration request that has expired"},
+			ssa:      r.config.SoftwareStatement,
+			issued:   r.now().Add(-time.Hour),
+			key:      configuredKey,
+		},
+		{
+			testCase: testCase{id: "DCR-006", detail: "Reject a registration request with an invalid signature"},
+			ssa:      r.config.SoftwareStatement,
+			issued:   r.now(),
+			key:      wrongKey,
+		},
+		{
+			testCase: testCase{id: "DCR-007", detail: "Reject a registration request with an unsupported token_endpoint_auth_method"},
+			ssa:      r.config.SoftwareStatement,
+			issued:   r.now(),
+			key:      configuredKey,
+			modify: func(c *RegistrationClaims) {
+				c.TokenEndpointAuthMethod = r.config.UnsupportedAuthMethod
+			},
+		},
+	}
+	expiredSSA := negativeCase{
+		testCase: testCase{id: "DCR-008", detail: "Reject a registration request with an expired software statement"},
+		ssa:      r.config.ExpiredSoftwareStatement,
+		issued:   r.now(),
+		key:      configuredKey,
+	}
+	if expiredSSA.ssa == "" {
+		expiredSSA.notRun = errNoExpiredSoftwareStatement
+	}
+	cases = append(cases, expiredSSA)
+
+	for _, nc := range cases {
+		if sink.ShouldStop() {
+			return
+		}
+		tc := nc.testCase
+		tc.method = http.MethodPost
+		tc.path = "/register"
+		tc.endpoint = r.config.RegistrationEndpoint
+		tc.expected = http.StatusBadRequest
+		testCtx, span := startTest(traceCtx, tc)
+		if nc.notRun != nil {
+			addResult(sink, span, r.fail(tc, nil, nc.notRun))
+			continue
+		}
+
+		key, err := nc.key()
+		if err != nil {
+			addResult(sink, span, r.fail(tc, nil, err))
+			continue
+		}
+		requestJWT, err := r.signedRequestWithKey(nc.ssa, nc.issued, nc.modify, key)
+		if err != nil {
+			addResult(sink, span, r.fail(tc, nil, err))
+			continue
+		}
+		resp, err := r.client.Register(testCtx, requestJWT)
+		addResult(sink, span, r.check(tc, resp, err))
+	}
+}
+
+// startTest - starts the span of `tc`, a child of the span in `traceCtx`
+func startTest(traceCtx context.Context, tc testCase) (context.Context, trace.Span) {
+	return tracing.Start(traceCtx, "test case",
+		tracing.TestCaseIDKey.String(tc.id),
+		attribute.String("fcs.test_case.name", tc.detail),
+		attribute.String("fcs.api", APIName),
+	)
+}
+
+// addResult - ends the span of the test case `result` is for, then adds `result` to `sink`
+func addResult(sink ResultSink, span trace.Span, result results.TestCase) {
+	span.SetAttributes(attribute.Bool("fcs.test_case.pass", result.Pass))
+	if !result.Pass {
+		span.SetStatus(codes.Error, "test case failed")
+	}
+	span.End()
+	sink.AddResult(result)
+}
+
+func (r *Runner) signedRequest(ssa string, issued time.Time, modify func(*RegistrationClaims)) (string, error) {
+	return r.signedRequestWithKey(ssa, issued, modify, r.config.SigningCert.Signer())
+}
+
+func (r *Runner) signedRequestWithKey(ssa string, issued time.Time, modify func(*RegistrationClaims), key crypto.Signer) (string, error) {
+	claims, err := NewRegistrationClaims(r.config, ssa, issued)
+	if err != nil {
+		return "", err
+	}
+	if modify != nil {
+		modify(&claims)
+	}
+	return claims.Sign(r.config.SigningAlg, r.config.KID, key)
+}
+
+// check - validates the status code and, when present, the body of the response against the DCR specification
+func (r *Runner) check(tc testCase, resp *resty.Response, err error) results.TestCase {
+	if err != nil {
+		return r.fail(tc, resp, err)
+	}
+
+	errs := []error{}
+	if resp.StatusCode() != tc.expected {
+		errs = append(errs, fmt.Errorf("(%s) %s: expected status code %d, got %d", tc.id, tc.detail, tc.expected, resp.StatusCode()))
+	}
+
+	failures, err := r.validator.Validate(schema.HTTPResponse{
+		Method:     tc.method,
+		Path:       tc.path,
+		Header:     resp.Header(),
+		Body:       bytes.NewReader(resp.Body()),
+		StatusCode: resp.StatusCode(),
+	})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("schema validation: %w", err))
+	}
+	for _, failure := range failures {
+		errs = append(errs, errors.New(failure.Message))
+	}
+
+	if len(errs) > 0 {
+		return r.fail(tc, resp, errs...)
+	}
+
+	r.logger.WithFields(logrus.Fields{"ID": tc.id, "result": "PASS"}).Info("test result")
+	return results.NewTestCaseResult(tc.id, true, metrics(resp), []error{}, tc.endpoint, APIName, APIVersion, tc.detail, specRefURI, strconv.Itoa(resp.StatusCode()))
+}
+
+func (r *Runner) fail(tc testCase, resp *resty.Response, errs ...error) results.TestCase {
+	r.logger.WithFields(logrus.Fields{"ID": tc.id, "result": "FAIL", "errs": errs}).Error("test result")
+	status := ""
+	if resp != nil {
+		status = strconv.Itoa(resp.StatusCode())
+	}
+	return results.NewTestCaseFail(tc.id, metrics(resp), errs, tc.endpoint, APIName, APIVersion, tc.detail, specRefURI, status)
+}
+
+func metrics(resp *resty.Response) results.Metrics {
+	if resp == nil {
+		return results.NoMetrics()
+	}
+	return results.NewMetrics(nil, resp.Time(), len(resp.Body()))
+}
+
+func sameClient(resp *resty.Response, clientID string) []error {
+	registration := Registration{}
+	if err := json.Unmarshal(resp.Body(), &registration); err != nil {
+		return []error{fmt.Errorf("cannot decode registration: %w", err)}
+	}
+	if registration.ClientID != clientID {
+		return []error{fmt.Errorf("expected client_id %q, got %q", clientID, registration.ClientID)}
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package dcr
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gopkg.in/resty.v1"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/authentication"
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors/results"
+	"github.com/OpenBankingUK/conformance-suite/pkg/test"
+	"github.com/OpenBankingUK/conformance-suite/pkg/tracing"
+)
+
+type resultCollector struct {
+	results []results.TestCase
+}
+
+func (c *resultCollector) AddResult(result results.TestCase) { c.results = append(c.results, result) }
+func (c *resultCollector) ShouldStop() bool                  { return false }
+
+func TestRunnerRunsAllTestCases(t *testing.T) {
+	cert := newTestCertificate(t)
+	srv := newFakeRegistrationServer(t, cert.PublicKey())
+	defer srv.Close()
+
+	runner, err := NewRunner(test.NullLogger(), resty.New(), Config{
+		RegistrationEndpoint:     srv.URL + "/register",
+		SoftwareStatement:        testSSA(t, time.Now().Add(time.Hour)),
+		ExpiredSoftwareStatement: testSSA(t, time.Now().Add(-time.Hour)),
+		Issuer:                   "https://aspsp.example.com",
+		RedirectURIs:             []string{"https://127.0.0.1:8443/conformancesuite/callback"},
+		TokenEndpoint:            srv.URL + "/token",
+		TokenEndpointAuthMethod:  authentication.ClientSecretBasic,
+		SigningCert:              cert,
+	})
+	require.NoError(t, err)
+
+	collector := &resultCollector{}
+	runner.Run(context.Background(), collector)
+
+	ids := []string{}
+	for _, result := range collector.results {
+		ids = append(ids, result.Id)
+		assert.Truef(t, result.Pass, "%s failed: %v", result.Id, result.Fail)
+		assert.Equal(t, APIName, result.API)
+		assert.Equal(t, APIVersion, result.APIVersion)
+	}
+	assert.Equal(t, []string{"DCR-001", "DCR-002", "DCR-003", "DCR-004", "DCR-005", "DCR-006", "DCR-007", "DCR-008"}, ids)
+}
+
+func TestRunnerFailsExpiredSoftwareStatementWhenNotSet(t *testing.T) {
+	cert := newTestCertificate(t)
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	runner, err := NewRunner(test.NullLogger(), resty.New(), Config{
+		RegistrationEndpoint:    srv.URL + "/register",
+		SoftwareStatement:       testSSA(t, time.Now().Add(time.Hour)),
+		TokenEndpointAuthMethod: authentication.TlsClientAuth,
+		SigningCert:             cert,
+	})
+	require.NoError(t, err)
+
+	collector := &resultCollector{}
+	runner.Run(context.Background(), collector)
+
+	require.NotEmpty(t, collector.results)
+	last := collector.results[len(collector.results)-1]
+	assert.Equal(t, "DCR-008", last.Id)
+	assert.False(t, last.Pass)
+	assert.Equal(t, []string{errNoExpiredSoftwareStatement.Error()}, last.Fail)
+	assert.Equal(t, 4, requests, "DCR-008 is not sent")
+}
+
+func TestRunnerFailsManagementWhenRegistrationFails(t *testing.T) {
+	cert := newTestCertificate(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_client_metadata"}`))
+	}))
+	defer srv.Close()
+
+	runner, err := NewRunner(test.NullLogger(), resty.New(), Config{
+		RegistrationEndpoint:    srv.URL + "/register",
+		SoftwareStatement:       testSSA(t, time.Now().Add(time.Hour)),
+		TokenEndpointAuthMethod: authentication.TlsClientAuth,
+		SigningCert:             cert,
+	})
+	require.NoError(t, err)
+
+	collector := &resultCollector{}
+	runner.Run(context.Background(), collector)
+
+	require.NotEmpty(t, collector.results)
+	assert.Equal(t, "DCR-001", collector.results[0].Id)
+	assert.False(t, collector.results[0].Pass)
+	// management test cases are skipped, negative test cases still run
+	assert.Equal(t, "DCR-005", collector.results[1].Id)
+}
+
+func TestRunnerTracesTestCases(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+	runner, err := NewRunner(test.NullLogger(), resty.New(), Config{
+		RegistrationEndpoint:     srv.URL + "/register",
+		SoftwareStatement:        testSSA(t, time.Now().Add(time.Hour)),
+		ExpiredSoftwareStatement: testSSA(t, time.Now().Add(-time.Hour)),
+		TokenEndpointAuthMethod:  authentication.TlsClientAuth,
+		SigningCert:              newTestCertificate(t),
+	})
+	require.NoError(t, err)
+
+	traceCtx, run := tracing.Start(context.Background(), "run")
+	runner.Run(traceCtx, &resultCollector{})
+	run.End()
+
+	testCases := map[trace.SpanID]string{}
+	requests := map[trace.SpanID]int{}
+	for _, span := range recorder.Ended() {
+		switch span.Name() {
+		case "test case":
+			assert.Equal(t, run.SpanContext().SpanID(), span.Parent().SpanID())
+			for _, kv := range span.Attributes() {
+				if kv.Key == tracing.TestCaseIDKey {
+					testCases[span.SpanContext().SpanID()] = kv.Value.AsString()
+				}
+			}
+		case "HTTP POST":
+			requests[span.Parent().SpanID()]++
+		}
+	}
+	ids := []string{}
+	for spanID, id := range testCases {
+		ids = append(ids, id)
+		assert.Equalf(t, 1, requests[spanID], "requests sent by %s", id)
+	}
+	assert.ElementsMatch(t, []string{"DCR-001", "DCR-005", "DCR-006", "DCR-007", "DCR-008"}, ids)
+}
+
+func TestNewRunnerRequiresSoftwareStatement(t *testing.T) {
+	_, err := NewRunner(test.NullLogger(), resty.New(), Config{RegistrationEndpoint: "https://aspsp.example.com/register"})
+	assert.Equal(t, errNoSoftwareStatement, err)
+}
+
+// newFakeRegistrationServer - a minimal ASPSP that verifies registration requests
+func newFakeRegistrationServer(t *testing.T, key *rsa.PublicKey) *httptest.Server {
+	const clientID = "client-10001"
+	registered := map[string]interface{}{
+		"client_id":                    clientID,
+		"client_secret":                "secret",
+		"token_endpoint_auth_method":   authentication.ClientSecretBasic,
+		"grant_types":                  []string{"authorization_code", "client_credentials"},
+		"software_statement":           "ssa",
+		"id_token_signed_response_alg": "PS256",
+		"request_object_signing_alg":   "PS256",
+	}
+
+	registrationError := func(w http.ResponseWriter, description string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client_metadata", "error_description": description})
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"token-10001","token_type":"Bearer"}`))
+	})
+	mux.HandleFunc("/register", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		claims := &RegistrationClaims{}
+		_, err := jwt.ParseWithClaims(string(body), claims, func(*jwt.Token) (interface{}, error) { return key, nil })
+		if err != nil {
+			registrationError(w, err.Error())
+			return
+		}
+		ssa := &jwt.StandardClaims{}
+		if _, _, err := new(jwt.Parser).ParseUnverified(claims.SoftwareStatement, ssa); err != nil || ssa.Valid() != nil {
+			registrationError(w, "invalid or expired software_statement")
+			return
+		}
+		if claims.TokenEndpointAuthMethod != authentication.ClientSecretBasic {
+			registrationError(w, "unsupported token_endpoint_auth_method")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(registered)
+	})
+	mux.HandleFunc("/register/"+clientID, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token-10001" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method {
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(registered)
+		}
+	})
+	return httptest.NewServer(mux)
+}
+
+func testSSA(t *testing.T, expires time.Time) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ssa, err := jwt.NewWithClaims(authentication.SigningMethodPS256, jwt.MapClaims{
+		"iss":         "OpenBanking Ltd",
+		"software_id": "software-10001",
+		"exp":         expires.Unix(),
+	}).SignedString(key)
+	require.NoError(t, err)
+	return ssa
+}
+
+func newTestCertificate(t *testing.T) authentication.Certificate {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{Organization: []string{"0015800001041RHAAY"}, CommonName: "software-10001"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	publicPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	privatePem := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	cert, err := authentication.NewCertificate(strings.TrimSpace(string(publicPem)), string(privatePem))
+	require.NoError(t, err)
+	return cert
+}
@@ -32,6 +32,15 @@ type ModelDiscoveryItem struct {
 	ResourceBaseURI        string                `json:"resourceBaseUri,omitempty" validate:"required,url"`
 	ResourceIds            ResourceIds           `json:"resourceIds,omitempty" validate:"-"`
 	Endpoints              []ModelEndpoint       `json:"endpoints,omitempty" validate:"required,gt=0,dive"`
+	StrictSchema           *ModelStrictSchema    `json:"strictSchema,omitempty" validate:"omitempty"`
+}
+
+// ModelStrictSchema - turns on strict schema validation of the responses of a discovery item,
+// reporting properties the spec does not define and codes outside the OB code lists
+type ModelStrictSchema struct {
+	// Extensions - JSON pointers of the fields the ASPSP documents as extensions to the spec,
+	// with `*` for any array index, e.g. `/Data/Account/*/Nickname2`
+	Extensions []string `json:"extensions,omitempty" validate:"dive,jsonpointer"`
 }
 
 // ResourceIds section allows the replacement of endpoint resourceid values with real data parameters like accountid
@@ -44,7 +53,20 @@ type ModelAPISpecification struct {
 	Version       string `json:"version" validate:"required"`
 	SchemaVersion string `json:"schemaVersion" validate:"required,url"`
 	Manifest      string `json:"manifest" validate:"required,fileorhttps"`
-	SpecType      string `json:"-"`
+	// SpecFile - file:// or https:// URL of a spec to validate against in place of the bundled one,
+	// such as a pre-release version or a bank extension. Set to SchemaVersion to load the published spec.
+	SpecFile string `json:"specFile,omitempty" validate:"omitempty,fileorhttps"`
+	SpecType string `json:"-"`
+}
+
+// SpecLocation - where the schema package finds the spec this API is validated against
+func (s ModelAPISpecification) SpecLocation() schema.SpecLocation {
+	return schema.SpecLocation{
+		Name:          s.Name,
+		Version:       s.Version,
+		SchemaVersion: s.SchemaVersion,
+		Override:      s.SpecFile,
+	}
 }
 
 // ModelEndpoint - Endpoint and methods that have been implemented by implementer.
@@ -99,7 +121,7 @@ func GetConditionalProperties(disco *Model) ([]ConditionalAPIProperties, bool, e
 	var haveProperties bool
 	conditionalProps := make([]ConditionalAPIProperties, 0, len(disco.DiscoveryModel.DiscoveryItems))
 	for k, discoitem := range disco.DiscoveryModel.DiscoveryItems {
-		validator, err := schema.NewSwaggerOBSpecValidator(discoitem.APISpecification.Name, discoitem.APISpecification.Version)
+		validator, err := schema.NewSpecValidator(discoitem.APISpecification.SpecLocation())
 		if err != nil {
 			logrus.Error(err)
 			return nil, false, err
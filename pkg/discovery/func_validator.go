@@ -44,6 +44,7 @@ const (
 	requiredErrorFormat          = "Field '%s' is required"
 	emptyArrayErrorFormat        = "Field '%s' cannot be empty"
 	fileOrHttpsErrorFormat       = "Field '%s' must be 'file://' or 'https://'"
+	jsonPointerErrorFormat       = "Field '%s' must be a JSON pointer starting with '/'"
 )
 
 // Validate - validates a discovery model, returns true when valid,
@@ -65,6 +66,12 @@ func Validate(checker model.ConditionalityChecker, discovery *Model) (bool, []Va
 	if err := v.RegisterValidation("fileorhttps", httpsValidate); err != nil {
 		return false, nil, errors.Wrap(err, "register `fileorhttps` validation")
 	}
+	jsonPointerValidate := func(f validation.FieldLevel) bool {
+		return strings.HasPrefix(f.Field().String(), "/")
+	}
+	if err := v.RegisterValidation("jsonpointer", jsonPointerValidate); err != nil {
+		return false, nil, errors.Wrap(err, "register `jsonpointer` validation")
+	}
 
 	if err := v.Struct(discovery); err != nil {
 		failures = appendStructValidationErrors(err.(validation.ValidationErrors), failures)
@@ -95,6 +102,8 @@ func appendStructValidationErrors(errs validation.ValidationErrors, failures []V
 			message = fmt.Sprintf(emptyArrayErrorFormat, key)
 		case "fileorhttps":
 			message = fmt.Sprintf(fileOrHttpsErrorFormat, key)
+		case "jsonpointer":
+			message = fmt.Sprintf(jsonPointerErrorFormat, key)
 		}
 		failure := ValidationFailure{
 			Key:   key,
@@ -483,6 +483,43 @@ func TestValidate(t *testing.T) {
 			},
 		})
 	})
+
+	t.Run("Validation should fail when `specFile` is a http URL instead of https", func(t *testing.T) {
+		testValidateFailures(t, conditionalityCheckerMock{}, &invalidTest{
+			discoveryJSON: discoveryStub("apiSpecification", `{
+				"name": "Account and Transaction API Specification",
+				"url": "https://openbanking.atlassian.net/wiki/spaces/DZ/pages/937820271/Account+and+Transaction+API+Specification+-+v3.1",
+				"version": "v3.1.0",
+				"schemaVersion": "https://raw.githubusercontent.com/OpenBankingUK/read-write-api-specs/v3.1.0/dist/account-info-swagger.json",
+				"manifest": "https://www.example.com",
+				"specFile": "http://www.example.com/account-info-swagger.json"
+			}`),
+			failures: []ValidationFailure{
+				{
+					Key:   "DiscoveryModel.DiscoveryItems[0].APISpecification.SpecFile",
+					Error: "Field 'DiscoveryModel.DiscoveryItems[0].APISpecification.SpecFile' must be 'file://' or 'https://'",
+				},
+			},
+		})
+	})
+
+	t.Run("Validation should fail when a `strictSchema` extension is not a JSON pointer", func(t *testing.T) {
+		testValidateFailures(t, conditionalityCheckerMock{}, &invalidTest{
+			discoveryJSON: discoveryStub("endpoints", `[
+				{
+					"method": "GET",
+					"path": "/accounts"
+				}
+			],
+			"strictSchema": {"extensions": ["/Data/Account/*/Nickname2", "Data.Account.Nickname2"]}`),
+			failures: []ValidationFailure{
+				{
+					Key:   "DiscoveryModel.DiscoveryItems[0].StrictSchema.Extensions[1]",
+					Error: "Field 'DiscoveryModel.DiscoveryItems[0].StrictSchema.Extensions[1]' must be a JSON pointer starting with '/'",
+				},
+			},
+		})
+	})
 }
 
 func TestDiscovery_Version(t *testing.T) {
@@ -80,9 +80,11 @@ func disableTestRun(t *testing.T) {
 	validatorEngine := discovery.NewFuncValidator(model.NewConditionalityChecker())
 	testGenerator := generation.NewGenerator()
 	tlsValidator := discovery.NewStdTLSValidator(tls.VersionTLS11)
-	journey := server.NewJourney(logger, testGenerator, validatorEngine, tlsValidator, false)
+	newJourney := func(string) server.Journey {
+		return server.NewJourney(logger, testGenerator, validatorEngine, tlsValidator, false)
+	}
 
-	echoServer := server.NewServer(journey, logger, ver)
+	echoServer := server.NewServer(server.NewJourneys(newJourney, 0, logger), nil, logger, ver)
 
 	go func() {
 		require.EqualError(t, echoServer.StartTLS(":0", certFile, keyFile), "http: Server closed")
@@ -1,21 +1,24 @@
 package executors
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/OpenBankingUK/conformance-suite/pkg/authentication"
 	"github.com/OpenBankingUK/conformance-suite/pkg/manifest"
 	"github.com/OpenBankingUK/conformance-suite/pkg/model"
+	"github.com/OpenBankingUK/conformance-suite/pkg/schemaprops"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
 func getCbpiiConsents(
+	traceCtx context.Context,
 	definition RunDefinition,
 	requiredTokens []manifest.RequiredTokens,
 	ctx *model.Context,
 ) (TokenConsentIDs, error) {
-	executor := &Executor{}
+	executor := &Executor{Client: definition.Client}
 	err := executor.SetCertificates(definition.SigningCert, definition.TransportCert)
 	if err != nil {
 		logrus.Error(fmt.Sprintf("error running cbpii consent acquisition: %s", err))
@@ -27,7 +30,7 @@ func getCbpiiConsents(
 		logrus.Tracef("%#v", rt)
 	}
 
-	requiredTokens, err = runCbpiiConsents(requiredTokens, ctx, executor)
+	requiredTokens, err = runCbpiiConsents(traceCtx, requiredTokens, ctx, executor, definition.Properties)
 	if err != nil {
 		logrus.Errorf("getCbpiiConsents error: %s", err)
 	}
@@ -42,7 +45,7 @@ func getCbpiiConsents(
 	return consentItems, err
 }
 
-func runCbpiiConsents(rt []manifest.RequiredTokens, ctx *model.Context, executor *Executor) ([]manifest.RequiredTokens, error) {
+func runCbpiiConsents(traceCtx context.Context, rt []manifest.RequiredTokens, ctx *model.Context, executor *Executor, properties schemaprops.PropertyCollector) ([]manifest.RequiredTokens, error) {
 	localCtx := model.Context{}
 	localCtx.PutContext(ctx)
 	localCtx.PutString("scope", "fundsconfirmations")
@@ -91,7 +94,7 @@ func runCbpiiConsents(rt []manifest.RequiredTokens, ctx *model.Context, executor
 	}
 
 	tc.ProcessReplacementFields(&localCtx, true)
-	err = executePaymentTest(&tc, &localCtx, executor)
+	err = executePaymentTest(traceCtx, &tc, &localCtx, executor, properties)
 	if err != nil {
 		return nil, errors.Wrap(err, "Cbpii PSU consent execute clientCredential grant testcase failed")
 	}
@@ -115,7 +118,7 @@ func runCbpiiConsents(rt []manifest.RequiredTokens, ctx *model.Context, executor
 		test.InjectBearerToken(ccgBearerToken)
 		test.Input.Headers["Content-Type"] = "application/json"
 
-		err = executePaymentTest(&test, &localCtx, executor)
+		err = executePaymentTest(traceCtx, &test, &localCtx, executor, properties)
 		if err != nil {
 			return nil, errors.Wrap(err, "Cbpii PSU consent test case failed")
 		}
@@ -125,6 +128,7 @@ func runCbpiiConsents(rt []manifest.RequiredTokens, ctx *model.Context, executor
 		}
 		localCtx.PutString("consent_id", v.ConsentID)
 		localCtx.PutString("token_name", v.Name)
+		putConsentState(&localCtx, v.Name)
 
 		exchange, err := readPsuExchange()
 		if err != nil {
@@ -142,7 +146,7 @@ func runCbpiiConsents(rt []manifest.RequiredTokens, ctx *model.Context, executor
 		}
 
 		localCtx.DumpContext("before exchange", "token_name", "consent_id")
-		err = executePaymentTest(&exchange, &localCtx, executor)
+		err = executePaymentTest(traceCtx, &exchange, &localCtx, executor, properties)
 		if err != nil {
 			return nil, errors.Wrap(err, "Cbpii PSU consent exchange code failed")
 		}
@@ -1,6 +1,7 @@
 package executors
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"regexp"
@@ -12,7 +13,10 @@ import (
 	"github.com/OpenBankingUK/conformance-suite/pkg/generation"
 	"github.com/OpenBankingUK/conformance-suite/pkg/manifest"
 	"github.com/OpenBankingUK/conformance-suite/pkg/model"
+	"github.com/OpenBankingUK/conformance-suite/pkg/schemaprops"
+	"github.com/OpenBankingUK/conformance-suite/pkg/tracing"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // GetHeadlessConsent -
@@ -20,8 +24,11 @@ func GetHeadlessConsent(definition RunDefinition, ctx *model.Context, specRun *g
 	logger := logrus.WithFields(logrus.Fields{
 		"module": "GetHeadlessConsent",
 	})
+	definition = definition.withDefaults()
 
 	allRequiredTokens := []manifest.RequiredTokens{}
+	traceCtx, span := tracing.Start(context.Background(), "consent acquisition", attribute.String("fcs.consent_type", "headless"))
+	defer span.End()
 
 	for specType := range permissions {
 		logger.Tracef("Getting Headless Consent for api type: %s", specType)
@@ -32,13 +39,13 @@ func GetHeadlessConsent(definition RunDefinition, ctx *model.Context, specRun *g
 
 		switch specType {
 		case "accounts":
-			requiredTokens, err := getAccountsHeadlessTokens(tests, ctx, definition, logger)
+			requiredTokens, err := getAccountsHeadlessTokens(traceCtx, tests, ctx, definition, logger)
 			if err != nil {
 				return nil, err
 			}
 			allRequiredTokens = append(allRequiredTokens, requiredTokens...)
 		case "payments":
-			requiredTokens, err := getPaymentHeadlessTokens(tests, ctx, definition, permissions["payments"], logger)
+			requiredTokens, err := getPaymentHeadlessTokens(traceCtx, tests, ctx, definition, permissions["payments"], logger)
 			if err != nil {
 				return nil, err
 			}
@@ -51,10 +58,10 @@ func GetHeadlessConsent(definition RunDefinition, ctx *model.Context, specRun *g
 	return allRequiredTokens, nil
 }
 
-func getPaymentHeadlessTokens(paymentTests []model.TestCase, ctx *model.Context, definition RunDefinition, requiredTokens []manifest.RequiredTokens, logger *logrus.Entry) ([]manifest.RequiredTokens, error) {
+func getPaymentHeadlessTokens(traceCtx context.Context, paymentTests []model.TestCase, ctx *model.Context, definition RunDefinition, requiredTokens []manifest.RequiredTokens, logger *logrus.Entry) ([]manifest.RequiredTokens, error) {
 	logger.Debug("getPaymentHeadlessTokens")
 
-	executor := Executor{}
+	executor := Executor{Client: definition.Client}
 	err := executor.SetCertificates(definition.SigningCert, definition.TransportCert)
 	if err != nil {
 		return nil, err
@@ -62,12 +69,12 @@ func getPaymentHeadlessTokens(paymentTests []model.TestCase, ctx *model.Context,
 
 	logger.Debugf("we have %d required tokens", len(requiredTokens))
 
-	requiredTokens, err = runPaymentConsents(requiredTokens, ctx, &executor)
+	requiredTokens, err = runPaymentConsents(traceCtx, requiredTokens, ctx, &executor, definition.Properties)
 	if err != nil {
 		logger.Errorf("getPaymentConsents error: " + err.Error())
 	}
 
-	tokendata, err := CallPaymentHeadlessConsentUrls(&requiredTokens, ctx, logger)
+	tokendata, err := CallPaymentHeadlessConsentUrls(definition.Client, &requiredTokens, ctx, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -86,8 +93,8 @@ func getPaymentHeadlessTokens(paymentTests []model.TestCase, ctx *model.Context,
 
 }
 
-// CallPaymentHeadlessConsentUrls -
-func CallPaymentHeadlessConsentUrls(rt *[]manifest.RequiredTokens, ctx *model.Context, logger *logrus.Entry) (map[string]string, error) {
+// CallPaymentHeadlessConsentUrls - the requests are sent with `client`
+func CallPaymentHeadlessConsentUrls(client *resty.Client, rt *[]manifest.RequiredTokens, ctx *model.Context, logger *logrus.Entry) (map[string]string, error) {
 	var matchingGroup []string
 	exchangeCode := ""
 	exhangeCodeRegex := "code=([^&]*)&"
@@ -97,7 +104,7 @@ func CallPaymentHeadlessConsentUrls(rt *[]manifest.RequiredTokens, ctx *model.Co
 		endpoint := tokendata.ConsentURL
 		var resp *resty.Response
 
-		resp, err := resty.R().
+		resp, err := client.R().
 			SetHeader("accept", "*/*").
 			Get(endpoint)
 
@@ -138,7 +145,7 @@ func CallPaymentHeadlessConsentUrls(rt *[]manifest.RequiredTokens, ctx *model.Co
 			return nil, err
 		}
 
-		resp, err = resty.R().
+		resp, err = client.R().
 			SetHeader("content-type", "application/x-www-form-urlencoded").
 			SetHeader("accept", "application/json").
 			SetHeader("authorization", "Basic "+params["basic_authentication"]).
@@ -182,7 +189,7 @@ func getAccessTokenFromJSONResponse(body string, logger *logrus.Entry) (string,
 	return accessToken, nil
 }
 
-func getAccountsHeadlessTokens(tests []model.TestCase, ctx *model.Context, definition RunDefinition, logger *logrus.Entry) ([]manifest.RequiredTokens, error) {
+func getAccountsHeadlessTokens(traceCtx context.Context, tests []model.TestCase, ctx *model.Context, definition RunDefinition, logger *logrus.Entry) ([]manifest.RequiredTokens, error) {
 	logger.Debug("getAccountsHeadlessTokens")
 	bodyDataStart := "{\"Data\": { \"Permissions\": ["
 	//TODO: sort out consent transaction timestamps
@@ -196,7 +203,7 @@ func getAccountsHeadlessTokens(tests []model.TestCase, ctx *model.Context, defin
 	}
 
 	bodyDataEnd := fmt.Sprintf(`], "TransactionFromDateTime": "%s", "TransactionToDateTime": "%s" },  "Risk": {} }`, txnFrom, txnTo)
-	executor := NewExecutor()
+	executor := NewExecutor(definition.Client)
 	err = executor.SetCertificates(definition.SigningCert, definition.TransportCert)
 	if err != nil {
 		return nil, err
@@ -222,7 +229,7 @@ func getAccountsHeadlessTokens(tests []model.TestCase, ctx *model.Context, defin
 		localCtx.PutString("permission_payload", bodyData)
 		localCtx.PutString("result_token", tokenName)
 
-		returnCtx, err := executeComponent(&localCtx, executor)
+		returnCtx, err := executeComponent(traceCtx, definition.Client, definition.Properties, &localCtx, executor)
 		if err != nil {
 			return nil, err
 		}
@@ -249,8 +256,8 @@ func getHeadlessTokenComponent() (*model.Component, error) {
 
 }
 
-// ExecuteComponent -
-func executeComponent(ctx *model.Context, executor TestCaseExecutor) (*model.Context, error) {
+// ExecuteComponent - the requests are prepared for `client`
+func executeComponent(traceCtx context.Context, client *resty.Client, properties schemaprops.PropertyCollector, ctx *model.Context, executor TestCaseExecutor) (*model.Context, error) {
 	comp, err := getHeadlessTokenComponent()
 	if err != nil {
 		return nil, err
@@ -274,16 +281,17 @@ func executeComponent(ctx *model.Context, executor TestCaseExecutor) (*model.Con
 		_, _ = k, test
 		logrus.Debug("Executing ------->>")
 
-		req, err := test.Prepare(executeCtx)
+		req, err := test.Prepare(client, executeCtx)
 		if err != nil {
 			return &model.Context{}, err
 		}
-		resp, _, err := executor.ExecuteTestCase(req, &test, executeCtx)
+		resp, _, err := executor.ExecuteTestCase(traceCtx, req, &test, executeCtx)
 		if err != nil {
 			return &model.Context{}, fmt.Errorf("Test case %s failed with error %s", test.ID, err.Error())
 		}
 
 		result, errs := test.Validate(resp, executeCtx)
+		collectProperties(properties, &test, resp)
 		if errs != nil {
 			return &model.Context{}, fmt.Errorf("Test case %s Validation faiilure error %s", test.ID, errs[0].Error())
 		}
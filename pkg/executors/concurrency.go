@@ -0,0 +1,80 @@
+package executors
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"gopkg.in/resty.v1"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors/results"
+	"github.com/OpenBankingUK/conformance-suite/pkg/model"
+	"github.com/OpenBankingUK/conformance-suite/pkg/tracing"
+)
+
+// executeConcurrently - sends `r` as `t.Concurrency.Requests` identical requests, released at the
+// same time. The status codes they get are kept in `t.ConcurrentStatus`, and the response returned
+// is the one `t` is validated against, see Concurrency.Representative.
+func (e *Executor) executeConcurrently(traceCtx context.Context, r *resty.Request, t *model.TestCase) (*resty.Response, results.Metrics, error) {
+	requests := make([]*resty.Request, t.Concurrency.Requests)
+	for i := range requests {
+		requests[i] = cloneRequest(e.Client, r)
+		// each its own key, so the ASPSP cannot answer them as one request sent again
+		if t.Input.IdempotencyKey {
+			requests[i].SetHeader("x-idempotency-key", fmt.Sprintf("%s-%d", r.Header.Get("x-idempotency-key"), i+1))
+		}
+	}
+
+	e.appMsg(fmt.Sprintf("attempting %d concurrent %s %s", len(requests), r.Method, r.URL))
+	responses := make([]*resty.Response, len(requests))
+	errs := make([]error, len(requests))
+	start := make(chan struct{})
+	wg := sync.WaitGroup{}
+	for i, req := range requests {
+		wg.Add(1)
+		go func(i int, req *resty.Request) {
+			defer wg.Done()
+			span := tracing.StartHTTP(traceCtx, req)
+			<-start
+			responses[i], errs[i] = req.Execute(req.Method, req.URL)
+			tracing.EndHTTP(span, responses[i], errs[i])
+		}(i, req)
+	}
+	close(start)
+	wg.Wait()
+
+	t.ConcurrentStatus = make([]int, len(responses))
+	for i, resp := range responses {
+		if errs[i] != nil {
+			return resp, responseMetrics(t, resp), errs[i]
+		}
+		t.ConcurrentStatus[i] = resp.StatusCode()
+	}
+	e.appMsg(fmt.Sprintf("Concurrent responses: %v", t.ConcurrentStatus))
+
+	resp := responses[t.Concurrency.Representative(t.ConcurrentStatus)]
+	t.StatusCode = resp.Status()
+	return resp, responseMetrics(t, resp), nil
+}
+
+// cloneRequest - a request with the method, URL, headers, query parameters, form data and body
+// of `r`, which can be sent alongside it with `client`
+func cloneRequest(client *resty.Client, r *resty.Request) *resty.Request {
+	clone := client.R()
+	clone.Method = r.Method
+	clone.URL = r.URL
+	clone.Header = r.Header.Clone()
+	clone.QueryParam = cloneValues(r.QueryParam)
+	clone.FormData = cloneValues(r.FormData)
+	clone.Body = r.Body
+	return clone
+}
+
+func cloneValues(values url.Values) url.Values {
+	clone := make(url.Values, len(values))
+	for k, v := range values {
+		clone[k] = append([]string(nil), v...)
+	}
+	return clone
+}
@@ -0,0 +1,101 @@
+package executors
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/resty.v1"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/model"
+)
+
+// singleUseConsentASPSP - creates one payment, later payments on the consent are rejected. Unless
+// `locked`, the consent is checked by every request before any consumes it, so payments sent at
+// once are all created.
+func singleUseConsentASPSP(locked bool, keys *sync.Map) *httptest.Server {
+	lock := sync.Mutex{}
+	consumed := false
+	checked := 0
+	allChecked := make(chan struct{})
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys.Store(r.Header.Get("x-idempotency-key"), true)
+		lock.Lock()
+		available := !consumed
+		if locked {
+			consumed = true
+			lock.Unlock()
+		} else {
+			if checked++; checked == 3 {
+				close(allChecked)
+			}
+			lock.Unlock()
+			<-allChecked
+		}
+		if !available {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"Data": {"DomesticPaymentId": "pv3-1"}}`)
+	}))
+}
+
+func concurrentPayment(client *resty.Client, url string) (model.TestCase, error) {
+	tc := model.MakeTestCase()
+	tc.ID = "OB-301-DOP-100650"
+	tc.Input.Method = http.MethodPost
+	tc.Input.Endpoint = url + "/domestic-payments"
+	tc.Input.IdempotencyKey = true
+	tc.Input.RequestBody = `{"Data": {"ConsentId": "sdp-1"}}`
+	tc.Expect.StatusCode = http.StatusCreated
+	tc.Concurrency = &model.Concurrency{
+		Requests: 3,
+		Expect:   []model.ConcurrentResponses{{StatusCode: "201", Count: 1}, {StatusCode: "4xx"}},
+	}
+	_, err := tc.Prepare(client, &model.Context{})
+	return tc, err
+}
+
+func TestExecuteTestCaseConcurrently(t *testing.T) {
+	keys := &sync.Map{}
+	aspsp := singleUseConsentASPSP(true, keys)
+	defer aspsp.Close()
+	client := resty.New()
+	tc, err := concurrentPayment(client, aspsp.URL)
+	require.NoError(t, err)
+
+	resp, _, err := NewExecutor(client).ExecuteTestCase(context.Background(), tc.Request, &tc, &model.Context{})
+
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int{http.StatusCreated, http.StatusBadRequest, http.StatusBadRequest}, tc.ConcurrentStatus)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode(), "the response validated is the one that created the payment")
+	sent := 0
+	keys.Range(func(_, _ interface{}) bool { sent++; return true })
+	assert.Equal(t, 3, sent, "each request has an x-idempotency-key of its own")
+
+	pass, errs := tc.Validate(resp, &model.Context{})
+	assert.True(t, pass)
+	assert.Empty(t, errs)
+}
+
+func TestExecuteTestCaseConcurrentlyRace(t *testing.T) {
+	aspsp := singleUseConsentASPSP(false, &sync.Map{})
+	defer aspsp.Close()
+	client := resty.New()
+	tc, err := concurrentPayment(client, aspsp.URL)
+	require.NoError(t, err)
+
+	resp, _, err := NewExecutor(client).ExecuteTestCase(context.Background(), tc.Request, &tc, &model.Context{})
+
+	require.NoError(t, err)
+	assert.Equal(t, []int{http.StatusCreated, http.StatusCreated, http.StatusCreated}, tc.ConcurrentStatus)
+	_, errs := tc.Validate(resp, &model.Context{})
+	require.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], "concurrency: expected 1 of 3 requests to get 201, got 3 (status codes [201 201 201])")
+}
@@ -1,37 +1,46 @@
 package executors
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/OpenBankingUK/conformance-suite/pkg/authentication"
 	"github.com/OpenBankingUK/conformance-suite/pkg/generation"
 	"github.com/OpenBankingUK/conformance-suite/pkg/manifest"
+	"github.com/OpenBankingUK/conformance-suite/pkg/metrics"
 	"github.com/OpenBankingUK/conformance-suite/pkg/model"
+	"github.com/OpenBankingUK/conformance-suite/pkg/tracing"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 	resty "gopkg.in/resty.v1"
 )
 
 const (
 	// consentChannelTimeout - seconds in 5 minutes.
 	consentChannelTimeout = 300
+	// consentStateSeparator - separates the token name from the journey ID in the OAuth state
+	consentStateSeparator = "."
 )
 
-// GetPsuConsent -
-func GetPsuConsent(definition RunDefinition, ctx *model.Context, runTests *generation.SpecRun, permissions map[string][]manifest.RequiredTokens) (TokenConsentIDs, map[string]string, error) {
+// GetPsuConsent - the requests are traced under the span in `traceCtx`
+func GetPsuConsent(traceCtx context.Context, definition RunDefinition, ctx *model.Context, runTests *generation.SpecRun, permissions map[string][]manifest.RequiredTokens) (TokenConsentIDs, map[string]string, error) {
 	var consentIdsToReturn TokenConsentIDs
+	definition = definition.withDefaults()
 
 	for specType := range permissions {
 		logrus.Tracef("Getting PSU Consent for api type: %s", specType)
 
 		switch specType {
 		case "accounts":
-			consentIds, _, err := getAccountConsents(definition, permissions["accounts"], ctx)
+			consentIds, _, err := getAccountConsents(traceCtx, definition, permissions["accounts"], ctx)
 			consentIdsToReturn = append(consentIdsToReturn, consentIds...)
 			if err != nil {
 				logrus.Error("GetPSUConsent - accounts error: " + err.Error())
@@ -39,21 +48,21 @@ func GetPsuConsent(definition RunDefinition, ctx *model.Context, runTests *gener
 			}
 
 		case "payments":
-			consentIds, err := getPaymentConsents(definition, permissions["payments"], ctx)
+			consentIds, err := getPaymentConsents(traceCtx, definition, permissions["payments"], ctx)
 			consentIdsToReturn = append(consentIdsToReturn, consentIds...)
 			if err != nil {
 				logrus.Error("GetPSUConsent - payments error: " + err.Error())
 				return nil, nil, err
 			}
 		case "cbpii":
-			consentIds, err := getCbpiiConsents(definition, permissions["cbpii"], ctx)
+			consentIds, err := getCbpiiConsents(traceCtx, definition, permissions["cbpii"], ctx)
 			consentIdsToReturn = append(consentIdsToReturn, consentIds...)
 			if err != nil {
 				logrus.Error("GetPSUConsent - cbpii error: " + err.Error())
 				return nil, nil, err
 			}
 		case "vrps":
-			consentIds, err := getPaymentConsents(definition, permissions["vrps"], ctx)
+			consentIds, err := getPaymentConsents(traceCtx, definition, permissions["vrps"], ctx)
 			consentIdsToReturn = append(consentIdsToReturn, consentIds...)
 			if err != nil {
 				logrus.Error("GetPSUConsent - vrps error: " + err.Error())
@@ -85,7 +94,7 @@ func getSpecForSpecType(stype string, specRun *generation.SpecRun) ([]model.Test
 }
 
 // getAccountConsents - get required tokens
-func getAccountConsents(definition RunDefinition, permissions []manifest.RequiredTokens, ctx *model.Context) (TokenConsentIDs, map[string]string, error) {
+func getAccountConsents(traceCtx context.Context, definition RunDefinition, permissions []manifest.RequiredTokens, ctx *model.Context) (TokenConsentIDs, map[string]string, error) {
 	consentIDChannel := make(chan TokenConsentIDItem, 100)
 	logger := logrus.StandardLogger().WithField("module", "getAccountConsents")
 	logger.Tracef("getAccountConsents")
@@ -106,7 +115,7 @@ func getAccountConsents(definition RunDefinition, permissions []manifest.Require
 		tokenAcquisitionType := definition.DiscoModel.DiscoveryModel.TokenAcquisition
 		permissionString := buildPermissionString(permissionList)
 		consentInfo := TokenConsentIDItem{TokenName: tokenName, Permissions: permissionString}
-		err := runner.RunConsentAcquisition(consentInfo, ctx, tokenAcquisitionType, consentIDChannel)
+		err := runner.RunConsentAcquisition(traceCtx, consentInfo, ctx, tokenAcquisitionType, consentIDChannel)
 		if err != nil {
 			logger.WithError(err).Debug("InitiationConsentAcquisition")
 		}
@@ -180,6 +189,26 @@ func buildPermissionString(permissionSlice []string) string {
 	return permissions
 }
 
+// putConsentState - puts the OAuth state of the consent URL for `tokenName` in `ctx` as
+// `consent_state`. It also names the journey in `ctx`, if any, so the consent callback reaches
+// the journey that asked for the consent, see SplitConsentState.
+func putConsentState(ctx *model.Context, tokenName string) {
+	state := tokenName
+	if journeyID, err := ctx.GetString("journey_id"); err == nil && journeyID != "" {
+		state += consentStateSeparator + journeyID
+	}
+	ctx.PutString("consent_state", state)
+}
+
+// SplitConsentState - the token name and journey ID in the OAuth `state` of a consent callback.
+// The journey ID is empty when the state is a token name only.
+func SplitConsentState(state string) (tokenName, journeyID string) {
+	if i := strings.LastIndex(state, consentStateSeparator); i >= 0 {
+		return state[:i], state[i+len(consentStateSeparator):]
+	}
+	return state, ""
+}
+
 // ExchangeParameters - Captures the parameters require to exchange a code for an access token
 type ExchangeParameters struct {
 	Code                string
@@ -190,70 +219,115 @@ type ExchangeParameters struct {
 	TokenName           string
 }
 
-// ExchangeCodeForAccessToken - runs a testcase to perform this operation
-func ExchangeCodeForAccessToken(tokenName, code string, ctx *model.Context) (accesstoken string, err error) {
+// ExchangeCodeForAccessToken - runs a testcase to perform this operation with `client`, traced
+// under the span in `traceCtx`
+func ExchangeCodeForAccessToken(traceCtx context.Context, client *resty.Client, tokenName, code string, ctx *model.Context) (AccessToken, error) {
 	logger := logrus.StandardLogger().WithFields(logrus.Fields{
 		"module":    "ExchangeCodeForAccessToken",
 		"tokenName": tokenName,
 		"code":      code,
 	})
 
-	grantToken, err := exchangeCodeForToken(code, ctx, logger)
+	traceCtx, span := tracing.Start(traceCtx, "token exchange",
+		tracing.TokenNameKey.String(tokenName),
+		attribute.String("oauth.grant_type", authentication.GrantTypeAuthorizationCode),
+	)
+	started := time.Now()
+	grantToken, err := exchangeCodeForToken(traceCtx, client, code, ctx, logger)
+	metrics.ObserveTokenRequest(authentication.GrantTypeAuthorizationCode, time.Since(started), err)
+	tracing.End(span, err)
 	if err != nil {
 		logger.WithFields(logrus.Fields{
 			"err": err,
 		}).Error("exchangeCodeForToken failed")
+		return AccessToken{}, err
 	}
 
-	return grantToken.AccessToken, err
+	return newAccessToken(grantToken, time.Now()), nil
+}
+
+// RefreshAccessToken - gets a new access token with `refreshToken`, using the client authentication
+// configured in `ctx`. The request is sent with `client`, traced under the span in `traceCtx`.
+func RefreshAccessToken(traceCtx context.Context, client *resty.Client, refreshToken string, ctx *model.Context) (AccessToken, error) {
+	logger := logrus.StandardLogger().WithField("module", "RefreshAccessToken")
+
+	traceCtx, span := tracing.Start(traceCtx, "token exchange",
+		attribute.String("oauth.grant_type", authentication.GrantTypeRefreshToken),
+	)
+	started := time.Now()
+	grantToken, err := requestToken(traceCtx, client, map[string]string{
+		authentication.GrantType:             authentication.GrantTypeRefreshToken,
+		authentication.GrantTypeRefreshToken: refreshToken,
+	}, ctx, logger)
+	metrics.ObserveTokenRequest(authentication.GrantTypeRefreshToken, time.Since(started), err)
+	tracing.End(span, err)
+	if err != nil {
+		return AccessToken{}, err
+	}
+
+	return newAccessToken(grantToken, time.Now()), nil
 }
 
 type grantToken struct {
-	AccessToken string `json:"access_token,omitempty"`
-	TokenType   string `json:"token_type,omitempty"`
-	Expires     int32  `json:"expires_in,omitempty"`
-	Scope       string `json:"scope,omitempty"`
-	IDToken     string `json:"id_token,omitempty"`
+	AccessToken  string `json:"access_token,omitempty"`
+	TokenType    string `json:"token_type,omitempty"`
+	Expires      int32  `json:"expires_in,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
 }
 
-func exchangeCodeForToken(code string, ctx *model.Context, logger *logrus.Entry) (*grantToken, error) {
+func exchangeCodeForToken(traceCtx context.Context, client *resty.Client, code string, ctx *model.Context, logger *logrus.Entry) (*grantToken, error) {
 	logger = logger.WithFields(logrus.Fields{
 		"function": "exchangeCodeForToken",
 		"code":     code,
 	})
+
+	redirectURI, err := ctx.GetString("redirect_url")
+	if err != nil {
+		return nil, errors.Wrap(err, "executors.exchangeCodeForToken: cannot get redirect_url for code exchange")
+	}
+
+	return requestToken(traceCtx, client, map[string]string{
+		authentication.GrantType: authentication.GrantTypeAuthorizationCode,
+		"code":                   code,
+		"redirect_uri":           redirectURI,
+	}, ctx, logger)
+}
+
+// requestToken - posts `form` to the token endpoint, authenticating with the
+// token_endpoint_auth_method in `ctx`. The request is sent with `client`, traced under the span
+// in `traceCtx`.
+func requestToken(traceCtx context.Context, client *resty.Client, form map[string]string, ctx *model.Context, logger *logrus.Entry) (*grantToken, error) {
 	ctx.DumpContext()
 
 	basicAuth, err := ctx.GetString("basic_authentication")
 	if err != nil {
-		return nil, errors.Wrap(err, "executors.exchangeCodeForToken: cannot get basic authentication for code")
+		return nil, errors.Wrap(err, "executors.requestToken: cannot get basic authentication")
 	}
 	tokenEndpoint, err := ctx.GetString("token_endpoint")
 	if err != nil {
-		return nil, errors.Wrap(err, "executors.exchangeCodeForToken: cannot get token_endpoint for code exchange")
-	}
-	redirectURI, err := ctx.GetString("redirect_url")
-	if err != nil {
-		return nil, errors.Wrap(err, "executors.exchangeCodeForToken: cannot get redirect_url for code exchange")
+		return nil, errors.Wrap(err, "executors.requestToken: cannot get token_endpoint")
 	}
 	clientID, err := ctx.GetString("client_id")
 	if err != nil {
-		return nil, errors.Wrap(err, "executors.exchangeCodeForToken: cannot get client_id for exchange code")
+		return nil, errors.Wrap(err, "executors.requestToken: cannot get client_id")
 	}
 	alg, err := ctx.GetString("requestObjectSigningAlg")
 	if err != nil {
-		return nil, errors.Wrap(err, "executors.exchangeCodeForToken: cannot get requestObjectSigningAlg for exchange code")
+		return nil, errors.Wrap(err, "executors.requestToken: cannot get requestObjectSigningAlg")
 	}
 	privKey, err := ctx.GetString("signingPrivate")
 	if err != nil {
-		return nil, errors.Wrap(err, "executors.exchangeCodeForToken: cannot get `signingPrivate` in context")
+		return nil, errors.Wrap(err, "executors.requestToken: cannot get `signingPrivate` in context")
 	}
 	pubKey, err := ctx.GetString("signingPublic")
 	if err != nil {
-		return nil, errors.Wrap(err, "executors.exchangeCodeForToken: cannot get `signingPublic` in context")
+		return nil, errors.Wrap(err, "executors.requestToken: cannot get `signingPublic` in context")
 	}
 	cert, err := authentication.NewCertificate(pubKey, privKey)
 	if err != nil {
-		return nil, errors.Wrap(err, "executors.exchangeCodeForToken: cannot get `certificate` from pub/priv keys")
+		return nil, errors.Wrap(err, "executors.requestToken: cannot get `certificate` from pub/priv keys")
 	}
 
 	// Check for MTLS vs client basic authentication
@@ -262,31 +336,17 @@ func exchangeCodeForToken(code string, ctx *model.Context, logger *logrus.Entry)
 		authMethod = authentication.ClientSecretBasic
 	}
 
-	var resp *resty.Response
-	var errResponse error
+	req := client.R().
+		SetHeader("content-type", "application/x-www-form-urlencoded").
+		SetHeader("accept", "application/json").
+		SetFormData(form)
 	switch authMethod {
 	case authentication.ClientSecretBasic:
-		resp, errResponse = resty.R().
-			SetHeader("content-type", "application/x-www-form-urlencoded").
-			SetHeader("accept", "application/json").
-			SetHeader("authorization", "Basic "+basicAuth).
-			SetFormData(map[string]string{
-				authentication.GrantType: authentication.GrantTypeAuthorizationCode,
-				"code":                   code,
-				"redirect_uri":           redirectURI,
-			}).
-			Post(tokenEndpoint)
+		req.SetHeader("authorization", "Basic "+basicAuth)
 	case authentication.TlsClientAuth:
-		resp, errResponse = resty.R().
-			SetHeader("content-type", "application/x-www-form-urlencoded").
-			SetHeader("accept", "application/json").
-			SetFormData(map[string]string{
-				authentication.GrantType: authentication.GrantTypeAuthorizationCode,
-				"code":                   code,
-				"redirect_uri":           redirectURI,
-				"client_id":              clientID,
-			}).
-			Post(tokenEndpoint)
+		req.SetFormData(map[string]string{
+			"client_id": clientID,
+		})
 	case authentication.PrivateKeyJwt:
 		now := time.Now()
 		iat := now.Unix()
@@ -310,47 +370,46 @@ func exchangeCodeForToken(code string, ctx *model.Context, logger *logrus.Entry)
 
 		signingMethod, err := authentication.GetSigningAlg(alg)
 		if err != nil {
-			return nil, errors.Wrap(err, "executors.exchangeCodeForToken: cannot get signingMethod")
+			return nil, errors.Wrap(err, "executors.requestToken: cannot get signingMethod")
 		}
 
 		token := jwt.NewWithClaims(signingMethod, claims) // create new token
 
 		kid, err := ctx.GetString("tpp_signature_kid")
 		if err != nil {
-			return nil, errors.Wrap(err, "executors.exchangeCodeForToken: cannot get KID")
+			return nil, errors.Wrap(err, "executors.requestToken: cannot get KID")
 		}
 		token.Header["kid"] = kid
 
-		clientAssertion, err := token.SignedString(cert.PrivateKey()) // sign the token - get as encoded string
+		clientAssertion, err := token.SignedString(cert.Signer()) // sign the token - get as encoded string
 		if err != nil {
-			return nil, errors.Wrap(err, "executors.exchangeCodeForToken: could not generate client_assertion")
+			return nil, errors.Wrap(err, "executors.requestToken: could not generate client_assertion")
 		}
 
-		resp, errResponse = resty.R().
-			SetHeader("content-type", "application/x-www-form-urlencoded").
-			SetHeader("accept", "application/json").
-			SetFormData(map[string]string{
-				authentication.GrantType:           authentication.GrantTypeAuthorizationCode,
-				"code":                             code,
-				"redirect_uri":                     redirectURI,
-				authentication.ClientAssertionType: authentication.ClientAssertionTypeValue,
-				authentication.ClientAssertion:     clientAssertion,
-			}).
-			Post(tokenEndpoint)
+		req.SetFormData(map[string]string{
+			authentication.ClientAssertionType: authentication.ClientAssertionTypeValue,
+			authentication.ClientAssertion:     clientAssertion,
+		})
 	default:
-		return nil, errors.Errorf("executors.exchangeCodeForToken: token_endpoint_auth_method %q unsupported", authMethod)
+		return nil, errors.Errorf("executors.requestToken: token_endpoint_auth_method %q unsupported", authMethod)
 	}
 
+	req.Method = http.MethodPost
+	req.URL = tokenEndpoint
+	span := tracing.StartHTTP(traceCtx, req)
+	resp, errResponse := req.Execute(req.Method, req.URL)
+	tracing.EndHTTP(span, resp, errResponse)
+
 	if errResponse != nil {
 		logger.WithFields(logrus.Fields{
 			"tokenEndpoint": tokenEndpoint,
 			"errResponse":   errResponse,
-		}).Debug("Error accessing exchange code")
+		}).Debug("Error accessing token endpoint")
 		return nil, errResponse
 	}
 
 	if resp.StatusCode() != 200 {
-		return nil, fmt.Errorf("executors.exchangeCodeForToken: bad status code %d from exchange token %q", resp.StatusCode(), tokenEndpoint)
+		return nil, fmt.Errorf("executors.requestToken: bad status code %d from token endpoint %q", resp.StatusCode(), tokenEndpoint)
 	}
 
 	grantToken := &grantToken{}
@@ -1,10 +1,22 @@
 package executors
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gopkg.in/resty.v1"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/authentication"
+	"github.com/OpenBankingUK/conformance-suite/pkg/model"
+	"github.com/OpenBankingUK/conformance-suite/pkg/tracing"
 )
 
 func TestBuildParameters(t *testing.T) {
@@ -28,3 +40,58 @@ func TestBuildParameters(t *testing.T) {
 	fmt.Println(buildstr)
 
 }
+
+func TestRefreshAccessTokenTracesRequest(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	tokenEndpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token": "a5c1f1fa-2", "expires_in": 3600}`)
+	}))
+	defer tokenEndpoint.Close()
+	ctx := model.Context{
+		"basic_authentication":       "Y2xpZW50OnNlY3JldA==",
+		"token_endpoint":             tokenEndpoint.URL + "/token",
+		"client_id":                  "client",
+		"requestObjectSigningAlg":    "PS256",
+		"signingPrivate":             signingPrivate,
+		"signingPublic":              signingPublic,
+		"token_endpoint_auth_method": authentication.ClientSecretBasic,
+	}
+
+	traceCtx, run := tracing.Start(context.Background(), "run")
+	token, err := RefreshAccessToken(traceCtx, resty.New(), "r1", &ctx)
+	run.End()
+	require.NoError(t, err)
+	assert.Equal(t, "a5c1f1fa-2", token.AccessToken)
+
+	ended := recorder.Ended()
+	require.Len(t, ended, 3)
+	assert.Equal(t, "HTTP POST", ended[0].Name())
+	assert.Equal(t, "token exchange", ended[1].Name())
+	assert.Equal(t, ended[1].SpanContext().SpanID(), ended[0].Parent().SpanID())
+	assert.Equal(t, run.SpanContext().SpanID(), ended[1].Parent().SpanID())
+}
+
+func TestConsentStateNamesJourney(t *testing.T) {
+	ctx := model.Context{}
+	putConsentState(&ctx, "Token001")
+	state, err := ctx.GetString("consent_state")
+	require.NoError(t, err)
+	assert.Equal(t, "Token001", state, "the default journey's state is the token name")
+
+	ctx.PutString("journey_id", "6c3b4ed5-1d2e-4b6f-9a0c-3f1e2d4c5b6a")
+	putConsentState(&ctx, "Token001")
+	state, err = ctx.GetString("consent_state")
+	require.NoError(t, err)
+	tokenName, journeyID := SplitConsentState(state)
+	assert.Equal(t, "Token001", tokenName)
+	assert.Equal(t, "6c3b4ed5-1d2e-4b6f-9a0c-3f1e2d4c5b6a", journeyID)
+
+	tokenName, journeyID = SplitConsentState("Token002")
+	assert.Equal(t, "Token002", tokenName)
+	assert.Empty(t, journeyID)
+}
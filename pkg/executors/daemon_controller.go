@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	"github.com/OpenBankingUK/conformance-suite/pkg/executors/results"
+	"github.com/OpenBankingUK/conformance-suite/pkg/model"
 )
 
 type DaemonController interface {
@@ -24,6 +25,23 @@ type DaemonController interface {
 	IsCompleted() <-chan bool
 }
 
+// Checkpointer - implemented by a DaemonController that records the progress of a run after
+// each test case, so the run can be resumed
+type Checkpointer interface {
+	Checkpoint(result results.TestCase, ctx *model.Context)
+}
+
+// IdempotencyRecorder - implemented by a DaemonController that keeps the requests sent with an
+// x-idempotency-key in its checkpoints, so the idempotency test cases of a resumed run can send them
+type IdempotencyRecorder interface {
+	RecordIdempotentRequest(testCaseID string, request IdempotentRequest)
+}
+
+// TestCaseObserver - implemented by a DaemonController told when each test case starts
+type TestCaseObserver interface {
+	TestCaseStarted(tc model.TestCase)
+}
+
 // daemonController manages routine running tests
 // allowing to stop and collect results/errors
 type daemonController struct {
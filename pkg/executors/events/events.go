@@ -1,5 +1,7 @@
 package events
 
+import "sync"
+
 // Events -
 type Events interface {
 	AddAcquiredAccessToken(acquiredAccessToken AcquiredAccessToken)
@@ -9,6 +11,10 @@ type Events interface {
 	AddAcquiredAllAccessTokens(acquiredAllAccessTokens AcquiredAllAccessTokens)
 	AllTokensChannel() <-chan AcquiredAllAccessTokens
 	AllAcquiredAllAccessTokens() []AcquiredAllAccessTokens
+
+	AddTokenStateChanged(tokenStateChanged TokenStateChanged)
+	TokenStatesChannel() <-chan TokenStateChanged
+	AllTokenStateChanges() []TokenStateChanged
 }
 
 // NewEvents -
@@ -19,6 +25,9 @@ func NewEvents() Events {
 		acquiredAccessTokensChan:   make(chan AcquiredAccessToken, size),
 		acquiredAllAccessTokens:    []AcquiredAllAccessTokens{},
 		aquiredAllAccessTokensChan: make(chan AcquiredAllAccessTokens, size),
+		tokenStatesLock:            &sync.Mutex{},
+		tokenStateChanges:          []TokenStateChanged{},
+		tokenStatesChan:            make(chan TokenStateChanged, size),
 	}
 }
 
@@ -27,6 +36,9 @@ type events struct {
 	acquiredAccessTokensChan   chan AcquiredAccessToken
 	acquiredAllAccessTokens    []AcquiredAllAccessTokens
 	aquiredAllAccessTokensChan chan AcquiredAllAccessTokens
+	tokenStatesLock            *sync.Mutex
+	tokenStateChanges          []TokenStateChanged
+	tokenStatesChan            chan TokenStateChanged
 }
 
 func (e *events) AddAcquiredAccessToken(acquiredAccessToken AcquiredAccessToken) {
@@ -54,3 +66,26 @@ func (e *events) AllTokensChannel() <-chan AcquiredAllAccessTokens {
 func (e *events) AllAcquiredAllAccessTokens() []AcquiredAllAccessTokens {
 	return e.acquiredAllAccessTokens
 }
+
+// AddTokenStateChanged - records the change, and sends it unless the channel is full. The changes
+// are sent while tests run, they must not block the run when no client is listening.
+func (e *events) AddTokenStateChanged(tokenStateChanged TokenStateChanged) {
+	e.tokenStatesLock.Lock()
+	e.tokenStateChanges = append(e.tokenStateChanges, tokenStateChanged)
+	e.tokenStatesLock.Unlock()
+
+	select {
+	case e.tokenStatesChan <- tokenStateChanged:
+	default:
+	}
+}
+
+func (e *events) TokenStatesChannel() <-chan TokenStateChanged {
+	return e.tokenStatesChan
+}
+
+func (e *events) AllTokenStateChanges() []TokenStateChanged {
+	e.tokenStatesLock.Lock()
+	defer e.tokenStatesLock.Unlock()
+	return append([]TokenStateChanged{}, e.tokenStateChanges...)
+}
@@ -1,5 +1,18 @@
 package events
 
+import "time"
+
+// TokenState - where an access token is in its lifecycle
+type TokenState string
+
+// Access token states
+const (
+	TokenValid     TokenState = "valid"     // collected, and not about to expire
+	TokenExpiring  TokenState = "expiring"  // about to expire, and cannot be refreshed
+	TokenRefreshed TokenState = "refreshed" // replaced using its refresh token
+	TokenExpired   TokenState = "expired"   // expired or rejected, and cannot be refreshed
+)
+
 // AcquiredAccessToken - When `code` has been exchanged for an `access_token`.
 type AcquiredAccessToken struct {
 	TokenName string `json:"token_name"`
@@ -23,3 +36,38 @@ func NewAcquiredAllAccessTokens(tokenNames []string) AcquiredAllAccessTokens {
 		TokenNames: tokenNames,
 	}
 }
+
+// TokenStateChanged - When an access token is collected, refreshed, about to expire or has expired.
+// Expires is empty when the token endpoint did not send `expires_in`.
+type TokenStateChanged struct {
+	TokenName string     `json:"token_name"`
+	State     TokenState `json:"state"`
+	Expires   string     `json:"expires,omitempty"`
+}
+
+// NewTokenStateChanged -
+func NewTokenStateChanged(tokenName string, state TokenState, expires time.Time) TokenStateChanged {
+	event := TokenStateChanged{
+		TokenName: tokenName,
+		State:     state,
+	}
+	if !expires.IsZero() {
+		event.Expires = expires.UTC().Format(time.RFC3339)
+	}
+	return event
+}
+
+// LatestTokenStates - the last state of each token in `changes`, in the order the tokens first appear
+func LatestTokenStates(changes []TokenStateChanged) []TokenStateChanged {
+	latest := []TokenStateChanged{}
+	index := map[string]int{}
+	for _, change := range changes {
+		if i, ok := index[change.TokenName]; ok {
+			latest[i] = change
+			continue
+		}
+		index[change.TokenName] = len(latest)
+		latest = append(latest, change)
+	}
+	return latest
+}
@@ -1,8 +1,10 @@
 package executors
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"sync"
 
 	"github.com/OpenBankingUK/conformance-suite/pkg/schema"
@@ -13,13 +15,18 @@ import (
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 
 	"github.com/OpenBankingUK/conformance-suite/pkg/authentication"
+	"github.com/OpenBankingUK/conformance-suite/pkg/dcr"
 	"github.com/OpenBankingUK/conformance-suite/pkg/discovery"
 	"github.com/OpenBankingUK/conformance-suite/pkg/executors/results"
 	"github.com/OpenBankingUK/conformance-suite/pkg/generation"
+	"github.com/OpenBankingUK/conformance-suite/pkg/metrics"
 	"github.com/OpenBankingUK/conformance-suite/pkg/model"
 	"github.com/OpenBankingUK/conformance-suite/pkg/tracer"
+	"github.com/OpenBankingUK/conformance-suite/pkg/tracing"
 )
 
 // RunDefinition captures all the information required to run the test cases
@@ -28,6 +35,33 @@ type RunDefinition struct {
 	SpecRun       generation.SpecRun
 	SigningCert   authentication.Certificate
 	TransportCert authentication.Certificate
+	DCR           *dcr.Config // optional, runs the Dynamic Client Registration tests when set
+	// Completed - optional, results of a run being resumed. They are reported again, and
+	// their test cases are not run.
+	Completed []results.TestCase
+	// Tokens - optional, refreshes the access tokens the test cases send
+	Tokens TokenRefresher
+	// Idempotent - optional, the requests the Completed test cases sent with an
+	// x-idempotency-key, by test case ID, for their idempotency test cases to send again
+	Idempotent map[string]IdempotentRequest
+	// RunID - optional, identifies the run in its trace
+	RunID string
+	// Client - optional, sends the requests of the run, a client of its own is made when not set
+	Client *resty.Client
+	// Properties - optional, collects the fields of the responses for the report, a collector of
+	// its own is made when not set
+	Properties schemaprops.PropertyCollector
+}
+
+// withDefaults - `definition` with a client and collector of its own, when it was not given them
+func (d RunDefinition) withDefaults() RunDefinition {
+	if d.Client == nil {
+		d.Client = resty.New()
+	}
+	if d.Properties == nil {
+		d.Properties = schemaprops.MakeCollector()
+	}
+	return d
 }
 
 type TestCaseRunner struct {
@@ -37,12 +71,16 @@ type TestCaseRunner struct {
 	logger           *logrus.Entry
 	runningLock      *sync.Mutex
 	running          bool
+	// idempotent - requests sent with an x-idempotency-key, by test case ID
+	idempotent     map[string]IdempotentRequest
+	idempotentLock sync.Mutex
 }
 
 // NewTestCaseRunner -
 func NewTestCaseRunner(logger *logrus.Entry, definition RunDefinition, daemonController DaemonController) *TestCaseRunner {
+	definition = definition.withDefaults()
 	return &TestCaseRunner{
-		executor:         NewExecutor(),
+		executor:         NewExecutor(definition.Client),
 		definition:       definition,
 		daemonController: daemonController,
 		logger:           logger.WithField("module", "TestCaseRunner"),
@@ -53,8 +91,9 @@ func NewTestCaseRunner(logger *logrus.Entry, definition RunDefinition, daemonCon
 
 // NewConsentAcquisitionRunner -
 func NewConsentAcquisitionRunner(logger *logrus.Entry, definition RunDefinition, daemonController DaemonController) *TestCaseRunner {
+	definition = definition.withDefaults()
 	return &TestCaseRunner{
-		executor:         NewExecutor(),
+		executor:         NewExecutor(definition.Client),
 		definition:       definition,
 		daemonController: daemonController,
 		logger:           logger.WithField("module", "ConsentAcquisitionRunner"),
@@ -65,8 +104,9 @@ func NewConsentAcquisitionRunner(logger *logrus.Entry, definition RunDefinition,
 
 // NewExchangeComponentRunner -
 func NewExchangeComponentRunner(definition RunDefinition, daemonController DaemonController) *TestCaseRunner {
+	definition = definition.withDefaults()
 	return &TestCaseRunner{
-		executor:         NewExecutor(),
+		executor:         NewExecutor(definition.Client),
 		definition:       definition,
 		daemonController: daemonController,
 		logger:           logrus.StandardLogger().WithField("module", "ExchangeComponent"),
@@ -89,8 +129,8 @@ func (r *TestCaseRunner) RunTestCases(ctx *model.Context) error {
 	return nil
 }
 
-// RunConsentAcquisition -
-func (r *TestCaseRunner) RunConsentAcquisition(item TokenConsentIDItem, ctx *model.Context, consentType string, consentIDChannel chan<- TokenConsentIDItem) error {
+// RunConsentAcquisition - the requests are traced under the span in `traceCtx`
+func (r *TestCaseRunner) RunConsentAcquisition(traceCtx context.Context, item TokenConsentIDItem, ctx *model.Context, consentType string, consentIDChannel chan<- TokenConsentIDItem) error {
 	r.runningLock.Lock()
 	defer r.runningLock.Unlock()
 	if r.running {
@@ -98,7 +138,7 @@ func (r *TestCaseRunner) RunConsentAcquisition(item TokenConsentIDItem, ctx *mod
 	}
 	r.running = true
 	logrus.Tracef("runConsentAquisition with %s, %s, %s", item.TokenName, item.ConsentURL, item.Permissions)
-	go r.runConsentAcquisitionAsync(item, ctx, consentType, consentIDChannel)
+	go r.runConsentAcquisitionAsync(traceCtx, item, ctx, consentType, consentIDChannel)
 
 	return nil
 }
@@ -111,20 +151,33 @@ func (r *TestCaseRunner) runTestCasesAsync(ctx *model.Context) {
 
 	ruleCtx := r.makeRuleCtx(ctx)
 
+	traceCtx, span := tracing.Start(context.Background(), "run",
+		tracing.RunIDKey.String(r.definition.RunID),
+		attribute.Int("fcs.resumed_tests", len(r.definition.Completed)),
+	)
+	defer span.End()
+
 	ctxLogger := r.logger.WithField("id", uuid.New())
+	completed := map[string]bool{}
+	for _, result := range r.definition.Completed {
+		r.daemonController.AddResult(result)
+		completed[result.Id] = true
+	}
+	if r.definition.DCR != nil && !r.specTestsStarted(completed) {
+		r.executeDCRTests(traceCtx, ruleCtx, ctxLogger, completed)
+	}
 	for _, spec := range r.definition.SpecRun.SpecTestCases {
-		r.executeSpecTests(spec, ruleCtx, ctxLogger) // Run Tests for each spec
+		r.executeSpecTests(traceCtx, spec, ruleCtx, ctxLogger, completed) // Run Tests for each spec
 	}
 
-	collector := schemaprops.GetPropertyCollector()
-	r.daemonController.AddResponseFields(collector.OutputJSON())
+	r.daemonController.AddResponseFields(r.definition.Properties.OutputJSON())
 
 	r.daemonController.SetCompleted()
 
 	r.setNotRunning()
 }
 
-func (r *TestCaseRunner) runConsentAcquisitionAsync(item TokenConsentIDItem, ctx *model.Context, consentType string, consentIDChannel chan<- TokenConsentIDItem) {
+func (r *TestCaseRunner) runConsentAcquisitionAsync(traceCtx context.Context, item TokenConsentIDItem, ctx *model.Context, consentType string, consentIDChannel chan<- TokenConsentIDItem) {
 	err := r.executor.SetCertificates(r.definition.SigningCert, r.definition.TransportCert)
 	if err != nil {
 		r.logger.WithError(err).Error("running consent acquisition async")
@@ -133,6 +186,7 @@ func (r *TestCaseRunner) runConsentAcquisitionAsync(item TokenConsentIDItem, ctx
 	ruleCtx := r.makeRuleCtx(ctx)
 	ruleCtx.PutString("consent_id", item.TokenName)
 	ruleCtx.PutString("token_name", item.TokenName)
+	putConsentState(ruleCtx, item.TokenName)
 	ruleCtx.PutString("permission_list", item.Permissions)
 
 	ctxLogger := r.logger.WithField("id", uuid.New())
@@ -175,7 +229,12 @@ func (r *TestCaseRunner) runConsentAcquisitionAsync(item TokenConsentIDItem, ctx
 		comp.Tests[k] = v
 	}
 
-	r.executeComponentTests(&comp, ruleCtx, ctxLogger, item, consentIDChannel, authMethod)
+	traceCtx, span := tracing.Start(traceCtx, "consent acquisition",
+		tracing.TokenNameKey.String(item.TokenName),
+		attribute.String("fcs.consent_type", consentType),
+	)
+	r.executeComponentTests(traceCtx, &comp, ruleCtx, ctxLogger, item, consentIDChannel, authMethod)
+	span.End()
 	clientGrantToken, err := ruleCtx.GetString("client_access_token")
 	if err == nil {
 		logrus.StandardLogger().WithFields(logrus.Fields{
@@ -187,7 +246,7 @@ func (r *TestCaseRunner) runConsentAcquisitionAsync(item TokenConsentIDItem, ctx
 	r.setNotRunning()
 }
 
-func (r *TestCaseRunner) executeComponentTests(comp *model.Component, ruleCtx *model.Context, logger *logrus.Entry, item TokenConsentIDItem, consentIDChannel chan<- TokenConsentIDItem, authMethod string) {
+func (r *TestCaseRunner) executeComponentTests(traceCtx context.Context, comp *model.Component, ruleCtx *model.Context, logger *logrus.Entry, item TokenConsentIDItem, consentIDChannel chan<- TokenConsentIDItem, authMethod string) {
 	ctxLogger := logger.WithFields(logrus.Fields{
 		"component": comp.Name,
 		"module":    "TestCaseRunner",
@@ -266,7 +325,7 @@ func (r *TestCaseRunner) executeComponentTests(comp *model.Component, ruleCtx *m
 			}
 		}
 
-		testResult := r.executeTest(testcase, ruleCtx, logger)
+		testResult := r.executeTest(traceCtx, testcase, ruleCtx, logger)
 		r.daemonController.AddResult(testResult)
 
 		if testResult.Pass {
@@ -300,6 +359,13 @@ func (r *TestCaseRunner) executeComponentTests(comp *model.Component, ruleCtx *m
 	}
 }
 
+// Running - whether the test cases or consent acquisition are being run
+func (r *TestCaseRunner) Running() bool {
+	r.runningLock.Lock()
+	defer r.runningLock.Unlock()
+	return r.running
+}
+
 func (r *TestCaseRunner) setNotRunning() {
 	logger := logrus.StandardLogger().WithFields(logrus.Fields{
 		"function": "setNotRunning",
@@ -323,42 +389,184 @@ func (r *TestCaseRunner) makeRuleCtx(ctx *model.Context) *model.Context {
 	return ruleCtx
 }
 
-func (r *TestCaseRunner) executeSpecTests(spec generation.SpecificationTestCases, ruleCtx *model.Context, ctxLogger *logrus.Entry) {
+func (r *TestCaseRunner) executeSpecTests(traceCtx context.Context, spec generation.SpecificationTestCases, ruleCtx *model.Context, ctxLogger *logrus.Entry, completed map[string]bool) {
 	ctxLogger = ctxLogger.WithField("spec", spec.Specification.Name)
-	collector := schemaprops.GetPropertyCollector()
-	collector.SetCollectorAPIDetails(spec.Specification.Name, spec.Specification.Version)
+	traceCtx, span := tracing.Start(traceCtx, "spec",
+		attribute.String("fcs.spec.name", spec.Specification.Name),
+		attribute.String("fcs.spec.version", spec.Specification.Version),
+	)
+	defer span.End()
+	r.definition.Properties.SetCollectorAPIDetails(spec.Specification.Name, spec.Specification.Version)
+	checkpointer, checkpoints := r.daemonController.(Checkpointer)
+	observer, observes := r.daemonController.(TestCaseObserver)
 
 	for _, testcase := range spec.TestCases {
 		if r.daemonController.ShouldStop() {
 			ctxLogger.Info("stop test run received, aborting runner")
 			return
 		}
+		if completed[testcase.ID] {
+			continue
+		}
 		ctxLogger = ctxLogger.WithField("ID", testcase.ID)
 		ruleCtx.DumpContext("ruleCtx before: " + testcase.ID)
-		testResult := r.executeTest(testcase, ruleCtx, ctxLogger)
+		if observes {
+			observer.TestCaseStarted(testcase)
+		}
+		testResult := r.executeTestRefreshingToken(traceCtx, testcase, ruleCtx, ctxLogger)
+		metrics.ObserveTest(testResult.API, testResult.Pass)
 		r.daemonController.AddResult(testResult)
+		if checkpoints {
+			checkpointer.Checkpoint(testResult, ruleCtx)
+		}
+	}
+}
+
+// specTestsStarted - whether a test case of the specifications completed before the run was
+// resumed. The DCR test cases run first, so they had all run by then.
+func (r *TestCaseRunner) specTestsStarted(completed map[string]bool) bool {
+	for _, spec := range r.definition.SpecRun.SpecTestCases {
+		for _, tc := range spec.TestCases {
+			if completed[tc.ID] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// executeDCRTests - runs the DCR test cases, checkpointing each result. When the run is resumed
+// part way through them they all run again, as they depend on the client registered first, but
+// only the results of those not `completed` are kept.
+func (r *TestCaseRunner) executeDCRTests(traceCtx context.Context, ruleCtx *model.Context, ctxLogger *logrus.Entry, completed map[string]bool) {
+	runner, err := dcr.NewRunner(ctxLogger, r.definition.Client, *r.definition.DCR)
+	if err != nil {
+		ctxLogger.WithError(err).Error("cannot run dynamic client registration tests")
+		return
+	}
+	traceCtx, span := tracing.Start(traceCtx, "spec",
+		attribute.String("fcs.spec.name", dcr.APIName),
+		attribute.String("fcs.spec.version", dcr.APIVersion),
+	)
+	defer span.End()
+	runner.Run(traceCtx, dcrResultSink{DaemonController: r.daemonController, ctx: ruleCtx, completed: completed})
+}
+
+// dcrResultSink - adds the DCR results not already completed to the run, checkpointing each
+type dcrResultSink struct {
+	DaemonController
+	ctx       *model.Context
+	completed map[string]bool
+}
+
+func (s dcrResultSink) AddResult(result results.TestCase) {
+	if s.completed[result.Id] {
+		return
+	}
+	s.DaemonController.AddResult(result)
+	if checkpointer, ok := s.DaemonController.(Checkpointer); ok {
+		checkpointer.Checkpoint(result, s.ctx)
 	}
 }
 
-func (r *TestCaseRunner) executeTest(tc model.TestCase, ruleCtx *model.Context, logger *logrus.Entry) results.TestCase {
+// executeTestRefreshingToken - runs `tc`, refreshing its access token first when it is about to
+// expire. When the ASPSP rejects the token with a 401 it is refreshed, and `tc` runs once more.
+func (r *TestCaseRunner) executeTestRefreshingToken(traceCtx context.Context, tc model.TestCase, ruleCtx *model.Context, logger *logrus.Entry) results.TestCase {
+	tokenName := bearerTokenName(tc)
+	if r.definition.Tokens == nil || tokenName == "" {
+		return r.executeTest(traceCtx, tc, ruleCtx, logger)
+	}
+	logger = logger.WithField("tokenName", tokenName)
+
+	if _, err := r.definition.Tokens.Refresh(traceCtx, tokenName, ruleCtx, false); err != nil {
+		logger.WithError(err).Warn("refreshing access token before it expires")
+	}
+	testResult := r.executeTest(traceCtx, tc, ruleCtx, logger)
+	if testResult.Pass || testResult.Evidence == nil || testResult.Evidence.Response.StatusCode != http.StatusUnauthorized {
+		return testResult
+	}
+
+	refreshed, err := r.definition.Tokens.Refresh(traceCtx, tokenName, ruleCtx, true)
+	if err != nil {
+		logger.WithError(err).Warn("refreshing access token after 401")
+	}
+	if !refreshed {
+		return testResult
+	}
+	logger.Info("access token refreshed after 401, running test case again")
+	return r.executeTest(traceCtx, tc, ruleCtx, logger)
+}
+
+// executeTest - runs `tc` in a span of its own, a child of the span in `traceCtx`
+func (r *TestCaseRunner) executeTest(traceCtx context.Context, tc model.TestCase, ruleCtx *model.Context, logger *logrus.Entry) results.TestCase {
+	traceCtx, span := tracing.Start(traceCtx, "test case",
+		tracing.TestCaseIDKey.String(tc.ID),
+		attribute.String("fcs.test_case.name", tc.Name),
+		attribute.String("fcs.api", tc.APIName),
+	)
+	testResult := r.runTest(traceCtx, tc, ruleCtx, logger)
+	span.SetAttributes(attribute.Bool("fcs.test_case.pass", testResult.Pass))
+	if !testResult.Pass {
+		span.SetStatus(codes.Error, "test case failed")
+	}
+	span.End()
+	return testResult
+}
+
+func (r *TestCaseRunner) runTest(traceCtx context.Context, tc model.TestCase, ruleCtx *model.Context, logger *logrus.Entry) results.TestCase {
 	ctxLogger := logWithTestCase(logger, tc)
-	req, err := tc.Prepare(ruleCtx)
+	endpoint := tc.Input.Endpoint // before Prepare fills in the base URL and resource IDs
+	var original *IdempotentRequest
+	if tc.IdempotencyOf != "" {
+		replay, sent, err := r.idempotencyReplay(tc)
+		if err != nil {
+			ctxLogger.WithError(err).Error("idempotency test case")
+			return results.NewTestCaseFail(tc.ID, results.NoMetrics(), []error{err}, tc.Input.Endpoint, tc.APIName, tc.APIVersion, tc.Detail, tc.RefURI, tc.StatusCode)
+		}
+		tc, original = replay, &sent
+	}
+	req, err := tc.Prepare(r.definition.Client, ruleCtx)
 	if err != nil {
 		ctxLogger.WithError(err).Error("preparing executing test")
 		return results.NewTestCaseFail(tc.ID, results.NoMetrics(), []error{err}, tc.Input.Endpoint, tc.APIName, tc.APIVersion, tc.Detail, tc.RefURI, tc.StatusCode)
 	}
-	resp, metrics, err := r.executor.ExecuteTestCase(req, &tc, ruleCtx)
+	if errs := tc.ValidateRequest(ruleCtx); len(errs) > 0 {
+		ctxLogger.WithField("errs", errs).WithFields(logrus.Fields{"result": "FAIL", "ID": tc.ID}).Error("invalid request not sent")
+		testResult := results.NewTestCaseFail(tc.ID, results.NoMetrics(), errs, tc.Input.Endpoint, tc.APIName, tc.APIVersion, tc.Detail, tc.RefURI, tc.StatusCode)
+		testResult.SuiteError = true
+		return testResult
+	}
+	resp, metrics, err := r.executor.ExecuteTestCase(traceCtx, req, &tc, ruleCtx)
 	ctxLogger = logWithMetrics(ctxLogger, metrics)
+	if !tc.DoNotCallEndpoint {
+		observeRequest(tc, endpoint, resp, metrics)
+	}
 	if err != nil {
 		ctxLogger.WithError(err).WithFields(logrus.Fields{"result": "FAIL", "ID": tc.ID}).Error("test result")
-		return results.NewTestCaseFail(tc.ID, metrics, []error{err}, tc.Input.Endpoint, tc.APIName, tc.APIVersion, tc.Detail, tc.RefURI, tc.StatusCode)
+		testResult := results.NewTestCaseFail(tc.ID, metrics, []error{err}, tc.Input.Endpoint, tc.APIName, tc.APIVersion, tc.Detail, tc.RefURI, tc.StatusCode)
+		testResult.Evidence = results.NewEvidenceFromRestyResponse(resp)
+		return testResult
 	}
 	tc.StatusCode = resp.Status()
 	result, errs := tc.Validate(resp, ruleCtx)
+	collectProperties(r.definition.Properties, &tc, resp)
+	if original != nil {
+		errs = append(errs, original.check(tc.IdempotencyCheck, resp)...)
+	}
+	headerChecks := validateHeaders(tc, endpoint, resp, ruleCtx)
+	for _, check := range headerChecks {
+		if !check.Pass {
+			errs = append(errs, fmt.Errorf("%s: %s", check.Rule, check.Detail))
+		}
+	}
 	if errs != nil {
 		detailedErrors := detailedErrors(errs, resp)
 		ctxLogger.WithField("errs", detailedErrors).WithFields(logrus.Fields{"result": passText()[result], "ID": tc.ID}).Error("test result validate")
-		return results.NewTestCaseFail(tc.ID, metrics, detailedErrors, tc.Input.Endpoint, tc.APIName, tc.APIVersion, tc.Detail, tc.RefURI, tc.StatusCode)
+		testResult := results.NewTestCaseFail(tc.ID, metrics, detailedErrors, tc.Input.Endpoint, tc.APIName, tc.APIVersion, tc.Detail, tc.RefURI, tc.StatusCode)
+		testResult.Signature = tc.SignatureReport
+		testResult.Headers = headerChecks
+		testResult.Evidence = results.NewEvidenceFromRestyResponse(resp)
+		return testResult
 	}
 
 	if !result {
@@ -367,7 +575,36 @@ func (r *TestCaseRunner) executeTest(tc model.TestCase, ruleCtx *model.Context,
 		ctxLogger.WithError(err).WithFields(logrus.Fields{"result": passText()[result], "ID": tc.ID}).Info("test result")
 	}
 
-	return results.NewTestCaseResult(tc.ID, result, metrics, []error{}, tc.Input.Endpoint, tc.APIName, tc.APIVersion, tc.Detail, tc.RefURI, tc.StatusCode)
+	if result {
+		r.recordIdempotentRequest(tc, req, resp)
+	}
+	testResult := results.NewTestCaseResult(tc.ID, result, metrics, []error{}, tc.Input.Endpoint, tc.APIName, tc.APIVersion, tc.Detail, tc.RefURI, tc.StatusCode)
+	testResult.Signature = tc.SignatureReport
+	testResult.Headers = headerChecks
+	testResult.Evidence = results.NewEvidenceFromRestyResponse(resp)
+	return testResult
+}
+
+// collectProperties - gathers the fields of the response `tc` validated, for the report
+func collectProperties(properties schemaprops.PropertyCollector, tc *model.TestCase, resp *resty.Response) {
+	properties.CollectProperties(tc.Input.Method, tc.Input.Endpoint, tc.Body, resp.StatusCode())
+}
+
+// validateHeaders - checks the response headers of `tc` against the OB header rules, none when
+// the endpoint was not called. `endpoint` is the manifest's, so the API is told by `tc`'s spec type.
+func validateHeaders(tc model.TestCase, endpoint string, resp *resty.Response, ctx *model.Context) []schema.HeaderCheck {
+	if tc.DoNotCallEndpoint || resp == nil || resp.Request == nil {
+		return nil
+	}
+	requestHeader := resp.Request.Header
+	if resp.Request.RawRequest != nil {
+		requestHeader = resp.Request.RawRequest.Header
+	}
+	return schema.ValidateHeaders(
+		schema.HTTPRequest{Method: tc.Input.Method, Path: endpoint, Header: requestHeader},
+		schema.HTTPResponse{Method: tc.Input.Method, Path: endpoint, Header: resp.Header(), StatusCode: resp.StatusCode()},
+		model.JWSStatus(ctx) != "disabled" && (tc.SpecType == "payments" || tc.SpecType == "vrps"),
+	)
 }
 
 type DetailError struct {
@@ -411,6 +648,15 @@ func logWithTestCase(logger *logrus.Entry, tc model.TestCase) *logrus.Entry {
 	})
 }
 
+// observeRequest - adds the request `tc` made to `endpoint` to the suite's Prometheus metrics
+func observeRequest(tc model.TestCase, endpoint string, resp *resty.Response, responseMetrics results.Metrics) {
+	status := 0
+	if resp != nil && resp.RawResponse != nil {
+		status = resp.StatusCode()
+	}
+	metrics.ObserveRequest(tc.APIName, endpoint, tc.Input.Method, status, responseMetrics.ResponseTime, responseMetrics.ResponseSize)
+}
+
 func logWithMetrics(logger *logrus.Entry, metrics results.Metrics) *logrus.Entry {
 	return logger.WithFields(logrus.Fields{
 		"responsetime": fmt.Sprintf("%v", metrics.ResponseTime),
@@ -1,21 +1,214 @@
 package executors
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"gopkg.in/resty.v1"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/authentication"
+	"github.com/OpenBankingUK/conformance-suite/pkg/dcr"
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors/results"
+	"github.com/OpenBankingUK/conformance-suite/pkg/generation"
+	"github.com/OpenBankingUK/conformance-suite/pkg/model"
+	"github.com/OpenBankingUK/conformance-suite/pkg/schema"
+	"github.com/OpenBankingUK/conformance-suite/pkg/schemaprops"
 	"github.com/OpenBankingUK/conformance-suite/pkg/test"
 
 	"github.com/OpenBankingUK/conformance-suite/pkg/executors/mocks"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewTestCaseRunner(t *testing.T) {
 	controller := &mocks.DaemonController{}
-	definition := RunDefinition{}
+	definition := RunDefinition{Client: resty.New(), Properties: schemaprops.MakeCollector()}
 	runner := NewTestCaseRunner(test.NullLogger(), definition, controller)
 
 	assert.NotNil(t, runner.runningLock)
 	assert.Equal(t, definition, runner.definition)
+	assert.Same(t, definition.Client, runner.executor.(*Executor).Client, "requests are sent with the client of the run")
 	assert.Equal(t, controller, runner.daemonController)
 	assert.False(t, runner.running)
+	assert.False(t, runner.Running())
+
+	runner.running = true
+	assert.True(t, runner.Running())
+
+	other := NewTestCaseRunner(test.NullLogger(), RunDefinition{}, controller)
+	assert.NotNil(t, other.definition.Properties)
+	assert.NotSame(t, runner.definition.Properties, other.definition.Properties, "each run collects the fields of its own responses")
+}
+
+// failingExecutor - fails every test case without sending a request
+type failingExecutor struct{}
+
+func (failingExecutor) ExecuteTestCase(traceCtx context.Context, r *resty.Request, t *model.TestCase, ctx *model.Context) (*resty.Response, results.Metrics, error) {
+	return nil, results.NoMetrics(), errors.New("not sent")
+}
+
+func (failingExecutor) SetCertificates(certificateSigning, certificationTransport authentication.Certificate) error {
+	return nil
+}
+
+type checkpointingDaemonController struct {
+	DaemonController
+	checkpoints []string
+}
+
+func (c *checkpointingDaemonController) Checkpoint(result results.TestCase, ctx *model.Context) {
+	c.checkpoints = append(c.checkpoints, result.Id)
+}
+
+func TestRunTestCasesResumesRun(t *testing.T) {
+	controller := &checkpointingDaemonController{DaemonController: NewBufferedDaemonController()}
+	definition := RunDefinition{
+		SpecRun: generation.SpecRun{
+			SpecTestCases: []generation.SpecificationTestCases{
+				{TestCases: []model.TestCase{{ID: "OB-301-ACC-120382"}, {ID: "OB-301-ACC-811741"}}},
+			},
+		},
+		Completed: []results.TestCase{{Id: "OB-301-ACC-120382", Pass: true}},
+	}
+	runner := NewTestCaseRunner(test.NullLogger(), definition, controller)
+	runner.executor = failingExecutor{}
+
+	require.NoError(t, runner.RunTestCases(&model.Context{}))
+	<-controller.IsCompleted()
+
+	allResults := controller.AllResults()
+	require.Len(t, allResults, 2)
+	assert.Equal(t, results.TestCase{Id: "OB-301-ACC-120382", Pass: true}, allResults[0])
+	assert.Equal(t, "OB-301-ACC-811741", allResults[1].Id)
+	assert.Equal(t, []string{"OB-301-ACC-811741"}, controller.checkpoints)
+}
+
+func TestRunTestCasesCheckpointsDCRResults(t *testing.T) {
+	signingCert, err := authentication.NewCertificate(signingPublic, signingPrivate)
+	require.NoError(t, err)
+	specRun := generation.SpecRun{
+		SpecTestCases: []generation.SpecificationTestCases{
+			{TestCases: []model.TestCase{{ID: "OB-301-ACC-120382"}}},
+		},
+	}
+	dcrConfig := &dcr.Config{
+		RegistrationEndpoint: "https://aspsp.example.com/register",
+		SoftwareStatement:    "not a software statement", // fails each test case before a request is sent
+		SigningCert:          signingCert,
+	}
+	run := func(completed ...results.TestCase) *checkpointingDaemonController {
+		controller := &checkpointingDaemonController{DaemonController: NewBufferedDaemonController()}
+		runner := NewTestCaseRunner(test.NullLogger(), RunDefinition{SpecRun: specRun, DCR: dcrConfig, Completed: completed}, controller)
+		runner.executor = failingExecutor{}
+		require.NoError(t, runner.RunTestCases(&model.Context{}))
+		<-controller.IsCompleted()
+		return controller
+	}
+
+	controller := run()
+	assert.Equal(t, []string{"DCR-001", "DCR-005", "DCR-006", "DCR-007", "DCR-008", "OB-301-ACC-120382"}, controller.checkpoints)
+
+	// resumed part way through the DCR test cases, the rest of them run again
+	controller = run(results.TestCase{Id: "DCR-001"}, results.TestCase{Id: "DCR-005"})
+	assert.Equal(t, []string{"DCR-006", "DCR-007", "DCR-008", "OB-301-ACC-120382"}, controller.checkpoints)
+	require.Len(t, controller.AllResults(), 6)
+
+	// resumed after the DCR test cases, they do not run again
+	controller = run(results.TestCase{Id: "DCR-001"}, results.TestCase{Id: "OB-301-ACC-120382"})
+	assert.Empty(t, controller.checkpoints)
+	require.Len(t, controller.AllResults(), 2)
+}
+
+// invalidRequestValidator - finds fault with every request
+type invalidRequestValidator struct {
+	schema.NullValidator
+}
+
+func (invalidRequestValidator) ValidateRequest(r schema.HTTPRequest) ([]schema.Failure, error) {
+	return []schema.Failure{{Message: "header x-fapi-financial-id: required: expected a value, got none"}}, nil
+}
+
+func TestRunTestDoesNotSendInvalidRequests(t *testing.T) {
+	runner := NewTestCaseRunner(test.NullLogger(), RunDefinition{}, NewBufferedDaemonController())
+	runner.executor = failingExecutor{}
+	tc := model.MakeTestCase()
+	tc.ID = "OB-301-ACC-811741"
+	tc.Validator = invalidRequestValidator{}
+	tc.Input.Method = "GET"
+	tc.Input.Endpoint = "/accounts"
+
+	result := runner.runTest(context.Background(), tc, &model.Context{}, test.NullLogger())
+
+	assert.False(t, result.Pass)
+	assert.True(t, result.SuiteError)
+	assert.Equal(t, []string{"suite sent an invalid request: header x-fapi-financial-id: required: expected a value, got none"}, result.Fail)
+
+	tc.Input.InvalidRequest = true
+	result = runner.runTest(context.Background(), tc, &model.Context{}, test.NullLogger())
+
+	assert.False(t, result.SuiteError)
+	assert.Equal(t, []string{"not sent"}, result.Fail, "requests invalid on purpose are sent")
+}
+
+func TestRunTestChecksResponseHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-fapi-interaction-id", "not-a-uuid")
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	runner := NewTestCaseRunner(test.NullLogger(), RunDefinition{}, NewBufferedDaemonController())
+	runner.executor = sendingExecutor{&Executor{}}
+	tc := model.MakeTestCase()
+	tc.ID = "OB-301-ACC-120382"
+	tc.Input.Method = http.MethodGet
+	tc.Input.Endpoint = server.URL + "/accounts"
+	tc.Expect.StatusCode = http.StatusOK
+
+	result := runner.runTest(context.Background(), tc, &model.Context{}, test.NullLogger())
+
+	assert.False(t, result.Pass)
+	assert.Equal(t, []schema.HeaderCheck{
+		{Rule: schema.HeaderRuleInteractionIDFormat, Detail: `expected an RFC 4122 UUID, got "not-a-uuid"`},
+		{Rule: schema.HeaderRuleContentType, Pass: true},
+	}, result.Headers)
+	require.Len(t, result.Fail, 1)
+	assert.Contains(t, result.Fail[0], `x-fapi-interaction-id format: expected an RFC 4122 UUID, got \"not-a-uuid\"`)
+}
+
+func TestRunTestChecksPaymentResponseSignature(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-fapi-interaction-id", "93bac548-d2de-4546-b106-880a5018460d")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	runner := NewTestCaseRunner(test.NullLogger(), RunDefinition{}, NewBufferedDaemonController())
+	runner.executor = sendingExecutor{&Executor{}}
+	tc := model.MakeTestCase()
+	tc.ID = "OB-301-DOP-100600"
+	tc.SpecType = "payments"
+	tc.Input.Method = http.MethodGet
+	tc.Input.Endpoint = "/domestic-payments/PDC_a16ba8" // as in the manifest, the base URL is added by Prepare
+	tc.Context = model.Context{"baseurl": server.URL + "/open-banking/v3.1/pisp"}
+	tc.Expect.StatusCode = http.StatusOK
+
+	result := runner.runTest(context.Background(), tc, &model.Context{}, test.NullLogger())
+
+	assert.False(t, result.Pass)
+	assert.Contains(t, result.Headers, schema.HeaderCheck{Rule: schema.HeaderRuleSignature, Detail: "expected a signature on GET /domestic-payments/PDC_a16ba8 responses, got none"})
+
+	tc.SpecType = "accounts"
+	result = runner.runTest(context.Background(), tc, &model.Context{}, test.NullLogger())
+
+	assert.True(t, result.Pass, result.Fail)
+	for _, check := range result.Headers {
+		assert.NotEqual(t, schema.HeaderRuleSignature, check.Rule, "accounts responses are not signed")
+	}
 }
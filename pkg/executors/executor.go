@@ -2,6 +2,7 @@ package executors
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
@@ -13,6 +14,7 @@ import (
 	"github.com/OpenBankingUK/conformance-suite/pkg/executors/results"
 	"github.com/OpenBankingUK/conformance-suite/pkg/model"
 	"github.com/OpenBankingUK/conformance-suite/pkg/tracer"
+	"github.com/OpenBankingUK/conformance-suite/pkg/tracing"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -20,21 +22,24 @@ import (
 	"gopkg.in/resty.v1"
 )
 
-// TestCaseExecutor defines an interface capable of executing a testcase
+// TestCaseExecutor defines an interface capable of executing a testcase. `traceCtx` holds the
+// span the request is traced under.
 type TestCaseExecutor interface {
-	ExecuteTestCase(r *resty.Request, t *model.TestCase, ctx *model.Context) (*resty.Response, results.Metrics, error)
+	ExecuteTestCase(traceCtx context.Context, r *resty.Request, t *model.TestCase, ctx *model.Context) (*resty.Response, results.Metrics, error)
 	SetCertificates(certificateSigning, certificationTransport authentication.Certificate) error
 }
 
-// NewExecutor creates an executor
-func NewExecutor() TestCaseExecutor {
-	return &Executor{}
+// NewExecutor creates an executor that sends requests with `client`
+func NewExecutor(client *resty.Client) TestCaseExecutor {
+	return &Executor{Client: client}
 }
 
 // Executor - passes request to system under test across an matls connection
 type Executor struct {
 	SigningCert   authentication.Certificate
 	TransportCert authentication.Certificate
+	// Client - sends the requests, SetCertificates sets up its matls connection
+	Client *resty.Client
 }
 
 // SetCertificates receives transport and signing certificates
@@ -45,22 +50,27 @@ func (e *Executor) SetCertificates(certificateSigning, certificationTransport au
 }
 
 // ExecuteTestCase - makes this a generic executor
-func (e *Executor) ExecuteTestCase(r *resty.Request, t *model.TestCase, ctx *model.Context) (*resty.Response, results.Metrics, error) {
+func (e *Executor) ExecuteTestCase(traceCtx context.Context, r *resty.Request, t *model.TestCase, ctx *model.Context) (*resty.Response, results.Metrics, error) {
 	if t.DoNotCallEndpoint {
 		e.appMsg(fmt.Sprintf("Not executing Testcase: %s: %s", t.ID, t.Name))
 		return emptyResponse(), results.NoMetrics(), nil
 	}
 
 	e.appMsg(fmt.Sprintf("Execute Testcase: %s: %s", t.ID, t.Name))
+	if t.Concurrency != nil && t.Concurrency.Requests > 1 {
+		return e.executeConcurrently(traceCtx, r, t)
+	}
 	e.appMsg(fmt.Sprintf("attempting %s %s", r.Method, r.URL))
+	span := tracing.StartHTTP(traceCtx, r)
 	resp, err := r.Execute(r.Method, r.URL)
+	tracing.EndHTTP(span, resp, err)
 	if err != nil {
 		if resp.StatusCode() == http.StatusFound { // catch status code 302 redirects and pass back as good response
 			header := resp.Header()
 			t.StatusCode = resp.Status()
 			logrus.StandardLogger().Printf("redirection headers: %#v\n", header)
 			e.appMsg(fmt.Sprintf("Response: (%.250s)", resp.String()))
-			return resp, metrics(t, resp), nil
+			return resp, responseMetrics(t, resp), nil
 		}
 	}
 	t.StatusCode = resp.Status()
@@ -69,10 +79,10 @@ func (e *Executor) ExecuteTestCase(r *resty.Request, t *model.TestCase, ctx *mod
 		elipsis = " ..."
 	}
 	e.appMsg(fmt.Sprintf("Response: (%.450s)%s", resp.String(), elipsis))
-	return resp, metrics(t, resp), err
+	return resp, responseMetrics(t, resp), err
 }
 
-func metrics(testCase *model.TestCase, response *resty.Response) results.Metrics {
+func responseMetrics(testCase *model.TestCase, response *resty.Response) results.Metrics {
 	return results.NewMetricsFromRestyResponse(testCase, response)
 }
 
@@ -112,7 +122,7 @@ func (e *Executor) setupTLSCertificate(tlsCert tls.Certificate) error {
 		},
 	}
 	tlsConfig.BuildNameToCertificate()
-	resty.SetTLSClientConfig(tlsConfig)
+	e.Client.SetTLSClientConfig(tlsConfig)
 	return nil
 }
 
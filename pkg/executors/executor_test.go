@@ -1,11 +1,21 @@
 package executors
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/OpenBankingUK/conformance-suite/pkg/authentication"
+	"github.com/OpenBankingUK/conformance-suite/pkg/model"
+	"github.com/OpenBankingUK/conformance-suite/pkg/tracing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	resty "gopkg.in/resty.v1"
 )
 
@@ -140,7 +150,8 @@ func disableTestExecutor_SetCertificates(t *testing.T) {
 	t.Run("InvalidTransportCertificate", func(t *testing.T) {
 		require := require.New(t)
 
-		executor := NewExecutor()
+		client := resty.New()
+		executor := NewExecutor(client)
 		require.NotNil(executor)
 
 		certificateSigning, err := authentication.NewCertificate(signingPublic, signingPrivate)
@@ -154,7 +165,7 @@ func disableTestExecutor_SetCertificates(t *testing.T) {
 		require.NoError(executor.SetCertificates(certificateSigning, certificateTransport))
 
 		// https://ob19-rs1.o3bank.co.uk:4501/open-banking/v3.1/aisp
-		res, err := resty.R().Get("https://ob19-rs1.o3bank.co.uk:4501/open-banking/v3.1/aisp")
+		res, err := client.R().Get("https://ob19-rs1.o3bank.co.uk:4501/open-banking/v3.1/aisp")
 		require.NotNil(res)
 		require.NoError(err)
 
@@ -167,7 +178,8 @@ func disableTestExecutor_SetCertificates(t *testing.T) {
 	t.Run("ValidTransportCertificate", func(t *testing.T) {
 		require := require.New(t)
 
-		executor := NewExecutor()
+		client := resty.New()
+		executor := NewExecutor(client)
 		require.NotNil(executor)
 
 		certificateSigning, err := authentication.NewCertificate(signingPublic, signingPrivate)
@@ -181,7 +193,7 @@ func disableTestExecutor_SetCertificates(t *testing.T) {
 		require.NoError(executor.SetCertificates(certificateSigning, certificateTransport))
 
 		// https://ob19-rs1.o3bank.co.uk:4501/open-banking/v3.1/aisp
-		res, err := resty.R().Get("https://ob19-rs1.o3bank.co.uk:4501/open-banking/v3.1/aisp")
+		res, err := client.R().Get("https://ob19-rs1.o3bank.co.uk:4501/open-banking/v3.1/aisp")
 		require.NotNil(res)
 		require.NoError(err)
 
@@ -191,3 +203,50 @@ func disableTestExecutor_SetCertificates(t *testing.T) {
 		)
 	})
 }
+
+func TestSetCertificatesConfiguresOwnClient(t *testing.T) {
+	certificateSigning, err := authentication.NewCertificate(signingPublic, signingPrivate)
+	require.NoError(t, err)
+	certificateTransport, err := authentication.NewCertificate(transportPublic, transportPrivate)
+	require.NoError(t, err)
+	client := resty.New()
+
+	require.NoError(t, NewExecutor(client).SetCertificates(certificateSigning, certificateTransport))
+
+	transport, ok := client.GetClient().Transport.(*http.Transport)
+	require.True(t, ok)
+	require.NotNil(t, transport.TLSClientConfig)
+	assert.Len(t, transport.TLSClientConfig.Certificates, 1)
+	if defaultTransport, ok := resty.DefaultClient.GetClient().Transport.(*http.Transport); ok && defaultTransport.TLSClientConfig != nil {
+		assert.Empty(t, defaultTransport.TLSClientConfig.Certificates, "the default client is left as it was")
+	}
+}
+
+func TestExecuteTestCaseTracesRequest(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	aspsp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(tracing.InteractionIDHeader, r.Header.Get(tracing.InteractionIDHeader))
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer aspsp.Close()
+
+	traceCtx, testCase := tracing.Start(context.Background(), "test case")
+	request := resty.R().SetHeader(tracing.InteractionIDHeader, "93bac548-d2de-4546-b106-880a5018460d")
+	request.Method = http.MethodGet
+	request.URL = aspsp.URL + "/accounts"
+	_, _, err := (&Executor{}).ExecuteTestCase(traceCtx, request, &model.TestCase{ID: "OB-301-ACC-120382"}, &model.Context{})
+	testCase.End()
+	require.NoError(t, err)
+
+	ended := recorder.Ended()
+	require.Len(t, ended, 2)
+	span := ended[0]
+	assert.Equal(t, "HTTP GET", span.Name())
+	assert.Equal(t, testCase.SpanContext().SpanID(), span.Parent().SpanID())
+	assert.Contains(t, span.Attributes(), attribute.Int("http.status_code", http.StatusForbidden))
+	assert.Contains(t, span.Attributes(), tracing.InteractionIDKey.String("93bac548-d2de-4546-b106-880a5018460d"))
+}
@@ -13,14 +13,15 @@ import (
 )
 
 // GetDynamicResourceIds retrieves the accounts and statements resource ids for the current token
-func GetDynamicResourceIds(tokenName, token string, ctx *model.Context, requiredTokens []manifest.RequiredTokens) error {
+// with `client`
+func GetDynamicResourceIds(client *resty.Client, tokenName, token string, ctx *model.Context, requiredTokens []manifest.RequiredTokens) error {
 	logger := logrus.WithFields(logrus.Fields{
 		"module":    "GetDynamicResourceIds",
 		"tokenName": tokenName,
 		"token":     token,
 	})
 
-	err := getDynamicResourceIds(tokenName, token, ctx, logger, requiredTokens)
+	err := getDynamicResourceIds(client, tokenName, token, ctx, logger, requiredTokens)
 	if err != nil {
 		logger.WithFields(logrus.Fields{
 			"err": err,
@@ -30,7 +31,7 @@ func GetDynamicResourceIds(tokenName, token string, ctx *model.Context, required
 	return nil
 }
 
-func getDynamicResourceIds(tokenName, token string, ctx *model.Context, logger *logrus.Entry, requiredTokens []manifest.RequiredTokens) error {
+func getDynamicResourceIds(client *resty.Client, tokenName, token string, ctx *model.Context, logger *logrus.Entry, requiredTokens []manifest.RequiredTokens) error {
 
 	if !strings.HasPrefix(tokenName, "account") {
 		return nil
@@ -53,7 +54,7 @@ func getDynamicResourceIds(tokenName, token string, ctx *model.Context, logger *
 
 	accountsEndpoint := resourceBaseURL + "/open-banking/" + apiVersion + "/aisp/accounts"
 	var resp *resty.Response
-	resp, err = resty.R().
+	resp, err = client.R().
 		SetHeader("Authorization", "Bearer "+token).
 		SetHeader("X-Fapi-Financial-Id", xFapiFinancialID).
 		SetHeader("X-Fapi-Interaction-Id", interactionId).
@@ -0,0 +1,160 @@
+package executors
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/sjson"
+	"gopkg.in/resty.v1"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/model"
+)
+
+// conflictingReference - set in the body of a request sent again with the same x-idempotency-key,
+// so it differs from the body first sent
+const conflictingReference = "fcs-idempotency-conflict"
+
+// IdempotentRequest - a request sent with an x-idempotency-key, and the response it got, kept for
+// the idempotency test cases that send it again. It is kept in the run's checkpoints, so the
+// idempotency test cases of a resumed run can send it.
+type IdempotentRequest struct {
+	Endpoint    string            `json:"endpoint"`
+	Body        string            `json:"body"`
+	Key         string            `json:"key"`
+	Sent        time.Time         `json:"sent"`
+	StatusCode  int               `json:"status_code"`
+	ResourceIDs map[string]string `json:"resource_ids,omitempty"`
+}
+
+// recordIdempotentRequest - keeps the request `req` of `tc` for the idempotency test cases of `tc`,
+// when it was sent with an x-idempotency-key
+func (r *TestCaseRunner) recordIdempotentRequest(tc model.TestCase, req *resty.Request, resp *resty.Response) {
+	key := req.Header.Get("x-idempotency-key")
+	if tc.IdempotencyOf != "" || key == "" {
+		return
+	}
+
+	request := IdempotentRequest{
+		Endpoint:    tc.Input.Endpoint,
+		Body:        tc.Input.RequestBody,
+		Key:         key,
+		Sent:        time.Now(),
+		StatusCode:  resp.StatusCode(),
+		ResourceIDs: resourceIDs(resp.Body()),
+	}
+	r.idempotentLock.Lock()
+	if r.idempotent == nil {
+		r.idempotent = map[string]IdempotentRequest{}
+	}
+	r.idempotent[tc.ID] = request
+	r.idempotentLock.Unlock()
+	if recorder, ok := r.daemonController.(IdempotencyRecorder); ok {
+		recorder.RecordIdempotentRequest(tc.ID, request)
+	}
+}
+
+// idempotencyReplay - idempotency test case `tc` sending the request of the test case it checks,
+// with the request that test case sent
+func (r *TestCaseRunner) idempotencyReplay(tc model.TestCase) (model.TestCase, IdempotentRequest, error) {
+	r.idempotentLock.Lock()
+	original, ok := r.idempotent[tc.IdempotencyOf]
+	if !ok {
+		original, ok = r.definition.Idempotent[tc.IdempotencyOf]
+	}
+	r.idempotentLock.Unlock()
+	if !ok {
+		return tc, original, fmt.Errorf("idempotency: %s did not pass, so there is no request to send again", tc.IdempotencyOf)
+	}
+
+	headers := make(map[string]string, len(tc.Input.Headers)+1)
+	for k, v := range tc.Input.Headers {
+		headers[k] = v
+	}
+	headers["x-idempotency-key"] = original.Key
+	tc.Input.Headers = headers
+	tc.Input.IdempotencyKey = false
+	tc.Input.Endpoint = original.Endpoint
+	tc.Input.RequestBody = original.Body
+
+	if tc.IdempotencyCheck == model.IdempotencyConflict {
+		body, err := sjson.Set(original.Body, "Risk.MerchantCustomerIdentification", conflictingReference)
+		if err != nil {
+			return tc, original, errors.Wrap(err, "idempotency: changing the body")
+		}
+		tc.Input.RequestBody = body
+	}
+	if tc.IdempotencyCheck == model.IdempotencyExpiry {
+		if err := r.waitUntil(original.Sent.Add(model.IdempotencyKeyExpiry())); err != nil {
+			return tc, original, err
+		}
+	}
+	return tc, original, nil
+}
+
+// waitUntil - waits until `t`, unless the run is stopped first
+func (r *TestCaseRunner) waitUntil(t time.Time) error {
+	for wait := time.Until(t); wait > 0; wait = time.Until(t) {
+		if r.daemonController.ShouldStop() {
+			return errors.New("idempotency: run stopped before the x-idempotency-key expired")
+		}
+		if wait > time.Second {
+			wait = time.Second
+		}
+		time.Sleep(wait)
+	}
+	return nil
+}
+
+// check - whether `resp`, to the request sent again, meets `check`
+func (o IdempotentRequest) check(check model.IdempotencyCheck, resp *resty.Response) []error {
+	resourceIDs := resourceIDs(resp.Body())
+	switch check {
+	case model.IdempotencyReplay:
+		if resp.StatusCode() != o.StatusCode {
+			return []error{fmt.Errorf("idempotency: expected status %d, as for the request first sent with x-idempotency-key %s, got %d", o.StatusCode, o.Key, resp.StatusCode())}
+		}
+		if !reflect.DeepEqual(resourceIDs, o.ResourceIDs) {
+			return []error{fmt.Errorf("idempotency: expected resource %s, as for the request first sent with x-idempotency-key %s, got %s", describeIDs(o.ResourceIDs), o.Key, describeIDs(resourceIDs))}
+		}
+	case model.IdempotencyConflict:
+		if resp.StatusCode() < http.StatusBadRequest || resp.StatusCode() >= http.StatusInternalServerError {
+			return []error{fmt.Errorf("idempotency: expected a different body sent with x-idempotency-key %s to be rejected with a 4xx status, got %d", o.Key, resp.StatusCode())}
+		}
+	case model.IdempotencyExpiry:
+		if resp.StatusCode() == o.StatusCode && len(resourceIDs) > 0 && reflect.DeepEqual(resourceIDs, o.ResourceIDs) {
+			return []error{fmt.Errorf("idempotency: expected x-idempotency-key %s to have expired, got resource %s again", o.Key, describeIDs(resourceIDs))}
+		}
+	}
+	return nil
+}
+
+// resourceIDs - the `Data.*Id` fields of response body `body`, such as `Data.ConsentId` and
+// `Data.DomesticPaymentId`, which identify the resource it is about
+func resourceIDs(body []byte) map[string]string {
+	response := struct {
+		Data map[string]interface{}
+	}{}
+	ids := map[string]string{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return ids
+	}
+	for name, value := range response.Data {
+		if id, ok := value.(string); ok && strings.HasSuffix(name, "Id") {
+			ids[name] = id
+		}
+	}
+	return ids
+}
+
+func describeIDs(ids map[string]string) string {
+	if len(ids) == 0 {
+		return "none"
+	}
+	encoded, _ := json.Marshal(ids)
+	return string(encoded)
+}
@@ -0,0 +1,151 @@
+package executors
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/model"
+	"github.com/OpenBankingUK/conformance-suite/pkg/test"
+)
+
+// idempotentASPSP - creates a consent for each new x-idempotency-key, answers a request sent again
+// with the consent created for it and rejects a different body. Ignores keys when `enforce` is false.
+func idempotentASPSP(enforce bool) *httptest.Server {
+	type created struct {
+		body      string
+		consentID string
+	}
+	consents := map[string]created{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		key := r.Header.Get("x-idempotency-key")
+		w.Header().Set("x-fapi-interaction-id", "93bac548-d2de-4546-b106-880a5018460d")
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		consent, ok := consents[key]
+		if ok && enforce && consent.body != string(body) {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"Code": "400", "Errors": [{"ErrorCode": "UK.OBIE.Resource.ConsentMismatch"}]}`)
+			return
+		}
+		if !ok || !enforce {
+			consent = created{body: string(body), consentID: fmt.Sprintf("sdp-%d", len(consents)+1)}
+			consents[key] = consent
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"Data": {"ConsentId": %q, "Status": "AwaitingAuthorisation"}}`, consent.consentID)
+	}))
+}
+
+func idempotencyTestCase(url string) model.TestCase {
+	tc := model.MakeTestCase()
+	tc.ID = "OB-301-DOP-100100"
+	tc.Input.Method = http.MethodPost
+	tc.Input.Endpoint = url + "/domestic-payment-consents"
+	tc.Input.IdempotencyKey = true
+	tc.Input.Headers["Content-Type"] = "application/json"
+	tc.Input.RequestBody = `{"Data": {"Initiation": {"InstructionIdentification": "ACME412"}}, "Risk": {}}`
+	tc.Expect.StatusCode = http.StatusCreated
+	return tc
+}
+
+func runIdempotencyTests(t *testing.T, server *httptest.Server) (replay, conflict []string) {
+	runner := NewTestCaseRunner(test.NullLogger(), RunDefinition{}, NewBufferedDaemonController())
+	runner.executor = sendingExecutor{&Executor{}}
+	tc := idempotencyTestCase(server.URL)
+
+	result := runner.runTest(context.Background(), tc, &model.Context{}, test.NullLogger())
+	require.True(t, result.Pass, result.Fail)
+
+	for _, check := range []model.IdempotencyCheck{model.IdempotencyReplay, model.IdempotencyConflict} {
+		idempotency := idempotencyTestCase(server.URL)
+		idempotency.ID += "-IDEMPOTENCY"
+		idempotency.IdempotencyOf = tc.ID
+		idempotency.IdempotencyCheck = check
+		if check == model.IdempotencyConflict {
+			idempotency.Expect.StatusCode = 0
+		}
+		result := runner.runTest(context.Background(), idempotency, &model.Context{}, test.NullLogger())
+		if check == model.IdempotencyReplay {
+			replay = result.Fail
+		} else {
+			conflict = result.Fail
+		}
+	}
+	return replay, conflict
+}
+
+func TestIdempotencyChecksPass(t *testing.T) {
+	server := idempotentASPSP(true)
+	defer server.Close()
+
+	replay, conflict := runIdempotencyTests(t, server)
+
+	assert.Empty(t, replay)
+	assert.Empty(t, conflict)
+}
+
+func TestIdempotencyChecksFail(t *testing.T) {
+	server := idempotentASPSP(false)
+	defer server.Close()
+
+	replay, conflict := runIdempotencyTests(t, server)
+
+	require.Len(t, replay, 1)
+	assert.Contains(t, replay[0], `idempotency: expected resource {\"ConsentId\":\"sdp-1\"}`)
+	require.Len(t, conflict, 1)
+	assert.Contains(t, conflict[0], "to be rejected with a 4xx status, got 201")
+}
+
+func TestIdempotencyTestCaseWithoutRequest(t *testing.T) {
+	runner := NewTestCaseRunner(test.NullLogger(), RunDefinition{}, NewBufferedDaemonController())
+	runner.executor = failingExecutor{}
+	tc := idempotencyTestCase("https://aspsp.example.com")
+	tc.IdempotencyOf = "OB-301-DOP-100100"
+	tc.IdempotencyCheck = model.IdempotencyReplay
+
+	result := runner.runTest(context.Background(), tc, &model.Context{}, test.NullLogger())
+
+	assert.False(t, result.Pass)
+	assert.Equal(t, []string{"idempotency: OB-301-DOP-100100 did not pass, so there is no request to send again"}, result.Fail)
+}
+
+type idempotencyRecordingController struct {
+	DaemonController
+	requests map[string]IdempotentRequest
+}
+
+func (c *idempotencyRecordingController) RecordIdempotentRequest(testCaseID string, request IdempotentRequest) {
+	c.requests[testCaseID] = request
+}
+
+func TestIdempotencyTestCaseOfResumedRun(t *testing.T) {
+	server := idempotentASPSP(true)
+	defer server.Close()
+	controller := &idempotencyRecordingController{DaemonController: NewBufferedDaemonController(), requests: map[string]IdempotentRequest{}}
+	runner := NewTestCaseRunner(test.NullLogger(), RunDefinition{}, controller)
+	runner.executor = sendingExecutor{&Executor{}}
+	tc := idempotencyTestCase(server.URL)
+
+	result := runner.runTest(context.Background(), tc, &model.Context{}, test.NullLogger())
+	require.True(t, result.Pass, result.Fail)
+	require.Contains(t, controller.requests, tc.ID)
+	assert.Equal(t, map[string]string{"ConsentId": "sdp-1"}, controller.requests[tc.ID].ResourceIDs)
+
+	resumed := NewTestCaseRunner(test.NullLogger(), RunDefinition{Idempotent: controller.requests}, NewBufferedDaemonController())
+	resumed.executor = sendingExecutor{&Executor{}}
+	replay := idempotencyTestCase(server.URL)
+	replay.ID += "-IDEMPOTENCY-REPLAY"
+	replay.IdempotencyOf = tc.ID
+	replay.IdempotencyCheck = model.IdempotencyReplay
+
+	result = resumed.runTest(context.Background(), replay, &model.Context{}, test.NullLogger())
+	assert.True(t, result.Pass, result.Fail)
+}
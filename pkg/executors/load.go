@@ -0,0 +1,506 @@
+package executors
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/resty.v1"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/authentication"
+	"github.com/OpenBankingUK/conformance-suite/pkg/model"
+)
+
+// loadMaxInFlight - requests a load run waits on at most. Requests due while this many are in
+// flight are dropped, as the ASPSP is not keeping up with the target rate.
+const loadMaxInFlight = 64
+
+// loadMaxRPS - the highest rate a load run sends at, above it requests would be due more often
+// than the ticker can tick
+const loadMaxRPS = 1000
+
+// LoadDefinition - the test cases a load run sends, how fast and for how long
+type LoadDefinition struct {
+	TestCases     []model.TestCase // read-only test cases, sent in turn
+	RPS           float64          // requests started each second
+	Duration      time.Duration
+	SigningCert   authentication.Certificate
+	TransportCert authentication.Certificate
+	// Tokens - optional, refreshes the access tokens the test cases send before they expire
+	Tokens TokenRefresher
+	// Client - optional, sends the requests, a client of its own is made when not set
+	Client *resty.Client
+}
+
+// IsReadOnly - whether `tc` can be sent over and over without changing resources at the ASPSP
+func IsReadOnly(tc model.TestCase) bool {
+	return (tc.Input.Method == http.MethodGet || tc.Input.Method == http.MethodHead) && !tc.DoNotCallEndpoint
+}
+
+// LoadReport - what a load run has measured so far. Latencies are in milliseconds.
+type LoadReport struct {
+	Running bool      `json:"running"`
+	Started time.Time `json:"started"`
+	// Elapsed - seconds from the start of the run to its last response, or to now while running
+	Elapsed     float64            `json:"elapsed"`
+	TargetRPS   float64            `json:"target_rps"`
+	Throughput  float64            `json:"throughput"` // responses a second
+	Requests    int                `json:"requests"`
+	Errors      int                `json:"errors"`
+	ErrorRate   float64            `json:"error_rate"`
+	RateLimited int                `json:"rate_limited"` // 429 responses, not counted as errors
+	Dropped     int                `json:"dropped"`      // requests not sent, too many were in flight or the ASPSP asked to retry later
+	StatusCodes map[string]int     `json:"status_codes"`
+	Latency     LoadLatency        `json:"latency"`
+	TestCases   []LoadTestCaseStat `json:"test_cases"`
+}
+
+// LoadLatency - response time percentiles, in milliseconds
+type LoadLatency struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+// LoadTestCaseStat - the requests a load run sent for one test case
+type LoadTestCaseStat struct {
+	ID       string      `json:"id"`
+	Endpoint string      `json:"endpoint"`
+	Requests int         `json:"requests"`
+	Errors   int         `json:"errors"`
+	Latency  LoadLatency `json:"latency"`
+}
+
+// loadSample - the outcome of one request of a load run
+type loadSample struct {
+	testCase   int
+	latency    time.Duration
+	statusCode int // 0 when no response was received
+	err        bool
+}
+
+// loadCounts - the requests, errors and response times a load run has recorded, overall or for a
+// test case
+type loadCounts struct {
+	requests int
+	errors   int
+	latency  latencyHistogram
+}
+
+// latencyGrowth - how much wider each latency histogram bucket is than the one before, percentiles
+// are within this much of the exact response time
+const latencyGrowth = 1.01
+
+// latencyBuckets - the number of latency histogram buckets, enough for response times up to an
+// hour. Longer ones are counted in the last bucket.
+var latencyBuckets = latencyBucket(time.Hour) + 1
+
+// latencyHistogram - counts of response times by bucket, so a load run keeps the same memory and
+// reports in the same time however many responses it gets
+type latencyHistogram struct {
+	counts []int // by bucket, grown up to the bucket of the longest response time
+	total  int
+	max    time.Duration
+}
+
+// latencyBucket - the bucket of `latency`, the one whose upper bound is the first not below it
+func latencyBucket(latency time.Duration) int {
+	micros := float64(latency) / float64(time.Microsecond)
+	if micros <= 1 {
+		return 0
+	}
+	return int(math.Ceil(math.Log(micros) / math.Log(latencyGrowth)))
+}
+
+func (h *latencyHistogram) add(latency time.Duration) {
+	bucket := latencyBucket(latency)
+	if bucket >= latencyBuckets {
+		bucket = latencyBuckets - 1
+	}
+	for len(h.counts) <= bucket {
+		h.counts = append(h.counts, 0)
+	}
+	h.counts[bucket]++
+	h.total++
+	if latency > h.max {
+		h.max = latency
+	}
+}
+
+// percentile - the upper bound of the bucket holding the nearest-rank percentile `p`, the longest
+// response time when that is lower
+func (h *latencyHistogram) percentile(p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(h.total)))
+	if rank < 1 {
+		rank = 1
+	}
+	seen := 0
+	for bucket, count := range h.counts {
+		seen += count
+		if seen >= rank {
+			upper := time.Duration(math.Pow(latencyGrowth, float64(bucket)) * float64(time.Microsecond))
+			if upper > h.max {
+				return h.max
+			}
+			return upper
+		}
+	}
+	return h.max
+}
+
+func (h *latencyHistogram) loadLatency() LoadLatency {
+	if h.total == 0 {
+		return LoadLatency{}
+	}
+	return LoadLatency{
+		P50: milliseconds(h.percentile(50)),
+		P90: milliseconds(h.percentile(90)),
+		P95: milliseconds(h.percentile(95)),
+		P99: milliseconds(h.percentile(99)),
+		Max: milliseconds(h.max),
+	}
+}
+
+// LoadRunner - sends read-only test cases at a target rate for a duration, recording latency,
+// errors and throughput
+type LoadRunner struct {
+	executor   TestCaseExecutor
+	definition LoadDefinition
+	logger     *logrus.Entry
+	stop       chan struct{}
+	stopOnce   sync.Once
+
+	lock        sync.Mutex
+	running     bool
+	started     time.Time
+	finished    time.Time
+	total       loadCounts
+	testCases   []loadCounts // by index in definition.TestCases
+	rateLimited int
+	statusCodes map[string]int
+	dropped     int
+	retryUntil  time.Time
+	lastArrival time.Time
+}
+
+// NewLoadRunner - checks `definition` sends only read-only test cases, at a rate above 0 and at
+// most loadMaxRPS, for a duration above 0
+func NewLoadRunner(logger *logrus.Entry, definition LoadDefinition) (*LoadRunner, error) {
+	if definition.RPS <= 0 {
+		return nil, errors.New("load: rps must be more than 0")
+	}
+	if definition.RPS > loadMaxRPS {
+		return nil, fmt.Errorf("load: rps must be at most %d", loadMaxRPS)
+	}
+	if definition.Duration <= 0 {
+		return nil, errors.New("load: duration must be more than 0")
+	}
+	if len(definition.TestCases) == 0 {
+		return nil, errors.New("load: no test cases selected")
+	}
+	for _, tc := range definition.TestCases {
+		if !IsReadOnly(tc) {
+			return nil, fmt.Errorf("load: test case %s is not read-only, %s requests cannot be sent repeatedly", tc.ID, tc.Input.Method)
+		}
+	}
+	if definition.Client == nil {
+		definition.Client = resty.New()
+	}
+	return &LoadRunner{
+		executor:    NewExecutor(definition.Client),
+		definition:  definition,
+		logger:      logger.WithField("module", "LoadRunner"),
+		stop:        make(chan struct{}),
+		statusCodes: map[string]int{},
+	}, nil
+}
+
+// Prepare - leaves out the test cases whose request cannot be prepared with `ctx`, such as those
+// using a resource id only known once an earlier test case of a test run has created it. Errors
+// when no test case is left. Call before Run.
+func (l *LoadRunner) Prepare(ctx *model.Context) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	prepared := make([]model.TestCase, 0, len(l.definition.TestCases))
+	for _, tc := range l.definition.TestCases {
+		preparing := loadTestCase(tc)
+		tcCtx := &model.Context{}
+		tcCtx.PutContext(ctx)
+		if _, err := preparing.Prepare(l.definition.Client, tcCtx); err != nil {
+			l.logger.WithError(err).WithField("ID", tc.ID).Warn("leaving test case out of load run, its request cannot be prepared")
+			continue
+		}
+		prepared = append(prepared, tc)
+	}
+	if len(prepared) == 0 {
+		return errors.New("load: no selected test case can be prepared")
+	}
+	l.definition.TestCases = prepared
+	return nil
+}
+
+// Start - marks the run as running, then runs it in the background. Running is true as soon as
+// Start returns, so a caller checking it cannot start something else before the run gets going.
+func (l *LoadRunner) Start(ctx *model.Context) {
+	l.begin()
+	go l.Run(ctx)
+}
+
+// Run - sends the test cases in turn until the duration has passed or Stop is called, then waits
+// for the requests in flight. `ctx` is not changed.
+func (l *LoadRunner) Run(ctx *model.Context) LoadReport {
+	l.begin()
+	if err := l.executor.SetCertificates(l.definition.SigningCert, l.definition.TransportCert); err != nil {
+		l.logger.WithError(err).Error("load run certificates")
+	}
+
+	l.logger.WithFields(logrus.Fields{
+		"rps":       l.definition.RPS,
+		"duration":  l.definition.Duration,
+		"testCases": len(l.definition.TestCases),
+	}).Info("load run started")
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / l.definition.RPS))
+	defer ticker.Stop()
+	deadline := time.NewTimer(l.definition.Duration)
+	defer deadline.Stop()
+	inFlight := make(chan struct{}, loadMaxInFlight)
+	wg := sync.WaitGroup{}
+
+	for next := 0; ; {
+		select {
+		case <-l.stop:
+		case <-deadline.C:
+		case now := <-ticker.C:
+			if l.retryingLater(now) {
+				l.drop()
+				continue
+			}
+			select {
+			case inFlight <- struct{}{}:
+			default:
+				l.drop()
+				continue
+			}
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				l.send(i, ctx)
+				<-inFlight
+			}(next)
+			next = (next + 1) % len(l.definition.TestCases)
+			continue
+		}
+		break
+	}
+	wg.Wait()
+
+	l.lock.Lock()
+	l.running = false
+	l.finished = time.Now()
+	l.lock.Unlock()
+	report := l.Report()
+	l.logger.WithFields(logrus.Fields{
+		"requests":   report.Requests,
+		"errors":     report.Errors,
+		"throughput": report.Throughput,
+	}).Info("load run finished")
+	return report
+}
+
+// begin - marks the run as running from now, unless Start already has
+func (l *LoadRunner) begin() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if !l.running {
+		l.running = true
+		l.started = time.Now()
+	}
+	if l.testCases == nil {
+		l.testCases = make([]loadCounts, len(l.definition.TestCases))
+	}
+}
+
+// Running - whether the run is sending requests or waiting for those in flight
+func (l *LoadRunner) Running() bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.running
+}
+
+// Stop - ends the run, requests in flight are still recorded
+func (l *LoadRunner) Stop() {
+	l.stopOnce.Do(func() { close(l.stop) })
+}
+
+// send - sends test case `i` with a context and input of its own, as requests are sent at once
+func (l *LoadRunner) send(i int, ruleCtx *model.Context) {
+	tc := loadTestCase(l.definition.TestCases[i])
+	ctx := &model.Context{}
+	ctx.PutContext(ruleCtx)
+	if tokenName := bearerTokenName(tc); l.definition.Tokens != nil && tokenName != "" {
+		if _, err := l.definition.Tokens.Refresh(context.Background(), tokenName, ctx, false); err != nil {
+			l.logger.WithError(err).WithField("tokenName", tokenName).Warn("refreshing access token before it expires")
+		}
+	}
+
+	req, err := tc.Prepare(l.definition.Client, ctx)
+	if err != nil {
+		l.logger.WithError(err).WithField("ID", tc.ID).Error("preparing load request")
+		l.record(loadSample{testCase: i, err: true})
+		return
+	}
+	resp, metrics, err := l.executor.ExecuteTestCase(context.Background(), req, &tc, ctx)
+	sample := loadSample{testCase: i, latency: metrics.ResponseTime, err: err != nil}
+	if resp != nil && resp.RawResponse != nil {
+		sample.statusCode = resp.StatusCode()
+	}
+	if sample.statusCode == http.StatusTooManyRequests {
+		l.retryAfter(resp)
+	} else if !sample.err {
+		sample.err = !expectedStatus(tc, sample.statusCode)
+	}
+	l.record(sample)
+}
+
+// expectedStatus - whether `statusCode` is the one `tc` expects, any 2xx when it expects none
+func expectedStatus(tc model.TestCase, statusCode int) bool {
+	if tc.Expect.StatusCode == 0 {
+		return statusCode >= 200 && statusCode < 300
+	}
+	return statusCode == tc.Expect.StatusCode
+}
+
+// retryAfter - holds back requests until the time the Retry-After header of 429 `resp` asks for,
+// one second when it has none
+func (l *LoadRunner) retryAfter(resp *resty.Response) {
+	until := time.Now().Add(time.Second)
+	retryAfter := resp.Header().Get("Retry-After")
+	if seconds, err := strconv.ParseUint(retryAfter, 10, 32); err == nil {
+		until = time.Now().Add(time.Duration(seconds) * time.Second)
+	} else if date, err := http.ParseTime(retryAfter); err == nil {
+		until = date
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if until.After(l.retryUntil) {
+		l.retryUntil = until
+	}
+}
+
+func (l *LoadRunner) retryingLater(now time.Time) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return now.Before(l.retryUntil)
+}
+
+func (l *LoadRunner) drop() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.dropped++
+}
+
+func (l *LoadRunner) record(sample loadSample) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.lastArrival = time.Now()
+
+	counts := &l.testCases[sample.testCase]
+	l.total.requests++
+	counts.requests++
+	switch {
+	case sample.statusCode == http.StatusTooManyRequests:
+		l.rateLimited++
+	case sample.err:
+		l.total.errors++
+		counts.errors++
+	}
+	if sample.statusCode == 0 {
+		l.statusCodes["none"]++
+		return
+	}
+	l.statusCodes[strconv.Itoa(sample.statusCode)]++
+	l.total.latency.add(sample.latency)
+	counts.latency.add(sample.latency)
+}
+
+// Report - what the run has measured so far
+func (l *LoadRunner) Report() LoadReport {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	report := LoadReport{
+		Running:     l.running,
+		Started:     l.started,
+		TargetRPS:   l.definition.RPS,
+		Requests:    l.total.requests,
+		Errors:      l.total.errors,
+		RateLimited: l.rateLimited,
+		Dropped:     l.dropped,
+		StatusCodes: make(map[string]int, len(l.statusCodes)),
+		TestCases:   make([]LoadTestCaseStat, len(l.definition.TestCases)),
+	}
+	for i, tc := range l.definition.TestCases {
+		report.TestCases[i] = LoadTestCaseStat{ID: tc.ID, Endpoint: tc.Input.Method + " " + tc.Input.Endpoint}
+	}
+	if l.started.IsZero() {
+		return report
+	}
+	end := l.finished
+	if l.running {
+		end = time.Now()
+	} else if !l.lastArrival.IsZero() {
+		end = l.lastArrival
+	}
+	report.Elapsed = end.Sub(l.started).Seconds()
+
+	for code, count := range l.statusCodes {
+		report.StatusCodes[code] = count
+	}
+	for i, counts := range l.testCases {
+		report.TestCases[i].Requests = counts.requests
+		report.TestCases[i].Errors = counts.errors
+		report.TestCases[i].Latency = counts.latency.loadLatency()
+	}
+	if report.Requests > 0 {
+		report.ErrorRate = float64(report.Errors) / float64(report.Requests)
+	}
+	if report.Elapsed > 0 {
+		report.Throughput = float64(l.total.latency.total) / report.Elapsed
+	}
+	report.Latency = l.total.latency.loadLatency()
+	return report
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// loadTestCase - a copy of `tc` with maps of its own, as preparing a request changes its input
+func loadTestCase(tc model.TestCase) model.TestCase {
+	tc.Input.Headers = copyStringMap(tc.Input.Headers)
+	tc.Input.FormData = copyStringMap(tc.Input.FormData)
+	tc.Input.QueryParameters = copyStringMap(tc.Input.QueryParameters)
+	tc.Input.Claims = copyStringMap(tc.Input.Claims)
+	tc.Input.Generation = copyStringMap(tc.Input.Generation)
+	tc.Input.RemoveHeaders = append([]string(nil), tc.Input.RemoveHeaders...)
+	return tc
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	copied := make(map[string]string, len(m))
+	for k, v := range m {
+		copied[k] = v
+	}
+	return copied
+}
@@ -0,0 +1,198 @@
+package executors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/resty.v1"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/model"
+	"github.com/OpenBankingUK/conformance-suite/pkg/test"
+)
+
+func readOnlyTestCase(id, endpoint string) model.TestCase {
+	tc := model.MakeTestCase()
+	tc.ID = id
+	tc.Input.Method = http.MethodGet
+	tc.Input.Endpoint = endpoint
+	tc.Input.Headers["Authorization"] = "Bearer $Token001"
+	tc.Expect.StatusCode = http.StatusOK
+	return tc
+}
+
+func newTestLoadRunner(t *testing.T, definition LoadDefinition) *LoadRunner {
+	runner, err := NewLoadRunner(test.NullLogger(), definition)
+	require.NoError(t, err)
+	runner.executor = sendingExecutor{&Executor{}}
+	return runner
+}
+
+func TestNewLoadRunnerSendsReadOnlyTestCases(t *testing.T) {
+	post := readOnlyTestCase("OB-301-DOP-100100", "/domestic-payment-consents")
+	post.Input.Method = http.MethodPost
+
+	_, err := NewLoadRunner(test.NullLogger(), LoadDefinition{TestCases: []model.TestCase{post}, RPS: 1, Duration: time.Second})
+
+	assert.EqualError(t, err, "load: test case OB-301-DOP-100100 is not read-only, POST requests cannot be sent repeatedly")
+}
+
+func TestNewLoadRunnerLimitsRPS(t *testing.T) {
+	testCases := []model.TestCase{readOnlyTestCase("OB-301-ACC-120382", "/accounts")}
+
+	_, err := NewLoadRunner(test.NullLogger(), LoadDefinition{TestCases: testCases, RPS: loadMaxRPS + 1, Duration: time.Second})
+	assert.EqualError(t, err, "load: rps must be at most 1000")
+
+	_, err = NewLoadRunner(test.NullLogger(), LoadDefinition{TestCases: testCases, RPS: loadMaxRPS, Duration: time.Second})
+	assert.NoError(t, err)
+}
+
+func TestNewLoadRunnerSendsWithDefinitionClient(t *testing.T) {
+	client := resty.New()
+	runner, err := NewLoadRunner(test.NullLogger(), LoadDefinition{
+		TestCases: []model.TestCase{readOnlyTestCase("OB-301-ACC-120382", "/accounts")},
+		RPS:       1,
+		Duration:  time.Second,
+		Client:    client,
+	})
+
+	require.NoError(t, err)
+	assert.Same(t, client, runner.executor.(*Executor).Client, "certificates are set on the client of the journey, not the resty default")
+}
+
+func TestLoadRunnerPrepareLeavesOutTestCases(t *testing.T) {
+	accounts := readOnlyTestCase("OB-301-ACC-120382", "/accounts")
+	account := readOnlyTestCase("OB-301-ACC-811741", "/accounts/$consentedAccountId")
+	runner := newTestLoadRunner(t, LoadDefinition{TestCases: []model.TestCase{accounts, account}, RPS: 1, Duration: time.Second})
+	ctx := &model.Context{"phase": "run", "Token001": "a5c1f1fa"}
+
+	require.NoError(t, runner.Prepare(ctx))
+
+	assert.Equal(t, []model.TestCase{accounts}, runner.definition.TestCases)
+	assert.Equal(t, &model.Context{"phase": "run", "Token001": "a5c1f1fa"}, ctx)
+	assert.Equal(t, "/accounts/$consentedAccountId", account.Input.Endpoint)
+
+	runner = newTestLoadRunner(t, LoadDefinition{TestCases: []model.TestCase{account}, RPS: 1, Duration: time.Second})
+	assert.EqualError(t, runner.Prepare(ctx), "load: no selected test case can be prepared")
+}
+
+func TestLoadRunnerReport(t *testing.T) {
+	var rejected int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer a5c1f1fa-2" {
+			atomic.AddInt32(&rejected, 1)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/balances" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	collector, _ := testCollector(t, AccessToken{AccessToken: "a5c1f1fa", RefreshToken: "r1", Expires: tokenTestTime.Add(30 * time.Second)})
+
+	runner := newTestLoadRunner(t, LoadDefinition{
+		TestCases: []model.TestCase{
+			readOnlyTestCase("OB-301-ACC-120382", server.URL+"/accounts"),
+			readOnlyTestCase("OB-301-ACC-811741", server.URL+"/balances"),
+		},
+		RPS:      100,
+		Duration: 200 * time.Millisecond,
+		Tokens:   collector,
+	})
+	report := runner.Run(&model.Context{"Token001": "a5c1f1fa"})
+
+	assert.False(t, report.Running)
+	assert.Zero(t, atomic.LoadInt32(&rejected), "the token is refreshed before it expires")
+	require.NotZero(t, report.Requests)
+	require.Len(t, report.TestCases, 2)
+	accounts, balances := report.TestCases[0], report.TestCases[1]
+	assert.Equal(t, report.Requests, accounts.Requests+balances.Requests)
+	assert.Zero(t, accounts.Errors)
+	assert.Equal(t, balances.Requests, balances.Errors)
+	assert.Equal(t, balances.Requests, report.Errors)
+	assert.Equal(t, map[string]int{"200": accounts.Requests, "500": balances.Requests}, report.StatusCodes)
+	assert.InDelta(t, float64(report.Errors)/float64(report.Requests), report.ErrorRate, 1e-9)
+	assert.Greater(t, report.Throughput, 0.0)
+	assert.Equal(t, "GET "+server.URL+"/accounts", accounts.Endpoint)
+}
+
+func TestLoadRunnerStartIsRunningAtOnce(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	runner := newTestLoadRunner(t, LoadDefinition{
+		TestCases: []model.TestCase{readOnlyTestCase("OB-301-ACC-120382", server.URL+"/accounts")},
+		RPS:       1,
+		Duration:  time.Minute,
+	})
+
+	runner.Start(&model.Context{})
+
+	assert.True(t, runner.Running())
+	assert.False(t, runner.Report().Started.IsZero())
+	runner.Stop()
+	assert.Eventually(t, func() bool { return !runner.Running() }, time.Second, 10*time.Millisecond)
+}
+
+func TestLoadRunnerHoldsBackWhenRateLimited(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	runner := newTestLoadRunner(t, LoadDefinition{
+		TestCases: []model.TestCase{readOnlyTestCase("OB-301-ACC-120382", server.URL+"/accounts")},
+		RPS:       20,
+		Duration:  300 * time.Millisecond,
+	})
+	report := runner.Run(&model.Context{"Token001": "a5c1f1fa"})
+
+	assert.Equal(t, 1, report.Requests, "no requests are sent until the Retry-After has passed")
+	assert.Equal(t, 1, report.RateLimited)
+	assert.Zero(t, report.Errors)
+	assert.NotZero(t, report.Dropped)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	histogram := latencyHistogram{}
+	for i := 100; i > 0; i-- {
+		histogram.add(time.Duration(i) * time.Millisecond)
+	}
+
+	latency := histogram.loadLatency()
+	assert.InEpsilon(t, 50, latency.P50, latencyGrowth-1)
+	assert.InEpsilon(t, 90, latency.P90, latencyGrowth-1)
+	assert.InEpsilon(t, 95, latency.P95, latencyGrowth-1)
+	assert.InEpsilon(t, 99, latency.P99, latencyGrowth-1)
+	assert.Equal(t, 100.0, latency.Max)
+
+	single := latencyHistogram{}
+	single.add(7 * time.Millisecond)
+	assert.Equal(t, LoadLatency{P50: 7, P90: 7, P95: 7, P99: 7, Max: 7}, single.loadLatency())
+	assert.Equal(t, LoadLatency{}, (&latencyHistogram{}).loadLatency())
+}
+
+func TestLatencyHistogramIsBounded(t *testing.T) {
+	histogram := latencyHistogram{}
+	for i := 0; i < 100000; i++ {
+		histogram.add(time.Duration(i) * time.Millisecond)
+	}
+	histogram.add(2 * time.Hour)
+
+	assert.Len(t, histogram.counts, latencyBuckets)
+	assert.Equal(t, 100001, histogram.total)
+	assert.Equal(t, 2*time.Hour, histogram.max)
+	assert.InEpsilon(t, 50000, histogram.loadLatency().P50, latencyGrowth-1)
+}
@@ -1,16 +1,19 @@
 package executors
 
 import (
+	"context"
+
 	"github.com/OpenBankingUK/conformance-suite/pkg/authentication"
 	"github.com/pkg/errors"
 
 	"github.com/OpenBankingUK/conformance-suite/pkg/manifest"
 	"github.com/OpenBankingUK/conformance-suite/pkg/model"
+	"github.com/OpenBankingUK/conformance-suite/pkg/schemaprops"
 	"github.com/sirupsen/logrus"
 )
 
-func getPaymentConsents(definition RunDefinition, requiredTokens []manifest.RequiredTokens, ctx *model.Context) (TokenConsentIDs, error) {
-	executor := &Executor{}
+func getPaymentConsents(traceCtx context.Context, definition RunDefinition, requiredTokens []manifest.RequiredTokens, ctx *model.Context) (TokenConsentIDs, error) {
+	executor := &Executor{Client: definition.Client}
 	err := executor.SetCertificates(definition.SigningCert, definition.TransportCert)
 	if err != nil {
 		logrus.Error("error running payment consent acquisition async: " + err.Error())
@@ -22,7 +25,7 @@ func getPaymentConsents(definition RunDefinition, requiredTokens []manifest.Requ
 		logrus.Tracef("%#v", rt)
 	}
 
-	requiredTokens, err = runPaymentConsents(requiredTokens, ctx, executor)
+	requiredTokens, err = runPaymentConsents(traceCtx, requiredTokens, ctx, executor, definition.Properties)
 	if err != nil {
 		logrus.Errorf("getPaymentConsents error: " + err.Error())
 	}
@@ -37,7 +40,7 @@ func getPaymentConsents(definition RunDefinition, requiredTokens []manifest.Requ
 	return consentItems, err
 }
 
-func runPaymentConsents(rt []manifest.RequiredTokens, ctx *model.Context, executor *Executor) ([]manifest.RequiredTokens, error) {
+func runPaymentConsents(traceCtx context.Context, rt []manifest.RequiredTokens, ctx *model.Context, executor *Executor, properties schemaprops.PropertyCollector) ([]manifest.RequiredTokens, error) {
 	localCtx := model.Context{}
 	localCtx.PutContext(ctx)
 	localCtx.PutString("scope", "payments")
@@ -89,7 +92,7 @@ func runPaymentConsents(rt []manifest.RequiredTokens, ctx *model.Context, execut
 	}
 
 	tc.ProcessReplacementFields(&localCtx, true)
-	err = executePaymentTest(&tc, &localCtx, executor)
+	err = executePaymentTest(traceCtx, &tc, &localCtx, executor, properties)
 	if err != nil {
 		return nil, errors.New("Payment PSU consent execute clientCredential grant testcase failed :" + err.Error())
 	}
@@ -112,7 +115,7 @@ func runPaymentConsents(rt []manifest.RequiredTokens, ctx *model.Context, execut
 		test.InjectBearerToken(bearerToken) //client credential grant token
 		test.Input.Headers["Content-Type"] = "application/json"
 
-		err = executePaymentTest(&test, &localCtx, executor)
+		err = executePaymentTest(traceCtx, &test, &localCtx, executor, properties)
 		if err != nil {
 			return nil, errors.New("Payment PSU consent test case failed " + err.Error())
 		}
@@ -122,6 +125,7 @@ func runPaymentConsents(rt []manifest.RequiredTokens, ctx *model.Context, execut
 		}
 		localCtx.PutString("consent_id", v.ConsentID)
 		localCtx.PutString("token_name", v.Name)
+		putConsentState(&localCtx, v.Name)
 
 		exchange, err := readPsuExchange()
 		if err != nil {
@@ -139,7 +143,7 @@ func runPaymentConsents(rt []manifest.RequiredTokens, ctx *model.Context, execut
 		}
 
 		localCtx.DumpContext("before exchange", "token_name", "consent_id")
-		err = executePaymentTest(&exchange, &localCtx, executor)
+		err = executePaymentTest(traceCtx, &exchange, &localCtx, executor, properties)
 		if err != nil {
 			return nil, errors.New("Payment PSU consent exchange code failed " + err.Error())
 		}
@@ -162,17 +166,18 @@ func runPaymentConsents(rt []manifest.RequiredTokens, ctx *model.Context, execut
 	return rt, nil
 }
 
-func executePaymentTest(tc *model.TestCase, ctx *model.Context, executor *Executor) error {
-	req, err := tc.Prepare(ctx)
+func executePaymentTest(traceCtx context.Context, tc *model.TestCase, ctx *model.Context, executor *Executor, properties schemaprops.PropertyCollector) error {
+	req, err := tc.Prepare(executor.Client, ctx)
 	if err != nil {
 		logrus.Errorf("preparing to execute test %s: %s", tc.ID, err.Error())
 		return err
 	}
-	resp, _, err := executor.ExecuteTestCase(req, tc, ctx)
+	resp, _, err := executor.ExecuteTestCase(traceCtx, req, tc, ctx)
 	if err != nil {
 		return err
 	}
 	result, errs := tc.Validate(resp, ctx)
+	collectProperties(properties, tc, resp)
 	if errs != nil {
 		return err
 	}
@@ -199,7 +204,7 @@ func readPsuExchange() (model.TestCase, error) {
 }
 
 func (r *TestCaseRunner) executePaymentConsent(tc model.TestCase, ruleCtx *model.Context, log *logrus.Entry) (bool, []string) {
-	testresult := r.executeTest(tc, ruleCtx, log)
+	testresult := r.executeTest(context.Background(), tc, ruleCtx, log)
 	return testresult.Pass, testresult.Fail
 
 }
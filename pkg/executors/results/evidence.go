@@ -0,0 +1,78 @@
+package results
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"gopkg.in/resty.v1"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/redact"
+)
+
+// Evidence - the HTTP exchange a test case result is based on
+type Evidence struct {
+	Request  EvidenceRequest  `json:"request"`
+	Response EvidenceResponse `json:"response"`
+}
+
+// EvidenceRequest - the request sent to the ASPSP
+type EvidenceRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// EvidenceResponse - the response received from the ASPSP
+type EvidenceResponse struct {
+	StatusCode int         `json:"status_code"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// NewEvidenceFromRestyResponse - the request and response of `response`, nil if there is no response.
+// Credentials in the headers and bodies are redacted.
+func NewEvidenceFromRestyResponse(response *resty.Response) *Evidence {
+	if response == nil || response.Request == nil {
+		return nil
+	}
+
+	request := EvidenceRequest{
+		Method:  response.Request.Method,
+		URL:     response.Request.URL,
+		Headers: response.Request.Header,
+		Body:    redact.String(requestBody(response.Request.Body)),
+	}
+	if raw := response.Request.RawRequest; raw != nil {
+		request.URL = raw.URL.String()
+		request.Headers = raw.Header
+	}
+	request.Headers = redact.Headers(request.Headers)
+
+	return &Evidence{
+		Request: request,
+		Response: EvidenceResponse{
+			StatusCode: response.StatusCode(),
+			Headers:    redact.Headers(response.Header()),
+			Body:       redact.String(string(response.Body())),
+		},
+	}
+}
+
+func requestBody(body interface{}) string {
+	switch body := body.(type) {
+	case nil:
+		return ""
+	case string:
+		return body
+	case []byte:
+		return string(body)
+	default:
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Sprintf("%v", body)
+		}
+		return string(encoded)
+	}
+}
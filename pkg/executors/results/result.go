@@ -1,17 +1,26 @@
 package results
 
+import (
+	"github.com/OpenBankingUK/conformance-suite/pkg/authentication"
+	"github.com/OpenBankingUK/conformance-suite/pkg/schema"
+)
+
 // TestCase result for a run
 type TestCase struct {
-	Id         string   `json:"id"`
-	Pass       bool     `json:"pass"`
-	Metrics    Metrics  `json:"metrics"`
-	Fail       []string `json:"fail,omitempty"`
-	Detail     string   `json:"detail"`
-	RefURI     string   `json:"refURI"`
-	Endpoint   string   `json:"endpoint"`
-	API        string   `json:"-"`
-	APIVersion string   `json:"-"`
-	HttpStatus string   `json:"httpStatusCode"`
+	Id         string                          `json:"id"`
+	Pass       bool                            `json:"pass"`
+	Metrics    Metrics                         `json:"metrics"`
+	Fail       []string                        `json:"fail,omitempty"`
+	Detail     string                          `json:"detail"`
+	RefURI     string                          `json:"refURI"`
+	Endpoint   string                          `json:"endpoint"`
+	API        string                          `json:"-"`
+	APIVersion string                          `json:"-"`
+	HttpStatus string                          `json:"httpStatusCode"`
+	Signature  *authentication.SignatureReport `json:"signature,omitempty"`  // x-jws-signature verification, when the response was signed
+	Headers    []schema.HeaderCheck            `json:"headers,omitempty"`    // OB header rules that applied to the response
+	Evidence   *Evidence                       `json:"-"`                    // request and response, kept in the run history rather than sent to the UI
+	SuiteError bool                            `json:"suiteError,omitempty"` // the suite is at fault, not the ASPSP: it built a request the spec does not allow
 }
 
 // NewTestCaseFail returns a failed test
@@ -1,13 +1,17 @@
 package executors
 
 import (
+	"context"
 	"errors"
 	"sync"
+	"time"
 
 	"github.com/OpenBankingUK/conformance-suite/pkg/executors/events"
+	"github.com/OpenBankingUK/conformance-suite/pkg/metrics"
 	"github.com/OpenBankingUK/conformance-suite/pkg/model"
 
 	"github.com/sirupsen/logrus"
+	"gopkg.in/resty.v1"
 )
 
 // Collector collects tokens for a set or permissions requirements and calls a
@@ -28,18 +32,22 @@ type TokenConsentIDs []TokenConsentIDItem
 
 // TokenConsentIDItem is a single consentId mapping to token name
 type TokenConsentIDItem struct {
-	TokenName   string
-	ConsentID   string
-	Permissions string
-	AccessToken string
-	ConsentURL  string
-	Error       string
+	TokenName    string
+	ConsentID    string
+	Permissions  string
+	AccessToken  string
+	RefreshToken string
+	Expires      time.Time // zero when the token endpoint did not send `expires_in`
+	State        events.TokenState
+	ConsentURL   string
+	Error        string
 }
 
-// TokenCollector - collects tokens
+// TokenCollector - collects tokens, and refreshes them once collected
 type TokenCollector interface {
-	Collect(tokenName, accesstoken string) error
+	Collect(tokenName string, token AccessToken) error
 	Tokens() TokenConsentIDs
+	TokenRefresher
 }
 
 type tokenCollector struct {
@@ -49,22 +57,37 @@ type tokenCollector struct {
 	consentTable TokenConsentIDs
 	log          *logrus.Entry
 	events       events.Events
+	refresh      func(traceCtx context.Context, refreshToken string, ctx *model.Context) (AccessToken, error)
+	now          func() time.Time
+	created      time.Time // when the consent URLs were issued
 }
 
-// NewTokenCollector -
-func NewTokenCollector(log *logrus.Entry, consentIds TokenConsentIDs, doneFunc func(), events events.Events) TokenCollector {
+// NewTokenCollector - `consentIds` that already have an access token, such as those of a resumed
+// run, count as collected. Tokens are refreshed with `client`.
+func NewTokenCollector(log *logrus.Entry, client *resty.Client, consentIds TokenConsentIDs, doneFunc func(), events events.Events) TokenCollector {
+	collected := 0
+	for _, item := range consentIds {
+		if item.AccessToken != "" {
+			collected++
+		}
+	}
 	return &tokenCollector{
+		created:      time.Now(),
 		tokensLock:   &sync.Mutex{},
-		collected:    0,
+		collected:    collected,
 		doneFunc:     doneFunc,
 		consentTable: consentIds,
 		log:          log.WithField("module", "tokenCollector"),
 		events:       events,
+		now:          time.Now,
+		refresh: func(traceCtx context.Context, refreshToken string, ctx *model.Context) (AccessToken, error) {
+			return RefreshAccessToken(traceCtx, client, refreshToken, ctx)
+		},
 	}
 }
 
 // Collect receives an accesstoken to match a named token for which we have a consentid
-func (c *tokenCollector) Collect(tokenName, accessToken string) error {
+func (c *tokenCollector) Collect(tokenName string, accessToken AccessToken) error {
 	logger := c.log.WithFields(logrus.Fields{
 		"module":   "tokenCollector",
 		"function": "Collect",
@@ -81,7 +104,7 @@ func (c *tokenCollector) Collect(tokenName, accessToken string) error {
 	tokenNameExists := c.tokenNameExists(tokenName)
 	logger.WithFields(logrus.Fields{
 		"tokenName":       tokenName,
-		"accessToken":     accessToken,
+		"accessToken":     accessToken.AccessToken,
 		"tokenNameExists": tokenNameExists,
 	}).Debug("Collecting ...")
 	if !tokenNameExists {
@@ -137,15 +160,22 @@ func (c *tokenCollector) tokenNameExists(tokenName string) bool {
 	return false
 }
 
-func (c *tokenCollector) addAccessToken(tokenName, accessToken string) {
+func (c *tokenCollector) addAccessToken(tokenName string, accessToken AccessToken) {
 	for k, item := range c.consentTable {
 		if tokenName == item.TokenName {
-			item.AccessToken = accessToken
+			if item.AccessToken == "" {
+				metrics.ObserveConsentWait(c.now().Sub(c.created))
+			}
+			item.AccessToken = accessToken.AccessToken
+			item.RefreshToken = accessToken.RefreshToken
+			item.Expires = accessToken.Expires
+			item.State = events.TokenValid
 			c.consentTable[k] = item
 			c.collected++
 
 			acquiredAccessToken := events.NewAcquiredAccessToken(tokenName)
 			c.events.AddAcquiredAccessToken(acquiredAccessToken)
+			c.events.AddTokenStateChanged(events.NewTokenStateChanged(tokenName, item.State, item.Expires))
 		}
 	}
 }
@@ -0,0 +1,145 @@
+package executors
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors/events"
+	"github.com/OpenBankingUK/conformance-suite/pkg/model"
+)
+
+// tokenRefreshMargin - access tokens expiring within this long are refreshed before a test case
+// uses them, so they do not expire while the request is in flight
+const tokenRefreshMargin = time.Minute
+
+var errNoRefreshToken = errors.New("access token was rejected, and the token endpoint did not send a refresh token")
+
+// AccessToken - an access token from the token endpoint, with the refresh token and expiry sent with it
+type AccessToken struct {
+	AccessToken  string
+	RefreshToken string
+	Expires      time.Time // zero when the token endpoint did not send `expires_in`
+}
+
+func newAccessToken(grant *grantToken, now time.Time) AccessToken {
+	token := AccessToken{
+		AccessToken:  grant.AccessToken,
+		RefreshToken: grant.RefreshToken,
+	}
+	if grant.Expires > 0 {
+		token.Expires = now.Add(time.Duration(grant.Expires) * time.Second)
+	}
+	return token
+}
+
+// TokenRefresher - keeps the access tokens used by a run valid
+type TokenRefresher interface {
+	// Refresh - puts the current access token named `tokenName` in `ctx`. The token is refreshed
+	// first when it expires within the refresh margin, or when `rejected` because the ASPSP
+	// answered 401. Returns true if the token was refreshed. Tokens it does not hold are ignored.
+	// The token request is traced under the span in `traceCtx`.
+	Refresh(traceCtx context.Context, tokenName string, ctx *model.Context, rejected bool) (bool, error)
+}
+
+func (c *tokenCollector) Refresh(traceCtx context.Context, tokenName string, ctx *model.Context, rejected bool) (bool, error) {
+	c.tokensLock.Lock()
+	defer c.tokensLock.Unlock()
+
+	k := c.indexOf(tokenName)
+	if k < 0 || c.consentTable[k].AccessToken == "" {
+		return false, nil
+	}
+	item := c.consentTable[k]
+	c.putAccessToken(ctx, item.TokenName, item.AccessToken)
+
+	now := c.now()
+	expiring := !item.Expires.IsZero() && item.Expires.Before(now.Add(tokenRefreshMargin))
+	if !rejected && !expiring {
+		return false, nil
+	}
+
+	logger := c.log.WithFields(logrus.Fields{
+		"function":  "Refresh",
+		"tokenName": tokenName,
+		"rejected":  rejected,
+		"expires":   item.Expires,
+	})
+
+	if item.RefreshToken == "" {
+		c.setState(k, expiredState(item, now, rejected))
+		if rejected {
+			return false, errNoRefreshToken
+		}
+		return false, nil
+	}
+
+	token, err := c.refresh(traceCtx, item.RefreshToken, ctx)
+	if err != nil {
+		logger.WithError(err).Warn("refreshing access token")
+		c.setState(k, expiredState(item, now, rejected))
+		return false, err
+	}
+
+	item.AccessToken = token.AccessToken
+	item.Expires = token.Expires
+	if token.RefreshToken != "" {
+		item.RefreshToken = token.RefreshToken // the ASPSP may rotate refresh tokens
+	}
+	c.consentTable[k] = item
+	c.putAccessToken(ctx, item.TokenName, item.AccessToken)
+	c.setState(k, events.TokenRefreshed)
+	logger.WithField("newExpires", item.Expires).Info("access token refreshed")
+	return true, nil
+}
+
+// expiredState - the state of `item` after it could not be refreshed
+func expiredState(item TokenConsentIDItem, now time.Time, rejected bool) events.TokenState {
+	if rejected || item.Expires.Before(now) {
+		return events.TokenExpired
+	}
+	return events.TokenExpiring
+}
+
+func (c *tokenCollector) indexOf(tokenName string) int {
+	for k, item := range c.consentTable {
+		if item.TokenName == tokenName {
+			return k
+		}
+	}
+	return -1
+}
+
+// putAccessToken - puts `accessToken` in `ctx` under its token name. The first token is also
+// kept as `access_token`, which is replaced when it holds an earlier value of this token.
+func (c *tokenCollector) putAccessToken(ctx *model.Context, tokenName, accessToken string) {
+	previous, _ := ctx.GetString(tokenName)
+	ctx.PutString(tokenName, accessToken)
+	if current, err := ctx.GetString("access_token"); err == nil && previous != "" && current == previous {
+		ctx.PutString("access_token", accessToken)
+	}
+}
+
+func (c *tokenCollector) setState(k int, state events.TokenState) {
+	item := c.consentTable[k]
+	if item.State == state && state != events.TokenRefreshed {
+		return
+	}
+	item.State = state
+	c.consentTable[k] = item
+	c.events.AddTokenStateChanged(events.NewTokenStateChanged(item.TokenName, state, item.Expires))
+}
+
+// bearerTokenName - the name of the access token `tc` sends, empty when it does not send one
+func bearerTokenName(tc model.TestCase) string {
+	const prefix = "Bearer $"
+	for name, value := range tc.Input.Headers {
+		if strings.EqualFold(name, "Authorization") && strings.HasPrefix(value, prefix) {
+			return strings.TrimPrefix(value, prefix)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,139 @@
+package executors
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/authentication"
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors/events"
+	"github.com/OpenBankingUK/conformance-suite/pkg/generation"
+	"github.com/OpenBankingUK/conformance-suite/pkg/model"
+	"github.com/OpenBankingUK/conformance-suite/pkg/schema"
+	"github.com/OpenBankingUK/conformance-suite/pkg/test"
+)
+
+var tokenTestTime = time.Date(2020, 5, 4, 10, 0, 0, 0, time.UTC)
+
+// testCollector - a collector holding Token001, which is refreshed to "a5c1f1fa-2"
+func testCollector(t *testing.T, token AccessToken) (*tokenCollector, events.Events) {
+	tokenEvents := events.NewEvents()
+	collector := NewTokenCollector(test.NullLogger(), nil, TokenConsentIDs{{TokenName: "Token001"}}, nil, tokenEvents).(*tokenCollector)
+	collector.now = func() time.Time { return tokenTestTime }
+	collector.refresh = func(traceCtx context.Context, refreshToken string, ctx *model.Context) (AccessToken, error) {
+		if refreshToken != "r1" {
+			return AccessToken{}, errors.New("invalid_grant")
+		}
+		return AccessToken{AccessToken: "a5c1f1fa-2", Expires: tokenTestTime.Add(time.Hour)}, nil
+	}
+	require.NoError(t, collector.Collect("Token001", token))
+	return collector, tokenEvents
+}
+
+func TestTokenCollectorRefreshesExpiringToken(t *testing.T) {
+	collector, tokenEvents := testCollector(t, AccessToken{AccessToken: "a5c1f1fa", RefreshToken: "r1", Expires: tokenTestTime.Add(30 * time.Second)})
+	ctx := model.Context{"Token001": "a5c1f1fa", "access_token": "a5c1f1fa"}
+
+	refreshed, err := collector.Refresh(context.Background(), "Token001", &ctx, false)
+
+	require.NoError(t, err)
+	assert.True(t, refreshed)
+	assert.Equal(t, model.Context{"Token001": "a5c1f1fa-2", "access_token": "a5c1f1fa-2"}, ctx)
+	token := collector.Tokens()[0]
+	assert.Equal(t, "r1", token.RefreshToken)
+	assert.Equal(t, tokenTestTime.Add(time.Hour), token.Expires)
+	assert.Equal(t, []events.TokenStateChanged{
+		{TokenName: "Token001", State: events.TokenValid, Expires: "2020-05-04T10:00:30Z"},
+		{TokenName: "Token001", State: events.TokenRefreshed, Expires: "2020-05-04T11:00:00Z"},
+	}, tokenEvents.AllTokenStateChanges())
+
+	refreshed, err = collector.Refresh(context.Background(), "Token001", &ctx, false)
+	require.NoError(t, err)
+	assert.False(t, refreshed)
+}
+
+func TestTokenCollectorRefreshPutsCurrentToken(t *testing.T) {
+	collector, _ := testCollector(t, AccessToken{AccessToken: "a5c1f1fa"})
+	ctx := model.Context{"Token001": "stale"}
+
+	refreshed, err := collector.Refresh(context.Background(), "Token001", &ctx, false)
+
+	require.NoError(t, err)
+	assert.False(t, refreshed)
+	assert.Equal(t, model.Context{"Token001": "a5c1f1fa"}, ctx)
+
+	refreshed, err = collector.Refresh(context.Background(), "Token002", &ctx, true)
+	require.NoError(t, err)
+	assert.False(t, refreshed)
+}
+
+func TestTokenCollectorRefreshWithoutRefreshToken(t *testing.T) {
+	collector, tokenEvents := testCollector(t, AccessToken{AccessToken: "a5c1f1fa", Expires: tokenTestTime.Add(30 * time.Second)})
+	ctx := model.Context{}
+
+	refreshed, err := collector.Refresh(context.Background(), "Token001", &ctx, false)
+	require.NoError(t, err)
+	assert.False(t, refreshed)
+	assert.Equal(t, events.TokenExpiring, collector.Tokens()[0].State)
+
+	refreshed, err = collector.Refresh(context.Background(), "Token001", &ctx, true)
+	assert.Equal(t, errNoRefreshToken, err)
+	assert.False(t, refreshed)
+	assert.Equal(t, events.TokenExpired, collector.Tokens()[0].State)
+	assert.Len(t, tokenEvents.AllTokenStateChanges(), 3)
+}
+
+// sendingExecutor - sends requests without client certificates
+type sendingExecutor struct {
+	*Executor
+}
+
+func (sendingExecutor) SetCertificates(certificateSigning, certificationTransport authentication.Certificate) error {
+	return nil
+}
+
+func TestRunTestCasesRefreshesRejectedToken(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("x-fapi-interaction-id", "93bac548-d2de-4546-b106-880a5018460d")
+		if r.Header.Get("Authorization") != "Bearer a5c1f1fa-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	collector, _ := testCollector(t, AccessToken{AccessToken: "a5c1f1fa", RefreshToken: "r1"})
+	testCase := model.TestCase{
+		ID:        "OB-301-ACC-120382",
+		Input:     model.Input{Method: http.MethodGet, Endpoint: server.URL + "/accounts", Headers: map[string]string{"Authorization": "Bearer $Token001"}},
+		Expect:    model.Expect{StatusCode: http.StatusOK},
+		Validator: schema.NewNullValidator(),
+	}
+	definition := RunDefinition{
+		SpecRun: generation.SpecRun{
+			SpecTestCases: []generation.SpecificationTestCases{{TestCases: []model.TestCase{testCase}}},
+		},
+		Tokens: collector,
+	}
+	controller := NewBufferedDaemonController()
+	runner := NewTestCaseRunner(test.NullLogger(), definition, controller)
+	runner.executor = sendingExecutor{&Executor{}}
+
+	require.NoError(t, runner.RunTestCases(&model.Context{"Token001": "a5c1f1fa"}))
+	<-controller.IsCompleted()
+
+	testResults := controller.AllResults()
+	require.Len(t, testResults, 1)
+	assert.True(t, testResults[0].Pass)
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, events.TokenRefreshed, collector.Tokens()[0].State)
+}
@@ -91,11 +91,23 @@ func (g generator) GenerateManifestTests(log *logrus.Entry, config GeneratorConf
 	tokens := map[string][]manifest.RequiredTokens{}
 
 	for _, item := range discovery.DiscoveryItems {
-		validator, err := schema.NewSwaggerOBSpecValidator(item.APISpecification.Name, item.APISpecification.Version)
+		validator, err := schema.NewSpecValidator(item.APISpecification.SpecLocation())
 		if err != nil {
 			log.WithError(err).Warnf("manifest testcase generation failed for %s", item.APISpecification.SchemaVersion)
 			validator = schema.NewNullValidator()
 		}
+		var extensions []string
+		if item.StrictSchema != nil {
+			extensions = item.StrictSchema.Extensions
+		}
+		codeSets, err := schema.NewCodeSetValidator(item.APISpecification.Version, extensions)
+		if err != nil {
+			log.WithError(err).Warnf("code lists not found for %s, codes left unchecked", item.APISpecification.Version)
+			codeSets = nil
+		}
+		if item.StrictSchema != nil {
+			validator = schema.NewStrictValidator(validator, extensions, codeSets)
+		}
 		log.WithFields(logrus.Fields{"name": item.APISpecification.Name, "version": item.APISpecification.Version}).
 			Info("swagger spec validator created")
 
@@ -109,6 +121,7 @@ func (g generator) GenerateManifestTests(log *logrus.Entry, config GeneratorConf
 			Endpoints:    item.Endpoints,
 			ManifestPath: item.APISpecification.Manifest,
 			Validator:    validator,
+			CodeSets:     codeSets,
 			Conditional:  conditionalProperties,
 		}
 		tcs, fsc, err := manifest.GenerateTestCases(&params)
@@ -0,0 +1,83 @@
+package manifest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/model"
+)
+
+// idempotencyTestCases - test cases sending the request of each test case in `tcs` that creates a
+// resource with an x-idempotency-key again with the same key, to check the ASPSP enforces idempotency
+func idempotencyTestCases(tcs []model.TestCase) []model.TestCase {
+	checks := []model.IdempotencyCheck{model.IdempotencyReplay, model.IdempotencyConflict}
+	if model.IdempotencyKeyExpiry() > 0 {
+		checks = append(checks, model.IdempotencyExpiry)
+	}
+
+	idempotency := []model.TestCase{}
+	for _, tc := range tcs {
+		if !createsWithIdempotencyKey(tc) {
+			continue
+		}
+		for _, check := range checks {
+			idempotency = append(idempotency, idempotencyTestCase(tc, check))
+		}
+	}
+	return idempotency
+}
+
+// createsWithIdempotencyKey - whether `tc` is a POST with an x-idempotency-key expected to succeed.
+// Consents authorised before the run are left out, their requests are not sent by the test run, as
+// are concurrent requests, which are each sent with a key of their own.
+func createsWithIdempotencyKey(tc model.TestCase) bool {
+	if tc.Concurrency != nil {
+		return false
+	}
+	if requestConsent, err := tc.Context.GetString("requestConsent"); err == nil && requestConsent == "true" {
+		return false
+	}
+	return tc.Input.Method == http.MethodPost && tc.Input.IdempotencyKey && !tc.Input.InvalidRequest &&
+		tc.Expect.StatusCode >= 200 && tc.Expect.StatusCode < 300
+}
+
+func idempotencyTestCase(tc model.TestCase, check model.IdempotencyCheck) model.TestCase {
+	idempotency := tc
+	idempotency.ID = fmt.Sprintf("%s-IDEMPOTENCY-%s", tc.ID, strings.ToUpper(string(check)))
+	idempotency.IdempotencyOf = tc.ID
+	idempotency.IdempotencyCheck = check
+	idempotency.Input.Headers = copyStrings(tc.Input.Headers)
+	idempotency.Input.Headers["x-fcs-testcase-id"] = idempotency.ID
+	idempotency.Input.Claims = copyStrings(tc.Input.Claims)
+	idempotency.Input.FormData = copyStrings(tc.Input.FormData)
+	idempotency.Input.QueryParameters = copyStrings(tc.Input.QueryParameters)
+	idempotency.Context = model.Context{}
+	idempotency.Context.PutContext(&tc.Context)
+	idempotency.ExpectOneOf = nil
+	// the resource is the one `tc` created, or none, so nothing is kept for later test cases
+	idempotency.Expect = model.Expect{SchemaValidation: tc.Expect.SchemaValidation}
+
+	switch check {
+	case model.IdempotencyReplay:
+		idempotency.Name = "Idempotency replay: " + tc.Name
+		idempotency.Detail = "Checks the same request sent again with the same x-idempotency-key gets the same response, for the same resource."
+		idempotency.Expect.StatusCode = tc.Expect.StatusCode
+	case model.IdempotencyConflict:
+		idempotency.Name = "Idempotency conflict: " + tc.Name
+		idempotency.Detail = "Checks a request with a different body sent with the same x-idempotency-key is rejected."
+	case model.IdempotencyExpiry:
+		idempotency.Name = "Idempotency key expiry: " + tc.Name
+		idempotency.Detail = fmt.Sprintf("Checks the same request sent again with the same x-idempotency-key %s later is not answered from the idempotency key.", model.IdempotencyKeyExpiry())
+	}
+	idempotency.Purpose = idempotency.Detail
+	return idempotency
+}
+
+func copyStrings(m map[string]string) map[string]string {
+	copied := make(map[string]string, len(m))
+	for k, v := range m {
+		copied[k] = v
+	}
+	return copied
+}
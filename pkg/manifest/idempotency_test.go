@@ -0,0 +1,61 @@
+package manifest
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/model"
+)
+
+func idempotentPost(id string) model.TestCase {
+	tc := model.MakeTestCase()
+	tc.ID = id
+	tc.Name = "Domestic payment consent"
+	tc.Input.Method = http.MethodPost
+	tc.Input.Endpoint = "/domestic-payment-consents"
+	tc.Input.IdempotencyKey = true
+	tc.Input.Headers["Authorization"] = "Bearer $payment_ccg_token"
+	tc.Expect.StatusCode = http.StatusCreated
+	tc.Expect.SchemaValidation = true
+	tc.Expect.ContextPut.Matches = []model.Match{{ContextName: "OB-301-DOP-100100-ConsentId", JSON: "Data.ConsentId"}}
+	return tc
+}
+
+func TestIdempotencyTestCases(t *testing.T) {
+	get := model.MakeTestCase()
+	get.Input.Method = http.MethodGet
+	rejected := idempotentPost("OB-301-DOP-100200")
+	rejected.Expect.StatusCode = http.StatusBadRequest
+	consent := idempotentPost("OB-301-DOP-100300")
+	consent.Context = model.Context{"requestConsent": "true"}
+
+	tcs := idempotencyTestCases([]model.TestCase{idempotentPost("OB-301-DOP-100100"), get, rejected, consent})
+
+	require.Len(t, tcs, 2)
+	replay, conflict := tcs[0], tcs[1]
+	assert.Equal(t, "OB-301-DOP-100100-IDEMPOTENCY-REPLAY", replay.ID)
+	assert.Equal(t, "OB-301-DOP-100100", replay.IdempotencyOf)
+	assert.Equal(t, model.IdempotencyReplay, replay.IdempotencyCheck)
+	assert.Equal(t, model.Expect{StatusCode: http.StatusCreated, SchemaValidation: true}, replay.Expect)
+	assert.Equal(t, "Bearer $payment_ccg_token", replay.Input.Headers["Authorization"])
+	assert.Equal(t, "OB-301-DOP-100100-IDEMPOTENCY-CONFLICT", conflict.ID)
+	assert.Equal(t, model.Expect{SchemaValidation: true}, conflict.Expect)
+
+	conflict.Input.Headers["Authorization"] = "Bearer $Token001"
+	assert.Equal(t, "Bearer $payment_ccg_token", replay.Input.Headers["Authorization"], "test cases have headers of their own")
+}
+
+func TestIdempotencyTestCasesKeyExpiry(t *testing.T) {
+	model.SetIdempotencyKeyExpiry(24 * time.Hour)
+	defer model.SetIdempotencyKeyExpiry(0)
+
+	tcs := idempotencyTestCases([]model.TestCase{idempotentPost("OB-301-DOP-100100")})
+
+	require.Len(t, tcs, 3)
+	assert.Equal(t, model.IdempotencyExpiry, tcs[2].IdempotencyCheck)
+	assert.Contains(t, tcs[2].Detail, "24h0m0s later")
+}
@@ -27,30 +27,32 @@ type Scripts struct {
 
 // Script represents a highlevel test definition
 type Script struct {
-	APIName               string            `json:"apiName"`
-	APIVersion            string            `json:"apiVersion"`
-	Description           string            `json:"description,omitempty"`
-	Detail                string            `json:"detail,omitempty"`
-	ID                    string            `json:"id,omitempty"`
-	RefURI                string            `json:"refURI,omitempty"`
-	Parameters            map[string]string `json:"parameters,omitempty"`
-	QueryParameters       map[string]string `json:"queryParameters"`
-	Headers               map[string]string `json:"headers,omitempty"`
-	RemoveHeaders         []string          `json:"removeHeaders,omitempty"`
-	RemoveSignatureClaims []string          `json:"removeSignatureClaims,omitempty"`
-	Body                  string            `json:"body,omitempty"`
-	Permissions           []string          `json:"permissions,omitemtpy"`
-	PermissionsExcluded   []string          `json:"permissions-excluded,omitemtpy"`
-	Resource              string            `json:"resource,omitempty"`
-	Asserts               []string          `json:"asserts,omitempty"`
-	AssertsOneOf          []string          `json:"asserts_one_of,omitempty"`
-	Method                string            `json:"method,omitempty"`
-	URI                   string            `json:"uri,omitempty"`
-	URIImplemenation      string            `json:"uriImplementation,omitempty"`
-	SchemaCheck           bool              `json:"schemaCheck,omitempty"`
-	ContextPut            map[string]string `json:"keepContextOnSuccess,omitempty"`
-	UseCCGToken           bool              `json:"useCCGToken,omitempty"`
-	ValidateSignature     bool              `json:"validateSignature,omitempty"`
+	APIName               string             `json:"apiName"`
+	APIVersion            string             `json:"apiVersion"`
+	Description           string             `json:"description,omitempty"`
+	Detail                string             `json:"detail,omitempty"`
+	ID                    string             `json:"id,omitempty"`
+	RefURI                string             `json:"refURI,omitempty"`
+	Parameters            map[string]string  `json:"parameters,omitempty"`
+	QueryParameters       map[string]string  `json:"queryParameters"`
+	Headers               map[string]string  `json:"headers,omitempty"`
+	RemoveHeaders         []string           `json:"removeHeaders,omitempty"`
+	RemoveSignatureClaims []string           `json:"removeSignatureClaims,omitempty"`
+	Body                  string             `json:"body,omitempty"`
+	Permissions           []string           `json:"permissions,omitemtpy"`
+	PermissionsExcluded   []string           `json:"permissions-excluded,omitemtpy"`
+	Resource              string             `json:"resource,omitempty"`
+	Asserts               []string           `json:"asserts,omitempty"`
+	AssertsOneOf          []string           `json:"asserts_one_of,omitempty"`
+	Method                string             `json:"method,omitempty"`
+	URI                   string             `json:"uri,omitempty"`
+	URIImplemenation      string             `json:"uriImplementation,omitempty"`
+	SchemaCheck           bool               `json:"schemaCheck,omitempty"`
+	ContextPut            map[string]string  `json:"keepContextOnSuccess,omitempty"`
+	UseCCGToken           bool               `json:"useCCGToken,omitempty"`
+	ValidateSignature     bool               `json:"validateSignature,omitempty"`
+	InvalidRequest        bool               `json:"invalidRequest,omitempty"`
+	Concurrency           *model.Concurrency `json:"concurrency,omitempty"`
 }
 
 // References - reference collection
@@ -104,6 +106,7 @@ type GenerationParameters struct {
 	Endpoints    []discovery.ModelEndpoint
 	ManifestPath string
 	Validator    schema.Validator
+	CodeSets     schema.Validator // OB external code lists validator, nil to leave codes unchecked
 	Conditional  []discovery.ConditionalAPIProperties
 }
 
@@ -167,6 +170,7 @@ func GenerateTestCases(params *GenerationParameters) ([]model.TestCase, Scripts,
 		if err != nil {
 			logger.WithError(err).Error("Error on testCaseBuilder")
 		}
+		tc.CodeSetValidator = params.CodeSets
 
 		localCtx.PutContext(params.Ctx)
 		showReplacementErrors := true
@@ -180,6 +184,10 @@ func GenerateTestCases(params *GenerationParameters) ([]model.TestCase, Scripts,
 		addQueryParametersToRequest(&tc, script.QueryParameters)
 		tests = append(tests, tc)
 	}
+	if specType == "payments" || specType == "vrps" {
+		// last, so expiry waits hold up no other test case of the spec
+		tests = append(tests, idempotencyTestCases(tests)...)
+	}
 
 	return tests, filteredScripts, nil
 }
@@ -340,8 +348,10 @@ func buildTestCase(s Script, refs map[string]Reference, ctx *model.Context, base
 	tc.RefURI = s.RefURI
 	tc.APIName = apiSpec.Name
 	tc.APIVersion = apiSpec.Version
+	tc.SpecType = specType
 	tc.Validator = validator
 	tc.ValidateSignature = s.ValidateSignature
+	tc.Concurrency = s.Concurrency
 
 	//TODO: make these more configurable - header also get set in buildInput Section
 	tc.Input.Headers["x-fapi-financial-id"] = "$x-fapi-financial-id"
@@ -449,6 +459,7 @@ func buildInputSection(s Script, i *model.Input) {
 	}
 
 	i.RequestBody = s.Body
+	i.InvalidRequest = s.InvalidRequest
 }
 
 func LoadGenerationResources(specType, manifestPath string, ctx *model.Context) (Scripts, References, error) {
@@ -11,6 +11,7 @@ import (
 	"github.com/OpenBankingUK/conformance-suite/pkg/schema"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/OpenBankingUK/conformance-suite/pkg/discovery"
 	"github.com/OpenBankingUK/conformance-suite/pkg/model"
@@ -169,8 +170,24 @@ func TestVrpGenerateTestCases(t *testing.T) {
 			Endpoints: endpoints,
 		},
 	}
-	_, _, err = GenerateTestCases(&params)
+	tests, _, err := GenerateTestCases(&params)
+	assert.Nil(t, err)
+
+	var concurrent *model.TestCase
+	for i, tc := range tests {
+		assert.NotEqual(t, "OB-301-VRP-100800", tc.IdempotencyOf, "concurrent requests are not sent again")
+		if tc.ID == "OB-301-VRP-100800" {
+			concurrent = &tests[i]
+		}
+	}
+	require.NotNil(t, concurrent)
+	assert.Equal(t, &model.Concurrency{
+		Requests: 5,
+		Expect:   []model.ConcurrentResponses{{StatusCode: "201", Count: 1}, {StatusCode: "4xx"}},
+	}, concurrent.Concurrency)
+	amount, err := concurrent.Context.GetString("instructedAmountValue")
 	assert.Nil(t, err)
+	assert.Equal(t, "6.00", amount, "more than one payment exceeds the 10.00 periodic limit")
 }
 
 func TestGenerateTestCases(t *testing.T) {
@@ -0,0 +1,131 @@
+// Package metrics exposes Prometheus metrics on the performance of the suite and of the ASPSP
+// under test: the requests made to it, test case results, access token requests, the time PSUs
+// take to give consent and schema validation failures.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "fcs"
+
+var (
+	registry = prometheus.NewRegistry()
+
+	requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "aspsp_requests_total",
+		Help:      "Requests made to the ASPSP by test cases.",
+	}, []string{"api", "endpoint", "method", "status"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "aspsp_request_duration_seconds",
+		Help:      "Time taken by the ASPSP to respond to test case requests.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"api", "endpoint", "method"})
+
+	responseSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "aspsp_response_size_bytes",
+		Help:      "Size of the ASPSP's responses to test case requests.",
+		Buckets:   prometheus.ExponentialBuckets(256, 4, 7),
+	}, []string{"api", "endpoint", "method"})
+
+	tests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tests_total",
+		Help:      "Test cases run, by result.",
+	}, []string{"api", "result"})
+
+	tokenRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "token_request_duration_seconds",
+		Help:      "Time taken by the ASPSP's token endpoint to issue access tokens.",
+		Buckets:   []float64{.1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"grant_type", "result"})
+
+	consentWait = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "consent_wait_seconds",
+		Help:      "Time from a consent URL being issued to its access token being collected.",
+		Buckets:   []float64{5, 15, 30, 60, 120, 300, 600, 1800, 3600},
+	})
+
+	schemaValidationFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "schema_validation_failures_total",
+		Help:      "Responses failing validation against the API's schema.",
+	}, []string{"api", "method"})
+)
+
+func init() {
+	registry.MustRegister(
+		requests,
+		requestDuration,
+		responseSize,
+		tests,
+		tokenRequestDuration,
+		consentWait,
+		schemaValidationFailures,
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler - serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest - counts a request a test case made to `endpoint`, the path as written in the
+// manifest so that resource IDs do not make a series of their own. A `status` of 0 means no
+// response was received.
+func ObserveRequest(api, endpoint, method string, status int, responseTime time.Duration, size int) {
+	statusLabel := strconv.Itoa(status)
+	if status == 0 {
+		statusLabel = "error"
+	}
+	requests.WithLabelValues(api, endpoint, method, statusLabel).Inc()
+	if status == 0 {
+		return
+	}
+	requestDuration.WithLabelValues(api, endpoint, method).Observe(responseTime.Seconds())
+	responseSize.WithLabelValues(api, endpoint, method).Observe(float64(size))
+}
+
+// ObserveTest - counts a test case result
+func ObserveTest(api string, pass bool) {
+	tests.WithLabelValues(api, result(pass)).Inc()
+}
+
+// ObserveTokenRequest - times a request to the token endpoint with `grantType`
+func ObserveTokenRequest(grantType string, duration time.Duration, err error) {
+	tokenRequestDuration.WithLabelValues(grantType, result(err == nil)).Observe(duration.Seconds())
+}
+
+// ObserveConsentWait - times how long a PSU took to give consent
+func ObserveConsentWait(wait time.Duration) {
+	consentWait.Observe(wait.Seconds())
+}
+
+// ObserveSchemaValidation - counts a response that failed schema validation with `failures`
+// failures. Responses without failures are not counted.
+func ObserveSchemaValidation(api, method string, failures int) {
+	if failures == 0 {
+		return
+	}
+	schemaValidationFailures.WithLabelValues(api, method).Inc()
+}
+
+func result(pass bool) string {
+	if pass {
+		return "pass"
+	}
+	return "fail"
+}
@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserveRequest(t *testing.T) {
+	ObserveRequest("Account and Transaction API", "/accounts/$consentedAccountId", http.MethodGet, http.StatusOK, 200*time.Millisecond, 1024)
+	ObserveRequest("Account and Transaction API", "/accounts/$consentedAccountId", http.MethodGet, 0, 0, 0)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(requests.WithLabelValues("Account and Transaction API", "/accounts/$consentedAccountId", http.MethodGet, "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(requests.WithLabelValues("Account and Transaction API", "/accounts/$consentedAccountId", http.MethodGet, "error")))
+	assert.Equal(t, 1, testutil.CollectAndCount(requestDuration))
+}
+
+func TestObserveTest(t *testing.T) {
+	ObserveTest("Payment Initiation API", true)
+	ObserveTest("Payment Initiation API", false)
+	ObserveTest("Payment Initiation API", false)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(tests.WithLabelValues("Payment Initiation API", "pass")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(tests.WithLabelValues("Payment Initiation API", "fail")))
+}
+
+func TestObserveSchemaValidation(t *testing.T) {
+	ObserveSchemaValidation("Confirmation of Funds API", http.MethodPost, 0)
+	ObserveSchemaValidation("Confirmation of Funds API", http.MethodPost, 3)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(schemaValidationFailures.WithLabelValues("Confirmation of Funds API", http.MethodPost)))
+}
+
+func TestHandler(t *testing.T) {
+	ObserveTokenRequest("refresh_token", time.Second, errors.New("invalid_grant"))
+	ObserveConsentWait(time.Minute)
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	body, err := ioutil.ReadAll(recorder.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `fcs_token_request_duration_seconds_count{grant_type="refresh_token",result="fail"} 1`)
+	assert.Contains(t, string(body), "fcs_consent_wait_seconds_count 1")
+	assert.Contains(t, string(body), "go_goroutines")
+}
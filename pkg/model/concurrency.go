@@ -0,0 +1,80 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Concurrency - sends the request of a testcase several times at once, to check how the ASPSP
+// handles requests racing for the same resource, such as payments on a single-use consent
+type Concurrency struct {
+	Requests int                   `json:"requests"` // number of identical requests sent at once
+	Expect   []ConcurrentResponses `json:"expect"`   // responses expected between the requests, a response counts towards the first it matches
+}
+
+// ConcurrentResponses - how many of the concurrent requests are expected to get a status code.
+// Any number of them may when neither Count nor Max is set.
+type ConcurrentResponses struct {
+	StatusCode string `json:"statusCode"`      // a status code, such as "201", or a class, such as "4xx"
+	Count      int    `json:"count,omitempty"` // exactly this many
+	Max        int    `json:"max,omitempty"`   // at most this many
+}
+
+// matches - whether `statusCode` is the status code, or in the class, of `c`
+func (c ConcurrentResponses) matches(statusCode int) bool {
+	expected := strings.ToLower(c.StatusCode)
+	if strings.HasSuffix(expected, "xx") && len(expected) == 3 {
+		return strconv.Itoa(statusCode/100) == expected[:1]
+	}
+	return strconv.Itoa(statusCode) == expected
+}
+
+// expectedResponses - the index in Expect of the responses `statusCode` counts towards, -1 if none
+func (c Concurrency) expectedResponses(statusCode int) int {
+	for i, expected := range c.Expect {
+		if expected.matches(statusCode) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Representative - the index of the response in `statusCodes` validated as the testcase's own
+// response: the first that matches the first expected responses, or the first if none does
+func (c Concurrency) Representative(statusCodes []int) int {
+	for i, statusCode := range statusCodes {
+		if c.expectedResponses(statusCode) == 0 {
+			return i
+		}
+	}
+	return 0
+}
+
+// Check - whether the status codes the concurrent requests got are those expected
+func (c Concurrency) Check(statusCodes []int) []error {
+	counts := make([]int, len(c.Expect))
+	unexpected := []int{}
+	for _, statusCode := range statusCodes {
+		i := c.expectedResponses(statusCode)
+		if i < 0 {
+			unexpected = append(unexpected, statusCode)
+			continue
+		}
+		counts[i]++
+	}
+
+	var errs []error
+	if len(unexpected) > 0 {
+		errs = append(errs, fmt.Errorf("concurrency: %d of %d requests got status codes %v none of the expected responses allow", len(unexpected), len(statusCodes), unexpected))
+	}
+	for i, expected := range c.Expect {
+		if expected.Count > 0 && counts[i] != expected.Count {
+			errs = append(errs, fmt.Errorf("concurrency: expected %d of %d requests to get %s, got %d (status codes %v)", expected.Count, len(statusCodes), expected.StatusCode, counts[i], statusCodes))
+		}
+		if expected.Max > 0 && counts[i] > expected.Max {
+			errs = append(errs, fmt.Errorf("concurrency: expected at most %d of %d requests to get %s, got %d (status codes %v)", expected.Max, len(statusCodes), expected.StatusCode, counts[i], statusCodes))
+		}
+	}
+	return errs
+}
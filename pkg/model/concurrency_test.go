@@ -0,0 +1,31 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var singleUse = Concurrency{
+	Requests: 5,
+	Expect:   []ConcurrentResponses{{StatusCode: "201", Count: 1}, {StatusCode: "4xx"}},
+}
+
+func TestConcurrencyCheck(t *testing.T) {
+	assert.Empty(t, singleUse.Check([]int{400, 201, 409, 400, 403}))
+
+	errs := singleUse.Check([]int{201, 201, 400, 500, 400})
+	require.Len(t, errs, 2)
+	assert.EqualError(t, errs[0], "concurrency: 1 of 5 requests got status codes [500] none of the expected responses allow")
+	assert.EqualError(t, errs[1], "concurrency: expected 1 of 5 requests to get 201, got 2 (status codes [201 201 400 500 400])")
+
+	limited := Concurrency{Requests: 3, Expect: []ConcurrentResponses{{StatusCode: "2XX", Max: 2}, {StatusCode: "4xx"}}}
+	assert.Empty(t, limited.Check([]int{201, 400, 400}))
+	assert.EqualError(t, limited.Check([]int{201, 202, 201})[0], "concurrency: expected at most 2 of 3 requests to get 2XX, got 3 (status codes [201 202 201])")
+}
+
+func TestConcurrencyRepresentative(t *testing.T) {
+	assert.Equal(t, 2, singleUse.Representative([]int{400, 409, 201, 400, 400}))
+	assert.Equal(t, 0, singleUse.Representative([]int{400, 409, 400, 400, 400}), "the first when none got the first expected responses")
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 
 	"github.com/sirupsen/logrus"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/redact"
 )
 
 // Context is intended to handle two types of object and make them available to various parts of the suite including
@@ -121,7 +123,7 @@ func EnableContextDumps() {
 	dumpContexts = true
 }
 
-// DumpContext - send the contents of a context to a logger
+// DumpContext - send the contents of a context to a logger, with sensitive values redacted
 func (c *Context) DumpContext(text ...string) {
 
 	if !dumpContexts {
@@ -136,14 +138,11 @@ func (c *Context) DumpContext(text ...string) {
 		for i := 1; i < len(text); i++ {
 			key := text[i]
 			value, _ := c.Get(key)
-			logrus.StandardLogger().Tracef("[Context] %s : %v", key, value)
+			logrus.StandardLogger().Tracef("[Context] %s : %v", key, redact.Value(key, value))
 		}
 	} else {
 		for k, v := range *c {
-			if k == "client_secret" || k == "basic_authentication" || k == "signingPublic" || k == "signingPrivate" { // skip potentially sensitive fields - likely need to be more robust
-				continue
-			}
-			logrus.StandardLogger().Tracef("[Context] %s:%v", k, v)
+			logrus.StandardLogger().Tracef("[Context] %s:%v", k, redact.Value(k, v))
 		}
 	}
 }
@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// IdempotencyCheck - what an idempotency test case checks when it sends the request of another
+// test case again with the same x-idempotency-key
+type IdempotencyCheck string
+
+// Idempotency checks
+const (
+	// IdempotencyReplay - the same body gets the response the request got, with the same resource
+	IdempotencyReplay IdempotencyCheck = "replay"
+	// IdempotencyConflict - a different body is rejected
+	IdempotencyConflict IdempotencyCheck = "conflict"
+	// IdempotencyExpiry - the same body, once the key expired, does not get the response the request got
+	IdempotencyExpiry IdempotencyCheck = "expiry"
+)
+
+// idempotencyKeyExpiry - how long ASPSPs keep x-idempotency-keys, 0 when expiry is not tested
+var idempotencyKeyExpiry time.Duration
+
+// SetIdempotencyKeyExpiry - sets how long ASPSPs keep x-idempotency-keys. Expiry test cases are
+// generated when it is more than 0, and wait this long after the request they send again.
+func SetIdempotencyKeyExpiry(expiry time.Duration) {
+	idempotencyKeyExpiry = expiry
+}
+
+// IdempotencyKeyExpiry - how long ASPSPs keep x-idempotency-keys, 0 when expiry is not tested
+func IdempotencyKeyExpiry() time.Duration {
+	return idempotencyKeyExpiry
+}
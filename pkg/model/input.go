@@ -40,14 +40,16 @@ type Input struct {
 	Claims          map[string]string `json:"claims,omitempty"`          // collects claims for input strategies that require them
 	JwsSig          bool              `json:"jws,omitempty"`             // controls inclusion of x-jws-signature header
 	IdempotencyKey  bool              `json:"idempotency,omitempty"`     // specifices the inclusion of x-idempotency-key in the request
+	InvalidRequest  bool              `json:"invalidRequest,omitempty"`  // the request breaks the spec on purpose, to check the ASPSP rejects it
 }
 
-var disableJws = false // defaults to JWS disabled in line with waiver 007
-var b64Status bool     // store b64 value for report
+// CtxDisableJWS - context key DisableJWS sets, so each journey's context says whether it sends
+// x-jws-signature
+const CtxDisableJWS = "disable_jws"
 
 // CreateRequest is the main Input work horse which examines the various Input parameters and generates an
-// http.Request object which represents the request
-func (i *Input) CreateRequest(tc *TestCase, ctx *Context) (*resty.Request, error) {
+// http.Request object which represents the request, sent with `client`
+func (i *Input) CreateRequest(client *resty.Client, tc *TestCase, ctx *Context) (*resty.Request, error) {
 	var err error
 
 	if tc == nil {
@@ -62,7 +64,7 @@ func (i *Input) CreateRequest(tc *TestCase, ctx *Context) (*resty.Request, error
 		return nil, i.AppErr(fmt.Sprintf("error empty Endpoint(%s) or Method(%s)", i.Endpoint, i.Method))
 	}
 
-	req := resty.R() // create basic request that will be sent to endpoint
+	req := client.R() // create basic request that will be sent to endpoint
 
 	tc.Input.Endpoint, err = replaceContextField(tc.Input.Endpoint, ctx)
 	if err != nil {
@@ -266,7 +268,7 @@ func (i *Input) createJWSDetachedSignature(ctx authentication.ContextInterface)
 		return i.AppErr("cannot create x-jws-signature, as request body is empty")
 	}
 
-	if disableJws {
+	if jwsDisabled(ctx) {
 		i.AppMsg("x-jws-signature disabled")
 		return nil
 	}
@@ -455,7 +457,7 @@ func (i *Input) generateRequestJWT(ctx *Context, alg jwt.SigningMethod) (string,
 	}).Debug("generateRequestJWT")
 	token.Header["kid"] = kid
 
-	tokenString, err := token.SignedString(cert.PrivateKey()) // sign the token - get as encoded string
+	tokenString, err := token.SignedString(cert.Signer()) // sign the token - get as encoded string
 	if err != nil {
 		return "", i.AppErr(fmt.Sprintf("error siging jwt: %s", err.Error()))
 	}
@@ -629,17 +631,23 @@ func makeMiliSecondStringTimestamp() string {
 	return milliString
 }
 
-// DisableJWS - disable jws-signature for ozone
-func DisableJWS() {
-	disableJws = true
+// DisableJWS - disable jws-signature for ozone, for requests made with `ctx`
+func DisableJWS(ctx *Context) {
+	ctx.Put(CtxDisableJWS, true)
 }
 
-// JWSStatus - return the status of the JWS file for X-JWS-Signature inclusion
-func JWSStatus() string {
-	if disableJws {
+func jwsDisabled(ctx authentication.ContextInterface) bool {
+	disabled, _ := ctx.Get(CtxDisableJWS)
+	return disabled == true
+}
+
+// JWSStatus - return the status of the JWS file for X-JWS-Signature inclusion with `ctx`, whether
+// the payments API version of `ctx` signs with a b64 encoded payload when enabled
+func JWSStatus(ctx *Context) string {
+	if jwsDisabled(ctx) {
 		return "disabled"
 	}
-	if authentication.GetB64Status() {
+	if b64, err := authentication.GetB64Encoding(ctx); err == nil && b64 {
 		return "true"
 	}
 	return "false"
@@ -18,21 +18,22 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/resty.v1"
 )
 
 func TestCreateRequestEmptyEndpointOrMethod(t *testing.T) {
 	i := &Input{}
-	req, err := i.CreateRequest(emptyTestCase, emptyContext)
+	req, err := i.CreateRequest(resty.New(), emptyTestCase, emptyContext)
 	assert.NotNil(t, err)
 	assert.Nil(t, req)
 
 	i = &Input{Endpoint: "http://google.com"}
-	req, err = i.CreateRequest(emptyTestCase, emptyContext)
+	req, err = i.CreateRequest(resty.New(), emptyTestCase, emptyContext)
 	assert.NotNil(t, err)
 	assert.Nil(t, req)
 
 	i = &Input{Method: "GET"}
-	req, err = i.CreateRequest(emptyTestCase, emptyContext)
+	req, err = i.CreateRequest(resty.New(), emptyTestCase, emptyContext)
 	assert.NotNil(t, err)
 	assert.Nil(t, req)
 }
@@ -41,21 +42,21 @@ func TestCreateRequestEmptyEndpointOrMethod(t *testing.T) {
 // 	match := Match{Description: "simple match test", ContextName: "GetValueToFind"}
 // 	accessor := ContextAccessor{Matches: []Match{match}}
 // 	i := &Input{Method: "GET", Endpoint: "http://google.com", ContextGet: accessor}
-// 	req, err := i.CreateRequest(emptyTestCase, emptyContext)
+// 	req, err := i.CreateRequest(resty.New(), emptyTestCase, emptyContext)
 // 	assert.NotNil(t, err)
 // 	assert.Nil(t, req)
 // }
 
 func TestCreateRequestionNilContext(t *testing.T) {
 	i := &Input{Method: "GET", Endpoint: "http://google.com"}
-	req, err := i.CreateRequest(emptyTestCase, nil)
+	req, err := i.CreateRequest(resty.New(), emptyTestCase, nil)
 	assert.NotNil(t, err)
 	assert.Nil(t, req)
 }
 
 func TestCreateRequestionNilTestcase(t *testing.T) {
 	i := &Input{Method: "GET", Endpoint: "http://google.com"}
-	req, err := i.CreateRequest(nil, emptyContext)
+	req, err := i.CreateRequest(resty.New(), nil, emptyContext)
 	assert.NotNil(t, err)
 	assert.Nil(t, req)
 }
@@ -65,7 +66,7 @@ func TestCreateRequestNilHeaderContext(t *testing.T) {
 		"Myheader": "myValue",
 	}
 	i := &Input{Method: "GET", Endpoint: "http://google.com", Headers: headers}
-	req, err := i.CreateRequest(emptyTestCase, emptyContext)
+	req, err := i.CreateRequest(resty.New(), emptyTestCase, emptyContext)
 	assert.Nil(t, err)
 	assert.NotNil(t, req)
 	for k, v := range req.Header {
@@ -83,7 +84,7 @@ func TestCreateRequestHeaderContext(t *testing.T) {
 		"authorisation_endpoint": "https://example.com/authorisation",
 	}
 	i := &Input{Method: "GET", Endpoint: "http://google.com", Headers: headers}
-	req, err := i.CreateRequest(emptyTestCase, &ctx)
+	req, err := i.CreateRequest(resty.New(), emptyTestCase, &ctx)
 	assert.Nil(t, err)
 	assert.NotNil(t, req)
 	for k, v := range req.Header {
@@ -101,7 +102,7 @@ func TestSetBearerAuthTokenFromContext(t *testing.T) {
 		"authorisation_endpoint": "https://example.com/authorisation",
 	}
 	i := &Input{Method: "GET", Endpoint: "http://google.com", Headers: headers}
-	req, err := i.CreateRequest(emptyTestCase, &ctx)
+	req, err := i.CreateRequest(resty.New(), emptyTestCase, &ctx)
 	assert.Nil(t, err)
 	assert.NotNil(t, req)
 	for k, v := range req.Header {
@@ -137,7 +138,7 @@ func TestCreateHeaderContextMissingForReplacement(t *testing.T) {
 // 	match := Match{Description: "test", ContextName: "access_token", Authorisation: "bearer"}
 // 	accessor := ContextAccessor{Matches: []Match{match}}
 // 	i := &Input{Method: "GET", Endpoint: "http://google.com", ContextGet: accessor}
-// 	req, err := i.CreateRequest(emptyTestCase, ctx)
+// 	req, err := i.CreateRequest(resty.New(), emptyTestCase, ctx)
 // 	assert.Nil(t, err)
 // 	assert.NotNil(t, req)
 // }
@@ -148,7 +149,7 @@ func TestFormData(t *testing.T) {
 		"scope":      "accounts openid"}}
 	ctx := Context{"baseurl": "http://mybaseurl", "authorisation_endpoint": "https://example.com/authorisation"}
 	tc := TestCase{Input: i, Context: ctx}
-	req, err := tc.Prepare(emptyContext)
+	req, err := tc.Prepare(resty.New(), emptyContext)
 	assert.Nil(t, err)
 	assert.NotNil(t, req)
 	assert.Equal(t, 2, len(req.FormData))
@@ -160,7 +161,7 @@ func TestQueryParam(t *testing.T) {
 		"toDate":   "2012-01-02T12:23:34Z"}}
 	ctx := Context{"baseurl": "http://mybaseurl", "authorisation_endpoint": "https://example.com/authorisation"}
 	tc := TestCase{Input: i, Context: ctx}
-	req, err := tc.Prepare(emptyContext)
+	req, err := tc.Prepare(resty.New(), emptyContext)
 	assert.Nil(t, err)
 	assert.NotNil(t, req)
 	assert.Equal(t, 2, len(req.QueryParam))
@@ -173,7 +174,7 @@ func TestFormDataMissingContextVariable(t *testing.T) {
 		"scope":      "accounts openid"}}
 	ctx := Context{"baseurl": "http://mybaseurl", "authorisation_endpoint": "https://example.com/authorisation"}
 	tc := TestCase{Input: i, Context: ctx}
-	req, err := tc.Prepare(&ctx1)
+	req, err := tc.Prepare(resty.New(), &ctx1)
 	assert.NotNil(t, err)
 	assert.Nil(t, req)
 }
@@ -182,7 +183,7 @@ func TestInputBody(t *testing.T) {
 	i := Input{Endpoint: "/accounts", Method: "POST", RequestBody: "The Rain in Spain Falls Mainly on the Plain"}
 	ctx := Context{"baseurl": "http://mybaseurl", "authorisation_endpoint": "https://example.com/authorisation"}
 	tc := TestCase{Input: i, Context: ctx}
-	req, err := tc.Prepare(emptyContext)
+	req, err := tc.Prepare(resty.New(), emptyContext)
 	assert.Nil(t, err)
 	assert.NotNil(t, req)
 	assert.Equal(t, "The Rain in Spain Falls Mainly on the Plain", req.Body.(string))
@@ -201,7 +202,7 @@ func TestInputClaims(t *testing.T) {
 		}}
 	ctx := Context{"baseurl": "http://mybaseurl", "authorisation_endpoint": "https://example.com/authorisation"}
 	tc := TestCase{Input: i, Context: ctx}
-	req, err := tc.Prepare(emptyContext)
+	req, err := tc.Prepare(resty.New(), emptyContext)
 	assert.Nil(t, err)
 	assert.NotNil(t, req)
 
@@ -225,7 +226,7 @@ func TestInputClaimsWithContextReplacementParameters(t *testing.T) {
 		}}
 	ctx := Context{"baseurl": "http://mybaseurl", "consent_id": "myconsentid", "authorisation_endpoint": "https://example.com/authorisation"}
 	tc := TestCase{Input: i, Context: ctx}
-	req, err := tc.Prepare(emptyContext)
+	req, err := tc.Prepare(resty.New(), emptyContext)
 	assert.Nil(t, err)
 	assert.NotNil(t, req)
 
@@ -250,7 +251,7 @@ func TestInputClaimsConsentId(t *testing.T) {
 			"responseType": "code",
 		}}
 	tc := TestCase{Input: i, Context: ctx}
-	res, err := i.CreateRequest(&tc, &ctx)
+	res, err := i.CreateRequest(resty.New(), &tc, &ctx)
 	assert.NoError(t, err, "create request should succeed")
 	assert.NotNil(t, res)
 }
@@ -263,7 +264,7 @@ func TestBodyLiteral(t *testing.T) {
 
 	i := Input{Method: "POST", Endpoint: "https://google.com", RequestBody: "This is my literal body"}
 	tc := TestCase{Input: i}
-	req, err := tc.Prepare(&ctx)
+	req, err := tc.Prepare(resty.New(), &ctx)
 	assert.Nil(t, err)
 	assert.Equal(t, "This is my literal body", req.Body)
 }
@@ -276,7 +277,7 @@ func TestBodyReplacement(t *testing.T) {
 
 	i := Input{Method: "POST", Endpoint: "https://google.com", RequestBody: "$replacebody"}
 	tc := TestCase{Input: i}
-	req, err := tc.Prepare(&ctx)
+	req, err := tc.Prepare(resty.New(), &ctx)
 	assert.Nil(t, err)
 	assert.Equal(t, "this is my body", req.Body)
 }
@@ -290,7 +291,7 @@ func TestBodyTwoReplacements(t *testing.T) {
 
 	i := Input{Method: "POST", Endpoint: "https://google.com", RequestBody: "$replacebody $replace2"}
 	tc := TestCase{Input: i}
-	req, err := tc.Prepare(&ctx)
+	req, err := tc.Prepare(resty.New(), &ctx)
 	assert.Nil(t, err)
 	assert.Equal(t, "this is my body and this is my heart", req.Body)
 }
@@ -305,7 +306,7 @@ func TestPaymentBodyReplace(t *testing.T) {
 
 	i := Input{Method: "POST", Endpoint: "https://google.com", RequestBody: "$domestic_payment_template"}
 	tc := TestCase{Input: i}
-	req, err := tc.Prepare(&ctx)
+	req, err := tc.Prepare(resty.New(), &ctx)
 	assert.Nil(t, err)
 	assert.Equal(t, "{\"Data\": {\"ConsentId\": \"sdp-1-b5bbdb18-eeb1-4c11-919d-9a237c8f1c7d\",\"Initiation\":{\"InstructionIdentification\":\"SIDP01\",\"EndToEndIdentification\":\"FRESCO.21302.GFX.20\",\"InstructedAmount\":{\"Amount\":\"15.00\",\"Currency\":\"GBP\"},\"CreditorAccount\":{\"SchemeName\":\"SortCodeAccountNumber\",\"Identification\":\"20000319470104\",\"Name\":\"Messers Simplex & Co\"}} },\"Risk\":{}}", req.Body)
 }
@@ -326,7 +327,7 @@ func TestPaymentBodyReplaceTestCase100300(t *testing.T) {
 	logrus.SetOutput(os.Stdout)
 	logrus.SetLevel(logrus.TraceLevel)
 	ctx.DumpContext("Testcase")
-	req, err := tc.Prepare(&ctx)
+	req, err := tc.Prepare(resty.New(), &ctx)
 
 	assert.Nil(t, err)
 	_ = req
@@ -343,7 +344,7 @@ func TestJWSSignaturNotPOST(t *testing.T) {
 
 	i := Input{JwsSig: true, Method: "GET", Endpoint: "https://google.com", RequestBody: ""}
 	tc := TestCase{Input: i}
-	req, err := tc.Prepare(&ctx)
+	req, err := tc.Prepare(resty.New(), &ctx)
 	assert.EqualError(t, err, "createRequest: cannot apply jws signature to method that isn't POST")
 	assert.Nil(t, req)
 }
@@ -358,7 +359,7 @@ func TestJWSSignatureEmptyBody(t *testing.T) {
 
 	i := Input{JwsSig: true, Method: "POST", Endpoint: "https://google.com", RequestBody: ""}
 	tc := TestCase{Input: i}
-	req, err := tc.Prepare(&ctx)
+	req, err := tc.Prepare(resty.New(), &ctx)
 	assert.EqualError(t, err, "createRequest: cannot create x-jws-signature, as request body is empty")
 	assert.Nil(t, req)
 }
@@ -373,11 +374,36 @@ func TestJWSDetachedSignatureGET(t *testing.T) {
 
 	i := Input{JwsSig: true, Method: "GET", Endpoint: "https://google.com", RequestBody: "$domestic_payment_template"}
 	tc := TestCase{Input: i}
-	req, err := tc.Prepare(&ctx)
+	req, err := tc.Prepare(resty.New(), &ctx)
 	assert.NotNil(t, err)
 	assert.Nil(t, req)
 }
 
+func TestJWSStatusIsPerContext(t *testing.T) {
+	disabled := Context{}
+	disabled.PutStringSlice("apiversions", []string{"payments_v3.1.4"})
+	DisableJWS(&disabled)
+	b64 := Context{}
+	b64.PutStringSlice("apiversions", []string{"payments_v3.1.4"})
+	unencoded := Context{}
+	unencoded.PutStringSlice("apiversions", []string{"payments_v3.1.2"})
+
+	assert.Equal(t, "disabled", JWSStatus(&disabled))
+	assert.Equal(t, "true", JWSStatus(&b64))
+	assert.Equal(t, "false", JWSStatus(&unencoded))
+	assert.Equal(t, "false", JWSStatus(&Context{}))
+}
+
+func TestDisableJWSSkipsSignature(t *testing.T) {
+	ctx := Context{"domestic_payment_template": "{\"Data\": {}}"}
+	DisableJWS(&ctx)
+
+	tc := TestCase{Input: Input{JwsSig: true, Method: "POST", Endpoint: "https://google.com", RequestBody: "$domestic_payment_template"}}
+	req, err := tc.Prepare(resty.New(), &ctx)
+	require.NoError(t, err)
+	assert.Empty(t, req.Header.Get("x-jws-signature"))
+}
+
 func TestCertDNRetrieval(t *testing.T) {
 	cert, err := loadSigningCert(t)
 	if err != nil {
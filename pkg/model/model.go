@@ -10,8 +10,8 @@ import (
 	"strings"
 
 	"github.com/OpenBankingUK/conformance-suite/pkg/authentication"
+	"github.com/OpenBankingUK/conformance-suite/pkg/metrics"
 	"github.com/OpenBankingUK/conformance-suite/pkg/schema"
-	"github.com/OpenBankingUK/conformance-suite/pkg/schemaprops"
 
 	"github.com/sirupsen/logrus"
 
@@ -59,27 +59,34 @@ type Rule struct {
 //     and therefore the testcase has passed
 //
 type TestCase struct {
-	ID                string           `json:"@id,omitempty"`                  // JSONLD ID Reference
-	Type              []string         `json:"@type,omitempty"`                // JSONLD type array
-	Name              string           `json:"name,omitempty"`                 // Name
-	Detail            string           `json:"detail,omitempty"`               // Detailed description of the test case
-	RefURI            string           `json:"refURI,omitempty"`               // Reference URI for the test case
-	Purpose           string           `json:"purpose,omitempty"`              // Purpose of the testcase in simple words
-	Input             Input            `json:"input,omitempty"`                // Input Object
-	Context           Context          `json:"context,omitempty"`              // Local Context Object
-	Expect            Expect           `json:"expect,omitempty"`               // Expected object
-	ExpectOneOf       []Expect         `json:"expect_one_of,omitempty"`        // Slice of possible expected objects
-	ParentRule        *Rule            `json:"-"`                              // Allows accessing parent Rule
-	Request           *resty.Request   `json:"-"`                              // The request that's been generated in order to call the endpoint
-	Header            http.Header      `json:"-"`                              // ResponseHeader
-	Body              string           `json:"-"`                              // ResponseBody
-	Bearer            string           `json:"bearer,omitempty"`               // Bear token if presented
-	DoNotCallEndpoint bool             `json:"do_not_call_endpoint,omitempty"` // If we should not call the endpoint, see `components/PSUConsentProviderComponent.json`
-	APIName           string           `json:"apiName"`
-	APIVersion        string           `json:"apiVersion"`
-	Validator         schema.Validator `json:"-"` // Swagger schema validator
-	ValidateSignature bool             `json:"validateSignature,omitempty"`
-	StatusCode        string           `json:"statusCode,omitempty"`
+	ID                string                          `json:"@id,omitempty"`                  // JSONLD ID Reference
+	Type              []string                        `json:"@type,omitempty"`                // JSONLD type array
+	Name              string                          `json:"name,omitempty"`                 // Name
+	Detail            string                          `json:"detail,omitempty"`               // Detailed description of the test case
+	RefURI            string                          `json:"refURI,omitempty"`               // Reference URI for the test case
+	Purpose           string                          `json:"purpose,omitempty"`              // Purpose of the testcase in simple words
+	Input             Input                           `json:"input,omitempty"`                // Input Object
+	Context           Context                         `json:"context,omitempty"`              // Local Context Object
+	Expect            Expect                          `json:"expect,omitempty"`               // Expected object
+	ExpectOneOf       []Expect                        `json:"expect_one_of,omitempty"`        // Slice of possible expected objects
+	ParentRule        *Rule                           `json:"-"`                              // Allows accessing parent Rule
+	Request           *resty.Request                  `json:"-"`                              // The request that's been generated in order to call the endpoint
+	Header            http.Header                     `json:"-"`                              // ResponseHeader
+	Body              string                          `json:"-"`                              // ResponseBody
+	Bearer            string                          `json:"bearer,omitempty"`               // Bear token if presented
+	DoNotCallEndpoint bool                            `json:"do_not_call_endpoint,omitempty"` // If we should not call the endpoint, see `components/PSUConsentProviderComponent.json`
+	APIName           string                          `json:"apiName"`
+	APIVersion        string                          `json:"apiVersion"`
+	SpecType          string                          `json:"specType,omitempty"` // accounts, payments, cbpii or vrps
+	Validator         schema.Validator                `json:"-"` // Swagger schema validator
+	CodeSetValidator  schema.Validator                `json:"-"` // OB external code lists validator
+	ValidateSignature bool                            `json:"validateSignature,omitempty"`
+	StatusCode        string                          `json:"statusCode,omitempty"`
+	SignatureReport   *authentication.SignatureReport `json:"-"` // x-jws-signature verification of the last response
+	IdempotencyOf     string                          `json:"idempotencyOf,omitempty"`    // ID of the testcase whose request this one sends again with the same x-idempotency-key
+	IdempotencyCheck  IdempotencyCheck                `json:"idempotencyCheck,omitempty"` // what is checked of the request sent again
+	Concurrency       *Concurrency                    `json:"concurrency,omitempty"`      // sends the request several times at once when set
+	ConcurrentStatus  []int                           `json:"-"`                          // status codes the concurrent requests got
 }
 
 // MakeTestCase builds an empty testcase
@@ -98,10 +105,66 @@ func MakeTestCase() TestCase {
 
 // Prepare a Testcase for execution at and endpoint,
 // results in a standard http request that encapsulates the testcase request
-// as defined in the test case object with any context inputs/replacements etc applied
-func (t *TestCase) Prepare(ctx *Context) (*resty.Request, error) {
+// as defined in the test case object with any context inputs/replacements etc applied.
+// The request is sent with `client`.
+func (t *TestCase) Prepare(client *resty.Client, ctx *Context) (*resty.Request, error) {
 	t.ApplyContext(ctx)
-	return t.ApplyInput(ctx)
+	return t.ApplyInput(client, ctx)
+}
+
+// ValidateRequest checks the headers, query parameters and body of the request Prepare built
+// against the operation in the spec. A request the spec does not allow is a fault of the suite
+// rather than the ASPSP, so is reported as an error unless the testcase sends it on purpose,
+// see Input.InvalidRequest.
+func (t *TestCase) ValidateRequest(ctx *Context) []error {
+	if t.Request == nil || t.Validator == nil || t.DoNotCallEndpoint {
+		return nil
+	}
+
+	failures, err := t.Validator.ValidateRequest(schema.HTTPRequest{
+		Method: t.Input.Method,
+		Path:   t.Input.Endpoint,
+		Header: t.Request.Header,
+		Query:  t.Request.QueryParam,
+		Body:   strings.NewReader(t.Input.RequestBody),
+	})
+	if err != nil {
+		return []error{t.AppErr("ValidateRequest: " + err.Error())}
+	}
+	if t.Input.JwsSig && signingDisabled(ctx) {
+		failures = withoutSignature(failures)
+	}
+
+	if t.Input.InvalidRequest {
+		if len(failures) == 0 {
+			logrus.WithField("TestCase", t.ID).Warn("TestCase.Input.InvalidRequest is set but the request conforms to the spec")
+		}
+		return nil
+	}
+	var errs []error
+	for _, failure := range failures {
+		errs = append(errs, fmt.Errorf("suite sent an invalid request: %s", failure.Message))
+	}
+	return errs
+}
+
+// signingDisabled - whether the suite has been configured not to send x-jws-signature
+func signingDisabled(ctx *Context) bool {
+	if jwsDisabled(ctx) {
+		return true
+	}
+	requestObjSigningAlg, _ := ctx.GetString("requestObjectSigningAlg")
+	return requestObjSigningAlg == "none"
+}
+
+func withoutSignature(failures []schema.Failure) []schema.Failure {
+	kept := []schema.Failure{}
+	for _, failure := range failures {
+		if !strings.EqualFold(failure.Parameter, "header x-jws-signature") {
+			kept = append(kept, failure)
+		}
+	}
+	return kept
 }
 
 // Validate takes the http response that results as a consequence of sending the testcase http
@@ -141,8 +204,24 @@ func (t *TestCase) Validate(resp *resty.Response, ctx *Context) (bool, []error)
 			StatusCode: resp.StatusCode(),
 		})
 		if err != nil {
+			metrics.ObserveSchemaValidation(t.APIName, t.Input.Method, 1)
 			return false, []error{t.AppErr("Validate: " + err.Error())}
 		}
+		if t.CodeSetValidator != nil {
+			codeSetFailures, err := t.CodeSetValidator.Validate(schema.HTTPResponse{
+				Method:     t.Input.Method,
+				Path:       t.Input.Endpoint,
+				Header:     resp.Header(),
+				Body:       strings.NewReader(t.Body),
+				StatusCode: resp.StatusCode(),
+			})
+			if err != nil {
+				metrics.ObserveSchemaValidation(t.APIName, t.Input.Method, 1)
+				return false, []error{t.AppErr("Validate: " + err.Error())}
+			}
+			failures = append(failures, codeSetFailures...)
+		}
+		metrics.ObserveSchemaValidation(t.APIName, t.Input.Method, len(failures))
 		for _, failure := range failures {
 			errs = append(errs, errors.New(failure.Message))
 		}
@@ -151,11 +230,12 @@ func (t *TestCase) Validate(resp *resty.Response, ctx *Context) (bool, []error)
 	}
 
 	// Apply Signature Validator
-	if t.ValidateSignature && !disableJws {
+	if t.ValidateSignature && !jwsDisabled(ctx) {
 		xJwsSignature := resp.Header().Get("x-jws-signature")
 		logrus.Warn("Validating Signature: " + xJwsSignature)
 		logrus.Warn("body: ", t.Body)
-		valid, err := validateSignature(xJwsSignature, t.Body, ctx)
+		valid, report, err := validateSignature(xJwsSignature, t.Body, ctx)
+		t.SignatureReport = report
 		if err != nil {
 			return false, []error{t.AppErr("Signature validation failed: " + err.Error())}
 		}
@@ -166,39 +246,38 @@ func (t *TestCase) Validate(resp *resty.Response, ctx *Context) (bool, []error)
 		}
 	}
 
-	// Gather fields within json response - for reporting
-	collector := schemaprops.GetPropertyCollector()
-	collector.CollectProperties(t.Input.Method, t.Input.Endpoint, t.Body, resp.StatusCode())
+	if t.Concurrency != nil {
+		errs = append(errs, t.Concurrency.Check(t.ConcurrentStatus)...)
+	}
 
 	return pass, errs
 }
 
-func validateSignature(signature, body string, ctx *Context) (bool, error) {
-	var pass bool
-	if signature != "" {
-		jwksURI, err := ctx.GetString("jwks_uri")
-		if err != nil {
-			return false, errors.New("ValidateSignature - JWKS_URI not present ")
-		}
+func validateSignature(signature, body string, ctx *Context) (bool, *authentication.SignatureReport, error) {
+	if signature == "" {
+		return false, nil, errors.New("x-jws-signature header not found for Validation")
+	}
 
-		b64encoding, err := authentication.GetB64Encoding(ctx)
-		if err != nil {
-			return false, errors.New("ValidationSignature cannot get B64Encoding: " + err.Error())
-		}
+	jwksURI, err := ctx.GetString("jwks_uri")
+	if err != nil {
+		return false, nil, errors.New("ValidateSignature - JWKS_URI not present ")
+	}
 
-		pass, err = authentication.ValidateSignature(signature, body, jwksURI, b64encoding)
-		if err != nil {
-			return false, errors.New("Invalid x-jws-signature found - unable to validate: " + err.Error())
-		}
-		if !pass {
-			return false, errors.New("Invalid x-jws-signature - fails validation")
-		}
-		logrus.Infoln("x-jws-signature validation succeded")
-	} else {
-		return false, errors.New("x-jws-signature header not found for Validation")
+	b64encoding, err := authentication.GetB64Encoding(ctx)
+	if err != nil {
+		return false, nil, errors.New("ValidationSignature cannot get B64Encoding: " + err.Error())
+	}
+
+	report, err := authentication.VerifySignature(signature, body, jwksURI, b64encoding)
+	if err != nil {
+		return false, &report, errors.New("Invalid x-jws-signature found - unable to validate: " + err.Error())
+	}
+	if !report.Valid {
+		return false, &report, errors.New("Invalid x-jws-signature - fails validation")
 	}
+	logrus.Infoln("x-jws-signature validation succeded")
 	logrus.Tracef("Signature validation succeeded")
-	return pass, nil
+	return true, &report, nil
 }
 
 func logSchemaValidationOffWarning(testCase *TestCase) {
@@ -279,11 +358,11 @@ type Expect struct {
 //     Rule - receives http response from endpoint and provides it back to testcase
 //     Testcase evaluates the http response object using its 'Expects' clause
 //     Testcase passes or fails depending on the 'Expects' outcome
-func (t *TestCase) ApplyInput(rulectx *Context) (*resty.Request, error) {
+func (t *TestCase) ApplyInput(client *resty.Client, rulectx *Context) (*resty.Request, error) {
 	if t.Input.Method == "" {
 		return nil, t.AppErr("error: TestCase input cannot have empty input.Method")
 	}
-	req, err := t.Input.CreateRequest(t, rulectx)
+	req, err := t.Input.CreateRequest(client, t, rulectx)
 	if err != nil {
 		return nil, t.AppErr("createRequest: " + err.Error())
 	}
@@ -55,7 +55,7 @@ func TestSimpleb64falseSignature(t *testing.T) {
 	_ = pubKey
 	i := Input{JwsSig: true, Method: "POST", Endpoint: "https://google.com", RequestBody: "$domestic_payment_template"}
 	tc := TestCase{Input: i}
-	req, err := tc.Prepare(&ctx)
+	req, err := tc.Prepare(resty.New(), &ctx)
 	assert.Nil(t, err)
 	sig := req.Header.Get("x-jws-signature")
 	assert.NotEmpty(t, sig)
@@ -75,7 +75,7 @@ func TestSimpleb64trueSignature(t *testing.T) {
 	_ = pubKey
 	i := Input{JwsSig: true, Method: "POST", Endpoint: "https://google.com", RequestBody: "$domestic_payment_template"}
 	tc := TestCase{Input: i}
-	req, err := tc.Prepare(&ctx)
+	req, err := tc.Prepare(resty.New(), &ctx)
 	assert.Nil(t, err)
 	sig := req.Header.Get("x-jws-signature")
 	assert.NotEmpty(t, sig)
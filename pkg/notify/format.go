@@ -0,0 +1,157 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/redact"
+)
+
+const (
+	colourPassed = "2EB886"
+	colourFailed = "D50200"
+	// maxListed - failures listed in chat messages, the rest are counted
+	maxListed = 10
+)
+
+// payload - the body posted to a hook in `format`. Failure reasons can quote responses, so the
+// body is redacted.
+func payload(format Format, summary Summary) ([]byte, error) {
+	var message interface{} = summary
+	switch format {
+	case FormatSlack:
+		message = slackMessage(summary)
+	case FormatTeams:
+		message = teamsMessage(summary)
+	}
+	body, err := json.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(redact.String(string(body))), nil
+}
+
+type slackPayload struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Fields []slackField `json:"fields"`
+	Text   string       `json:"text,omitempty"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+func slackMessage(summary Summary) slackPayload {
+	attachment := slackAttachment{
+		Color: "#" + colour(summary),
+		Text:  failureList(summary, "• "),
+	}
+	for _, api := range summary.APIs {
+		attachment.Fields = append(attachment.Fields, slackField{
+			Title: api.Name + " " + api.Version,
+			Value: counts(api.Passed, api.Failed),
+			Short: true,
+		})
+	}
+	return slackPayload{
+		Text:        "*" + headline(summary) + "*",
+		Attachments: []slackAttachment{attachment},
+	}
+}
+
+type teamsPayload struct {
+	Type       string         `json:"@type"`
+	Context    string         `json:"@context"`
+	Summary    string         `json:"summary"`
+	ThemeColor string         `json:"themeColor"`
+	Title      string         `json:"title"`
+	Sections   []teamsSection `json:"sections"`
+}
+
+type teamsSection struct {
+	Facts []teamsFact `json:"facts,omitempty"`
+	Text  string      `json:"text,omitempty"`
+}
+
+type teamsFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func teamsMessage(summary Summary) teamsPayload {
+	facts := teamsSection{}
+	for _, api := range summary.APIs {
+		facts.Facts = append(facts.Facts, teamsFact{
+			Name:  api.Name + " " + api.Version,
+			Value: counts(api.Passed, api.Failed),
+		})
+	}
+	sections := []teamsSection{facts}
+	if failures := failureList(summary, "- "); failures != "" {
+		sections = append(sections, teamsSection{Text: failures})
+	}
+	return teamsPayload{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		Summary:    headline(summary),
+		ThemeColor: colour(summary),
+		Title:      headline(summary),
+		Sections:   sections,
+	}
+}
+
+// headline - e.g. `ob-v3.1-ozone run completed: 118 passed, 2 failed, 1 regression`
+func headline(summary Summary) string {
+	text := fmt.Sprintf("%s run %s: %s", summary.Name, summary.Status, counts(summary.Passed, summary.Failed))
+	switch len(summary.Regressions) {
+	case 0:
+	case 1:
+		text += ", 1 regression"
+	default:
+		text += fmt.Sprintf(", %d regressions", len(summary.Regressions))
+	}
+	return strings.TrimSpace(text)
+}
+
+func counts(passed, failed int) string {
+	return fmt.Sprintf("%d passed, %d failed", passed, failed)
+}
+
+func colour(summary Summary) string {
+	if summary.Failed > 0 {
+		return colourFailed
+	}
+	return colourPassed
+}
+
+// failureList - a line starting with `bullet` for each failed test case, up to maxListed
+func failureList(summary Summary, bullet string) string {
+	isRegression := map[string]bool{}
+	for _, id := range summary.Regressions {
+		isRegression[id] = true
+	}
+
+	lines := []string{}
+	for _, api := range summary.APIs {
+		for _, failure := range api.Failures {
+			line := bullet + failure.ID + " " + failure.Endpoint
+			if isRegression[failure.ID] {
+				line += " (regression)"
+			}
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > maxListed {
+		more := len(lines) - maxListed
+		lines = append(lines[:maxListed], fmt.Sprintf("and %d more", more))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,206 @@
+// Package notify posts a summary of each completed test run to webhooks, either as signed JSON or
+// formatted for Slack or Microsoft Teams incoming webhooks.
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Format - the payload posted to a hook
+type Format string
+
+// Formats
+const (
+	FormatJSON  Format = "json"  // the Summary
+	FormatSlack Format = "slack" // a Slack incoming webhook message
+	FormatTeams Format = "teams" // a Microsoft Teams incoming webhook MessageCard
+)
+
+// Condition - when a hook is notified
+type Condition string
+
+// Conditions
+const (
+	Always       Condition = "always"
+	OnFailure    Condition = "failure"    // a test case failed
+	OnRegression Condition = "regression" // a test case that passed in the previous run failed
+)
+
+// SignatureHeader - HMAC-SHA256 of the body with the hook's secret, as `sha256=<hex>`
+const SignatureHeader = "X-FCS-Signature-256"
+
+const defaultRetries = 3
+
+// Hook - a webhook notified when a run completes
+type Hook struct {
+	URL     string      `json:"url"`
+	Format  Format      `json:"format,omitempty"`  // json when empty
+	Secret  string      `json:"secret,omitempty"`  // signs the body when set
+	On      []Condition `json:"on,omitempty"`      // notified when any of these holds, always when empty
+	Retries int         `json:"retries,omitempty"` // retries after a failed post, 3 when zero
+}
+
+// Validate - used by https://github.com/go-ozzo/ozzo-validation to validate struct.
+func (h Hook) Validate() error {
+	parsed, err := url.Parse(h.URL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return fmt.Errorf("notification url %q must be an http or https URL", h.URL)
+	}
+	switch h.Format {
+	case "", FormatJSON, FormatSlack, FormatTeams:
+	default:
+		return fmt.Errorf("notification format %q must be one of json, slack or teams", h.Format)
+	}
+	for _, condition := range h.On {
+		switch condition {
+		case Always, OnFailure, OnRegression:
+		default:
+			return fmt.Errorf("notification condition %q must be one of always, failure or regression", condition)
+		}
+	}
+	if h.Retries < 0 {
+		return fmt.Errorf("notification retries must not be negative")
+	}
+	return nil
+}
+
+// wants - true if the hook is notified of `summary`
+func (h Hook) wants(summary Summary) bool {
+	if len(h.On) == 0 {
+		return true
+	}
+	for _, condition := range h.On {
+		switch condition {
+		case Always:
+			return true
+		case OnFailure:
+			if summary.Failed > 0 {
+				return true
+			}
+		case OnRegression:
+			if len(summary.Regressions) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (h Hook) maxRetries() int {
+	if h.Retries == 0 {
+		return defaultRetries
+	}
+	return h.Retries
+}
+
+// Notifier - posts run summaries to hooks
+type Notifier interface {
+	// Notify - posts `summary` to each of `hooks` that wants it, retrying failed posts. Returns
+	// once every hook has been notified or has run out of retries.
+	Notify(hooks []Hook, summary Summary)
+}
+
+type notifier struct {
+	client  *http.Client
+	backoff time.Duration // before the first retry, doubled for each retry after
+	logger  *logrus.Entry
+}
+
+// NewNotifier - a Notifier posting with `client`
+func NewNotifier(client *http.Client, logger *logrus.Entry) Notifier {
+	return notifier{
+		client:  client,
+		backoff: 2 * time.Second,
+		logger:  logger.WithField("module", "notify"),
+	}
+}
+
+func (n notifier) Notify(hooks []Hook, summary Summary) {
+	for _, hook := range hooks {
+		logger := n.logger.WithFields(logrus.Fields{
+			"run_id": summary.RunID,
+			"format": hook.Format,
+			"host":   hostOf(hook.URL),
+		})
+		if !hook.wants(summary) {
+			logger.Debug("run does not meet the notification conditions")
+			continue
+		}
+		if err := n.post(hook, summary); err != nil {
+			logger.WithError(err).Error("notifying webhook of run")
+			continue
+		}
+		logger.Info("notified webhook of run")
+	}
+}
+
+func (n notifier) post(hook Hook, summary Summary) error {
+	body, err := payload(hook.Format, summary)
+	if err != nil {
+		return err
+	}
+
+	backoff := n.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := n.send(hook, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= hook.maxRetries() {
+			return err
+		}
+		n.logger.WithError(err).WithField("attempt", attempt+1).Warn("posting to webhook, retrying")
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// send - posts `body` once, returning whether a failed post can be retried
+func (n notifier) send(hook Hook, body []byte) (bool, error) {
+	request, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if hook.Secret != "" {
+		request.Header.Set(SignatureHeader, Sign(hook.Secret, body))
+	}
+
+	response, err := n.client.Do(request)
+	if err != nil {
+		return true, errors.Wrap(err, "posting to webhook")
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return false, nil
+	}
+	retry := response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("webhook responded %s", response.Status)
+}
+
+// Sign - the value of SignatureHeader for `body` posted to a hook with `secret`
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// hostOf - the host of `hookURL`, logged instead of the URL as incoming webhook URLs hold a token
+func hostOf(hookURL string) string {
+	parsed, err := url.Parse(hookURL)
+	if err != nil {
+		return ""
+	}
+	return parsed.Host
+}
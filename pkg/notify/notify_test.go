@@ -0,0 +1,156 @@
+package notify
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors/results"
+	"github.com/OpenBankingUK/conformance-suite/pkg/test"
+)
+
+func testSummary() Summary {
+	summary := Summary{RunID: "run-1", Name: "ob-v3.1-ozone", Status: "completed"}
+	summary.AddResults(map[results.ResultKey][]results.TestCase{
+		{APIName: "Payment Initiation API", APIVersion: "v3.1"}: {
+			{Id: "OB-301-DOP-100300", Pass: true},
+		},
+		{APIName: "Account and Transaction API", APIVersion: "v3.1"}: {
+			{Id: "OB-301-ACC-120382", Pass: true},
+			{Id: "OB-301-ACC-811741", Endpoint: "/accounts", Fail: []string{"status code 401, Bearer a5c1f1fa rejected"}},
+		},
+	}, map[string]bool{"OB-301-ACC-811741": true})
+	return summary
+}
+
+// hookServer - a webhook answering with `statuses` in turn, recording the requests it receives
+func hookServer(statuses ...int) (*httptest.Server, *[]*http.Request, *[][]byte) {
+	requests := &[]*http.Request{}
+	bodies := &[][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		*bodies = append(*bodies, body)
+		*requests = append(*requests, r)
+		w.WriteHeader(statuses[len(*requests)-1])
+	}))
+	return server, requests, bodies
+}
+
+func testNotifier() notifier {
+	n := NewNotifier(&http.Client{Timeout: time.Second}, test.NullLogger()).(notifier)
+	n.backoff = time.Millisecond
+	return n
+}
+
+func TestSummaryAddResults(t *testing.T) {
+	summary := testSummary()
+
+	assert.Equal(t, 3, summary.Tests)
+	assert.Equal(t, 2, summary.Passed)
+	assert.Equal(t, 1, summary.Failed)
+	assert.Equal(t, []string{"OB-301-ACC-811741"}, summary.Regressions)
+	require.Len(t, summary.APIs, 2)
+	assert.Equal(t, "Account and Transaction API", summary.APIs[0].Name)
+	assert.Equal(t, []Failure{{ID: "OB-301-ACC-811741", Endpoint: "/accounts", Reasons: []string{"status code 401, Bearer a5c1f1fa rejected"}}}, summary.APIs[0].Failures)
+}
+
+func TestNotifyPostsSignedSummary(t *testing.T) {
+	server, requests, bodies := hookServer(http.StatusOK)
+	defer server.Close()
+
+	testNotifier().Notify([]Hook{{URL: server.URL, Secret: "s3cret"}}, testSummary())
+
+	require.Len(t, *requests, 1)
+	body := (*bodies)[0]
+	assert.Equal(t, Sign("s3cret", body), (*requests)[0].Header.Get(SignatureHeader))
+	assert.Equal(t, "application/json", (*requests)[0].Header.Get("Content-Type"))
+	posted := Summary{}
+	require.NoError(t, json.Unmarshal(body, &posted))
+	assert.Equal(t, "run-1", posted.RunID)
+	assert.Equal(t, []string{"status code 401, Bearer [redacted] rejected"}, posted.APIs[0].Failures[0].Reasons)
+}
+
+func TestNotifyRetries(t *testing.T) {
+	server, requests, _ := hookServer(http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusNoContent)
+	defer server.Close()
+
+	testNotifier().Notify([]Hook{{URL: server.URL}}, testSummary())
+
+	assert.Len(t, *requests, 3)
+}
+
+func TestNotifyGivesUp(t *testing.T) {
+	server, requests, _ := hookServer(http.StatusBadGateway, http.StatusBadGateway, http.StatusOK)
+	defer server.Close()
+	testNotifier().Notify([]Hook{{URL: server.URL, Retries: 1}}, testSummary())
+	assert.Len(t, *requests, 2)
+
+	rejecting, rejected, _ := hookServer(http.StatusBadRequest, http.StatusOK)
+	defer rejecting.Close()
+	testNotifier().Notify([]Hook{{URL: rejecting.URL}}, testSummary())
+	assert.Len(t, *rejected, 1, "a 400 is not retried")
+}
+
+func TestNotifyConditions(t *testing.T) {
+	passed := Summary{RunID: "run-2", Tests: 1, Passed: 1}
+	failed := Summary{RunID: "run-3", Tests: 1, Failed: 1}
+	regressed := testSummary()
+
+	cases := map[string]struct {
+		on   []Condition
+		want []bool // passed, failed, regressed
+	}{
+		"default":               {on: nil, want: []bool{true, true, true}},
+		"always":                {on: []Condition{Always}, want: []bool{true, true, true}},
+		"failure":               {on: []Condition{OnFailure}, want: []bool{false, true, true}},
+		"regression":            {on: []Condition{OnRegression}, want: []bool{false, false, true}},
+		"failure or regression": {on: []Condition{OnFailure, OnRegression}, want: []bool{false, true, true}},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			hook := Hook{URL: "https://hooks.example.com", On: c.on}
+			assert.Equal(t, c.want, []bool{hook.wants(passed), hook.wants(failed), hook.wants(regressed)})
+		})
+	}
+}
+
+func TestPayloadFormats(t *testing.T) {
+	slack, err := payload(FormatSlack, testSummary())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"text": "*ob-v3.1-ozone run completed: 2 passed, 1 failed, 1 regression*",
+		"attachments": [{
+			"color": "#D50200",
+			"text": "• OB-301-ACC-811741 /accounts (regression)",
+			"fields": [
+				{"title": "Account and Transaction API v3.1", "value": "1 passed, 1 failed", "short": true},
+				{"title": "Payment Initiation API v3.1", "value": "1 passed, 0 failed", "short": true}
+			]
+		}]
+	}`, string(slack))
+
+	teams, err := payload(FormatTeams, Summary{Name: "ob-v3.1-ozone", Status: "completed", Tests: 1, Passed: 1})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"@type": "MessageCard",
+		"@context": "https://schema.org/extensions",
+		"summary": "ob-v3.1-ozone run completed: 1 passed, 0 failed",
+		"themeColor": "2EB886",
+		"title": "ob-v3.1-ozone run completed: 1 passed, 0 failed",
+		"sections": [{}]
+	}`, string(teams))
+}
+
+func TestHookValidate(t *testing.T) {
+	assert.NoError(t, Hook{URL: "https://hooks.slack.com/services/T0/B0/x", Format: FormatSlack, On: []Condition{OnFailure}}.Validate())
+	assert.Error(t, Hook{URL: "hooks.slack.com/services/T0/B0/x"}.Validate())
+	assert.Error(t, Hook{URL: "https://hooks.example.com", Format: "email"}.Validate())
+	assert.Error(t, Hook{URL: "https://hooks.example.com", On: []Condition{"success"}}.Validate())
+	assert.Error(t, Hook{URL: "https://hooks.example.com", Retries: -1}.Validate())
+}
@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"sort"
+	"time"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors/results"
+)
+
+// Summary - the outcome of a run, posted as it is to json hooks
+type Summary struct {
+	RunID    string    `json:"run_id"`
+	Name     string    `json:"name"`   // of the discovery model
+	Status   string    `json:"status"` // completed, or stopped when some test cases did not run
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Tests    int       `json:"tests"`
+	Passed   int       `json:"passed"`
+	Failed   int       `json:"failed"`
+	APIs     []API     `json:"apis"`
+	// Regressions - test cases that failed and passed in the previous run with the same discovery model
+	Regressions []string `json:"regressions,omitempty"`
+}
+
+// API - the results of one API specification
+type API struct {
+	Name     string    `json:"name"`
+	Version  string    `json:"version"`
+	Tests    int       `json:"tests"`
+	Passed   int       `json:"passed"`
+	Failed   int       `json:"failed"`
+	Failures []Failure `json:"failures,omitempty"`
+}
+
+// Failure - a test case that failed
+type Failure struct {
+	ID       string   `json:"id"`
+	Endpoint string   `json:"endpoint"`
+	Reasons  []string `json:"reasons,omitempty"`
+}
+
+// AddResults - counts `grouped`, the results of the run by API, into the summary. `previous`
+// holds whether each test case passed in the previous run, nil when there was none.
+func (s *Summary) AddResults(grouped map[results.ResultKey][]results.TestCase, previous map[string]bool) {
+	for key, testCases := range grouped {
+		api := API{Name: key.APIName, Version: key.APIVersion}
+		for _, testCase := range testCases {
+			api.Tests++
+			if testCase.Pass {
+				api.Passed++
+				continue
+			}
+			api.Failed++
+			api.Failures = append(api.Failures, Failure{ID: testCase.Id, Endpoint: testCase.Endpoint, Reasons: testCase.Fail})
+			if previous[testCase.Id] {
+				s.Regressions = append(s.Regressions, testCase.Id)
+			}
+		}
+		s.Tests += api.Tests
+		s.Passed += api.Passed
+		s.Failed += api.Failed
+		s.APIs = append(s.APIs, api)
+	}
+
+	sort.Slice(s.APIs, func(i, j int) bool {
+		if s.APIs[i].Name != s.APIs[j].Name {
+			return s.APIs[i].Name < s.APIs[j].Name
+		}
+		return s.APIs[i].Version < s.APIs[j].Version
+	})
+	sort.Strings(s.Regressions)
+}
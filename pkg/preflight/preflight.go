@@ -0,0 +1,350 @@
+// Package preflight checks the signing and transport certificates in a configuration before a test run,
+// so that a misconfigured certificate is reported up front rather than as a TLS or signature failure
+// part way through a run.
+package preflight
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/authentication"
+	"github.com/OpenBankingUK/conformance-suite/pkg/authentication/certificates"
+)
+
+// expiryWarning - certificates expiring sooner than this are reported as a warning
+const expiryWarning = 30 * 24 * time.Hour
+
+const handshakeTimeout = 10 * time.Second
+
+// Status of a check
+type Status string
+
+// Check statuses
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+	StatusSkip Status = "skip"
+)
+
+// Check - the outcome of one pre-flight check
+type Check struct {
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// CertificateInfo - what was found in a certificate
+type CertificateInfo struct {
+	Subject     string    `json:"subject,omitempty"`
+	Issuer      string    `json:"issuer,omitempty"`
+	OrgID       string    `json:"orgId,omitempty"`
+	SoftwareID  string    `json:"softwareId,omitempty"`
+	NotBefore   time.Time `json:"notBefore,omitempty"`
+	NotAfter    time.Time `json:"notAfter,omitempty"`
+	KeyUsage    []string  `json:"keyUsage,omitempty"`
+	ExtKeyUsage []string  `json:"extKeyUsage,omitempty"`
+	Kid         string    `json:"kid,omitempty"`
+}
+
+// Report - result of the pre-flight checks, Pass is false when any check failed
+type Report struct {
+	Pass      bool            `json:"pass"`
+	Signing   CertificateInfo `json:"signing"`
+	Transport CertificateInfo `json:"transport"`
+	Checks    []Check         `json:"checks"`
+}
+
+// Config - the parts of the global configuration the checks use
+type Config struct {
+	SigningPublic    string
+	SigningPrivate   string
+	TransportPublic  string
+	TransportPrivate string
+	// SigningKID overrides the kid calculated from the signing certificate
+	SigningKID string
+	// JWKSURI is the key set the signing key should be published in. It can use the same
+	// placeholders as trust anchor `jwks_urls`, e.g. https://keystore.openbankingtest.org.uk/{org_id}/{software_id}.jwks
+	JWKSURI       string
+	TrustAnchor   string
+	Issuer        string
+	TokenEndpoint string
+}
+
+// Run - runs every check that the configuration allows
+func Run(config Config) Report {
+	r := &Report{}
+	now := time.Now()
+
+	signing, signingCert := r.certificate("signing", config.SigningPublic, config.SigningPrivate, now)
+	r.Signing = signing
+	if signingCert != nil {
+		r.result("signing key usage", checkSigningUsage(signingCert))
+	}
+
+	transport, transportCert := r.certificate("transport", config.TransportPublic, config.TransportPrivate, now)
+	r.Transport = transport
+	if transportCert != nil {
+		r.result("transport key usage", checkTransportUsage(transportCert))
+	}
+
+	r.checkKid(config)
+	r.checkHandshake(config)
+
+	r.Pass = true
+	for _, check := range r.Checks {
+		if check.Status == StatusFail {
+			r.Pass = false
+		}
+	}
+	return *r
+}
+
+func (r *Report) add(name string, status Status, detail string) {
+	r.Checks = append(r.Checks, Check{Name: name, Status: status, Detail: detail})
+}
+
+func (r *Report) result(name string, err error) {
+	if err != nil {
+		r.add(name, StatusFail, err.Error())
+		return
+	}
+	r.add(name, StatusPass, "")
+}
+
+// certificate - key pair, DN and expiry checks for one certificate
+func (r *Report) certificate(use, publicPem, privatePem string, now time.Time) (CertificateInfo, *x509.Certificate) {
+	info := CertificateInfo{}
+	cert, err := authentication.NewCertificate(publicPem, privatePem)
+	if err != nil {
+		r.add(use+" key pair", StatusFail, err.Error())
+		return info, nil
+	}
+	r.add(use+" key pair", StatusPass, "")
+
+	crt, err := cert.X509()
+	if err != nil {
+		r.add(use+" certificate", StatusFail, "cannot parse certificate: "+err.Error())
+		return info, nil
+	}
+	info.Subject = crt.Subject.String()
+	info.Issuer = crt.Issuer.String()
+	info.NotBefore = crt.NotBefore
+	info.NotAfter = crt.NotAfter
+	info.KeyUsage = keyUsageNames(crt.KeyUsage)
+	info.ExtKeyUsage = extKeyUsageNames(crt.ExtKeyUsage)
+	info.Kid, _ = authentication.CalcKid(base64.RawURLEncoding.EncodeToString(cert.PublicKey().N.Bytes()))
+
+	_, orgID, softwareID, err := cert.DN()
+	info.OrgID, info.SoftwareID = orgID, softwareID
+	switch {
+	case err != nil:
+		r.add(use+" subject DN", StatusFail, err.Error())
+	case orgID == "" || softwareID == "":
+		r.add(use+" subject DN", StatusWarn, "OrgId (OU) or SoftwareId (CN) is empty, an issuer must be configured for non OB directory certificates")
+	default:
+		r.add(use+" subject DN", StatusPass, fmt.Sprintf("OrgId %s, SoftwareId %s", orgID, softwareID))
+	}
+
+	switch {
+	case now.Before(crt.NotBefore):
+		r.add(use+" expiry", StatusFail, fmt.Sprintf("not valid until %s", crt.NotBefore.Format(time.RFC3339)))
+	case now.After(crt.NotAfter):
+		r.add(use+" expiry", StatusFail, fmt.Sprintf("expired on %s", crt.NotAfter.Format(time.RFC3339)))
+	case crt.NotAfter.Sub(now) < expiryWarning:
+		r.add(use+" expiry", StatusWarn, fmt.Sprintf("expires on %s", crt.NotAfter.Format(time.RFC3339)))
+	default:
+		r.add(use+" expiry", StatusPass, fmt.Sprintf("expires on %s", crt.NotAfter.Format(time.RFC3339)))
+	}
+
+	return info, crt
+}
+
+// checkKid - the signing key is published in the configured JWKS under the kid the suite will sign with
+func (r *Report) checkKid(config Config) {
+	if config.JWKSURI == "" {
+		r.add("signing kid", StatusSkip, "no JWKS configured")
+		return
+	}
+	if r.Signing.Kid == "" && config.SigningKID == "" {
+		r.add("signing kid", StatusSkip, "signing certificate is not usable")
+		return
+	}
+
+	kid := config.SigningKID
+	if kid == "" {
+		kid = r.Signing.Kid
+	}
+	trustAnchor := config.TrustAnchor
+	if trustAnchor == "" {
+		trustAnchor = authentication.TrustAnchorOB
+	}
+	issuer := config.Issuer
+	if issuer == "" {
+		issuer = r.Signing.OrgID + "/" + r.Signing.SoftwareID
+	}
+
+	resolver := authentication.NewDirectoryResolver([]authentication.TrustAnchor{{
+		Names:    []string{trustAnchor},
+		JWKSURLs: []string{config.JWKSURI},
+	}})
+	jwks, err := resolver.KeySet(authentication.KeyRequest{TrustAnchor: trustAnchor, Issuer: issuer, Kid: kid})
+	if err != nil {
+		r.add("signing kid", StatusFail, fmt.Sprintf("cannot get JWKS: %v", err))
+		return
+	}
+	jwk, found := jwks.Key(kid)
+	if !found {
+		r.add("signing kid", StatusFail, fmt.Sprintf("kid %s not found in JWKS", kid))
+		return
+	}
+	if jwk.N != "" && r.Signing.Kid != "" {
+		publishedKid, _ := authentication.CalcKid(jwk.N)
+		if publishedKid != r.Signing.Kid {
+			r.add("signing kid", StatusFail, fmt.Sprintf("kid %s is published with a different public key to the signing certificate", kid))
+			return
+		}
+	}
+	r.add("signing kid", StatusPass, fmt.Sprintf("kid %s found in JWKS", kid))
+}
+
+// checkHandshake - the token endpoint asks for a client certificate and accepts the transport
+// certificate for mutual TLS. Any HTTP response after the certificate was presented means the
+// handshake succeeded.
+func (r *Report) checkHandshake(config Config) {
+	if config.TokenEndpoint == "" {
+		r.add("mTLS handshake", StatusSkip, "no token endpoint configured")
+		return
+	}
+	// the key may be held by a key provider, such as a PKCS#11 token, so it is used through the
+	// certificate rather than parsed from PEM
+	cert, err := authentication.NewCertificate(config.TransportPublic, config.TransportPrivate)
+	if err != nil {
+		r.add("mTLS handshake", StatusFail, "transport certificate cannot be used for TLS: "+err.Error())
+		return
+	}
+	tlsCert := cert.TLSCert()
+	if len(tlsCert.Certificate) == 0 {
+		r.add("mTLS handshake", StatusFail, "transport certificate cannot be used for TLS: no certificate in the public key PEM")
+		return
+	}
+
+	var presented int32
+	client := &http.Client{
+		Timeout: handshakeTimeout,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+					atomic.StoreInt32(&presented, 1)
+					return &tlsCert, nil
+				},
+				RootCAs:       rootCAs(),
+				MinVersion:    tls.VersionTLS12,
+				Renegotiation: tls.RenegotiateFreelyAsClient,
+			},
+		},
+	}
+	resp, err := client.Get(config.TokenEndpoint)
+	if err != nil {
+		r.add("mTLS handshake", StatusFail, err.Error())
+		return
+	}
+	resp.Body.Close()
+	if atomic.LoadInt32(&presented) == 0 {
+		r.add("mTLS handshake", StatusFail, fmt.Sprintf("%s responded %s without asking for a client certificate", config.TokenEndpoint, resp.Status))
+		return
+	}
+	r.add("mTLS handshake", StatusPass, fmt.Sprintf("%s responded %s", config.TokenEndpoint, resp.Status))
+}
+
+// rootCAs - CAs trusted for the token endpoint, the same set the executor uses
+var rootCAs = func() *x509.CertPool {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	for _, ca := range [][]byte{
+		certificates.OpenBankingSandBoxIssuingCA(),
+		certificates.OpenBankingSandBoxRootCA(),
+		certificates.OpenBankingIssuingCA(),
+		certificates.OpenBankingRootCA(),
+	} {
+		pool.AppendCertsFromPEM(ca)
+	}
+	return pool
+}
+
+func checkSigningUsage(crt *x509.Certificate) error {
+	if crt.KeyUsage != 0 && crt.KeyUsage&x509.KeyUsageDigitalSignature == 0 {
+		return fmt.Errorf("key usage %s does not include digitalSignature", strings.Join(keyUsageNames(crt.KeyUsage), ", "))
+	}
+	return nil
+}
+
+func checkTransportUsage(crt *x509.Certificate) error {
+	if crt.KeyUsage != 0 && crt.KeyUsage&x509.KeyUsageDigitalSignature == 0 {
+		return fmt.Errorf("key usage %s does not include digitalSignature", strings.Join(keyUsageNames(crt.KeyUsage), ", "))
+	}
+	if len(crt.ExtKeyUsage) == 0 {
+		return nil
+	}
+	for _, usage := range crt.ExtKeyUsage {
+		if usage == x509.ExtKeyUsageClientAuth || usage == x509.ExtKeyUsageAny {
+			return nil
+		}
+	}
+	return fmt.Errorf("extended key usage %s does not include clientAuth", strings.Join(extKeyUsageNames(crt.ExtKeyUsage), ", "))
+}
+
+var keyUsages = []struct {
+	usage x509.KeyUsage
+	name  string
+}{
+	{x509.KeyUsageDigitalSignature, "digitalSignature"},
+	{x509.KeyUsageContentCommitment, "nonRepudiation"},
+	{x509.KeyUsageKeyEncipherment, "keyEncipherment"},
+	{x509.KeyUsageDataEncipherment, "dataEncipherment"},
+	{x509.KeyUsageKeyAgreement, "keyAgreement"},
+	{x509.KeyUsageCertSign, "keyCertSign"},
+	{x509.KeyUsageCRLSign, "cRLSign"},
+	{x509.KeyUsageEncipherOnly, "encipherOnly"},
+	{x509.KeyUsageDecipherOnly, "decipherOnly"},
+}
+
+func keyUsageNames(usage x509.KeyUsage) []string {
+	names := []string{}
+	for _, u := range keyUsages {
+		if usage&u.usage != 0 {
+			names = append(names, u.name)
+		}
+	}
+	return names
+}
+
+var extKeyUsages = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:             "any",
+	x509.ExtKeyUsageServerAuth:      "serverAuth",
+	x509.ExtKeyUsageClientAuth:      "clientAuth",
+	x509.ExtKeyUsageCodeSigning:     "codeSigning",
+	x509.ExtKeyUsageEmailProtection: "emailProtection",
+	x509.ExtKeyUsageTimeStamping:    "timeStamping",
+	x509.ExtKeyUsageOCSPSigning:     "OCSPSigning",
+}
+
+func extKeyUsageNames(usages []x509.ExtKeyUsage) []string {
+	names := []string{}
+	for _, usage := range usages {
+		name, ok := extKeyUsages[usage]
+		if !ok {
+			name = fmt.Sprintf("unknown (%d)", usage)
+		}
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,227 @@
+package preflight
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/authentication"
+)
+
+type testCert struct {
+	public  string
+	private string
+	key     *rsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, notAfter time.Time, keyUsage x509.KeyUsage, extKeyUsage ...x509.ExtKeyUsage) testCert {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			Country:            []string{"GB"},
+			Organization:       []string{"OpenBanking"},
+			OrganizationalUnit: []string{"0015800001041RHAAY"},
+			CommonName:         "2Kz9M8fnFM5ZGgxFEqy0Jr",
+		},
+		NotBefore:   time.Now().Add(-time.Hour),
+		NotAfter:    notAfter,
+		KeyUsage:    keyUsage,
+		ExtKeyUsage: extKeyUsage,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return testCert{
+		public:  string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		private: string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		key:     key,
+	}
+}
+
+func checkStatus(report Report, name string) Status {
+	for _, check := range report.Checks {
+		if check.Name == name {
+			return check.Status
+		}
+	}
+	return ""
+}
+
+func TestRunValidCertificates(t *testing.T) {
+	signing := newTestCert(t, time.Now().AddDate(1, 0, 0), x509.KeyUsageDigitalSignature|x509.KeyUsageContentCommitment)
+	transport := newTestCert(t, time.Now().AddDate(1, 0, 0), x509.KeyUsageDigitalSignature, x509.ExtKeyUsageClientAuth)
+
+	modulus := base64.RawURLEncoding.EncodeToString(signing.key.N.Bytes())
+	kid, err := authentication.CalcKid(modulus)
+	require.NoError(t, err)
+	requested := ""
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Path
+		_ = json.NewEncoder(w).Encode(authentication.JWKS{Keys: []authentication.JWK{{Kid: kid, Kty: "RSA", N: modulus, E: "AQAB"}}})
+	}))
+	defer jwksServer.Close()
+
+	tokenServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	tokenServer.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	tokenServer.StartTLS()
+	defer tokenServer.Close()
+	trustTestServer(t, tokenServer)
+
+	report := Run(Config{
+		SigningPublic:    signing.public,
+		SigningPrivate:   signing.private,
+		TransportPublic:  transport.public,
+		TransportPrivate: transport.private,
+		JWKSURI:          jwksServer.URL + "/{org_id}/{software_id}.jwks",
+		TokenEndpoint:    tokenServer.URL + "/token",
+	})
+
+	assert.Truef(t, report.Pass, "%+v", report.Checks)
+	for _, check := range report.Checks {
+		assert.Equalf(t, StatusPass, check.Status, "%s: %s", check.Name, check.Detail)
+	}
+	assert.Equal(t, "/0015800001041RHAAY/2Kz9M8fnFM5ZGgxFEqy0Jr.jwks", requested)
+	assert.Equal(t, "0015800001041RHAAY", report.Signing.OrgID)
+	assert.Equal(t, "2Kz9M8fnFM5ZGgxFEqy0Jr", report.Signing.SoftwareID)
+	assert.Equal(t, kid, report.Signing.Kid)
+	assert.Equal(t, []string{"digitalSignature", "nonRepudiation"}, report.Signing.KeyUsage)
+	assert.Equal(t, []string{"clientAuth"}, report.Transport.ExtKeyUsage)
+}
+
+func TestRunReportsMisconfiguredCertificates(t *testing.T) {
+	signing := newTestCert(t, time.Now().Add(-time.Minute), x509.KeyUsageKeyEncipherment)
+	transport := newTestCert(t, time.Now().Add(24*time.Hour), x509.KeyUsageDigitalSignature, x509.ExtKeyUsageServerAuth)
+
+	report := Run(Config{
+		SigningPublic:    signing.public,
+		SigningPrivate:   signing.private,
+		TransportPublic:  transport.public,
+		TransportPrivate: transport.private,
+	})
+
+	assert.False(t, report.Pass)
+	assert.Equal(t, StatusFail, checkStatus(report, "signing expiry"))
+	assert.Equal(t, StatusFail, checkStatus(report, "signing key usage"))
+	assert.Equal(t, StatusWarn, checkStatus(report, "transport expiry"))
+	assert.Equal(t, StatusFail, checkStatus(report, "transport key usage"))
+	assert.Equal(t, StatusSkip, checkStatus(report, "signing kid"))
+	assert.Equal(t, StatusSkip, checkStatus(report, "mTLS handshake"))
+}
+
+func TestRunReportsMismatchedKeyPair(t *testing.T) {
+	signing := newTestCert(t, time.Now().AddDate(1, 0, 0), x509.KeyUsageDigitalSignature)
+	other := newTestCert(t, time.Now().AddDate(1, 0, 0), x509.KeyUsageDigitalSignature)
+
+	report := Run(Config{SigningPublic: signing.public, SigningPrivate: other.private})
+
+	assert.False(t, report.Pass)
+	assert.Equal(t, StatusFail, checkStatus(report, "signing key pair"))
+	assert.Equal(t, Status(""), checkStatus(report, "signing expiry"))
+}
+
+func TestRunReportsKidNotPublished(t *testing.T) {
+	signing := newTestCert(t, time.Now().AddDate(1, 0, 0), x509.KeyUsageDigitalSignature)
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"keys":[{"kid":"another-kid","kty":"RSA"}]}`))
+	}))
+	defer jwksServer.Close()
+
+	report := Run(Config{SigningPublic: signing.public, SigningPrivate: signing.private, JWKSURI: jwksServer.URL})
+
+	assert.Equal(t, StatusFail, checkStatus(report, "signing kid"))
+}
+
+// tokenKeyProvider - holds keys the way a PKCS#11 token does, only usable through crypto.Signer
+type tokenKeyProvider struct {
+	keys map[string]*rsa.PrivateKey
+}
+
+func (p tokenKeyProvider) Supports(privateKey string) bool {
+	_, ok := p.keys[privateKey]
+	return ok
+}
+
+func (p tokenKeyProvider) Signer(privateKey string, _ *rsa.PublicKey) (crypto.Signer, error) {
+	return tokenSigner{p.keys[privateKey]}, nil
+}
+
+type tokenSigner struct {
+	key *rsa.PrivateKey
+}
+
+func (s tokenSigner) Public() crypto.PublicKey {
+	return &s.key.PublicKey
+}
+
+func (s tokenSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return s.key.Sign(rand, digest, opts)
+}
+
+func tokenServer(t *testing.T, clientAuth tls.ClientAuthType) *httptest.Server {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	server.TLS = &tls.Config{ClientAuth: clientAuth}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	trustTestServer(t, server)
+	return server
+}
+
+func TestRunHandshakeWithKeyFromKeyProvider(t *testing.T) {
+	transport := newTestCert(t, time.Now().AddDate(1, 0, 0), x509.KeyUsageDigitalSignature, x509.ExtKeyUsageClientAuth)
+	authentication.SetKeyProviders(tokenKeyProvider{keys: map[string]*rsa.PrivateKey{"pkcs11:object=transport": transport.key}})
+	defer authentication.SetKeyProviders(authentication.NewPKCS11KeyProvider("", ""), authentication.PEMKeyProvider())
+	server := tokenServer(t, tls.RequireAnyClientCert)
+
+	report := Run(Config{
+		TransportPublic:  transport.public,
+		TransportPrivate: "pkcs11:object=transport",
+		TokenEndpoint:    server.URL + "/token",
+	})
+
+	assert.Equal(t, StatusPass, checkStatus(report, "transport key pair"))
+	assert.Equal(t, StatusPass, checkStatus(report, "mTLS handshake"))
+}
+
+func TestRunHandshakeFailsWithoutClientCertificateRequest(t *testing.T) {
+	transport := newTestCert(t, time.Now().AddDate(1, 0, 0), x509.KeyUsageDigitalSignature, x509.ExtKeyUsageClientAuth)
+	server := tokenServer(t, tls.NoClientCert)
+
+	report := Run(Config{
+		TransportPublic:  transport.public,
+		TransportPrivate: transport.private,
+		TokenEndpoint:    server.URL + "/token",
+	})
+
+	assert.False(t, report.Pass)
+	assert.Equal(t, StatusFail, checkStatus(report, "mTLS handshake"))
+}
+
+func trustTestServer(t *testing.T, server *httptest.Server) {
+	previous := rootCAs
+	rootCAs = func() *x509.CertPool {
+		pool := x509.NewCertPool()
+		pool.AddCert(server.Certificate())
+		return pool
+	}
+	t.Cleanup(func() { rootCAs = previous })
+}
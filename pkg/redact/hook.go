@@ -0,0 +1,50 @@
+package redact
+
+import (
+	"io"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Hook - a logrus hook redacting the message and fields of every entry with the default Redactor
+type Hook struct{}
+
+// NewHook - a Hook, add it with `logger.AddHook`
+func NewHook() Hook {
+	return Hook{}
+}
+
+// Levels - all levels are redacted
+func (Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire - redacts `entry`. The fields are copied, the entry shares its map with the
+// `*logrus.Entry` it was logged from.
+func (Hook) Fire(entry *logrus.Entry) error {
+	r := Default()
+	entry.Message = r.String(entry.Message)
+	data := make(logrus.Fields, len(entry.Data))
+	for key, value := range entry.Data {
+		data[key] = r.Value(key, value)
+	}
+	entry.Data = data
+	return nil
+}
+
+type writer struct {
+	out io.Writer
+}
+
+// NewWriter - writes to `out` redacted with the default Redactor. Each write is redacted on its
+// own, so it suits writers given whole messages, such as the resty logger.
+func NewWriter(out io.Writer) io.Writer {
+	return writer{out: out}
+}
+
+func (w writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.out, String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,206 @@
+// Package redact removes access tokens, client secrets, private keys and PSU identifiers from
+// text before it reaches a log, a websocket client or an exported report.
+package redact
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Redacted - replaces a sensitive value
+const Redacted = "[redacted]"
+
+// Config - what is sensitive. Keys are the names of JSON fields, form and query values and
+// context entries, matched case-insensitively. A key may be a pattern as understood by
+// `path.Match`, e.g. `*secret*`. Headers are HTTP header names.
+type Config struct {
+	Keys    []string
+	Headers []string
+}
+
+// DefaultConfig - the keys and headers redacted when nothing else is configured
+func DefaultConfig() Config {
+	return Config{
+		Keys: []string{
+			"*secret*",
+			"*password*",
+			"*private*",
+			"*_pin",
+			"authorization",
+			"basic_authentication",
+			"access_token",
+			"*_access_token",
+			"refresh_token",
+			"id_token",
+			"token[0-9]*",
+			"code_verifier",
+			"x_fapi_customer_ip_address",
+			"psu_*",
+		},
+		Headers: []string{
+			"Authorization",
+			"Proxy-Authorization",
+			"Cookie",
+			"Set-Cookie",
+			"x-jws-signature",
+			"x-fapi-customer-ip-address",
+		},
+	}
+}
+
+// Redactor - removes sensitive values
+type Redactor interface {
+	// SensitiveKey - true if values named `key` are redacted
+	SensitiveKey(key string) bool
+	// Value - `value` named `key`, redacted
+	Value(key string, value interface{}) interface{}
+	// String - `s` with sensitive header lines, JSON fields, form values, bearer tokens,
+	// JWTs and PEM private keys redacted
+	String(s string) string
+	// Headers - a copy of `headers` with sensitive headers redacted
+	Headers(headers http.Header) http.Header
+}
+
+var (
+	privateKeyPattern = regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?-----END [A-Z ]*PRIVATE KEY-----`)
+	jsonFieldPattern  = regexp.MustCompile(`"([\w.\-]+)"(\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	formValuePattern  = regexp.MustCompile(`(^|[?&\s])([\w.\-]+)=([^&\s"]*)`)
+	credentialPattern = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9\-._~+/]+=*`)
+	jwtPattern        = regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]*\.[A-Za-z0-9_\-]*`)
+)
+
+type redactor struct {
+	keys          []string
+	headers       map[string]bool
+	headerPattern *regexp.Regexp
+}
+
+// New - a Redactor for `config`
+func New(config Config) Redactor {
+	r := &redactor{headers: map[string]bool{}}
+	for _, key := range config.Keys {
+		r.keys = append(r.keys, strings.ToLower(key))
+	}
+
+	names := []string{}
+	for _, header := range config.Headers {
+		r.headers[http.CanonicalHeaderKey(header)] = true
+		names = append(names, regexp.QuoteMeta(header))
+	}
+	if len(names) > 0 {
+		r.headerPattern = regexp.MustCompile(`(?im)^(\s*(?:` + strings.Join(names, "|") + `)\s*:[ \t]*)\S.*$`)
+	}
+	return r
+}
+
+func (r *redactor) SensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range r.keys {
+		if matched, _ := path.Match(pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *redactor) Value(key string, value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	if r.SensitiveKey(key) {
+		return Redacted
+	}
+	switch value := value.(type) {
+	case string:
+		return r.String(value)
+	case error:
+		return r.String(value.Error())
+	case bool, int, int64, float64:
+		return value
+	}
+	// only values whose text would change are replaced, others keep their type for the formatter
+	text := fmt.Sprintf("%+v", value)
+	if redacted := r.String(text); redacted != text {
+		return redacted
+	}
+	return value
+}
+
+func (r *redactor) String(s string) string {
+	s = privateKeyPattern.ReplaceAllString(s, Redacted)
+	if r.headerPattern != nil {
+		s = r.headerPattern.ReplaceAllString(s, "${1}"+Redacted)
+	}
+	s = jsonFieldPattern.ReplaceAllStringFunc(s, func(field string) string {
+		match := jsonFieldPattern.FindStringSubmatch(field)
+		if !r.SensitiveKey(match[1]) {
+			return field
+		}
+		return `"` + match[1] + `"` + match[2] + `"` + Redacted + `"`
+	})
+	s = formValuePattern.ReplaceAllStringFunc(s, func(value string) string {
+		match := formValuePattern.FindStringSubmatch(value)
+		if !r.SensitiveKey(match[2]) {
+			return value
+		}
+		return match[1] + match[2] + "=" + Redacted
+	})
+	s = credentialPattern.ReplaceAllString(s, "${1} "+Redacted)
+	return jwtPattern.ReplaceAllString(s, Redacted)
+}
+
+func (r *redactor) Headers(headers http.Header) http.Header {
+	if headers == nil {
+		return nil
+	}
+	redacted := http.Header{}
+	for name, values := range headers {
+		if r.headers[http.CanonicalHeaderKey(name)] {
+			redacted[name] = []string{Redacted}
+			continue
+		}
+		for _, value := range values {
+			redacted[name] = append(redacted[name], r.String(value))
+		}
+	}
+	return redacted
+}
+
+var (
+	defaultRedactor = New(DefaultConfig())
+	defaultLock     sync.RWMutex
+)
+
+// SetDefault - replaces the Redactor used by the package functions
+func SetDefault(r Redactor) {
+	defaultLock.Lock()
+	defer defaultLock.Unlock()
+	defaultRedactor = r
+}
+
+// Default - the Redactor used by the package functions, built from DefaultConfig unless
+// SetDefault was called
+func Default() Redactor {
+	defaultLock.RLock()
+	defer defaultLock.RUnlock()
+	return defaultRedactor
+}
+
+// String - `s` redacted by the default Redactor
+func String(s string) string {
+	return Default().String(s)
+}
+
+// Value - `value` named `key` redacted by the default Redactor
+func Value(key string, value interface{}) interface{} {
+	return Default().Value(key, value)
+}
+
+// Headers - `headers` redacted by the default Redactor
+func Headers(headers http.Header) http.Header {
+	return Default().Headers(headers)
+}
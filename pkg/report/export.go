@@ -14,6 +14,8 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/redact"
 )
 
 const (
@@ -58,7 +60,8 @@ func NewZipExporter(report Report, writer io.Writer) Exporter {
 	}
 }
 
-// Export - export `report` as a `.zip` to file named `filename`.
+// Export - export `report` as a `.zip` to file named `filename`. Sensitive values in the report and
+// discovery files are redacted before the checksum is calculated.
 func (e *zipExporter) Export() error {
 	// Create a new zip archive.
 	zipWriter := zip.NewWriter(e.writer)
@@ -79,8 +82,9 @@ func (e *zipExporter) Export() error {
 		return fmt.Errorf("%w: json.MarshalIndent failed: %s, discovery=%+v", ErrExportFailure, err.Error(), e.report.Discovery)
 	}
 
+	reportJSON = []byte(redact.String(string(reportJSON)))
 	toExport[reportFilename] = reportJSON
-	toExport[discoveryFilename] = discoveryJSON
+	toExport[discoveryFilename] = []byte(redact.String(string(discoveryJSON)))
 	toExport[responseFieldsFilename] = []byte(e.report.ResponseFields)
 	toExport["report.checksum"] = createChecksum(exportSecret, reportJSON)
 
@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors/results"
 	"github.com/OpenBankingUK/conformance-suite/pkg/test"
 	"github.com/stretchr/testify/require"
 )
@@ -22,6 +23,37 @@ func TestNewZipExporter(t *testing.T) {
 	require.NotNil(NewZipExporter(report, writer))
 }
 
+func TestZipExporterRedactsReport(t *testing.T) {
+	require := test.NewRequire(t)
+
+	report := Report{
+		Status:      StatusComplete,
+		CertifiedBy: CertifiedBy{Environment: CertifiedByEnvironmentTesting},
+		APISpecification: []APISpecification{{
+			Name:    "Account and Transaction API Specification",
+			Results: []results.TestCase{{Id: "OB-301-ACC-120382", Fail: []string{"401 for Authorization: Bearer a5c1f1fa"}}},
+		}},
+	}
+	writer := bytes.NewBuffer([]byte{})
+	require.NoError(NewZipExporter(report, writer).Export())
+
+	zipReader, err := zip.NewReader(bytes.NewReader(writer.Bytes()), int64(writer.Len()))
+	require.NoError(err)
+	for _, file := range zipReader.File {
+		if file.Name != reportFilename {
+			continue
+		}
+		reader, err := file.Open()
+		require.NoError(err)
+		reportJSON, err := ioutil.ReadAll(reader)
+		require.NoError(err)
+		require.Contains(string(reportJSON), "401 for Authorization: Bearer [redacted]")
+		require.NotContains(string(reportJSON), "a5c1f1fa")
+		return
+	}
+	require.Fail("report.json not exported")
+}
+
 func Test_zipExporter_Export(t *testing.T) {
 	t.Skip()
 	tempDir, err := ioutil.TempDir("", "Test_zipExporter_Export")
@@ -8,7 +8,9 @@ import (
 	"github.com/OpenBankingUK/conformance-suite/pkg/version"
 
 	"github.com/OpenBankingUK/conformance-suite/pkg/discovery"
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors/events"
 	"github.com/OpenBankingUK/conformance-suite/pkg/executors/results"
+	"github.com/OpenBankingUK/conformance-suite/pkg/schemaprops"
 	"github.com/OpenBankingUK/conformance-suite/pkg/server/models"
 	internal_time "github.com/OpenBankingUK/conformance-suite/pkg/time"
 	validation "github.com/go-ozzo/ozzo-validation"
@@ -24,22 +26,23 @@ const (
 
 // Report - The Report.
 type Report struct {
-	ID               string             `json:"id"`                       // A unique and immutable identifier used to identify the report. The v4 UUIDs generated conform to RFC 4122.
-	Created          string             `json:"created"`                  // Date and time when the report was created, formatted accorrding to RFC3339 (https://tools.ietf.org/html/rfc3339). Note RFC3339 is derived from ISO 8601 (https://en.wikipedia.org/wiki/ISO_8601).
-	Expiration       *string            `json:"expiration,omitempty"`     // Date and time when the report should not longer be accepted, formatted accorrding to RFC3339 (https://tools.ietf.org/html/rfc3339). Note RFC3339 is derived from ISO 8601 (https://en.wikipedia.org/wiki/ISO_8601).
-	Fails            int                `json:"fails"`                    // Calculates *total* failures across the whole report, accumulated for each specification.
-	Version          string             `json:"version"`                  // The current version of the report model used.
-	Status           Status             `json:"status"`                   // A status describing overall condition of the report.
-	CertifiedBy      CertifiedBy        `json:"certifiedBy"`              // The certifier of the report.
-	APIVersions      APIVersionList     `json:"apiVersions"`              // List with the version & name of the tested APIs
-	SignatureChain   *[]SignatureChain  `json:"signatureChain,omitempty"` // When Add digital signature is set this contains the signature chain.
-	Discovery        discovery.Model    `json:"-"`                        // Original used discovery model
-	ResponseFields   string             `json:"-"`                        // ResponseFields - already in JSON format
-	APISpecification []APISpecification `json:"apiSpecification"`         // API and version tested, along with test cases
-	FCSVersion       string             `json:"fcsVersion"`               // Version of FCS running the tests
-	Products         []string           `json:"products"`                 // Products tested, e.g., "Business, Personal, Cards"
-	JWSStatus        string             `json:"jwsStatus"`                // Signature status
-	AgreedTC         bool               `json:"agreedTermsConditions"`    // Implementer acknowledged and agreed to T&C as displayed on the UI
+	ID               string                     `json:"id"`                       // A unique and immutable identifier used to identify the report. The v4 UUIDs generated conform to RFC 4122.
+	Created          string                     `json:"created"`                  // Date and time when the report was created, formatted accorrding to RFC3339 (https://tools.ietf.org/html/rfc3339). Note RFC3339 is derived from ISO 8601 (https://en.wikipedia.org/wiki/ISO_8601).
+	Expiration       *string                    `json:"expiration,omitempty"`     // Date and time when the report should not longer be accepted, formatted accorrding to RFC3339 (https://tools.ietf.org/html/rfc3339). Note RFC3339 is derived from ISO 8601 (https://en.wikipedia.org/wiki/ISO_8601).
+	Fails            int                        `json:"fails"`                    // Calculates *total* failures across the whole report, accumulated for each specification.
+	Version          string                     `json:"version"`                  // The current version of the report model used.
+	Status           Status                     `json:"status"`                   // A status describing overall condition of the report.
+	CertifiedBy      CertifiedBy                `json:"certifiedBy"`              // The certifier of the report.
+	APIVersions      APIVersionList             `json:"apiVersions"`              // List with the version & name of the tested APIs
+	SignatureChain   *[]SignatureChain          `json:"signatureChain,omitempty"` // When Add digital signature is set this contains the signature chain.
+	Discovery        discovery.Model            `json:"-"`                        // Original used discovery model
+	ResponseFields   string                     `json:"-"`                        // ResponseFields - already in JSON format
+	APISpecification []APISpecification         `json:"apiSpecification"`         // API and version tested, along with test cases
+	FCSVersion       string                     `json:"fcsVersion"`               // Version of FCS running the tests
+	Products         []string                   `json:"products"`                 // Products tested, e.g., "Business, Personal, Cards"
+	JWSStatus        string                     `json:"jwsStatus"`                // Signature status
+	AgreedTC         bool                       `json:"agreedTermsConditions"`    // Implementer acknowledged and agreed to T&C as displayed on the UI
+	TokenStates      []events.TokenStateChanged `json:"tokenStates,omitempty"`    // Last state of each access token, e.g. refreshed or expired
 }
 
 // APIVersionList is a sortable collection of API name and version pairs
@@ -55,11 +58,12 @@ func (avl APIVersionList) Len() int           { return len(avl) }
 func (avl APIVersionList) Swap(i, j int)      { avl[i], avl[j] = avl[j], avl[i] }
 
 type APISpecification struct {
-	Name            string             `json:"name"`
-	TLSVersion      string             `json:"tls_version"`
-	TLSVersionValid bool               `json:"tls_version_valid"`
-	Version         string             `json:"version"`
-	Results         []results.TestCase `json:"results"`
+	Name            string                         `json:"name"`
+	TLSVersion      string                         `json:"tls_version"`
+	TLSVersionValid bool                           `json:"tls_version_valid"`
+	Version         string                         `json:"version"`
+	Results         []results.TestCase             `json:"results"`
+	Coverage        []schemaprops.EndpointCoverage `json:"coverage,omitempty"` // Optional and conditional response properties populated, per endpoint and response code
 }
 
 func (r *Report) requiresTCAgreement() bool {
@@ -110,6 +114,7 @@ func NewReport(exportResults models.ExportResults, environment string) (Report,
 	signatureChain := []SignatureChain{}
 
 	fails := GetFails(exportResults.Results)
+	coverage := responseCoverage(exportResults.DiscoveryModel, exportResults.ResponseFields)
 	apiSpecs := []APISpecification{}
 	apiVersions := make(APIVersionList, 0, len(exportResults.Results))
 	for k, results := range exportResults.Results {
@@ -129,6 +134,7 @@ func NewReport(exportResults models.ExportResults, environment string) (Report,
 			Results:         results,
 			TLSVersion:      tlsVersionResult.TLSVersion,
 			TLSVersionValid: tlsVersionResult.Valid,
+			Coverage:        coverage[k],
 		}
 		apiSpecs = append(apiSpecs, apiSpec)
 	}
@@ -151,6 +157,7 @@ func NewReport(exportResults models.ExportResults, environment string) (Report,
 		Products:         exportResults.ExportRequest.Products,
 		JWSStatus:        exportResults.JWSStatus,
 		AgreedTC:         exportResults.ExportRequest.HasAgreed,
+		TokenStates:      exportResults.TokenStates,
 	}, nil
 }
 
@@ -0,0 +1,47 @@
+package report
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/discovery"
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors/results"
+	"github.com/OpenBankingUK/conformance-suite/pkg/schema"
+	"github.com/OpenBankingUK/conformance-suite/pkg/schemaprops"
+)
+
+// responseCoverage - the coverage of the optional and conditional response properties of each
+// API in the discovery model, from the fields collected in the run
+func responseCoverage(discoveryModel discovery.Model, responseFields string) map[results.ResultKey][]schemaprops.EndpointCoverage {
+	coverage := map[results.ResultKey][]schemaprops.EndpointCoverage{}
+	if responseFields == "" {
+		return coverage
+	}
+	apis, err := schemaprops.ParseOutputJSON(responseFields)
+	if err != nil {
+		logrus.WithError(err).Warn("report: response fields are not valid JSON, leaving out coverage")
+		return coverage
+	}
+
+	for _, item := range discoveryModel.DiscoveryModel.DiscoveryItems {
+		for _, api := range apis {
+			if api.Api != item.APISpecification.Name || api.Version != item.APISpecification.Version {
+				continue
+			}
+			validator, err := schema.NewSpecValidator(item.APISpecification.SpecLocation())
+			if err != nil {
+				logrus.WithError(err).Warnf("report: leaving out coverage of %s", item.APISpecification.Name)
+				continue
+			}
+			conditional := map[string][]string{}
+			for _, endpoint := range item.Endpoints {
+				for _, property := range endpoint.ConditionalProperties {
+					key := endpoint.Method + " " + endpoint.Path
+					conditional[key] = append(conditional[key], property.Path)
+				}
+			}
+			key := results.ResultKey{APIName: api.Api, APIVersion: api.Version}
+			coverage[key] = schemaprops.NewCoverage(validator, api, conditional)
+		}
+	}
+	return coverage
+}
@@ -0,0 +1,49 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/discovery"
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors/results"
+	"github.com/OpenBankingUK/conformance-suite/pkg/schemaprops"
+	"github.com/OpenBankingUK/conformance-suite/pkg/test"
+)
+
+func TestResponseCoverage(t *testing.T) {
+	require := test.NewRequire(t)
+
+	discoveryModel := discovery.Model{DiscoveryModel: discovery.ModelDiscovery{
+		DiscoveryItems: []discovery.ModelDiscoveryItem{
+			{
+				APISpecification: discovery.ModelAPISpecification{
+					Name:    "Account and Transaction API Specification",
+					Version: "v3.1.10",
+				},
+				Endpoints: []discovery.ModelEndpoint{
+					{
+						Method: "GET",
+						Path:   "/accounts",
+						ConditionalProperties: []discovery.ConditionalProperty{
+							{Schema: "OBAccount6", Name: "Nickname", Path: "Data.Account.*.Nickname"},
+						},
+					},
+				},
+			},
+		},
+	}}
+	responseFields := `{"responseFields": [{
+		"api": "Account and Transaction API Specification",
+		"version": "v3.1.10",
+		"endpoints": [{"method": "GET", "path": "/accounts", "responses": [{"code": "200", "fields": ["Data", "Data.Account", "Data.Account.Nickname"]}]}]
+	}]}`
+
+	coverage := responseCoverage(discoveryModel, responseFields)
+	key := results.ResultKey{APIName: "Account and Transaction API Specification", APIVersion: "v3.1.10"}
+	require.Len(coverage[key], 1)
+	require.Equal("/accounts", coverage[key][0].Path)
+	require.Equal(2, coverage[key][0].Populated)
+	require.Contains(coverage[key][0].Properties, schemaprops.PropertyCoverage{Path: "Data.Account.Nickname", Conditional: true, Populated: true})
+
+	require.Empty(responseCoverage(discoveryModel, ""))
+	require.Empty(responseCoverage(discoveryModel, "not json"))
+}
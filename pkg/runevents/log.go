@@ -0,0 +1,80 @@
+package runevents
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+// ErrNoLog - there is no event log for the requested run
+var ErrNoLog = errors.New("run has no event log")
+
+// runIDPattern - run IDs are UUIDs, anything else must not become part of a path
+var runIDPattern = regexp.MustCompile(`^[0-9a-fA-F-]+$`)
+
+// Log - keeps the events of each run
+type Log interface {
+	Write(runID string, event Event) error
+	// Open - the events of run `runID` as NDJSON, one event per line
+	Open(runID string) (io.ReadCloser, error)
+}
+
+type fileLog struct {
+	dir  string
+	lock sync.Mutex
+}
+
+// NewFileLog - a Log keeping the events of each run in `dir`/<run id>.ndjson. A run that is resumed
+// adds to its log.
+func NewFileLog(dir string) (Log, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &fileLog{dir: dir}, nil
+}
+
+func (l *fileLog) Write(runID string, event Event) error {
+	filename, err := l.filename(runID)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (l *fileLog) Open(runID string) (io.ReadCloser, error) {
+	filename, err := l.filename(runID)
+	if err != nil {
+		return nil, ErrNoLog
+	}
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, ErrNoLog
+	}
+	return file, err
+}
+
+func (l *fileLog) filename(runID string) (string, error) {
+	if !runIDPattern.MatchString(runID) {
+		return "", errors.New("invalid run ID " + runID)
+	}
+	return filepath.Join(l.dir, runID+".ndjson"), nil
+}
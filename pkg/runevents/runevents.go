@@ -0,0 +1,252 @@
+// Package runevents publishes the progress of test runs as a stream of versioned events, so tools
+// other than the web UI can follow a run. The stream is served as Server-Sent Events and kept as
+// an NDJSON log per run.
+package runevents
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/redact"
+)
+
+// SchemaVersion - the version of the event schema. It changes only when a field is removed or
+// its meaning changes, new event types and fields do not change it.
+const SchemaVersion = 1
+
+// Type - what happened
+type Type string
+
+// Event types
+const (
+	RunStarted      Type = "run_started"
+	TestStarted     Type = "test_started"
+	TestFinished    Type = "test_finished"
+	ConsentRequired Type = "consent_required"
+	ConsentGranted  Type = "consent_granted"
+	TokenRefreshed  Type = "token_refreshed"
+	TokenExpired    Type = "token_expired"
+	RunCompleted    Type = "run_completed"
+	Error           Type = "error"
+)
+
+// Event - one entry in the stream. Seq increases by one with each event published by a journey,
+// clients resume a stream from the last Seq they saw. RunID is empty for events published before
+// the run they lead to has started, such as the consent for a new run.
+type Event struct {
+	Version int             `json:"version"`
+	Seq     uint64          `json:"seq"`
+	Type    Type            `json:"type"`
+	Time    time.Time       `json:"time"`
+	RunID   string          `json:"run_id,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// Run - data of run_started
+type Run struct {
+	Tests   int  `json:"tests"`
+	Resumed bool `json:"resumed"`
+}
+
+// Test - data of test_started
+type Test struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Method     string `json:"method"`
+	Endpoint   string `json:"endpoint"`
+	API        string `json:"api"`
+	APIVersion string `json:"api_version"`
+}
+
+// TestResult - data of test_finished
+type TestResult struct {
+	ID           string   `json:"id"`
+	Pass         bool     `json:"pass"`
+	Fail         []string `json:"fail,omitempty"`
+	HTTPStatus   string   `json:"http_status,omitempty"`
+	ResponseTime float64  `json:"response_time_ms"`
+}
+
+// Consent - data of consent_required and consent_granted. ConsentURL is only sent with consent_required.
+type Consent struct {
+	TokenName  string `json:"token_name"`
+	ConsentURL string `json:"consent_url,omitempty"`
+}
+
+// Token - data of token_refreshed and token_expired
+type Token struct {
+	TokenName string `json:"token_name"`
+	Expires   string `json:"expires,omitempty"`
+}
+
+// RunResult - data of run_completed. Status is "completed", or "stopped" when some test cases did not run.
+type RunResult struct {
+	Status string `json:"status"`
+	Tests  int    `json:"tests"`
+	Passed int    `json:"passed"`
+	Failed int    `json:"failed"`
+}
+
+// Failure - data of error
+type Failure struct {
+	Message string `json:"message"`
+}
+
+// Stream - the events of one journey. StartRun publishes run_started, and the events that follow
+// belong to that run until run_completed is published.
+type Stream interface {
+	StartRun(runID string, run Run) Event
+	Publish(eventType Type, data interface{}) Event
+	// Subscribe - a channel of the events published after `after`, starting with those still
+	// kept, and a func to unsubscribe. The channel is closed if the subscriber falls behind.
+	Subscribe(after uint64) (<-chan Event, func())
+}
+
+const (
+	// historySize - events kept for subscribers resuming a stream
+	historySize = 1000
+	// subscriberBuffer - events a subscriber can fall behind by before it is dropped
+	subscriberBuffer = 100
+	// pendingSize - events published before a run starts kept for its log
+	pendingSize = 100
+)
+
+type stream struct {
+	log    Log
+	logger *logrus.Entry
+	now    func() time.Time
+
+	lock        sync.Mutex
+	seq         uint64
+	runID       string
+	history     []Event
+	pending     []Event
+	subscribers map[chan Event]struct{}
+}
+
+// NewStream - a Stream writing each run's events to `log`, which may be nil
+func NewStream(log Log, logger *logrus.Entry) Stream {
+	return &stream{
+		log:         log,
+		logger:      logger.WithField("module", "runevents"),
+		now:         time.Now,
+		subscribers: map[chan Event]struct{}{},
+	}
+}
+
+func (s *stream) StartRun(runID string, run Run) Event {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.runID = runID
+	pending := s.pending
+	s.pending = nil
+	for _, event := range pending {
+		s.write(runID, event)
+	}
+	return s.publish(RunStarted, run)
+}
+
+func (s *stream) Publish(eventType Type, data interface{}) Event {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	event := s.publish(eventType, data)
+	if eventType == RunCompleted {
+		s.runID = ""
+	}
+	return event
+}
+
+// publish - the caller holds the lock
+func (s *stream) publish(eventType Type, data interface{}) Event {
+	s.seq++
+	event := Event{
+		Version: SchemaVersion,
+		Seq:     s.seq,
+		Type:    eventType,
+		Time:    s.now().UTC(),
+		RunID:   s.runID,
+		Data:    encodeData(eventType, data, s.logger),
+	}
+
+	s.history = append(s.history, event)
+	if len(s.history) > historySize {
+		s.history = s.history[len(s.history)-historySize:]
+	}
+	if s.runID == "" {
+		if len(s.pending) < pendingSize {
+			s.pending = append(s.pending, event)
+		}
+	} else {
+		s.write(s.runID, event)
+	}
+
+	for subscriber := range s.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			// a subscriber that falls behind must not hold up the run, it can resume from the history
+			delete(s.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+	return event
+}
+
+func (s *stream) write(runID string, event Event) {
+	if s.log == nil {
+		return
+	}
+	if err := s.log.Write(runID, event); err != nil {
+		s.logger.WithError(err).WithField("run_id", runID).Error("writing run event log")
+	}
+}
+
+func (s *stream) Subscribe(after uint64) (<-chan Event, func()) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	kept := []Event{}
+	for _, event := range s.history {
+		if event.Seq > after {
+			kept = append(kept, event)
+		}
+	}
+	subscriber := make(chan Event, len(kept)+subscriberBuffer)
+	for _, event := range kept {
+		subscriber <- event
+	}
+	s.subscribers[subscriber] = struct{}{}
+
+	unsubscribe := func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if _, ok := s.subscribers[subscriber]; ok {
+			delete(s.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+	return subscriber, unsubscribe
+}
+
+// encodeData - `data` as JSON with sensitive values redacted, as events are written to logs and
+// sent to clients as they are. Consent URLs are kept whole, the PSU has to open them and the
+// request object they carry is signed rather than secret.
+func encodeData(eventType Type, data interface{}, logger *logrus.Entry) json.RawMessage {
+	if data == nil {
+		return nil
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		logger.WithError(err).Error("encoding event data")
+		return nil
+	}
+	if eventType == ConsentRequired {
+		return json.RawMessage(encoded)
+	}
+	return json.RawMessage(redact.String(string(encoded)))
+}
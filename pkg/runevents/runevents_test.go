@@ -0,0 +1,129 @@
+package runevents
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/test"
+)
+
+const runID = "4c5f1d2e-4a43-4e8e-9d0a-3b5b3a4bb0e1"
+
+// memoryLog - a Log keeping events in memory
+type memoryLog map[string][]Event
+
+func (l memoryLog) Write(runID string, event Event) error {
+	l[runID] = append(l[runID], event)
+	return nil
+}
+
+func (l memoryLog) Open(runID string) (io.ReadCloser, error) {
+	return nil, ErrNoLog
+}
+
+func types(events []Event) []Type {
+	eventTypes := []Type{}
+	for _, event := range events {
+		eventTypes = append(eventTypes, event.Type)
+	}
+	return eventTypes
+}
+
+func TestStreamLogsRunEvents(t *testing.T) {
+	log := memoryLog{}
+	stream := NewStream(log, test.NullLogger())
+
+	stream.Publish(ConsentRequired, Consent{TokenName: "Token001", ConsentURL: "https://aspsp.example.com/auth?request=eyJhbGciOiJQUzI1NiJ9.e30.c2ln"})
+	stream.Publish(ConsentGranted, Consent{TokenName: "Token001"})
+	started := stream.StartRun(runID, Run{Tests: 1})
+	stream.Publish(TestFinished, TestResult{ID: "OB-301-ACC-120382", Pass: true})
+	stream.Publish(RunCompleted, RunResult{Status: "completed", Tests: 1, Passed: 1})
+	after := stream.Publish(ConsentRequired, Consent{TokenName: "Token002"})
+
+	assert.Equal(t, []Type{ConsentRequired, ConsentGranted, RunStarted, TestFinished, RunCompleted}, types(log[runID]))
+	assert.Equal(t, SchemaVersion, started.Version)
+	assert.Equal(t, uint64(3), started.Seq)
+	assert.Equal(t, runID, started.RunID)
+	assert.Equal(t, "", log[runID][0].RunID)
+	assert.JSONEq(t, `{"token_name":"Token001","consent_url":"https://aspsp.example.com/auth?request=eyJhbGciOiJQUzI1NiJ9.e30.c2ln"}`, string(log[runID][0].Data))
+	assert.Equal(t, "", after.RunID)
+}
+
+func TestStreamRedactsData(t *testing.T) {
+	stream := NewStream(nil, test.NullLogger())
+
+	event := stream.Publish(Error, Failure{Message: "token endpoint rejected Bearer a5c1f1fa"})
+
+	assert.JSONEq(t, `{"message":"token endpoint rejected Bearer [redacted]"}`, string(event.Data))
+}
+
+func TestStreamSubscribe(t *testing.T) {
+	stream := NewStream(nil, test.NullLogger())
+	stream.StartRun(runID, Run{Tests: 2})
+	stream.Publish(TestStarted, Test{ID: "OB-301-ACC-120382"})
+
+	events, unsubscribe := stream.Subscribe(1)
+	stream.Publish(TestFinished, TestResult{ID: "OB-301-ACC-120382"})
+
+	assert.Equal(t, uint64(2), (<-events).Seq)
+	assert.Equal(t, uint64(3), (<-events).Seq)
+
+	unsubscribe()
+	_, ok := <-events
+	assert.False(t, ok)
+	unsubscribe()
+}
+
+func TestStreamDropsSlowSubscriber(t *testing.T) {
+	stream := NewStream(nil, test.NullLogger())
+	events, unsubscribe := stream.Subscribe(0)
+	defer unsubscribe()
+
+	for i := 0; i <= subscriberBuffer; i++ {
+		stream.Publish(TestStarted, Test{ID: "OB-301-ACC-120382"})
+	}
+
+	received := 0
+	for range events {
+		received++
+	}
+	assert.Equal(t, subscriberBuffer, received)
+}
+
+func TestFileLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "runevents")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	log, err := NewFileLog(dir)
+	require.NoError(t, err)
+	stream := NewStream(log, test.NullLogger())
+	stream.StartRun(runID, Run{Tests: 1})
+	stream.Publish(RunCompleted, RunResult{Status: "completed", Tests: 1, Passed: 1})
+
+	file, err := log.Open(runID)
+	require.NoError(t, err)
+	defer file.Close()
+	lines := bufio.NewScanner(file)
+	logged := []Event{}
+	for lines.Scan() {
+		event := Event{}
+		require.NoError(t, json.Unmarshal(lines.Bytes(), &event))
+		logged = append(logged, event)
+	}
+	assert.Equal(t, []Type{RunStarted, RunCompleted}, types(logged))
+	assert.WithinDuration(t, time.Now(), logged[0].Time, time.Minute)
+
+	_, err = log.Open("a5c1f1fa-0000-0000-0000-000000000000")
+	assert.Equal(t, ErrNoLog, err)
+	_, err = log.Open("../runs.db")
+	assert.Equal(t, ErrNoLog, err)
+}
@@ -0,0 +1,245 @@
+package runstore
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	runsBucket        = []byte("runs")
+	summariesBucket   = []byte("summaries")
+	resultsBucket     = []byte("results")
+	checkpointsBucket = []byte("checkpoints")
+)
+
+// boltStore - runs in a bbolt file. Summaries are kept in their own bucket so listing runs
+// does not decode every result. Results are kept in a bucket per run, so a checkpoint writes
+// one result rather than the whole run.
+type boltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore - opens, or creates, the run history at `path`
+func NewBoltStore(path string) (RunStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("runstore: creating directory for %s: %w", path, err)
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("runstore: opening %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{runsBucket, summariesBucket, resultsBucket, checkpointsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("runstore: creating buckets in %s: %w", path, err)
+	}
+	return &boltStore{db: db}, nil
+}
+
+func (s *boltStore) Save(run Run) error {
+	encodedResults := make([][]byte, 0, len(run.Results))
+	for _, result := range run.Results {
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("runstore: encoding result %s of run %s: %w", result.ID, run.ID, err)
+		}
+		encodedResults = append(encodedResults, encoded)
+	}
+	encodedSummary, err := json.Marshal(run.Summary())
+	if err != nil {
+		return fmt.Errorf("runstore: encoding summary of run %s: %w", run.ID, err)
+	}
+	run.Results = nil
+	encodedRun, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("runstore: encoding run %s: %w", run.ID, err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		id := []byte(run.ID)
+		if err := tx.Bucket(runsBucket).Put(id, encodedRun); err != nil {
+			return err
+		}
+		if err := tx.Bucket(summariesBucket).Put(id, encodedSummary); err != nil {
+			return err
+		}
+
+		allResults := tx.Bucket(resultsBucket)
+		if allResults.Bucket(id) != nil {
+			if err := allResults.DeleteBucket(id); err != nil {
+				return err
+			}
+		}
+		results, err := allResults.CreateBucket(id)
+		if err != nil {
+			return err
+		}
+		for _, encoded := range encodedResults {
+			if err := putResult(results, encoded); err != nil {
+				return err
+			}
+		}
+
+		if run.Status == StatusCompleted {
+			return tx.Bucket(checkpointsBucket).Delete(id)
+		}
+		return nil
+	})
+}
+
+func (s *boltStore) Checkpoint(id string, result Result, checkpoint Checkpoint) error {
+	encodedResult, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("runstore: encoding result %s of run %s: %w", result.ID, id, err)
+	}
+	encodedCheckpoint, err := json.Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("runstore: encoding checkpoint of run %s: %w", id, err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		summaries := tx.Bucket(summariesBucket)
+		encodedSummary := summaries.Get([]byte(id))
+		if encodedSummary == nil {
+			return ErrNotFound
+		}
+		summary := Summary{}
+		if err := json.Unmarshal(encodedSummary, &summary); err != nil {
+			return err
+		}
+		summary.Tests++
+		if result.Pass {
+			summary.Passed++
+		} else {
+			summary.Failed++
+		}
+		encodedSummary, err := json.Marshal(summary)
+		if err != nil {
+			return err
+		}
+		if err := summaries.Put([]byte(id), encodedSummary); err != nil {
+			return err
+		}
+
+		results, err := tx.Bucket(resultsBucket).CreateBucketIfNotExists([]byte(id))
+		if err != nil {
+			return err
+		}
+		if err := putResult(results, encodedResult); err != nil {
+			return err
+		}
+		return tx.Bucket(checkpointsBucket).Put([]byte(id), encodedCheckpoint)
+	})
+}
+
+// putResult - appends a result, keys are sequence numbers so results keep their order
+func putResult(results *bolt.Bucket, encoded []byte) error {
+	sequence, err := results.NextSequence()
+	if err != nil {
+		return err
+	}
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, sequence)
+	return results.Put(key, encoded)
+}
+
+func (s *boltStore) Get(id string) (Run, error) {
+	run := Run{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		encoded := tx.Bucket(runsBucket).Get([]byte(id))
+		if encoded == nil {
+			return ErrNotFound
+		}
+		if err := json.Unmarshal(encoded, &run); err != nil {
+			return err
+		}
+
+		run.Results = []Result{}
+		results := tx.Bucket(resultsBucket).Bucket([]byte(id))
+		if results == nil {
+			return nil
+		}
+		return results.ForEach(func(_, encoded []byte) error {
+			result := Result{}
+			if err := json.Unmarshal(encoded, &result); err != nil {
+				return err
+			}
+			run.Results = append(run.Results, result)
+			return nil
+		})
+	})
+	return run, err
+}
+
+func (s *boltStore) LoadCheckpoint(id string) (Checkpoint, error) {
+	checkpoint := Checkpoint{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(runsBucket).Get([]byte(id)) == nil {
+			return ErrNotFound
+		}
+		encoded := tx.Bucket(checkpointsBucket).Get([]byte(id))
+		if encoded == nil {
+			return ErrNoCheckpoint
+		}
+		return json.Unmarshal(encoded, &checkpoint)
+	})
+	return checkpoint, err
+}
+
+func (s *boltStore) List() ([]Summary, error) {
+	summaries := []Summary{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(summariesBucket).ForEach(func(_, encoded []byte) error {
+			summary := Summary{}
+			if err := json.Unmarshal(encoded, &summary); err != nil {
+				return err
+			}
+			summaries = append(summaries, summary)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("runstore: listing runs: %w", err)
+	}
+	sortSummaries(summaries)
+	return summaries, nil
+}
+
+func (s *boltStore) Delete(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		key := []byte(id)
+		runs := tx.Bucket(runsBucket)
+		if runs.Get(key) == nil {
+			return ErrNotFound
+		}
+		if err := runs.Delete(key); err != nil {
+			return err
+		}
+		if err := tx.Bucket(summariesBucket).Delete(key); err != nil {
+			return err
+		}
+		if tx.Bucket(resultsBucket).Bucket(key) != nil {
+			if err := tx.Bucket(resultsBucket).DeleteBucket(key); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(checkpointsBucket).Delete(key)
+	})
+}
+
+func (s *boltStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,272 @@
+// Package runstore keeps the history of test runs, so results survive a restart of the server.
+package runstore
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/authentication"
+	"github.com/OpenBankingUK/conformance-suite/pkg/discovery"
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors"
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors/results"
+	"github.com/OpenBankingUK/conformance-suite/pkg/generation"
+	"github.com/OpenBankingUK/conformance-suite/pkg/manifest"
+)
+
+var (
+	// ErrNotFound - there is no run with the requested ID
+	ErrNotFound = errors.New("run not found")
+	// ErrNoCheckpoint - the run finished, or was saved before checkpoints were kept
+	ErrNoCheckpoint = errors.New("run has no checkpoint to resume from")
+)
+
+// Status - how a run ended
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusStopped   Status = "stopped"
+)
+
+// RunStore - persists runs. Save replaces any run with the same ID, and drops its checkpoint
+// once the run is completed. Checkpoint adds one result to a saved run and replaces its checkpoint.
+type RunStore interface {
+	Save(run Run) error
+	Checkpoint(id string, result Result, checkpoint Checkpoint) error
+	Get(id string) (Run, error)
+	LoadCheckpoint(id string) (Checkpoint, error)
+	List() ([]Summary, error)
+	Delete(id string) error
+	Close() error
+}
+
+// Run - everything needed to look back at a test run
+type Run struct {
+	ID             string                 `json:"id"`
+	Status         Status                 `json:"status"`
+	Started        time.Time              `json:"started"`
+	Finished       time.Time              `json:"finished"`
+	DiscoveryModel discovery.Model        `json:"discovery_model"`
+	Config         map[string]interface{} `json:"config"` // the configuration without keys or secrets
+	SpecRun        generation.SpecRun     `json:"spec_run"`
+	Results        []Result               `json:"results"`
+}
+
+// Result - a test case result with its evidence
+type Result struct {
+	ID           string                          `json:"id"`
+	Pass         bool                            `json:"pass"`
+	Fail         []string                        `json:"fail,omitempty"`
+	Detail       string                          `json:"detail"`
+	RefURI       string                          `json:"ref_uri"`
+	Endpoint     string                          `json:"endpoint"`
+	API          string                          `json:"api"`
+	APIVersion   string                          `json:"api_version"`
+	HTTPStatus   string                          `json:"http_status"`
+	ResponseTime time.Duration                   `json:"response_time"`
+	ResponseSize int                             `json:"response_size"`
+	Signature    *authentication.SignatureReport `json:"signature,omitempty"`
+	Evidence     *results.Evidence               `json:"evidence,omitempty"`
+}
+
+// NewResult - the stored form of `testCase`
+func NewResult(testCase results.TestCase) Result {
+	return Result{
+		ID:           testCase.Id,
+		Pass:         testCase.Pass,
+		Fail:         testCase.Fail,
+		Detail:       testCase.Detail,
+		RefURI:       testCase.RefURI,
+		Endpoint:     testCase.Endpoint,
+		API:          testCase.API,
+		APIVersion:   testCase.APIVersion,
+		HTTPStatus:   testCase.HttpStatus,
+		ResponseTime: testCase.Metrics.ResponseTime,
+		ResponseSize: testCase.Metrics.ResponseSize,
+		Signature:    testCase.Signature,
+		Evidence:     testCase.Evidence,
+	}
+}
+
+// Checkpoint - the state needed to resume a run that did not complete. It holds access tokens,
+// so it is not part of Run and is not served by the API.
+type Checkpoint struct {
+	Context     map[string]interface{}               `json:"context"`
+	Permissions map[string][]manifest.RequiredTokens `json:"permissions"`
+	Tokens      []Token                              `json:"tokens"`
+	// Idempotent - the requests sent with an x-idempotency-key, by test case ID, which the
+	// idempotency test cases not yet run send again
+	Idempotent map[string]executors.IdempotentRequest `json:"idempotent,omitempty"`
+}
+
+// Token - an access token used by the run, the token itself is in the checkpoint context
+type Token struct {
+	Name         string    `json:"name"`
+	Expires      time.Time `json:"expires"` // zero when the token endpoint did not send expires_in
+	RefreshToken string    `json:"refresh_token,omitempty"`
+}
+
+// ExpiredTokens - names of the tokens that expire before `at`
+func (c Checkpoint) ExpiredTokens(at time.Time) []string {
+	expired := []string{}
+	for _, token := range c.Tokens {
+		if !token.Expires.IsZero() && token.Expires.Before(at) {
+			expired = append(expired, token.Name)
+		}
+	}
+	return expired
+}
+
+// TestCase - the result as the test runner reports it, used to replay the results of a resumed run
+func (r Result) TestCase() results.TestCase {
+	return results.TestCase{
+		Id:         r.ID,
+		Pass:       r.Pass,
+		Fail:       r.Fail,
+		Detail:     r.Detail,
+		RefURI:     r.RefURI,
+		Endpoint:   r.Endpoint,
+		API:        r.API,
+		APIVersion: r.APIVersion,
+		HttpStatus: r.HTTPStatus,
+		Metrics: results.Metrics{
+			ResponseTime: r.ResponseTime,
+			ResponseSize: r.ResponseSize,
+		},
+		Signature: r.Signature,
+		Evidence:  r.Evidence,
+	}
+}
+
+// Summary - a run as listed by RunStore.List
+type Summary struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Status   Status    `json:"status"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Duration string    `json:"duration"`
+	Tests    int       `json:"tests"`
+	Passed   int       `json:"passed"`
+	Failed   int       `json:"failed"`
+}
+
+// Summary - counts of the run's results
+func (r Run) Summary() Summary {
+	summary := Summary{
+		ID:       r.ID,
+		Name:     r.DiscoveryModel.DiscoveryModel.Name,
+		Status:   r.Status,
+		Started:  r.Started,
+		Finished: r.Finished,
+		Tests:    len(r.Results),
+	}
+	if !r.Finished.IsZero() {
+		summary.Duration = r.Finished.Sub(r.Started).String()
+	}
+	for _, result := range r.Results {
+		if result.Pass {
+			summary.Passed++
+		} else {
+			summary.Failed++
+		}
+	}
+	return summary
+}
+
+// sortSummaries - most recent first
+func sortSummaries(summaries []Summary) {
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Started.After(summaries[j].Started)
+	})
+}
+
+type memoryStore struct {
+	lock        sync.RWMutex
+	runs        map[string]Run
+	checkpoints map[string]Checkpoint
+}
+
+// NewMemoryStore - a RunStore that keeps runs until the server stops
+func NewMemoryStore() RunStore {
+	return &memoryStore{
+		runs:        map[string]Run{},
+		checkpoints: map[string]Checkpoint{},
+	}
+}
+
+func (s *memoryStore) Save(run Run) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	run.Results = append([]Result(nil), run.Results...)
+	s.runs[run.ID] = run
+	if run.Status == StatusCompleted {
+		delete(s.checkpoints, run.ID)
+	}
+	return nil
+}
+
+func (s *memoryStore) Checkpoint(id string, result Result, checkpoint Checkpoint) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	run, ok := s.runs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	run.Results = append(run.Results, result)
+	s.runs[id] = run
+	s.checkpoints[id] = checkpoint
+	return nil
+}
+
+func (s *memoryStore) LoadCheckpoint(id string) (Checkpoint, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if _, ok := s.runs[id]; !ok {
+		return Checkpoint{}, ErrNotFound
+	}
+	checkpoint, ok := s.checkpoints[id]
+	if !ok {
+		return Checkpoint{}, ErrNoCheckpoint
+	}
+	return checkpoint, nil
+}
+
+func (s *memoryStore) Get(id string) (Run, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	run, ok := s.runs[id]
+	if !ok {
+		return Run{}, ErrNotFound
+	}
+	return run, nil
+}
+
+func (s *memoryStore) List() ([]Summary, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	summaries := make([]Summary, 0, len(s.runs))
+	for _, run := range s.runs {
+		summaries = append(summaries, run.Summary())
+	}
+	sortSummaries(summaries)
+	return summaries, nil
+}
+
+func (s *memoryStore) Delete(id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.runs[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.runs, id)
+	delete(s.checkpoints, id)
+	return nil
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,150 @@
+package runstore
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/discovery"
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors/results"
+)
+
+func testRun(id string, started time.Time) Run {
+	run := Run{
+		ID:       id,
+		Status:   StatusCompleted,
+		Started:  started,
+		Finished: started.Add(90 * time.Second),
+		Config:   map[string]interface{}{"resource_base_url": "https://aspsp.example.com"},
+		Results: []Result{
+			NewResult(results.TestCase{Id: "OB-301-ACC-120382", Pass: true}),
+			NewResult(results.TestCase{Id: "OB-301-ACC-811741", Pass: false, Fail: []string{"status code"}}),
+		},
+	}
+	run.DiscoveryModel.DiscoveryModel = discovery.ModelDiscovery{Name: "ob-v3.1-ozone"}
+	return run
+}
+
+func testStores(t *testing.T) map[string]RunStore {
+	bolt, err := NewBoltStore(filepath.Join(t.TempDir(), "data", "runs.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { bolt.Close() })
+
+	return map[string]RunStore{
+		"memory": NewMemoryStore(),
+		"bolt":   bolt,
+	}
+}
+
+func TestRunStore(t *testing.T) {
+	started := time.Date(2020, 5, 4, 10, 0, 0, 0, time.UTC)
+
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			require.NoError(store.Save(testRun("first", started)))
+			require.NoError(store.Save(testRun("second", started.Add(time.Hour))))
+
+			run, err := store.Get("first")
+			require.NoError(err)
+			assert.Equal(t, "ob-v3.1-ozone", run.DiscoveryModel.DiscoveryModel.Name)
+			assert.Equal(t, "https://aspsp.example.com", run.Config["resource_base_url"])
+			require.Len(run.Results, 2)
+			assert.Equal(t, []string{"status code"}, run.Results[1].Fail)
+
+			summaries, err := store.List()
+			require.NoError(err)
+			require.Len(summaries, 2)
+			assert.Equal(t, "second", summaries[0].ID)
+			assert.Equal(t, Summary{
+				ID:       "first",
+				Name:     "ob-v3.1-ozone",
+				Status:   StatusCompleted,
+				Started:  started,
+				Finished: started.Add(90 * time.Second),
+				Duration: "1m30s",
+				Tests:    2,
+				Passed:   1,
+				Failed:   1,
+			}, summaries[1])
+
+			require.NoError(store.Delete("first"))
+			_, err = store.Get("first")
+			assert.Equal(t, ErrNotFound, err)
+			assert.Equal(t, ErrNotFound, store.Delete("first"))
+
+			summaries, err = store.List()
+			require.NoError(err)
+			require.Len(summaries, 1)
+		})
+	}
+}
+
+func TestBoltStoreKeepsRunsAcrossRestarts(t *testing.T) {
+	require := require.New(t)
+	path := filepath.Join(t.TempDir(), "runs.db")
+
+	store, err := NewBoltStore(path)
+	require.NoError(err)
+	require.NoError(store.Save(testRun("first", time.Now())))
+	require.NoError(store.Close())
+
+	store, err = NewBoltStore(path)
+	require.NoError(err)
+	defer store.Close()
+
+	run, err := store.Get("first")
+	require.NoError(err)
+	assert.Equal(t, "first", run.ID)
+}
+
+func TestRunStoreCheckpoint(t *testing.T) {
+	started := time.Date(2020, 5, 4, 10, 0, 0, 0, time.UTC)
+
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			run := testRun("first", started)
+			run.Status = StatusRunning
+			run.Results = nil
+			require.NoError(store.Save(run))
+
+			_, err := store.LoadCheckpoint("first")
+			assert.Equal(t, ErrNoCheckpoint, err)
+			assert.Equal(t, ErrNotFound, store.Checkpoint("unknown", Result{}, Checkpoint{}))
+
+			checkpoint := Checkpoint{
+				Context: map[string]interface{}{"Token001": "a5c1f1fa"},
+				Tokens:  []Token{{Name: "Token001", Expires: started.Add(time.Hour)}},
+			}
+			require.NoError(store.Checkpoint("first", NewResult(results.TestCase{Id: "OB-301-ACC-120382", Pass: true}), checkpoint))
+			require.NoError(store.Checkpoint("first", NewResult(results.TestCase{Id: "OB-301-ACC-811741"}), checkpoint))
+
+			saved, err := store.Get("first")
+			require.NoError(err)
+			require.Len(saved.Results, 2)
+			assert.Equal(t, "OB-301-ACC-811741", saved.Results[1].ID)
+
+			summaries, err := store.List()
+			require.NoError(err)
+			assert.Equal(t, 1, summaries[0].Passed)
+			assert.Equal(t, 1, summaries[0].Failed)
+
+			loaded, err := store.LoadCheckpoint("first")
+			require.NoError(err)
+			assert.Equal(t, "a5c1f1fa", loaded.Context["Token001"])
+			assert.Equal(t, []string{"Token001"}, loaded.ExpiredTokens(started.Add(2*time.Hour)))
+			assert.Empty(t, loaded.ExpiredTokens(started))
+
+			saved.Status = StatusCompleted
+			require.NoError(store.Save(saved))
+			_, err = store.LoadCheckpoint("first")
+			assert.Equal(t, ErrNoCheckpoint, err)
+		})
+	}
+}
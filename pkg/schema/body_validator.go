@@ -31,6 +31,10 @@ func (v bodyValidator) Validate(r HTTPResponse) ([]Failure, error) {
 	return v.validate(r, body)
 }
 
+func (v bodyValidator) ValidateRequest(r HTTPRequest) ([]Failure, error) {
+	return nil, nil
+}
+
 func (v bodyValidator) IsRequestProperty(method, path, propertpath string) (bool, string, error) {
 	return false, "", nil
 }
@@ -58,17 +62,23 @@ func (v bodyValidator) validate(r HTTPResponse, body []byte) ([]Failure, error)
 	val := validate.NewSchemaValidator(response.Schema, v.finder.doc, "", strfmt.Default)
 	result := val.Validate(data)
 	if result.HasErrors() {
-		return mapToFailures(result), nil
+		return mapToFailures(result, data), nil
 	}
 
 	return nil, nil
 }
 
-// mapToFailures maps between swagger error and this package Failure object
-func mapToFailures(result *validate.Result) []Failure {
+// mapToFailures maps between swagger error and this package Failure object, `data` is the body validated
+func mapToFailures(result *validate.Result, data interface{}) []Failure {
 	failures := []Failure{}
+	reported := map[string]bool{}
 	for _, err := range result.Errors {
-		failures = append(failures, newFailure(err.Error()))
+		for _, failure := range swaggerFailures(err, data) {
+			if !reported[failure.Message] {
+				reported[failure.Message] = true
+				failures = append(failures, failure)
+			}
+		}
 	}
 	return failures
 }
@@ -48,9 +48,9 @@ func TestBodyValidator_Validate_ReturnsFailures(t *testing.T) {
 	require.NoError(t, err)
 	assert.Len(t, failures, 3)
 	expected := []Failure{
-		{Message: ".Data in body is required"},
-		{Message: ".Links in body is required"},
-		{Message: ".Meta in body is required"},
+		{Message: "/Data: required: expected a value, got none", Pointer: "/Data", Rule: "required", Expected: "a value", Actual: "none"},
+		{Message: "/Links: required: expected a value, got none", Pointer: "/Links", Rule: "required", Expected: "a value", Actual: "none"},
+		{Message: "/Meta: required: expected a value, got none", Pointer: "/Meta", Rule: "required", Expected: "a value", Actual: "none"},
 	}
 	assert.Equal(t, expected, failures)
 }
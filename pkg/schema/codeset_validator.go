@@ -0,0 +1,190 @@
+package schema
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// codeList - the rule of failures for values outside the code list of their field
+const codeList = "codeList"
+
+// obNamespace - the namespace of the codes OB defines in namespaced code lists
+const obNamespace = "UK.OBIE."
+
+// bundledCodeSets - the OB external code lists the suite ships with, one file per version they changed in
+//
+//go:embed codesets
+var bundledCodeSets embed.FS
+
+// CodeSets - the OB external code lists of a version and the response fields that use them
+type CodeSets struct {
+	Version string `json:"version"`
+	// CodeLists - the code lists by name, e.g. `OBExternalAccountSubType1Code`
+	CodeLists map[string]CodeList `json:"codeLists"`
+	// Fields - the name of the code list of each field, by JSON pointer with `*` for any token,
+	// e.g. `/Data/Account/*/AccountSubType`
+	Fields map[string]string `json:"fields"`
+}
+
+// CodeList - the codes of an OB external code list
+type CodeList struct {
+	Codes []string `json:"codes"`
+	// Namespaced - whether ASPSPs may extend the list with codes in their own namespace, any code
+	// outside the `UK.OBIE.` namespace is then allowed
+	Namespaced bool `json:"namespaced,omitempty"`
+}
+
+// LoadCodeSets - the code lists for `version`: the vendored `<dir>/<version>/codesets.json` when
+// SetSpecDir was given one, else the bundled code lists of the latest version not after `version`
+func LoadCodeSets(version string) (CodeSets, error) {
+	if specDir != "" {
+		filename := filepath.Join(specDir, version, "codesets.json")
+		if data, err := ioutil.ReadFile(filename); err == nil {
+			logrus.WithFields(logrus.Fields{
+				"version":  version,
+				"codesets": filename,
+			}).Info("validating against code lists in place of the bundled ones")
+			return parseCodeSets(data, filename)
+		} else if !os.IsNotExist(err) {
+			return CodeSets{}, errors.Wrapf(err, "schema: opening code sets file, filename=%q", filename)
+		}
+	}
+
+	filename, err := bundledCodeSetsFor(version)
+	if err != nil {
+		return CodeSets{}, err
+	}
+	data, err := fs.ReadFile(bundledCodeSets, filename)
+	if err != nil {
+		return CodeSets{}, errors.Wrapf(err, "schema: opening code sets file, filename=%q", filename)
+	}
+	return parseCodeSets(data, filename)
+}
+
+// bundledCodeSetsFor - the bundled code sets file of the latest version not after `version`
+func bundledCodeSetsFor(version string) (string, error) {
+	wanted, err := semver.ParseTolerant(version)
+	if err != nil {
+		return "", errors.Wrapf(err, "schema: parsing version number failed, version=%q", version)
+	}
+	files, err := fs.ReadDir(bundledCodeSets, "codesets")
+	if err != nil {
+		return "", errors.Wrap(err, "schema: opening code sets folder failed")
+	}
+
+	filename := ""
+	var latest semver.Version
+	for _, f := range files {
+		fileVersion, err := semver.ParseTolerant(strings.TrimSuffix(f.Name(), ".json"))
+		if err != nil || fileVersion.GT(wanted) || (filename != "" && fileVersion.LTE(latest)) {
+			continue
+		}
+		filename, latest = "codesets/"+f.Name(), fileVersion
+	}
+	if filename == "" {
+		return "", fmt.Errorf("schema: could not find code sets for version %s", version)
+	}
+	return filename, nil
+}
+
+func parseCodeSets(data []byte, filename string) (CodeSets, error) {
+	codeSets := CodeSets{}
+	if err := json.Unmarshal(data, &codeSets); err != nil {
+		return CodeSets{}, errors.Wrapf(err, "schema: parsing code sets file, filename=%q", filename)
+	}
+	for pointer, name := range codeSets.Fields {
+		if _, ok := codeSets.CodeLists[name]; !ok {
+			return CodeSets{}, fmt.Errorf("schema: field %s uses code list %s not in %s", pointer, name, filename)
+		}
+	}
+	return codeSets, nil
+}
+
+// NewCodeSetValidator - returns a validator reporting response fields with codes outside the OB
+// external code lists of `version`, except at `extensions`, JSON pointers as for NewStrictValidator
+func NewCodeSetValidator(version string, extensions []string) (Validator, error) {
+	codeSets, err := LoadCodeSets(version)
+	if err != nil {
+		return nil, err
+	}
+	return codeSetValidator{codeSets: codeSets, extensions: extensions}, nil
+}
+
+// codeSetValidator implements a validator that checks codes in response bodies against code lists
+type codeSetValidator struct {
+	codeSets   CodeSets
+	extensions []string
+}
+
+func (v codeSetValidator) Validate(r HTTPResponse) ([]Failure, error) {
+	if r.Body == nil || r.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		// not JSON, left to the schema validator to report
+		return nil, nil
+	}
+	return v.failures(data, ""), nil
+}
+
+// failures - the codes in `data` at `pointer` outside the code list of their field
+func (v codeSetValidator) failures(data interface{}, pointer string) []Failure {
+	if isExtension(pointer, v.extensions) {
+		return nil
+	}
+	failures := []Failure{}
+	switch value := data.(type) {
+	case map[string]interface{}:
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			failures = append(failures, v.failures(value[name], pointer+toPointer([]string{name}))...)
+		}
+	case []interface{}:
+		for i, item := range value {
+			failures = append(failures, v.failures(item, fmt.Sprintf("%s/%d", pointer, i))...)
+		}
+	case string:
+		for field, name := range v.codeSets.Fields {
+			if !isExtension(pointer, []string{field}) {
+				continue
+			}
+			list := v.codeSets.CodeLists[name]
+			if contains(list.Codes, value) || (list.Namespaced && !strings.HasPrefix(value, obNamespace)) {
+				continue
+			}
+			failures = append(failures, newValueFailure(pointer, codeList, "a code in "+name, describe(value), ""))
+		}
+	}
+	return failures
+}
+
+// ValidateRequest - here to satisfy Validator interface
+func (v codeSetValidator) ValidateRequest(r HTTPRequest) ([]Failure, error) {
+	return nil, nil
+}
+
+// IsRequestProperty - here to satisfy Validator interface
+func (v codeSetValidator) IsRequestProperty(method, path, propertpath string) (bool, string, error) {
+	return false, "", nil
+}
@@ -0,0 +1,107 @@
+package schema
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const transactionsWithCodes = `{
+	"Data": {
+		"Transaction": [{
+			"AccountId": "500000000000000000000001",
+			"BankTransactionCode": {"Code": "RCDT", "SubCode": "ESCT"}
+		}, {
+			"AccountId": "500000000000000000000001",
+			"BankTransactionCode": {"Code": "CounterTransactions", "SubCode": "ESCT"}
+		}]
+	}
+}`
+
+func TestLoadCodeSetsPicksLatestBundledVersion(t *testing.T) {
+	codeSets, err := LoadCodeSets("v3.1.7")
+	require.NoError(t, err)
+	assert.Equal(t, "v3.0.0", codeSets.Version)
+
+	codeSets, err = LoadCodeSets("v3.1.10")
+	require.NoError(t, err)
+	assert.Equal(t, "v3.1.8", codeSets.Version)
+	assert.Contains(t, codeSets.Fields, "/Data/PaymentStatus/*/StatusDetail/StatusReason")
+
+	_, err = LoadCodeSets("v2.0.0")
+	assert.EqualError(t, err, "schema: could not find code sets for version v2.0.0")
+}
+
+func TestLoadCodeSetsVendored(t *testing.T) {
+	dir, err := ioutil.TempDir("", "codesets")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "v3.1.10"), 0755))
+	codeSets := `{"version": "v3.1.10-rc1", "codeLists": {"Codes": {"codes": ["A"]}}, "fields": {"/Data/Code": "Codes"}}`
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "v3.1.10", "codesets.json"), []byte(codeSets), 0644))
+	SetSpecDir(dir)
+	defer SetSpecDir("")
+
+	loaded, err := LoadCodeSets("v3.1.10")
+	require.NoError(t, err)
+	assert.Equal(t, "v3.1.10-rc1", loaded.Version)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "v3.1.10", "codesets.json"), []byte(`{"fields": {"/Data/Code": "Codes"}}`), 0644))
+	_, err = LoadCodeSets("v3.1.10")
+	assert.Error(t, err)
+}
+
+func TestCodeSetValidator(t *testing.T) {
+	validator, err := NewCodeSetValidator("v3.1.10", nil)
+	require.NoError(t, err)
+
+	failures, err := validator.Validate(accountsResponse(accountsWithDivergence))
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+
+	failures, err = validator.Validate(accountsResponse(strings.Replace(accountsWithDivergence, `"CurrentAccount"`, `"Current"`, 1)))
+	require.NoError(t, err)
+	assert.Equal(t, []Failure{{
+		Message:  `/Data/Account/0/AccountSubType: codeList: expected a code in OBExternalAccountSubType1Code, got "Current"`,
+		Pointer:  "/Data/Account/0/AccountSubType",
+		Rule:     "codeList",
+		Expected: "a code in OBExternalAccountSubType1Code",
+		Actual:   `"Current"`,
+	}}, failures)
+
+	failures, err = validator.Validate(HTTPResponse{Body: strings.NewReader(transactionsWithCodes), StatusCode: http.StatusOK})
+	require.NoError(t, err)
+	require.Len(t, failures, 1)
+	assert.Equal(t, "/Data/Transaction/1/BankTransactionCode/Code", failures[0].Pointer)
+}
+
+func TestCodeSetValidatorExtensions(t *testing.T) {
+	validator, err := NewCodeSetValidator("v3.1.10", []string{"/Data/Account/*/AccountSubType"})
+	require.NoError(t, err)
+
+	failures, err := validator.Validate(accountsResponse(strings.Replace(accountsWithDivergence, `"CurrentAccount"`, `"Current"`, 1)))
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+}
+
+func TestCodeSetValidatorNamespacedCodes(t *testing.T) {
+	validator, err := NewCodeSetValidator("v3.1.10", nil)
+	require.NoError(t, err)
+
+	for code, valid := range map[string]bool{
+		"UK.OBIE.OtherReason":   true,
+		"UK.BANK.CustomReason":  true,
+		"UK.OBIE.UnknownReason": false,
+	} {
+		body := `{"Data": {"StatusReason": "` + code + `"}}`
+		failures, err := validator.Validate(HTTPResponse{Body: strings.NewReader(body), StatusCode: http.StatusOK})
+		require.NoError(t, err)
+		assert.Equal(t, valid, len(failures) == 0, code)
+	}
+}
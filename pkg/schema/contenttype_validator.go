@@ -58,6 +58,11 @@ func (v contentTypeValidator) Validate(r HTTPResponse) ([]Failure, error) {
 
 }
 
+// here to satisfy Validator interface
+func (v contentTypeValidator) ValidateRequest(r HTTPRequest) ([]Failure, error) {
+	return nil, nil
+}
+
 // here to satisfy Validator interface
 func (v contentTypeValidator) IsRequestProperty(method, path, propertpath string) (bool, string, error) {
 	return false, "", nil
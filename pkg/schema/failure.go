@@ -0,0 +1,437 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	openapierrors "github.com/go-openapi/errors"
+)
+
+// maxActualLength - longer actual values are cut short in failure messages
+const maxActualLength = 80
+
+// Failure represents a validation failure
+type Failure struct {
+	Message string
+	// Pointer - JSON pointer (RFC 6901) to the value in the body that failed, empty for the whole
+	// body or when the failure is not about the body
+	Pointer string
+	// Parameter - the request header or query parameter that failed, as `header x-fapi-financial-id`
+	// or `query fromBookingDateTime`, empty when the failure is not about one
+	Parameter string
+	// Rule - the schema keyword that failed, e.g. required, type, pattern or enum
+	Rule     string
+	Expected string
+	Actual   string
+}
+
+func newFailure(message string) Failure {
+	return Failure{
+		Message: message,
+	}
+}
+
+// newValueFailure - a failure of `rule` for the value at `pointer`. The message reads
+// `<pointer>: <rule>: expected <expected>, got <actual>`, or gives `reason` when what was
+// expected is not known.
+func newValueFailure(pointer, rule, expected, actual, reason string) Failure {
+	location := pointer
+	if location == "" {
+		location = "(body)"
+	}
+	message := fmt.Sprintf("%s: %s: expected %s, got %s", location, rule, expected, actual)
+	if expected == "" {
+		message = fmt.Sprintf("%s: %s: %s", location, rule, reason)
+	}
+	return Failure{
+		Message:  message,
+		Pointer:  pointer,
+		Rule:     rule,
+		Expected: expected,
+		Actual:   actual,
+	}
+}
+
+// inParameter - `failure`, found validating the value of request header or query parameter
+// `parameter` on its own, as a failure of that parameter
+func inParameter(failure Failure, parameter string) Failure {
+	failure.Parameter = parameter
+	if strings.HasPrefix(failure.Message, "(body):") {
+		failure.Message = parameter + strings.TrimPrefix(failure.Message, "(body)")
+	} else {
+		failure.Message = parameter + ": " + failure.Message
+	}
+	return failure
+}
+
+// toPointer - the JSON pointer of a path given as its tokens
+func toPointer(tokens []string) string {
+	pointer := ""
+	for _, token := range tokens {
+		token = strings.Replace(token, "~", "~0", -1)
+		token = strings.Replace(token, "/", "~1", -1)
+		pointer += "/" + token
+	}
+	return pointer
+}
+
+// valueAt - the value at JSON pointer `pointer` in `data`
+func valueAt(data interface{}, pointer string) (interface{}, bool) {
+	if pointer == "" {
+		return data, true
+	}
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.Replace(token, "~1", "/", -1)
+		token = strings.Replace(token, "~0", "~", -1)
+		switch value := data.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if data, ok = value[token]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(value) {
+				return nil, false
+			}
+			data = value[index]
+		default:
+			return nil, false
+		}
+	}
+	return data, true
+}
+
+// describe - `value` as JSON, cut short when long
+func describe(value interface{}) string {
+	encoded := toJSON(value)
+	if len(encoded) > maxActualLength {
+		return encoded[:maxActualLength] + "..."
+	}
+	return encoded
+}
+
+func toJSON(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(encoded)
+}
+
+var unsupportedProperty = regexp.MustCompile(`^property "(.*)" is unsupported$`)
+
+// oas3Failures - a failure for each schema error in `err`, as returned by kin-openapi's multi-error validation
+func oas3Failures(err error) []Failure {
+	switch err := err.(type) {
+	case openapi3.MultiError:
+		failures := []Failure{}
+		for _, e := range err {
+			failures = append(failures, oas3Failures(e)...)
+		}
+		return failures
+	case *openapi3.SchemaError:
+		return []Failure{oas3SchemaFailure(err)}
+	}
+	return []Failure{newFailure(err.Error())}
+}
+
+func oas3SchemaFailure(err *openapi3.SchemaError) Failure {
+	pointer := toPointer(err.JSONPointer())
+	rule := err.SchemaField
+	schema := err.Schema
+	expected := ""
+	actual := describe(err.Value)
+
+	switch rule {
+	case "required":
+		expected, actual = "a value", "none"
+	case "properties":
+		if match := unsupportedProperty.FindStringSubmatch(err.Reason); match != nil {
+			pointer += toPointer([]string{match[1]})
+			rule, expected, actual = "additionalProperties", "no such property", "a value"
+		}
+	case "nullable":
+		expected, actual = "a value", "null"
+	case "type":
+		expected = schema.Type
+	case "format":
+		expected = schema.Format
+	case "pattern":
+		expected = schema.Pattern
+	case "enum":
+		expected = "one of " + toJSON(schema.Enum)
+	case "minLength":
+		expected = fmt.Sprintf("at least %d characters", schema.MinLength)
+	case "maxLength":
+		expected = fmt.Sprintf("at most %d characters", derefUint64(schema.MaxLength))
+	case "minItems":
+		expected = fmt.Sprintf("at least %d items", schema.MinItems)
+	case "maxItems":
+		expected = fmt.Sprintf("at most %d items", derefUint64(schema.MaxItems))
+	case "minimum", "exclusiveMinimum":
+		expected = fmt.Sprintf("at least %v", derefFloat64(schema.Min))
+		if schema.ExclusiveMin {
+			expected = fmt.Sprintf("more than %v", derefFloat64(schema.Min))
+		}
+	case "maximum", "exclusiveMaximum":
+		expected = fmt.Sprintf("at most %v", derefFloat64(schema.Max))
+		if schema.ExclusiveMax {
+			expected = fmt.Sprintf("less than %v", derefFloat64(schema.Max))
+		}
+	}
+	if expected == "" {
+		actual = ""
+	}
+	return newValueFailure(pointer, rule, expected, actual, err.Reason)
+}
+
+func derefUint64(value *uint64) uint64 {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
+
+func derefFloat64(value *float64) float64 {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
+
+// swaggerRules - the schema keyword of each go-openapi validation error code
+var swaggerRules = map[int32]string{
+	openapierrors.InvalidTypeCode:           "type",
+	openapierrors.RequiredFailCode:          "required",
+	openapierrors.TooLongFailCode:           "maxLength",
+	openapierrors.TooShortFailCode:          "minLength",
+	openapierrors.PatternFailCode:           "pattern",
+	openapierrors.EnumFailCode:              "enum",
+	openapierrors.MultipleOfFailCode:        "multipleOf",
+	openapierrors.MaxFailCode:               "maximum",
+	openapierrors.MinFailCode:               "minimum",
+	openapierrors.UniqueFailCode:            "uniqueItems",
+	openapierrors.MaxItemsFailCode:          "maxItems",
+	openapierrors.MinItemsFailCode:          "minItems",
+	openapierrors.NoAdditionalItemsCode:     "additionalItems",
+	openapierrors.TooFewPropertiesCode:      "minProperties",
+	openapierrors.TooManyPropertiesCode:     "maxProperties",
+	openapierrors.UnallowedPropertyCode:     "additionalProperties",
+	openapierrors.FailedAllPatternPropsCode: "patternProperties",
+}
+
+// swaggerBounds - the bound each go-openapi validation message gives for its rule
+var swaggerBounds = map[string]*regexp.Regexp{
+	"type":      regexp.MustCompile(`must be of type ([^\s:,]+)`),
+	"maxLength": regexp.MustCompile(`should be at most (\d+) chars long`),
+	"minLength": regexp.MustCompile(`should be at least (\d+) chars long`),
+	"pattern":   regexp.MustCompile(`should match '(.*)'$`),
+	"maxItems":  regexp.MustCompile(`should have at most (\d+) items`),
+	"minItems":  regexp.MustCompile(`should have at least (\d+) items`),
+}
+
+// swaggerFailures - the failures of go-openapi validation error `err` on the body `data`, one
+// for each value failing the rule, see locate
+func swaggerFailures(err error, data interface{}) []Failure {
+	validation, ok := err.(*openapierrors.Validation)
+	if !ok {
+		return []Failure{newFailure(err.Error())}
+	}
+	rule, ok := swaggerRules[validation.Code()]
+	if !ok {
+		return []Failure{newFailure(err.Error())}
+	}
+
+	tokens := swaggerPath(validation.Name)
+	if rule == "additionalProperties" {
+		tokens = append(tokens, fmt.Sprintf("%v", validation.Value))
+	}
+	bound := ""
+	if pattern, ok := swaggerBounds[rule]; ok {
+		if match := pattern.FindStringSubmatch(err.Error()); match != nil {
+			bound = match[1]
+		}
+	}
+	pointers := locate(data, tokens, "", func(value interface{}, present bool) bool {
+		return violates(rule, bound, validation.Values, value, present)
+	})
+	if len(pointers) == 0 {
+		pointers = []string{toPointer(tokens)}
+	}
+
+	expected := ""
+	switch rule {
+	case "required":
+		expected = "a value"
+	case "additionalProperties":
+		expected = "no such property"
+	case "enum":
+		expected = "one of " + toJSON(validation.Values)
+	case "maximum", "minimum", "multipleOf":
+		expected = swaggerExpected(rule, err.Error(), fmt.Sprintf("%v", validation.Value))
+	default:
+		if bound != "" {
+			expected = swaggerExpected(rule, err.Error(), bound)
+		}
+	}
+
+	failures := []Failure{}
+	for _, pointer := range pointers {
+		actual := ""
+		switch {
+		case expected == "":
+		case rule == "required":
+			actual = "none"
+		case rule == "additionalProperties":
+			actual = "a value"
+		default:
+			if value, ok := valueAt(data, pointer); ok {
+				actual = describe(value)
+			}
+		}
+		failures = append(failures, newValueFailure(pointer, rule, expected, actual, err.Error()))
+	}
+	return failures
+}
+
+// swaggerExpected - `bound` worded as for OAS3 failures
+func swaggerExpected(rule, message, bound string) string {
+	switch rule {
+	case "maxLength":
+		return fmt.Sprintf("at most %s characters", bound)
+	case "minLength":
+		return fmt.Sprintf("at least %s characters", bound)
+	case "maxItems":
+		return fmt.Sprintf("at most %s items", bound)
+	case "minItems":
+		return fmt.Sprintf("at least %s items", bound)
+	case "maximum":
+		if !strings.Contains(message, "or equal to") {
+			return "less than " + bound
+		}
+		return "at most " + bound
+	case "minimum":
+		if !strings.Contains(message, "or equal to") {
+			return "more than " + bound
+		}
+		return "at least " + bound
+	case "multipleOf":
+		return "a multiple of " + bound
+	}
+	return bound
+}
+
+// swaggerPath - the tokens of a go-openapi property name such as `.Data` or `Data.Account.0.Currency`
+func swaggerPath(name string) []string {
+	name = strings.TrimPrefix(name, ".")
+	if name == "" {
+		return nil
+	}
+	return strings.Split(name, ".")
+}
+
+// locate - the JSON pointers in `data` of the values at `tokens` failing `violated`. go-openapi
+// leaves the index of array items out of the names of the properties failing validation, and
+// reports items failing in the same way once, so each item of an array met where a property was
+// expected is searched.
+func locate(data interface{}, tokens []string, prefix string, violated func(value interface{}, present bool) bool) []string {
+	if len(tokens) == 0 {
+		if violated(data, true) {
+			return []string{prefix}
+		}
+		return nil
+	}
+	pointer := prefix + toPointer(tokens[:1])
+	switch value := data.(type) {
+	case map[string]interface{}:
+		child, ok := value[tokens[0]]
+		if !ok {
+			if len(tokens) == 1 && violated(nil, false) {
+				return []string{pointer}
+			}
+			return nil
+		}
+		return locate(child, tokens[1:], pointer, violated)
+	case []interface{}:
+		if index, err := strconv.Atoi(tokens[0]); err == nil && index >= 0 && index < len(value) {
+			return locate(value[index], tokens[1:], pointer, violated)
+		}
+		pointers := []string{}
+		for i, item := range value {
+			pointers = append(pointers, locate(item, tokens, prefix+"/"+strconv.Itoa(i), violated)...)
+		}
+		return pointers
+	}
+	return nil
+}
+
+// violates - whether `value` fails `rule` with `bound`, or `values` for enum. Rules that cannot be
+// checked here are taken to fail for any value that is present.
+func violates(rule, bound string, values []interface{}, value interface{}, present bool) bool {
+	switch rule {
+	case "required":
+		return !present
+	case "maxLength", "minLength":
+		s, ok := value.(string)
+		limit, err := strconv.Atoi(bound)
+		if !ok || err != nil {
+			return present
+		}
+		if rule == "maxLength" {
+			return utf8.RuneCountInString(s) > limit
+		}
+		return utf8.RuneCountInString(s) < limit
+	case "maxItems", "minItems":
+		items, ok := value.([]interface{})
+		limit, err := strconv.Atoi(bound)
+		if !ok || err != nil {
+			return present
+		}
+		if rule == "maxItems" {
+			return len(items) > limit
+		}
+		return len(items) < limit
+	case "pattern":
+		s, ok := value.(string)
+		pattern, err := regexp.Compile(bound)
+		if !ok || err != nil {
+			return present
+		}
+		return !pattern.MatchString(s)
+	case "enum":
+		for _, v := range values {
+			if toJSON(v) == toJSON(value) {
+				return false
+			}
+		}
+		return present
+	case "type":
+		return present && bound != "" && !hasType(value, bound)
+	}
+	return present
+}
+
+// hasType - whether `value`, decoded from JSON, is of JSON schema type `name`
+func hasType(value interface{}, name string) bool {
+	switch value := value.(type) {
+	case string:
+		return name == "string"
+	case float64:
+		return name == "number" || (name == "integer" && value == float64(int64(value)))
+	case bool:
+		return name == "boolean"
+	case map[string]interface{}:
+		return name == "object"
+	case []interface{}:
+		return name == "array"
+	case nil:
+		return name == "null"
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package schema
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-openapi/loads"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// accountsWithViolations - two accounts, the first without an AccountId and with a lower case
+// currency, the second with a currency that is too long
+const accountsWithViolations = `{
+	"Data": {
+		"Account": [
+			{"Currency": "gbp", "AccountType": "Personal", "AccountSubType": "CurrentAccount"},
+			{"AccountId": "500000000000000000000002", "Currency": "GBPX", "AccountType": "Personal", "AccountSubType": "CurrentAccount"}
+		]
+	},
+	"Links": {"Self": "http://localhost/accounts"},
+	"Meta": {"TotalPages": 1}
+}`
+
+func TestOpenAPI3ValidatorReportsEveryViolation(t *testing.T) {
+	validator, err := NewRawOpenAPI3Validator("Account and Transaction API Specification", "v3.1.8")
+	require.NoError(t, err)
+
+	failures, err := validator.Validate(HTTPResponse{
+		Method:     "GET",
+		Path:       "/open-banking/v3.1/aisp/accounts",
+		StatusCode: http.StatusOK,
+		Body:       strings.NewReader(accountsWithViolations),
+		Header:     http.Header{"Content-Type": []string{"application/json; charset=utf-8"}},
+	})
+
+	require.NoError(t, err)
+	byPointer := map[string]Failure{}
+	for _, failure := range failures {
+		byPointer[failure.Pointer+" "+failure.Rule] = failure
+	}
+	assert.Equal(t, Failure{
+		Message:  "/Data/Account/0/AccountId: required: expected a value, got none",
+		Pointer:  "/Data/Account/0/AccountId",
+		Rule:     "required",
+		Expected: "a value",
+		Actual:   "none",
+	}, byPointer["/Data/Account/0/AccountId required"])
+	assert.Equal(t, Failure{
+		Message:  `/Data/Account/0/Currency: pattern: expected ^[A-Z]{3,3}$, got "gbp"`,
+		Pointer:  "/Data/Account/0/Currency",
+		Rule:     "pattern",
+		Expected: "^[A-Z]{3,3}$",
+		Actual:   `"gbp"`,
+	}, byPointer["/Data/Account/0/Currency pattern"])
+	assert.Contains(t, byPointer, "/Data/Account/1/Currency pattern")
+}
+
+func TestSwaggerValidatorReportsEveryViolation(t *testing.T) {
+	doc, err := loads.Spec("spec/v3.1.0/account-info-swagger.flattened.json")
+	require.NoError(t, err)
+	validator, err := newValidator(doc)
+	require.NoError(t, err)
+
+	failures, err := validator.Validate(HTTPResponse{
+		Method:     "GET",
+		Path:       "/accounts",
+		StatusCode: http.StatusOK,
+		Body:       strings.NewReader(accountsWithViolations),
+		Header:     http.Header{"Content-Type": []string{"application/json; charset=utf-8"}},
+	})
+
+	require.NoError(t, err)
+	byPointer := map[string]Failure{}
+	for _, failure := range failures {
+		byPointer[failure.Pointer+" "+failure.Rule] = failure
+	}
+	assert.Equal(t, Failure{
+		Message:  "/Data/Account/0/AccountId: required: expected a value, got none",
+		Pointer:  "/Data/Account/0/AccountId",
+		Rule:     "required",
+		Expected: "a value",
+		Actual:   "none",
+	}, byPointer["/Data/Account/0/AccountId required"])
+	assert.Equal(t, Failure{
+		Message:  `/Data/Account/0/Currency: pattern: expected ^[A-Z]{3,3}$, got "gbp"`,
+		Pointer:  "/Data/Account/0/Currency",
+		Rule:     "pattern",
+		Expected: "^[A-Z]{3,3}$",
+		Actual:   `"gbp"`,
+	}, byPointer["/Data/Account/0/Currency pattern"])
+	assert.Contains(t, byPointer, "/Data/Account/1/Currency pattern")
+}
+
+func TestLocateFindsItemsOfArrays(t *testing.T) {
+	data := map[string]interface{}{
+		"Data": map[string]interface{}{
+			"Transaction": []interface{}{
+				map[string]interface{}{"TransactionReference": "ok"},
+				map[string]interface{}{"TransactionReference": ""},
+				map[string]interface{}{},
+			},
+		},
+	}
+	tooShort := func(value interface{}, present bool) bool {
+		return violates("minLength", "1", nil, value, present)
+	}
+	missing := func(value interface{}, present bool) bool {
+		return violates("required", "", nil, value, present)
+	}
+
+	assert.Equal(t, []string{"/Data/Transaction/1/TransactionReference"},
+		locate(data, []string{"Data", "Transaction", "TransactionReference"}, "", tooShort))
+	assert.Equal(t, []string{"/Data/Transaction/2/TransactionReference"},
+		locate(data, []string{"Data", "Transaction", "TransactionReference"}, "", missing))
+	assert.Equal(t, "/a~1b/c~0d", toPointer([]string{"a/b", "c~d"}))
+}
@@ -0,0 +1,136 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Rules checked by ValidateHeaders
+const (
+	HeaderRuleInteractionIDPlayback = "x-fapi-interaction-id playback"
+	HeaderRuleInteractionIDFormat   = "x-fapi-interaction-id format"
+	HeaderRuleSignature             = "x-jws-signature"
+	HeaderRuleContentType           = "Content-Type charset"
+	HeaderRuleCacheControl          = "Cache-Control"
+	HeaderRuleRetryAfter            = "Retry-After"
+)
+
+// rfc4122UUID - the textual form of an RFC 4122 UUID
+var rfc4122UUID = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[1-5][0-9a-fA-F]{3}-[89abAB][0-9a-fA-F]{3}-[0-9a-fA-F]{12}$`)
+
+// HeaderCheck - the outcome of one OB header rule for a response
+type HeaderCheck struct {
+	Rule   string `json:"rule"`
+	Pass   bool   `json:"pass"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func newHeaderCheck(rule string, err error) HeaderCheck {
+	check := HeaderCheck{Rule: rule, Pass: err == nil}
+	if err != nil {
+		check.Detail = err.Error()
+	}
+	return check
+}
+
+// ValidateHeaders - checks the headers of response `r` to `request` against the OB and FAPI rules
+// that apply to it, one check per rule. `signed` is whether the ASPSP must sign the response with
+// x-jws-signature, as it does for the payment and VRP APIs unless JWS is disabled in this run.
+func ValidateHeaders(request HTTPRequest, r HTTPResponse, signed bool) []HeaderCheck {
+	checks := []HeaderCheck{}
+
+	interactionID := r.Header.Get("x-fapi-interaction-id")
+	if sent := request.Header.Get("x-fapi-interaction-id"); sent != "" {
+		var err error
+		if interactionID != sent {
+			err = fmt.Errorf("expected %q played back, got %q", sent, interactionID)
+		}
+		checks = append(checks, newHeaderCheck(HeaderRuleInteractionIDPlayback, err))
+	}
+	checks = append(checks, newHeaderCheck(HeaderRuleInteractionIDFormat, checkInteractionID(interactionID)))
+
+	if signed && r.StatusCode >= 200 && r.StatusCode < 300 && r.StatusCode != http.StatusNoContent {
+		var err error
+		if r.Header.Get("x-jws-signature") == "" {
+			err = fmt.Errorf("expected a signature on %s %s responses, got none", r.Method, r.Path)
+		}
+		checks = append(checks, newHeaderCheck(HeaderRuleSignature, err))
+	}
+
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		checks = append(checks, newHeaderCheck(HeaderRuleContentType, checkCharset(contentType)))
+	}
+
+	if cacheControl := r.Header.Get("Cache-Control"); cacheControl != "" {
+		checks = append(checks, newHeaderCheck(HeaderRuleCacheControl, checkCacheControl(cacheControl)))
+	}
+
+	if r.StatusCode == http.StatusTooManyRequests {
+		checks = append(checks, newHeaderCheck(HeaderRuleRetryAfter, checkRetryAfter(r.Header.Get("Retry-After"))))
+	}
+	return checks
+}
+
+func checkInteractionID(interactionID string) error {
+	if interactionID == "" {
+		return errors.New("expected an RFC 4122 UUID, got none")
+	}
+	if !rfc4122UUID.MatchString(interactionID) {
+		return fmt.Errorf("expected an RFC 4122 UUID, got %q", interactionID)
+	}
+	return nil
+}
+
+// checkCharset - JSON bodies must be sent with charset=utf-8
+func checkCharset(contentType string) error {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("expected a media type, got %q", contentType)
+	}
+	if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+		return nil
+	}
+	if !strings.EqualFold(params["charset"], "utf-8") {
+		return fmt.Errorf("expected %s; charset=utf-8, got %q", mediaType, contentType)
+	}
+	return nil
+}
+
+// checkCacheControl - responses carry customer data so must not be kept by shared caches
+func checkCacheControl(cacheControl string) error {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name := strings.ToLower(strings.TrimSpace(directive))
+		value := ""
+		if i := strings.Index(name, "="); i >= 0 {
+			name, value = name[:i], strings.Trim(name[i+1:], `"`)
+		}
+		switch name {
+		case "public":
+			return fmt.Errorf("expected responses not to be cached by shared caches, got %q", cacheControl)
+		case "max-age", "s-maxage":
+			if seconds, err := strconv.Atoi(value); err != nil || seconds > 0 {
+				return fmt.Errorf("expected responses not to be cached, got %q", cacheControl)
+			}
+		}
+	}
+	return nil
+}
+
+// checkRetryAfter - a 429 must say when to retry, in seconds or as an HTTP date
+func checkRetryAfter(retryAfter string) error {
+	if retryAfter == "" {
+		return errors.New("expected a delay in seconds or an HTTP date, got none")
+	}
+	if _, err := strconv.ParseUint(retryAfter, 10, 64); err == nil {
+		return nil
+	}
+	if _, err := http.ParseTime(retryAfter); err == nil {
+		return nil
+	}
+	return fmt.Errorf("expected a delay in seconds or an HTTP date, got %q", retryAfter)
+}
@@ -0,0 +1,70 @@
+package schema
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const interactionID = "93bac548-d2de-4546-b106-880a5018460d"
+
+func headerResponse(path string, statusCode int, header ...string) HTTPResponse {
+	r := HTTPResponse{Method: "GET", Path: path, Header: http.Header{}, StatusCode: statusCode}
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	return r
+}
+
+func TestValidateHeadersPasses(t *testing.T) {
+	request := HTTPRequest{Header: http.Header{"X-Fapi-Interaction-Id": []string{interactionID}}}
+	r := headerResponse("/domestic-payments/1", http.StatusOK,
+		"x-fapi-interaction-id", interactionID,
+		"x-jws-signature", "eyJhbGciOiJQUzI1NiJ9..c2lnbmF0dXJl",
+		"Content-Type", "application/json; charset=UTF-8",
+		"Cache-Control", "no-cache, no-store, max-age=0",
+	)
+
+	assert.Equal(t, []HeaderCheck{
+		{Rule: HeaderRuleInteractionIDPlayback, Pass: true},
+		{Rule: HeaderRuleInteractionIDFormat, Pass: true},
+		{Rule: HeaderRuleSignature, Pass: true},
+		{Rule: HeaderRuleContentType, Pass: true},
+		{Rule: HeaderRuleCacheControl, Pass: true},
+	}, ValidateHeaders(request, r, true))
+}
+
+func TestValidateHeadersFailures(t *testing.T) {
+	request := HTTPRequest{Header: http.Header{"X-Fapi-Interaction-Id": []string{interactionID}}}
+	r := headerResponse("/domestic-payments/1", http.StatusOK,
+		"x-fapi-interaction-id", "1234",
+		"Content-Type", "application/json",
+		"Cache-Control", "public, max-age=3600",
+	)
+
+	assert.Equal(t, []HeaderCheck{
+		{Rule: HeaderRuleInteractionIDPlayback, Detail: `expected "93bac548-d2de-4546-b106-880a5018460d" played back, got "1234"`},
+		{Rule: HeaderRuleInteractionIDFormat, Detail: `expected an RFC 4122 UUID, got "1234"`},
+		{Rule: HeaderRuleSignature, Detail: "expected a signature on GET /domestic-payments/1 responses, got none"},
+		{Rule: HeaderRuleContentType, Detail: `expected application/json; charset=utf-8, got "application/json"`},
+		{Rule: HeaderRuleCacheControl, Detail: `expected responses not to be cached by shared caches, got "public, max-age=3600"`},
+	}, ValidateHeaders(request, r, true))
+}
+
+func TestValidateHeadersRulesThatApply(t *testing.T) {
+	r := headerResponse("/accounts", http.StatusOK, "x-fapi-interaction-id", interactionID)
+	assert.Equal(t, []HeaderCheck{{Rule: HeaderRuleInteractionIDFormat, Pass: true}}, ValidateHeaders(HTTPRequest{}, r, false),
+		"no playback check when the suite sent no x-fapi-interaction-id, no signature check for unsigned responses")
+
+	r = headerResponse("/domestic-payments/1", http.StatusNoContent, "x-fapi-interaction-id", interactionID)
+	assert.Len(t, ValidateHeaders(HTTPRequest{}, r, true), 1, "no signature check on a 204")
+
+	r = headerResponse("/accounts", http.StatusTooManyRequests, "x-fapi-interaction-id", interactionID)
+	assert.Equal(t, HeaderCheck{Rule: HeaderRuleRetryAfter, Detail: "expected a delay in seconds or an HTTP date, got none"}, ValidateHeaders(HTTPRequest{}, r, true)[1])
+
+	for _, retryAfter := range []string{"120", "Wed, 21 Oct 2026 07:28:00 GMT"} {
+		r.Header.Set("Retry-After", retryAfter)
+		assert.True(t, ValidateHeaders(HTTPRequest{}, r, true)[1].Pass, retryAfter)
+	}
+}
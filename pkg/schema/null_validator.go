@@ -13,6 +13,11 @@ func (v NullValidator) Validate(r HTTPResponse) ([]Failure, error) {
 	return nil, nil
 }
 
+// ValidateRequest - nop
+func (v NullValidator) ValidateRequest(r HTTPRequest) ([]Failure, error) {
+	return nil, nil
+}
+
 // IsRequestProperty - nop
 func (v NullValidator) IsRequestProperty(method, path, propertpath string) (bool, string, error) {
 	return false, "", nil
@@ -3,8 +3,10 @@ package schema
 import (
 	"context"
 	"fmt"
+	"io/fs"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
@@ -93,13 +95,17 @@ func getRouterForSpec(specName, version string) (routers.Router, *openapi3.T, er
 
 	filename := fmt.Sprintf(filenamePattern, version)
 
-	doc, err := loadSpecFromFile(filename)
+	doc, err := loadBundledSpec(filename)
 
 	if err != nil {
 		return nil, nil, fmt.Errorf("cannot Load OpenApi Spec from file %s, %s", filename, err)
 	}
 
-	err = doc.Validate(context.Background())
+	return newRouter(specName, filename, doc)
+}
+
+func newRouter(specName, filename string, doc *openapi3.T) (routers.Router, *openapi3.T, error) {
+	err := doc.Validate(context.Background())
 	if err != nil {
 		return nil, nil, fmt.Errorf("cannot Load OpenApi Spec from file %s, %s", filename, err)
 	}
@@ -112,22 +118,36 @@ func getRouterForSpec(specName, version string) (routers.Router, *openapi3.T, er
 	return router, doc, nil
 }
 
-func loadSpecFromFile(filename string) (*openapi3.T, error) {
-	prodDir := "pkg/schema/" + filename
-	testDir := "../../pkg/schema/" + filename
-	specPaths := []string{filename, prodDir, testDir}
+func loadBundledSpec(filename string) (*openapi3.T, error) {
+	data, err := fs.ReadFile(bundledSpecs, filename)
+	if err != nil {
+		return nil, err
+	}
+	return openapi3.NewLoader().LoadFromData(data)
+}
 
-	var doc *openapi3.T
-	var err error
+// newOpenAPI3ValidatorFromLocation - a validator for the spec at local path or https URL
+// `location`, which may refer to other files next to it
+func newOpenAPI3ValidatorFromLocation(specName, location string) (Validator, error) {
 	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
 
-	for _, specPath := range specPaths {
-		doc, err = loader.LoadFromFile(specPath)
-		if err == nil {
-			break
-		}
+	var doc *openapi3.T
+	specURL, err := url.Parse(location)
+	if err == nil && specURL.Scheme == "https" {
+		doc, err = loader.LoadFromURI(specURL)
+	} else {
+		doc, err = loader.LoadFromFile(location)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot Load OpenApi Spec from file %s, %s", location, err)
+	}
+
+	router, doc, err := newRouter(specName, location, doc)
+	if err != nil {
+		return nil, err
 	}
-	return doc, err
+	return OpenAPI3Validator{router: router, doc: doc}, nil
 }
 
 func getSpecFilePathPattern(specName string) string {
@@ -146,6 +166,9 @@ func getSpecFilePathPattern(specName string) string {
 	case "OBIE VRP Profile":
 		filename = "spec/%s/variable-recurring-payments-openapi.json"
 
+	case "Dynamic Client Registration API":
+		filename = "spec/dcr/%s/dcr-openapi.json"
+
 	default:
 		filename = ""
 	}
@@ -155,18 +178,7 @@ func getSpecFilePathPattern(specName string) string {
 
 // Validate - validates the response
 func (v OpenAPI3Validator) Validate(r HTTPResponse) ([]Failure, error) {
-	failures := []Failure{}
-
-	serverPath := v.doc.Servers[0].URL
-	var path string
-	serverIndex := strings.Index(r.Path, serverPath)
-	if serverIndex != -1 {
-		path = r.Path[serverIndex:]
-	} else {
-		path = serverPath + r.Path
-	}
-
-	httpReq, err := createHTTPReq(r.Method, path)
+	httpReq, err := createHTTPReq(r.Method, v.serverPath(r.Path))
 	if err != nil {
 		return nil, err
 	}
@@ -193,11 +205,33 @@ func (v OpenAPI3Validator) Validate(r HTTPResponse) ([]Failure, error) {
 
 	// accumulate failures
 	err = v.validateResponse(params)
-	if err != nil {
+	if err == nil {
+		return []Failure{}, nil
+	}
+	responseErr, ok := err.(*openapi3filter.ResponseError)
+	if !ok {
 		return nil, fmt.Errorf("Validate error response:  %s", err.Error())
 	}
+	if responseErr.Err == nil {
+		return []Failure{newFailure(responseErr.Reason)}, nil
+	}
+	if _, ok := responseErr.Err.(*openapi3.SchemaError); ok {
+		return oas3Failures(responseErr.Err), nil
+	}
+	if _, ok := responseErr.Err.(openapi3.MultiError); ok {
+		return oas3Failures(responseErr.Err), nil
+	}
+	return []Failure{newFailure(responseErr.Reason + ": " + responseErr.Err.Error())}, nil
+}
 
-	return failures, nil
+// serverPath - `path` from the spec's server URL on, which the router finds operations by
+func (v OpenAPI3Validator) serverPath(path string) string {
+	serverPath := v.doc.Servers[0].URL
+	serverIndex := strings.Index(path, serverPath)
+	if serverIndex != -1 {
+		return path[serverIndex:]
+	}
+	return serverPath + path
 }
 
 func (v OpenAPI3Validator) validateResponse(params validateParams) error {
@@ -214,7 +248,7 @@ func (v OpenAPI3Validator) validateResponse(params validateParams) error {
 		Options: &openapi3filter.Options{
 			ExcludeRequestBody:    true,
 			IncludeResponseStatus: true,
-			MultiError:            false,
+			MultiError:            true,
 		},
 	}
 
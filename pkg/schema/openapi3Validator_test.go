@@ -88,6 +88,70 @@ func TestVrpRoutes(t *testing.T) {
 	}
 }
 
+func TestDcrRoutes(t *testing.T) {
+	data := []struct {
+		method string
+		url    string
+	}{
+		{"POST", "/register"},
+		{"GET", "/register/10001"},
+		{"PUT", "/register/10002"},
+		{"DELETE", "/register/10003"},
+	}
+
+	validator, err := NewRawOpenAPI3Validator("Dynamic Client Registration API", "v3.3")
+	require.NoError(t, err)
+
+	for _, row := range data {
+		req, err := createHTTPReq(row.method, row.url)
+		require.NoError(t, err)
+		_, _, err = validator.findTestRoute(req)
+		require.NoError(t, err)
+	}
+}
+
+func TestDcrRegistrationResponse(t *testing.T) {
+	validator, err := NewRawOpenAPI3Validator("Dynamic Client Registration API", "v3.3")
+	require.NoError(t, err)
+
+	good := HTTPResponse{
+		Method:     "POST",
+		Path:       "/register",
+		StatusCode: http.StatusCreated,
+		Body:       strings.NewReader(dcrRegistrationResponse),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+	}
+	failures, err := validator.Validate(good)
+	require.NoError(t, err)
+	require.Empty(t, failures)
+
+	bad := HTTPResponse{
+		Method:     "POST",
+		Path:       "/register",
+		StatusCode: http.StatusBadRequest,
+		Body:       strings.NewReader(`{"error":"not_a_registration_error"}`),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+	}
+	failures, err = validator.Validate(bad)
+	require.NoError(t, err)
+	require.Len(t, failures, 1)
+	assert.Equal(t, "/error", failures[0].Pointer)
+	assert.Equal(t, "enum", failures[0].Rule)
+	assert.Equal(t, `"not_a_registration_error"`, failures[0].Actual)
+	assert.Contains(t, failures[0].Expected, `"invalid_redirect_uri"`)
+}
+
+const dcrRegistrationResponse = `{
+	"client_id": "0a1b2c3d",
+	"client_id_issued_at": 1600000000,
+	"token_endpoint_auth_method": "tls_client_auth",
+	"grant_types": ["authorization_code", "client_credentials", "refresh_token"],
+	"software_statement": "eyJhbGciOiJQUzI1NiJ9.e30.c2ln",
+	"id_token_signed_response_alg": "PS256",
+	"request_object_signing_alg": "PS256",
+	"redirect_uris": ["https://127.0.0.1:8443/conformancesuite/callback"]
+}`
+
 func createHTTPReqFromResponse(resp HTTPResponse) (*http.Request, error) {
 	req, err := http.NewRequest(resp.Method, resp.Path, strings.NewReader(""))
 	req.Header = http.Header{"Content-type": []string{"application/json; charset=utf-8"}}
@@ -0,0 +1,62 @@
+package schema
+
+import (
+	"errors"
+	"sort"
+)
+
+// maxPropertyDepth - properties nested deeper are left out of ResponseProperties, which keeps
+// recursive schemas finite
+const maxPropertyDepth = 12
+
+// Property - a property of a response body defined by the spec
+type Property struct {
+	// Path - the property in dot notation with array indexes left out, e.g. `Data.Account.Nickname`,
+	// as the property collector records it
+	Path string
+	// Required - whether every valid response has the property, that is it and each property it is
+	// nested in are required
+	Required bool
+}
+
+// ResponseProperties - the properties of the body of a response with `statusCode` to `method`
+// `path`, as defined by the spec `validator` validates against, sorted by path
+func ResponseProperties(validator Validator, method, path string, statusCode int) ([]Property, error) {
+	responseSchema := responseSchemaOf(validator)
+	if responseSchema == nil {
+		return nil, errors.New("schema: validator does not validate against a spec")
+	}
+	node := responseSchema(HTTPResponse{Method: method, Path: path, StatusCode: statusCode})
+	if node == nil {
+		return nil, ErrNotFound
+	}
+
+	properties := propertiesOf(node, "", true, 0)
+	sort.Slice(properties, func(i, j int) bool {
+		return properties[i].Path < properties[j].Path
+	})
+	return properties, nil
+}
+
+// propertiesOf - the properties of `node`, at `prefix`, which every valid response has when `required`
+func propertiesOf(node schemaNode, prefix string, required bool, depth int) []Property {
+	if depth >= maxPropertyDepth {
+		return nil
+	}
+	if items := node.items(); items != nil {
+		return propertiesOf(items, prefix, required, depth+1)
+	}
+
+	properties := []Property{}
+	requiredNames := node.required()
+	for name, property := range node.properties() {
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		propertyRequired := required && requiredNames[name]
+		properties = append(properties, Property{Path: path, Required: propertyRequired})
+		properties = append(properties, propertiesOf(property, path, propertyRequired, depth+1)...)
+	}
+	return properties
+}
@@ -0,0 +1,41 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/go-openapi/loads"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponsePropertiesOpenAPI3(t *testing.T) {
+	validator, err := NewOpenAPI3Validator("Account and Transaction API Specification", "v3.1.10")
+	require.NoError(t, err)
+
+	properties, err := ResponseProperties(validator, "GET", "/accounts", 200)
+	require.NoError(t, err)
+	assert.Equal(t, Property{Path: "Data", Required: true}, properties[0])
+	assert.Contains(t, properties, Property{Path: "Data.Account.Nickname"})
+	assert.Contains(t, properties, Property{Path: "Data.Account.Servicer.Identification"})
+	// required by accounts, which are optional themselves
+	assert.Contains(t, properties, Property{Path: "Data.Account.AccountId"})
+
+	_, err = ResponseProperties(validator, "GET", "/foobar", 200)
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestResponsePropertiesSwagger(t *testing.T) {
+	doc, err := loads.Spec("spec/v3.1.6/account-info-swagger-flattened.json")
+	require.NoError(t, err)
+	validator, err := newValidator(doc)
+	require.NoError(t, err)
+
+	properties, err := ResponseProperties(NewStrictValidator(validator, nil, nil), "GET", "/accounts/{AccountId}/balances", 200)
+	require.NoError(t, err)
+	assert.Contains(t, properties, Property{Path: "Data.Balance.Amount.Currency", Required: true})
+	assert.Contains(t, properties, Property{Path: "Data.Balance.CreditLine.Amount.Currency"})
+	assert.Contains(t, properties, Property{Path: "Meta.TotalPages"})
+
+	_, err = ResponseProperties(NewNullValidator(), "GET", "/accounts/{AccountId}/balances", 200)
+	assert.Error(t, err)
+}
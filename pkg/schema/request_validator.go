@@ -0,0 +1,237 @@
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/go-openapi/spec"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/validate"
+)
+
+// requestValidator implements a validator that checks the headers, query
+// parameters and body of a request against its operation in the swagger spec
+type requestValidator struct {
+	finder finder
+}
+
+func newRequestValidator(finder finder) Validator {
+	return requestValidator{
+		finder: finder,
+	}
+}
+
+// here to satisfy Validator interface
+func (v requestValidator) Validate(r HTTPResponse) ([]Failure, error) {
+	return nil, nil
+}
+
+// here to satisfy Validator interface
+func (v requestValidator) IsRequestProperty(method, path, propertpath string) (bool, string, error) {
+	return false, "", nil
+}
+
+func (v requestValidator) ValidateRequest(r HTTPRequest) ([]Failure, error) {
+	operation, err := v.finder.Operation(r.Method, r.Path)
+	if err == ErrNotFound {
+		return []Failure{notInSpec(r)}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	failures := []Failure{}
+	for i := range operation.Parameters {
+		param := &operation.Parameters[i]
+		switch param.In {
+		case "header":
+			failures = append(failures, validateParameter(param, r.Header.Get(param.Name))...)
+		case "query":
+			failures = append(failures, validateParameter(param, r.Query.Get(param.Name))...)
+		case "body":
+			bodyFailures, err := v.validateBody(param, r)
+			if err != nil {
+				return nil, err
+			}
+			failures = append(failures, bodyFailures...)
+		}
+	}
+	return failures, nil
+}
+
+func (v requestValidator) validateBody(param *spec.Parameter, r HTTPRequest) ([]Failure, error) {
+	body := []byte{}
+	if r.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(r.Body); err != nil {
+			return nil, err
+		}
+	}
+	if len(body) == 0 {
+		if param.Required {
+			return []Failure{newValueFailure("", "required", "a value", "none", "")}, nil
+		}
+		return nil, nil
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return []Failure{newFailure(fmt.Sprintf("could not unmarshal request body %s", err.Error()))}, nil
+	}
+
+	val := validate.NewSchemaValidator(param.Schema, v.finder.doc, "", strfmt.Default)
+	result := val.Validate(data)
+	if result.HasErrors() {
+		return mapToFailures(result, data), nil
+	}
+	return nil, nil
+}
+
+// validateParameter - the failures of header or query parameter `param` given `value`, empty when
+// the request leaves it out
+func validateParameter(param *spec.Parameter, value string) []Failure {
+	name := param.In + " " + param.Name
+	if value == "" {
+		if param.Required {
+			return []Failure{newParameterFailure(name, "required", "a value", "none", "")}
+		}
+		return nil
+	}
+
+	data, ok := parameterValue(param.Type, value)
+	if !ok {
+		return []Failure{newParameterFailure(name, "type", param.Type, strconv.Quote(value), "")}
+	}
+	schema := &spec.Schema{SchemaProps: spec.SchemaProps{
+		Type:             spec.StringOrArray{param.Type},
+		Format:           param.Format,
+		Enum:             param.Enum,
+		Pattern:          param.Pattern,
+		MaxLength:        param.MaxLength,
+		MinLength:        param.MinLength,
+		Maximum:          param.Maximum,
+		ExclusiveMaximum: param.ExclusiveMaximum,
+		Minimum:          param.Minimum,
+		ExclusiveMinimum: param.ExclusiveMinimum,
+		MultipleOf:       param.MultipleOf,
+	}}
+	result := validate.NewSchemaValidator(schema, nil, "", strfmt.Default).Validate(data)
+	if !result.HasErrors() {
+		return nil
+	}
+	failures := []Failure{}
+	for _, failure := range mapToFailures(result, data) {
+		failures = append(failures, inParameter(failure, name))
+	}
+	return failures
+}
+
+// parameterValue - header or query parameter `value` as a value of swagger type `typeName`
+func parameterValue(typeName, value string) (interface{}, bool) {
+	switch typeName {
+	case "integer":
+		i, err := strconv.ParseInt(value, 10, 64)
+		return i, err == nil
+	case "number":
+		f, err := strconv.ParseFloat(value, 64)
+		return f, err == nil
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		return b, err == nil
+	}
+	return value, true
+}
+
+func newParameterFailure(parameter, rule, expected, actual, reason string) Failure {
+	return inParameter(newValueFailure("", rule, expected, actual, reason), parameter)
+}
+
+func notInSpec(r HTTPRequest) Failure {
+	return newFailure(fmt.Sprintf("%s %s is not defined by the spec", r.Method, r.Path))
+}
+
+// ValidateRequest - validates the headers, query parameters and body of the request against its
+// operation in the spec. Security requirements are left to the ASPSP.
+func (v OpenAPI3Validator) ValidateRequest(r HTTPRequest) ([]Failure, error) {
+	url := v.serverPath(r.Path)
+	if len(r.Query) > 0 {
+		url += "?" + r.Query.Encode()
+	}
+	body := []byte{}
+	if r.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(r.Body); err != nil {
+			return nil, err
+		}
+	}
+	httpReq, err := http.NewRequest(r.Method, url, strings.NewReader(string(body)))
+	if err != nil {
+		return nil, err
+	}
+	if r.Header != nil {
+		httpReq.Header = r.Header
+	}
+
+	route, pathParams, err := v.router.FindRoute(httpReq)
+	if _, ok := err.(*routers.RouteError); ok {
+		return []Failure{notInSpec(r)}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = openapi3filter.ValidateRequest(context.Background(), &openapi3filter.RequestValidationInput{
+		Request:    httpReq,
+		PathParams: pathParams,
+		Route:      route,
+		Options: &openapi3filter.Options{
+			MultiError:         true,
+			AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+		},
+	})
+	if err == nil {
+		return []Failure{}, nil
+	}
+	return oas3RequestFailures(err), nil
+}
+
+// oas3RequestFailures - a failure for each parameter and body error in `err`, as returned by
+// kin-openapi's multi-error request validation
+func oas3RequestFailures(err error) []Failure {
+	switch err := err.(type) {
+	case openapi3.MultiError:
+		failures := []Failure{}
+		for _, e := range err {
+			failures = append(failures, oas3RequestFailures(e)...)
+		}
+		return failures
+	case *openapi3filter.RequestError:
+		if err.Parameter != nil {
+			name := err.Parameter.In + " " + err.Parameter.Name
+			if err.Err == openapi3filter.ErrInvalidRequired {
+				return []Failure{newParameterFailure(name, "required", "a value", "none", "")}
+			}
+			failures := []Failure{}
+			for _, failure := range oas3Failures(err.Err) {
+				failures = append(failures, inParameter(failure, name))
+			}
+			return failures
+		}
+		if err.RequestBody != nil {
+			if err.Err == openapi3filter.ErrInvalidRequired {
+				return []Failure{newValueFailure("", "required", "a value", "none", "")}
+			}
+			switch err.Err.(type) {
+			case *openapi3.SchemaError, openapi3.MultiError:
+				return oas3Failures(err.Err)
+			}
+		}
+	}
+	return []Failure{newFailure(err.Error())}
+}
@@ -0,0 +1,115 @@
+package schema
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-openapi/loads"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// consentWithViolations - an account access consent asking for a permission that does not exist
+const consentWithViolations = `{
+	"Data": {"Permissions": ["ReadAccountsBasic", "ReadEverything"]},
+	"Risk": {}
+}`
+
+func consentRequest(body string) HTTPRequest {
+	return HTTPRequest{
+		Method: "POST",
+		Path:   "https://ob19-rs1.o3bank.co.uk:4501/open-banking/v3.1/aisp/account-access-consents",
+		Header: http.Header{
+			"Authorization":         []string{"Bearer 6ab1bcba-ee86-4c6f-9d0e-7b5c2a1a6a8e"},
+			"Content-Type":          []string{"application/json"},
+			"X-Fapi-Financial-Id":   []string{"0015800001041RHAAY"},
+			"X-Fapi-Auth-Date":      []string{"Sun, 10 Sep 2017 19:43:31 UTC"},
+			"X-Fapi-Interaction-Id": []string{"b4405450-febe-11e8-80a5-0fcebb1574e1"},
+		},
+		Body: strings.NewReader(body),
+	}
+}
+
+func TestOpenAPI3ValidatorValidatesRequests(t *testing.T) {
+	validator, err := NewRawOpenAPI3Validator("Account and Transaction API Specification", "v3.1.8")
+	require.NoError(t, err)
+
+	failures, err := validator.ValidateRequest(consentRequest(`{"Data": {"Permissions": ["ReadAccountsBasic"]}, "Risk": {}}`))
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+
+	request := consentRequest(consentWithViolations)
+	request.Header.Del("Authorization")
+	request.Header.Set("X-Fapi-Auth-Date", "yesterday")
+	failures, err = validator.ValidateRequest(request)
+	require.NoError(t, err)
+	assert.Contains(t, failures, Failure{
+		Message:   "header Authorization: required: expected a value, got none",
+		Parameter: "header Authorization",
+		Rule:      "required",
+		Expected:  "a value",
+		Actual:    "none",
+	})
+	messages := []string{}
+	for _, failure := range failures {
+		messages = append(messages, failure.Message)
+	}
+	assert.Contains(t, strings.Join(messages, "\n"), "header x-fapi-auth-date: pattern: expected")
+	assert.Contains(t, strings.Join(messages, "\n"), `/Data/Permissions/1: enum: expected one of [`)
+
+	failures, err = validator.ValidateRequest(HTTPRequest{Method: "GET", Path: "/open-banking/v3.1/aisp/foobar", Query: url.Values{}})
+	require.NoError(t, err)
+	assert.Equal(t, []Failure{{Message: "GET /open-banking/v3.1/aisp/foobar is not defined by the spec"}}, failures)
+}
+
+func TestSwaggerValidatorValidatesRequests(t *testing.T) {
+	doc, err := loads.Spec("spec/v3.1.0/account-info-swagger.flattened.json")
+	require.NoError(t, err)
+	validator, err := newValidator(doc)
+	require.NoError(t, err)
+
+	failures, err := validator.ValidateRequest(consentRequest(`{"Data": {"Permissions": ["ReadAccountsBasic"]}, "Risk": {}}`))
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+
+	request := consentRequest(`{"Data": {}, "Risk": {}}`)
+	request.Header.Del("X-Fapi-Financial-Id")
+	failures, err = validator.ValidateRequest(request)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []Failure{
+		{
+			Message:   "header x-fapi-financial-id: required: expected a value, got none",
+			Parameter: "header x-fapi-financial-id",
+			Rule:      "required",
+			Expected:  "a value",
+			Actual:    "none",
+		},
+		{
+			Message:  "/Data/Permissions: required: expected a value, got none",
+			Pointer:  "/Data/Permissions",
+			Rule:     "required",
+			Expected: "a value",
+			Actual:   "none",
+		},
+	}, failures)
+
+	failures, err = validator.ValidateRequest(HTTPRequest{
+		Method: "GET",
+		Path:   "/accounts/500000000000000000000001/transactions",
+		Header: http.Header{
+			"Authorization":       []string{"Bearer 6ab1bcba-ee86-4c6f-9d0e-7b5c2a1a6a8e"},
+			"X-Fapi-Financial-Id": []string{"0015800001041RHAAY"},
+		},
+		Query: url.Values{"fromBookingDateTime": []string{"yesterday"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []Failure{{
+		Message:   `query fromBookingDateTime: type: expected date-time, got "yesterday"`,
+		Parameter: "query fromBookingDateTime",
+		Rule:      "type",
+		Expected:  "date-time",
+		Actual:    `"yesterday"`,
+	}}, failures)
+}
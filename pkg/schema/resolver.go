@@ -0,0 +1,115 @@
+package schema
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-openapi/loads"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// bundledSpecs - the specs the suite ships with, embedded so the binary does not depend on the
+// directory it runs from
+//
+//go:embed spec/v3.* spec/dcr
+var bundledSpecs embed.FS
+
+// specDir - directory of vendored specs, see SetSpecDir
+var specDir string
+
+// SetSpecDir - sets the directory searched for vendored copies of specs before the bundled
+// ones. A copy of the spec published at schemaVersion URL `.../<file>` for version `<version>`
+// is looked for at `<dir>/<version>/<file>`.
+func SetSpecDir(dir string) {
+	specDir = dir
+}
+
+// SpecLocation - where to find the spec an API is validated against
+type SpecLocation struct {
+	Name          string // API name, the title of the spec
+	Version       string // API version, such as v3.1.8
+	SchemaVersion string // URL the spec is published at
+	Override      string // file:// or https:// URL of a spec used in place of the vendored and bundled ones
+}
+
+// NewSpecValidator - returns a validator for the spec at `location`: its override when it has
+// one, else a vendored copy, else the bundled spec with its name and version
+func NewSpecValidator(location SpecLocation) (Validator, error) {
+	shouldUseOpenApi3, err := ShouldUseOpenApi3(location.Version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "schema: parsing version number failed, version=%q", location.Version)
+	}
+
+	specPath := strings.TrimPrefix(location.Override, "file://")
+	if specPath == "" {
+		specPath = vendoredSpec(location)
+	}
+	if specPath == "" {
+		if shouldUseOpenApi3 {
+			return NewOpenAPI3Validator(location.Name, location.Version)
+		}
+		return newBundledSwaggerValidator(location.Name, location.Version)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"name":    location.Name,
+		"version": location.Version,
+		"spec":    specPath,
+	}).Info("validating against spec in place of the bundled one")
+	if shouldUseOpenApi3 {
+		return newOpenAPI3ValidatorFromLocation(location.Name, specPath)
+	}
+	return NewSwaggerValidator(specPath)
+}
+
+// vendoredSpec - the path of the vendored copy of the spec at `location`, empty when there is none
+func vendoredSpec(location SpecLocation) string {
+	if specDir == "" || location.SchemaVersion == "" {
+		return ""
+	}
+	schemaVersion, err := url.Parse(location.SchemaVersion)
+	if err != nil {
+		return ""
+	}
+	filename := filepath.Join(specDir, location.Version, path.Base(schemaVersion.Path))
+	if _, err := os.Stat(filename); err != nil {
+		return ""
+	}
+	return filename
+}
+
+// newBundledSwaggerValidator - a validator for the bundled swagger spec titled `specName` for `version`
+func newBundledSwaggerValidator(specName, version string) (Validator, error) {
+	dirname := "spec/" + version
+	files, err := fs.ReadDir(bundledSpecs, dirname)
+	if err != nil {
+		return nil, errors.Wrapf(err, "schema: opening spec folder failed, dirname=%q", dirname)
+	}
+
+	for _, f := range files {
+		filename := dirname + "/" + f.Name()
+		data, err := fs.ReadFile(bundledSpecs, filename)
+		if err != nil {
+			return nil, errors.Wrapf(err, "schema: opening spec file, filename=%q", filename)
+		}
+		doc, err := loads.Analyzed(json.RawMessage(data), "")
+		if err != nil {
+			return nil, errors.Wrapf(err, "schema: opening spec file, filename=%q", filename)
+		}
+
+		if doc.Spec().Info.Version == version && doc.Spec().Info.Title == specName {
+			logrus.Traceln("Returning swagger validator filename: " + filename)
+			return newValidator(doc)
+		}
+	}
+
+	return nil, fmt.Errorf("schema: could not find spec file for spec %s version %s", specName, version)
+}
@@ -0,0 +1,100 @@
+package schema
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// balancesOnlySwagger - a swagger spec defining only the balances endpoint
+const balancesOnlySwagger = `{
+	"swagger": "2.0",
+	"info": {"title": "Account and Transaction API Specification", "version": "v3.1.0"},
+	"basePath": "/open-banking/v3.1/aisp",
+	"paths": {"/balances": {"get": {"responses": {"200": {"description": "Balances"}}}}}
+}`
+
+// balancesOnlyOpenAPI3 - an OpenAPI 3 spec defining only the balances endpoint
+const balancesOnlyOpenAPI3 = `{
+	"openapi": "3.0.0",
+	"info": {"title": "Account and Transaction API Specification", "version": "v3.1.9"},
+	"servers": [{"url": "/open-banking/v3.1/aisp"}],
+	"paths": {"/balances": {"get": {"responses": {"200": {"description": "Balances"}}}}}
+}`
+
+func writeSpec(t *testing.T, filename, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(filename), 0755))
+	require.NoError(t, ioutil.WriteFile(filename, []byte(content), 0644))
+}
+
+func TestNewSpecValidatorUsesBundledSpecsFromAnyDirectory(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(wd)
+
+	for _, version := range []string{"v3.1.0", "v3.1.8"} {
+		validator, err := NewSpecValidator(SpecLocation{Name: "Account and Transaction API Specification", Version: version})
+		require.NoError(t, err, version)
+		assert.NotNil(t, validator, version)
+	}
+
+	_, err = NewSpecValidator(SpecLocation{Name: "Account and Transaction API Specification", Version: "v3.0.9"})
+	assert.EqualError(t, err, `schema: opening spec folder failed, dirname="spec/v3.0.9": open spec/v3.0.9: file does not exist`)
+}
+
+func TestNewSpecValidatorPrefersVendoredSpec(t *testing.T) {
+	dir := t.TempDir()
+	writeSpec(t, filepath.Join(dir, "v3.1.0", "account-info-swagger.json"), balancesOnlySwagger)
+	SetSpecDir(dir)
+	defer SetSpecDir("")
+
+	location := SpecLocation{
+		Name:          "Account and Transaction API Specification",
+		Version:       "v3.1.0",
+		SchemaVersion: "https://raw.githubusercontent.com/OpenBankingUK/read-write-api-specs/v3.1.0/dist/account-info-swagger.json",
+	}
+	validator, err := NewSpecValidator(location)
+	require.NoError(t, err)
+	failures, err := validator.ValidateRequest(HTTPRequest{Method: "GET", Path: "/open-banking/v3.1/aisp/accounts"})
+	require.NoError(t, err)
+	assert.Equal(t, []Failure{{Message: "GET /open-banking/v3.1/aisp/accounts is not defined by the spec"}}, failures)
+
+	location.SchemaVersion = "https://example.com/specs/account-info-openapi.json"
+	validator, err = NewSpecValidator(location)
+	require.NoError(t, err)
+	failures, err = validator.ValidateRequest(HTTPRequest{Method: "GET", Path: "/open-banking/v3.1/aisp/accounts"})
+	require.NoError(t, err)
+	assert.NotContains(t, failures, Failure{Message: "GET /open-banking/v3.1/aisp/accounts is not defined by the spec"})
+}
+
+func TestNewSpecValidatorPrefersOverride(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "account-info-openapi.json")
+	writeSpec(t, filename, balancesOnlyOpenAPI3)
+
+	validator, err := NewSpecValidator(SpecLocation{
+		Name:     "Account and Transaction API Specification",
+		Version:  "v3.1.9",
+		Override: "file://" + filename,
+	})
+	require.NoError(t, err)
+
+	failures, err := validator.ValidateRequest(HTTPRequest{Method: "GET", Path: "/open-banking/v3.1/aisp/balances"})
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+
+	failures, err = validator.ValidateRequest(HTTPRequest{Method: "GET", Path: "/open-banking/v3.1/aisp/accounts"})
+	require.NoError(t, err)
+	assert.Equal(t, []Failure{{Message: "GET /open-banking/v3.1/aisp/accounts is not defined by the spec"}}, failures)
+
+	_, err = NewSpecValidator(SpecLocation{
+		Name:     "Account and Transaction API Specification",
+		Version:  "v3.1.9",
+		Override: "file://" + filename + ".missing",
+	})
+	assert.Error(t, err)
+}
@@ -28,6 +28,10 @@ func (v statusCodeValidator) Validate(r HTTPResponse) ([]Failure, error) {
 	return nil, nil
 }
 
+func (v statusCodeValidator) ValidateRequest(r HTTPRequest) ([]Failure, error) {
+	return nil, nil
+}
+
 func (v statusCodeValidator) IsRequestProperty(method, path, propertpath string) (bool, string, error) {
 	return false, "", nil
 }
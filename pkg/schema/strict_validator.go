@@ -0,0 +1,400 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-openapi/spec"
+)
+
+// namespacedEnum - the extension the OB specs list code values in, for code sets ASPSPs may extend
+// with their own namespace
+const namespacedEnum = "x-namespaced-enum"
+
+// NewStrictValidator - returns `validator` also reporting response properties the spec does not
+// define, where the spec leaves additionalProperties out, and codes in the `UK.OBIE.` namespace
+// outside the OB code lists. `extensions` are JSON pointers, `*` matching any token, of the fields
+// the ASPSP documents as extensions: any property or code is allowed there. Codes of the fields
+// `codeSets` checks, when it is a code set validator, are left to it.
+func NewStrictValidator(validator Validator, extensions []string, codeSets Validator) Validator {
+	responseSchema := responseSchemaOf(validator)
+	if responseSchema == nil {
+		return validator
+	}
+	strict := strictValidator{
+		Validator:      validator,
+		extensions:     extensions,
+		responseSchema: responseSchema,
+	}
+	if codeSets, ok := codeSets.(codeSetValidator); ok {
+		for field := range codeSets.codeSets.Fields {
+			strict.codeSetFields = append(strict.codeSetFields, field)
+		}
+	}
+	return strict
+}
+
+// responseSchemaOf - finds the schema of the body of a response in the spec `validator`
+// validates against, nil when it does not validate against a spec
+func responseSchemaOf(validator Validator) func(r HTTPResponse) schemaNode {
+	switch v := validator.(type) {
+	case validators:
+		f := newFinder(v.document)
+		return func(r HTTPResponse) schemaNode {
+			response, err := f.Response(r.Method, r.Path, r.StatusCode)
+			if err != nil || response.Schema == nil {
+				return nil
+			}
+			return swaggerNode{schema: response.Schema, root: f.Spec()}
+		}
+	case OpenAPI3Validator:
+		return func(r HTTPResponse) schemaNode {
+			if schema := oas3ResponseSchema(v, r); schema != nil {
+				return oas3Node{schema}
+			}
+			return nil
+		}
+	case strictValidator:
+		return v.responseSchema
+	}
+	return nil
+}
+
+// strictValidator implements a validator that checks response bodies strictly, on top of the
+// checks of the validator it wraps
+type strictValidator struct {
+	Validator
+	extensions []string
+	// codeSetFields - pointers of the fields whose codes the code set validator checks
+	codeSetFields []string
+	// responseSchema - the schema of the body of a response, nil when the spec has none
+	responseSchema func(r HTTPResponse) schemaNode
+}
+
+func (v strictValidator) Validate(r HTTPResponse) ([]Failure, error) {
+	body := []byte{}
+	if r.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(r.Body); err != nil {
+			return nil, err
+		}
+	}
+	r.Body = bytes.NewReader(body)
+	validated, err := v.Validator.Validate(r)
+	if err != nil {
+		return nil, err
+	}
+	failures := []Failure{}
+	for _, failure := range validated {
+		if failure.Rule != "additionalProperties" || !isExtension(failure.Pointer, v.extensions) {
+			failures = append(failures, failure)
+		}
+	}
+
+	node := v.responseSchema(r)
+	if node == nil || len(body) == 0 || r.StatusCode == http.StatusNoContent {
+		return failures, nil
+	}
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		// reported by the validator wrapped
+		return failures, nil
+	}
+	return append(failures, v.strictFailures(node, data, "")...), nil
+}
+
+// oas3ResponseSchema - the schema of the body of response `r` in the spec of `v`, nil when there is none
+func oas3ResponseSchema(v OpenAPI3Validator, r HTTPResponse) *openapi3.Schema {
+	httpReq, err := createHTTPReq(r.Method, v.serverPath(r.Path))
+	if err != nil {
+		return nil
+	}
+	route, _, err := v.router.FindRoute(httpReq)
+	if err != nil {
+		return nil
+	}
+	response := route.Operation.Responses.Get(r.StatusCode)
+	if response == nil {
+		response = route.Operation.Responses.Default()
+	}
+	if response == nil || response.Value == nil {
+		return nil
+	}
+
+	mediaType := response.Value.Content.Get(r.Header.Get("Content-Type"))
+	if mediaType == nil {
+		for contentType, content := range response.Value.Content {
+			if strings.HasPrefix(contentType, "application/json") {
+				mediaType = content
+			}
+		}
+	}
+	if mediaType == nil || mediaType.Schema == nil {
+		return nil
+	}
+	return mediaType.Schema.Value
+}
+
+// schemaNode - a swagger or OAS3 schema, as far as strict validation needs it
+type schemaNode interface {
+	// properties - the properties defined by the schema and its allOf, anyOf and oneOf schemas
+	properties() map[string]schemaNode
+	// closed - whether properties the schema does not define are reported by strict validation
+	// only, that is it defines some and none of its schemas sets additionalProperties
+	closed() bool
+	// required - the properties the schema and its allOf schemas require
+	required() map[string]bool
+	// items - the schema of the items of an array, nil when there is none
+	items() schemaNode
+	// codes - the values of the code list of a string, nil when it has none
+	codes() []string
+}
+
+// strictFailures - the properties of `data` at `pointer` not defined by `node` and the codes outside its code lists
+func (v strictValidator) strictFailures(node schemaNode, data interface{}, pointer string) []Failure {
+	if isExtension(pointer, v.extensions) {
+		return nil
+	}
+
+	failures := []Failure{}
+	switch value := data.(type) {
+	case map[string]interface{}:
+		properties := node.properties()
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			child := pointer + toPointer([]string{name})
+			property, ok := properties[name]
+			if ok {
+				failures = append(failures, v.strictFailures(property, value[name], child)...)
+			} else if node.closed() && !isExtension(child, v.extensions) {
+				failures = append(failures, newValueFailure(child, "additionalProperties", "no such property", "a value", ""))
+			}
+		}
+	case []interface{}:
+		if items := node.items(); items != nil {
+			for i, item := range value {
+				failures = append(failures, v.strictFailures(items, item, fmt.Sprintf("%s/%d", pointer, i))...)
+			}
+		}
+	case string:
+		// x-namespaced-enum lists allow ASPSPs codes in a namespace of their own
+		codes := node.codes()
+		if len(codes) > 0 && !contains(codes, value) && strings.HasPrefix(value, obNamespace) && !isExtension(pointer, v.codeSetFields) {
+			failures = append(failures, newValueFailure(pointer, namespacedEnum, "one of "+toJSON(codes), describe(value), ""))
+		}
+	}
+	return failures
+}
+
+// isExtension - whether `pointer` is one of `extensions`, which use `*` for any token
+func isExtension(pointer string, extensions []string) bool {
+	tokens := strings.Split(pointer, "/")
+	for _, extension := range extensions {
+		extensionTokens := strings.Split(extension, "/")
+		if len(extensionTokens) != len(tokens) {
+			continue
+		}
+		matches := true
+		for i, token := range extensionTokens {
+			if token != "*" && token != tokens[i] {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// extensionCodes - the code list in spec extension `extension`, however it was decoded
+func extensionCodes(extension interface{}) []string {
+	if extension == nil {
+		return nil
+	}
+	encoded, err := json.Marshal(extension)
+	if err != nil {
+		return nil
+	}
+	codes := []string{}
+	if err := json.Unmarshal(encoded, &codes); err != nil {
+		return nil
+	}
+	return codes
+}
+
+// swaggerNode - a schema of swagger spec `root`
+type swaggerNode struct {
+	schema *spec.Schema
+	root   *spec.Swagger
+}
+
+// schemas - the schema, resolved, with its allOf, anyOf and oneOf schemas
+func (n swaggerNode) schemas() []*spec.Schema {
+	schema := n.schema
+	if schema.Ref.String() != "" {
+		resolved, err := spec.ResolveRef(n.root, &schema.Ref)
+		if err != nil || resolved == nil {
+			return nil
+		}
+		schema = resolved
+	}
+	schemas := []*spec.Schema{schema}
+	for _, composed := range [][]spec.Schema{schema.AllOf, schema.AnyOf, schema.OneOf} {
+		for i := range composed {
+			schemas = append(schemas, swaggerNode{schema: &composed[i], root: n.root}.schemas()...)
+		}
+	}
+	return schemas
+}
+
+func (n swaggerNode) properties() map[string]schemaNode {
+	properties := map[string]schemaNode{}
+	for _, schema := range n.schemas() {
+		for name := range schema.Properties {
+			property := schema.Properties[name]
+			properties[name] = swaggerNode{schema: &property, root: n.root}
+		}
+	}
+	return properties
+}
+
+func (n swaggerNode) closed() bool {
+	defined := false
+	for _, schema := range n.schemas() {
+		if schema.AdditionalProperties != nil {
+			return false
+		}
+		defined = defined || len(schema.Properties) > 0
+	}
+	return defined
+}
+
+func (n swaggerNode) required() map[string]bool {
+	required := map[string]bool{}
+	schemas := n.schemas()
+	if len(schemas) == 0 {
+		return required
+	}
+	for _, name := range schemas[0].Required {
+		required[name] = true
+	}
+	for i := range schemas[0].AllOf {
+		for name := range (swaggerNode{schema: &schemas[0].AllOf[i], root: n.root}).required() {
+			required[name] = true
+		}
+	}
+	return required
+}
+
+func (n swaggerNode) items() schemaNode {
+	for _, schema := range n.schemas() {
+		if schema.Items != nil && schema.Items.Schema != nil {
+			return swaggerNode{schema: schema.Items.Schema, root: n.root}
+		}
+	}
+	return nil
+}
+
+func (n swaggerNode) codes() []string {
+	for _, schema := range n.schemas() {
+		if codes := extensionCodes(schema.Extensions[namespacedEnum]); codes != nil {
+			return codes
+		}
+	}
+	return nil
+}
+
+// oas3Node - an OpenAPI 3 schema, with references resolved by the loader
+type oas3Node struct {
+	schema *openapi3.Schema
+}
+
+// schemas - the schema with its allOf, anyOf and oneOf schemas
+func (n oas3Node) schemas() []*openapi3.Schema {
+	schemas := []*openapi3.Schema{n.schema}
+	for _, composed := range []openapi3.SchemaRefs{n.schema.AllOf, n.schema.AnyOf, n.schema.OneOf} {
+		for _, ref := range composed {
+			if ref != nil && ref.Value != nil {
+				schemas = append(schemas, oas3Node{ref.Value}.schemas()...)
+			}
+		}
+	}
+	return schemas
+}
+
+func (n oas3Node) properties() map[string]schemaNode {
+	properties := map[string]schemaNode{}
+	for _, schema := range n.schemas() {
+		for name, property := range schema.Properties {
+			if property != nil && property.Value != nil {
+				properties[name] = oas3Node{property.Value}
+			}
+		}
+	}
+	return properties
+}
+
+func (n oas3Node) closed() bool {
+	defined := false
+	for _, schema := range n.schemas() {
+		if schema.AdditionalPropertiesAllowed != nil || schema.AdditionalProperties != nil {
+			return false
+		}
+		defined = defined || len(schema.Properties) > 0
+	}
+	return defined
+}
+
+func (n oas3Node) required() map[string]bool {
+	required := map[string]bool{}
+	for _, name := range n.schema.Required {
+		required[name] = true
+	}
+	for _, ref := range n.schema.AllOf {
+		if ref != nil && ref.Value != nil {
+			for name := range (oas3Node{ref.Value}).required() {
+				required[name] = true
+			}
+		}
+	}
+	return required
+}
+
+func (n oas3Node) items() schemaNode {
+	for _, schema := range n.schemas() {
+		if schema.Items != nil && schema.Items.Value != nil {
+			return oas3Node{schema.Items.Value}
+		}
+	}
+	return nil
+}
+
+func (n oas3Node) codes() []string {
+	for _, schema := range n.schemas() {
+		if codes := extensionCodes(schema.Extensions[namespacedEnum]); codes != nil {
+			return codes
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package schema
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-openapi/loads"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// accountsWithDivergence - an accounts response with a misspelt field, a bank extension field
+// and a scheme name in the OB namespace outside the OB code list
+const accountsWithDivergence = `{
+	"Data": {
+		"Account": [{
+			"AccountId": "500000000000000000000001",
+			"Currency": "GBP",
+			"AccountType": "Personal",
+			"AccountSubType": "CurrentAccount",
+			"Nickname2": "Bills",
+			"Account": [{
+				"SchemeName": "UK.OBIE.InternalId",
+				"Identification": "10000119820101",
+				"SecondaryIdentifcation": "002"
+			}]
+		}]
+	},
+	"Links": {"Self": "https://ob19-rs1.o3bank.co.uk:4501/open-banking/v3.1/aisp/accounts"},
+	"Meta": {"TotalPages": 1}
+}`
+
+func accountsResponse(body string) HTTPResponse {
+	return HTTPResponse{
+		Method:     "GET",
+		Path:       "/open-banking/v3.1/aisp/accounts",
+		Header:     http.Header{"Content-Type": []string{"application/json; charset=utf-8"}},
+		Body:       strings.NewReader(body),
+		StatusCode: http.StatusOK,
+	}
+}
+
+func TestStrictOpenAPI3Validator(t *testing.T) {
+	validator, err := NewOpenAPI3Validator("Account and Transaction API Specification", "v3.1.10")
+	require.NoError(t, err)
+
+	failures, err := validator.Validate(accountsResponse(accountsWithDivergence))
+	require.NoError(t, err)
+	nickname := Failure{
+		Message:  "/Data/Account/0/Nickname2: additionalProperties: expected no such property, got a value",
+		Pointer:  "/Data/Account/0/Nickname2",
+		Rule:     "additionalProperties",
+		Expected: "no such property",
+		Actual:   "a value",
+	}
+	assert.Equal(t, []Failure{nickname}, failures)
+
+	failures, err = NewStrictValidator(validator, nil, nil).Validate(accountsResponse(accountsWithDivergence))
+	require.NoError(t, err)
+	assert.Equal(t, []Failure{
+		nickname,
+		{
+			Message:  `/Data/Account/0/Account/0/SchemeName: x-namespaced-enum: expected one of ["UK.OBIE.BBAN","UK.OBIE.IBAN","UK.OBIE.PAN","UK.OBIE.Paym","UK.OBIE.SortCodeAccountNumber"], got "UK.OBIE.InternalId"`,
+			Pointer:  "/Data/Account/0/Account/0/SchemeName",
+			Rule:     "x-namespaced-enum",
+			Expected: `one of ["UK.OBIE.BBAN","UK.OBIE.IBAN","UK.OBIE.PAN","UK.OBIE.Paym","UK.OBIE.SortCodeAccountNumber"]`,
+			Actual:   `"UK.OBIE.InternalId"`,
+		},
+		{
+			Message:  "/Data/Account/0/Account/0/SecondaryIdentifcation: additionalProperties: expected no such property, got a value",
+			Pointer:  "/Data/Account/0/Account/0/SecondaryIdentifcation",
+			Rule:     "additionalProperties",
+			Expected: "no such property",
+			Actual:   "a value",
+		},
+	}, failures)
+
+	strict := NewStrictValidator(validator, []string{"/Data/Account/*/Nickname2", "/Data/Account/*/Account/*/SchemeName"}, nil)
+	failures, err = strict.Validate(accountsResponse(accountsWithDivergence))
+	require.NoError(t, err)
+	require.Len(t, failures, 1)
+	assert.Equal(t, "/Data/Account/0/Account/0/SecondaryIdentifcation", failures[0].Pointer)
+}
+
+func TestStrictSwaggerValidator(t *testing.T) {
+	doc, err := loads.Spec("spec/v3.1.6/account-info-swagger-flattened.json")
+	require.NoError(t, err)
+	validator, err := newValidator(doc)
+	require.NoError(t, err)
+
+	rules := func(failures []Failure) map[string][]string {
+		rules := map[string][]string{}
+		for _, failure := range failures {
+			rules[failure.Pointer] = append(rules[failure.Pointer], failure.Rule)
+		}
+		return rules
+	}
+
+	failures, err := validator.Validate(accountsResponse(accountsWithDivergence))
+	require.NoError(t, err)
+	assert.NotContains(t, rules(failures), "/Data/Account/0/Account/0/SchemeName")
+
+	strict := NewStrictValidator(validator, []string{"/Data/Account/*/Nickname2"}, nil)
+	failures, err = strict.Validate(accountsResponse(accountsWithDivergence))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"x-namespaced-enum"}, rules(failures)["/Data/Account/0/Account/0/SchemeName"])
+	assert.NotContains(t, rules(failures), "/Data/Account/0/Nickname2")
+}
+
+func TestStrictValidatorCodes(t *testing.T) {
+	validator, err := NewOpenAPI3Validator("Account and Transaction API Specification", "v3.1.10")
+	require.NoError(t, err)
+	schemeNames := func(strict Validator, schemeName string) []Failure {
+		body := strings.Replace(accountsWithDivergence, "UK.OBIE.InternalId", schemeName, 1)
+		failures, err := strict.Validate(accountsResponse(body))
+		require.NoError(t, err)
+		schemeNames := []Failure{}
+		for _, failure := range failures {
+			if failure.Pointer == "/Data/Account/0/Account/0/SchemeName" {
+				schemeNames = append(schemeNames, failure)
+			}
+		}
+		return schemeNames
+	}
+
+	strict := NewStrictValidator(validator, nil, nil)
+	assert.Len(t, schemeNames(strict, "UK.OBIE.InternalId"), 1)
+	assert.Empty(t, schemeNames(strict, "UK.BANK.InternalId"), "ASPSPs may add codes in their own namespace")
+
+	codeSets := codeSetValidator{codeSets: CodeSets{
+		CodeLists: map[string]CodeList{"OBExternalAccountIdentification4Code": {Codes: []string{"UK.OBIE.IBAN"}, Namespaced: true}},
+		Fields:    map[string]string{"/Data/Account/*/Account/*/SchemeName": "OBExternalAccountIdentification4Code"},
+	}}
+	strict = NewStrictValidator(validator, nil, codeSets)
+	assert.Empty(t, schemeNames(strict, "UK.OBIE.InternalId"), "codes the code set validator checks are left to it")
+}
+
+func TestNewStrictValidatorLeavesNullValidator(t *testing.T) {
+	assert.Equal(t, NewNullValidator(), NewStrictValidator(NewNullValidator(), nil, nil))
+}
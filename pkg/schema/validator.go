@@ -4,14 +4,12 @@ import (
 	"fmt"
 	"github.com/blang/semver/v4"
 	"io"
-	"io/ioutil"
 	"net/http"
-	"os"
+	"net/url"
 
 	"github.com/go-openapi/loads"
 	"github.com/go-openapi/spec"
 	"github.com/pkg/errors"
-	"github.com/sirupsen/logrus"
 )
 
 // HTTPResponse represents a response object from a HTTP Call
@@ -23,82 +21,25 @@ type HTTPResponse struct {
 	StatusCode int
 }
 
-// Failure represents a validation failure
-type Failure struct {
-	Message string
+// HTTPRequest represents a request the suite is about to send
+type HTTPRequest struct {
+	Method string
+	Path   string
+	Header http.Header
+	Query  url.Values
+	Body   io.Reader
 }
 
-func newFailure(message string) Failure {
-	return Failure{
-		Message: message,
-	}
-}
-
-// Validator validates a HTTP response object against a schema
+// Validator validates a HTTP response object against a schema, and the request that lead to it
 type Validator interface {
 	Validate(HTTPResponse) ([]Failure, error)
+	ValidateRequest(HTTPRequest) ([]Failure, error)
 	IsRequestProperty(method, path, propertpath string) (bool, string, error)
 }
 
-// NewSwaggerOBSpecValidator -
+// NewSwaggerOBSpecValidator - returns a validator for the bundled spec named `specName` for `version`
 func NewSwaggerOBSpecValidator(specName, version string) (Validator, error) {
-
-	shouldUseOpenApi3, errVersion := ShouldUseOpenApi3(version)
-	if errVersion != nil {
-		return nil, errors.Wrapf(errVersion, "schema: parsing version number failed, version=%q", version)
-	}
-
-	if shouldUseOpenApi3 {
-		return NewOpenAPI3Validator(specName, version)
-	}
-
-	var err error
-
-	prodDir := "pkg/schema/spec/" + version
-	testDir := "../../pkg/schema/spec/" + version
-
-	dirnameIndex := 0
-	dirNames := []string{prodDir, testDir}
-
-	files := []os.FileInfo{}
-	for index, dirname := range dirNames {
-		logrus.Traceln("Returning swagger validator filename: " + dirname)
-		filesReadDir, errReadDir := ioutil.ReadDir(dirname)
-		if errReadDir != nil {
-			wd, errGetwd := os.Getwd()
-			if errGetwd != nil {
-				err = errors.Wrapf(errGetwd, "schema: opening spec folder failed in os.Getwd, dirname=%q", dirname)
-			} else {
-				err = errors.Wrapf(errReadDir, "schema: opening spec folder failed, dirname=%q, wd=%q", dirname, wd)
-			}
-		} else {
-			err = nil
-			dirnameIndex = index
-			files = filesReadDir
-			break
-		}
-	}
-
-	if err != nil {
-		return nil, err
-	}
-
-	dirname := dirNames[dirnameIndex]
-	for _, f := range files {
-		filename := dirname + "/" + f.Name()
-		logrus.Traceln("Returning swagger validator filenameplus: " + filename)
-		doc, err := loads.Spec(filename)
-		if err != nil {
-			return nil, errors.Wrapf(err, "schema: opening spec file, filename=%q", filename)
-		}
-
-		if doc.Spec().Info.Version == version && doc.Spec().Info.Title == specName {
-			logrus.Traceln("Returning swagger validator filename: " + filename)
-			return NewSwaggerValidator(filename)
-		}
-	}
-
-	return nil, fmt.Errorf("schema: could not find spec file for spec %s version %s", specName, version)
+	return NewSpecValidator(SpecLocation{Name: specName, Version: version})
 }
 
 func ShouldUseOpenApi3(version string) (bool, error) {
@@ -161,6 +102,7 @@ func newValidator(doc *loads.Document) (Validator, error) {
 				newContentTypeValidator(f),
 				newStatusCodeValidator(f),
 				newBodyValidator(f),
+				newRequestValidator(f),
 			},
 			document: doc,
 		}, nil
@@ -181,6 +123,18 @@ func (v validators) Validate(r HTTPResponse) ([]Failure, error) {
 	return allFailures, nil
 }
 
+func (v validators) ValidateRequest(r HTTPRequest) ([]Failure, error) {
+	allFailures := []Failure{}
+	for _, validator := range v.validators {
+		failures, err := validator.ValidateRequest(r)
+		if err != nil {
+			return nil, err
+		}
+		allFailures = append(allFailures, failures...)
+	}
+	return allFailures, nil
+}
+
 func (v validators) IsRequestProperty(checkmethod, checkpath, propertyPath string) (bool, string, error) {
 	spec := v.document.Spec()
 
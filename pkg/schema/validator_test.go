@@ -79,7 +79,13 @@ func TestValidators_Validate_FailureEmptyOptionalProperty(t *testing.T) {
 
 	require.NoError(t, err)
 	assert.Len(t, failures, 1)
-	assert.Equal(t, []Failure{{"Data.Transaction.TransactionReference in body should be at least 1 chars long"}}, failures)
+	assert.Equal(t, []Failure{{
+		Message:  `/Data/Transaction/0/TransactionReference: minLength: expected at least 1 characters, got ""`,
+		Pointer:  "/Data/Transaction/0/TransactionReference",
+		Rule:     "minLength",
+		Expected: "at least 1 characters",
+		Actual:   `""`,
+	}}, failures)
 }
 
 const getTransactionsResponseEmptyTransactionReference = `
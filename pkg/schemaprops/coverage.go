@@ -0,0 +1,115 @@
+package schemaprops
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/schema"
+)
+
+// EndpointCoverage - the optional and conditional properties the spec defines for the responses
+// of an endpoint with a status code, and whether any response in the run populated them
+type EndpointCoverage struct {
+	Method     string             `json:"method"`
+	Path       string             `json:"path"`
+	Code       string             `json:"code"`
+	Populated  int                `json:"populated"`
+	Total      int                `json:"total"`
+	Percentage float64            `json:"percentage"`
+	Properties []PropertyCoverage `json:"properties"`
+}
+
+// PropertyCoverage - whether any response populated an optional or conditional property
+type PropertyCoverage struct {
+	Path        string `json:"path"`
+	Conditional bool   `json:"conditional,omitempty"`
+	Populated   bool   `json:"populated"`
+}
+
+// ParseOutputJSON - the APIs in `output`, as returned by OutputJSON
+func ParseOutputJSON(output string) ([]PropertyOutput, error) {
+	parsed := struct {
+		ResponseFields []PropertyOutput `json:"responseFields"`
+	}{}
+	if err := json.Unmarshal([]byte(output), &parsed); err != nil {
+		return nil, err
+	}
+	return parsed.ResponseFields, nil
+}
+
+// NewCoverage - the coverage of the responses in `api` per endpoint and response code, against the
+// spec `validator` validates against. `conditional` has the paths of the conditional properties
+// of each endpoint, keyed by `<method> <path>`, in dot notation as in the discovery model.
+func NewCoverage(validator schema.Validator, api PropertyOutput, conditional map[string][]string) []EndpointCoverage {
+	type responseKey struct {
+		method, path, code string
+	}
+	populated := map[responseKey]map[string]bool{}
+	keys := []responseKey{}
+	for _, endpoint := range api.Endpoints {
+		for _, response := range endpoint.Responses {
+			key := responseKey{endpoint.Method, endpoint.Path, response.Code}
+			if populated[key] == nil {
+				populated[key] = map[string]bool{}
+				keys = append(keys, key)
+			}
+			for _, field := range response.Fields {
+				populated[key][field] = true
+			}
+		}
+	}
+
+	coverage := []EndpointCoverage{}
+	for _, key := range keys {
+		statusCode, err := strconv.Atoi(key.code)
+		if err != nil {
+			continue
+		}
+		properties, err := schema.ResponseProperties(validator, key.method, key.path, statusCode)
+		if err != nil {
+			continue
+		}
+
+		conditionalPaths := map[string]bool{}
+		for _, conditionalPath := range conditional[key.method+" "+key.path] {
+			conditionalPaths[fieldPath(conditionalPath)] = true
+		}
+		endpoint := EndpointCoverage{Method: key.method, Path: key.path, Code: key.code, Properties: []PropertyCoverage{}}
+		for _, property := range properties {
+			if property.Required && !conditionalPaths[property.Path] {
+				continue
+			}
+			propertyCoverage := PropertyCoverage{
+				Path:        property.Path,
+				Conditional: conditionalPaths[property.Path],
+				Populated:   populated[key][property.Path],
+			}
+			endpoint.Properties = append(endpoint.Properties, propertyCoverage)
+			endpoint.Total++
+			if propertyCoverage.Populated {
+				endpoint.Populated++
+			}
+		}
+		endpoint.Percentage = 100
+		if endpoint.Total > 0 {
+			endpoint.Percentage = math.Round(1000*float64(endpoint.Populated)/float64(endpoint.Total)) / 10
+		}
+		coverage = append(coverage, endpoint)
+	}
+	return coverage
+}
+
+// fieldPath - JSON dot notation path `path`, such as `Data.Transaction.*.Balance`, with array
+// indexes left out as in the fields collected
+func fieldPath(path string) string {
+	tokens := []string{}
+	for _, token := range strings.Split(path, ".") {
+		if _, err := strconv.Atoi(token); err == nil || token == "*" || token == "#" {
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+	return strings.Join(tokens, ".")
+}
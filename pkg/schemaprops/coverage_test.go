@@ -0,0 +1,76 @@
+package schemaprops
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/schema"
+)
+
+const (
+	accountWithNickname = `{
+		"Data": {"Account": [{"AccountId": "1", "Currency": "GBP", "AccountType": "Personal", "AccountSubType": "CurrentAccount", "Nickname": "Bills"}]},
+		"Links": {"Self": "https://aspsp.example.com/open-banking/v3.1/aisp/accounts"},
+		"Meta": {"TotalPages": 1}
+	}`
+	accountWithServicer = `{
+		"Data": {"Account": [{"AccountId": "2", "Currency": "GBP", "AccountType": "Business", "AccountSubType": "CurrentAccount",
+			"Servicer": {"SchemeName": "UK.OBIE.BICFI", "Identification": "BANKGB2L"}}]},
+		"Links": {"Self": "https://aspsp.example.com/open-banking/v3.1/aisp/accounts"}
+	}`
+)
+
+func TestNewCoverage(t *testing.T) {
+	c := MakeCollector()
+	c.SetCollectorAPIDetails("Account and Transaction API Specification", "v3.1.10")
+	c.CollectProperties("GET", "https://aspsp.example.com/open-banking/v3.1/aisp/accounts", accountWithNickname, 200)
+	c.CollectProperties("GET", "https://aspsp.example.com/open-banking/v3.1/aisp/accounts", accountWithServicer, 200)
+	apis, err := ParseOutputJSON(c.OutputJSON())
+	require.NoError(t, err)
+	require.Len(t, apis, 1)
+
+	validator, err := schema.NewOpenAPI3Validator("Account and Transaction API Specification", "v3.1.10")
+	require.NoError(t, err)
+	coverage := NewCoverage(validator, apis[0], map[string][]string{
+		"GET /accounts": {"Data.Account.*.OpeningDate"},
+	})
+	require.Len(t, coverage, 1)
+
+	accounts := coverage[0]
+	assert.Equal(t, "GET", accounts.Method)
+	assert.Equal(t, "/accounts", accounts.Path)
+	assert.Equal(t, "200", accounts.Code)
+	assert.Equal(t, len(accounts.Properties), accounts.Total)
+	assert.Contains(t, accounts.Properties, PropertyCoverage{Path: "Data.Account.Nickname", Populated: true})
+	assert.Contains(t, accounts.Properties, PropertyCoverage{Path: "Data.Account.Servicer.Identification", Populated: true})
+	assert.Contains(t, accounts.Properties, PropertyCoverage{Path: "Meta.TotalPages", Populated: true})
+	assert.Contains(t, accounts.Properties, PropertyCoverage{Path: "Data.Account.OpeningDate", Conditional: true})
+	assert.Contains(t, accounts.Properties, PropertyCoverage{Path: "Links.Next"})
+	assert.NotContains(t, accounts.Properties, PropertyCoverage{Path: "Data", Populated: true})
+
+	populated := 0
+	for _, property := range accounts.Properties {
+		if property.Populated {
+			populated++
+		}
+	}
+	assert.Equal(t, populated, accounts.Populated)
+	assert.InDelta(t, 100*float64(populated)/float64(accounts.Total), accounts.Percentage, 0.05)
+}
+
+func TestNewCoverageLeavesOutEndpointsNotInSpec(t *testing.T) {
+	api := PropertyOutput{Endpoints: []Endpoint{
+		{Method: "GET", Path: "/foobar", Responses: []Response{{Code: "200", Fields: []string{"Data"}}}},
+	}}
+	validator, err := schema.NewOpenAPI3Validator("Account and Transaction API Specification", "v3.1.10")
+	require.NoError(t, err)
+
+	assert.Empty(t, NewCoverage(validator, api, nil))
+}
+
+func TestFieldPath(t *testing.T) {
+	assert.Equal(t, "Data.Transaction.Balance", fieldPath("Data.Transaction.*.Balance"))
+	assert.Equal(t, "Data.Account.Nickname", fieldPath("Data.Account.0.Nickname"))
+}
@@ -9,6 +9,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/sirupsen/logrus"
 )
@@ -20,7 +21,10 @@ type PropertyCollector interface {
 	OutputJSON() string
 }
 
+// Collector - collects the fields of the responses of one test run, or of consent gathering.
+// Safe to use from several goroutines.
 type Collector struct {
+	lock       sync.Mutex
 	level      int
 	currentApi int
 	path       []string
@@ -56,32 +60,58 @@ var ConsentGathering = "ConsentGathering"
 
 var subPathx = "[a-zA-Z0-9_{}-]+" // url sub path regex
 
-var (
-	collector PropertyCollector
-)
-
-func GetPropertyCollector() PropertyCollector {
-	if collector == nil {
-		collector = MakeCollector()
-	}
-	return collector
+func MakeCollector() PropertyCollector {
+	c := &Collector{}
+	c.path = make([]string, 20)
+	c.Apis = []PropertyOutput{}
+	return c
 }
 
-func MakeCollector() PropertyCollector {
+// MakeRunCollector - a collector for a test run, starting with a copy of the fields `consents`
+// collected while gathering consent, so they are merged into the output of the run
+func MakeRunCollector(consents PropertyCollector) PropertyCollector {
 	c := &Collector{}
 	c.path = make([]string, 20)
 	c.Apis = []PropertyOutput{}
+	gathered, ok := consents.(*Collector)
+	if !ok {
+		return c
+	}
+
+	gathered.lock.Lock()
+	defer gathered.lock.Unlock()
+	for _, api := range gathered.Apis {
+		if api.Api != ConsentGathering {
+			continue
+		}
+		c.setCollectorAPIDetails(api.Api, api.Version)
+		for endpoint, fields := range api.endpoints {
+			copied := make(map[string]int, len(fields))
+			for field, v := range fields {
+				copied[field] = v
+			}
+			c.Apis[c.currentApi].endpoints[endpoint] = copied
+		}
+	}
 	return c
 }
 
 func (c *Collector) SetCollectorAPIDetails(api, version string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.setCollectorAPIDetails(api, version)
+}
+
+func (c *Collector) setCollectorAPIDetails(api, version string) {
 	p := PropertyOutput{Api: api, Version: version}
 	p.endpoints = make(map[string]map[string]int, 0)
 	c.Apis = append(c.Apis, p)
 	c.currentApi = len(c.Apis) - 1
 }
 
-func (c Collector) GetProperties() map[string]map[string]int {
+func (c *Collector) GetProperties() map[string]map[string]int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	return c.Apis[c.currentApi].endpoints
 }
 
@@ -118,9 +148,11 @@ func sortPathStrings(m map[string]string) []string {
 }
 
 func (c *Collector) CollectProperties(method, endpoint, body string, code int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	if len(c.Apis) == 0 {
 		logrus.Warnln("Warning no API defined yet")
-		c.SetCollectorAPIDetails("undefined", "0.0")
+		c.setCollectorAPIDetails("undefined", "0.0")
 	}
 
 	requestPaths := make(map[string]int, 20)
@@ -144,13 +176,17 @@ func (c *Collector) CollectProperties(method, endpoint, body string, code int) {
 	}
 	sort.Strings(keyslice)
 
-	pathmap := make(map[string]int, 0)
+	// merge with the fields of earlier responses, so a field any response had is kept
+	shortname := c.stripName(endpoint)
+	key := method + " " + shortname + " " + strconv.Itoa(code)
+	pathmap := c.Apis[c.currentApi].endpoints[key]
+	if pathmap == nil {
+		pathmap = make(map[string]int, 0)
+	}
 	for _, v := range keyslice {
 		pathmap[v] = 0
 	}
-
-	shortname := c.stripName(endpoint)
-	c.Apis[c.currentApi].endpoints[method+" "+shortname+" "+strconv.Itoa(code)] = pathmap
+	c.Apis[c.currentApi].endpoints[key] = pathmap
 
 	return
 }
@@ -197,7 +233,9 @@ func (c *Collector) expand(i interface{}, m map[string]int) {
 	c.level--
 }
 
-func (c Collector) OutputJSON() string {
+func (c *Collector) OutputJSON() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	apis := c.Apis
 	var err error
 
@@ -270,7 +308,7 @@ func (c Collector) OutputJSON() string {
 	return "{ \n\"responseFields\": " + string(jsondata) + "\n}"
 }
 
-func (c Collector) addConsentsToApiGroup(group string, consents, api *PropertyOutput) {
+func (c *Collector) addConsentsToApiGroup(group string, consents, api *PropertyOutput) {
 	for k, _ := range consents.endpoints {
 		_, path, _ := c.parseEndpoint(k)
 		apigroup, _ := FindApi(path)
@@ -286,7 +324,9 @@ func (c Collector) addConsentsToApiGroup(group string, consents, api *PropertyOu
 
 }
 
-func (c Collector) OutputJSON1() string {
+func (c *Collector) OutputJSON1() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	apis := c.Apis
 	var err error
 	for i, api := range apis {
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"sync"
 	"testing"
 
 	"github.com/sirupsen/logrus"
@@ -12,7 +13,7 @@ import (
 
 func TestCollectReturnedJSONFields(t *testing.T) {
 	logrus.SetLevel(logrus.TraceLevel)
-	c := GetPropertyCollector()
+	c := MakeCollector()
 	c.SetCollectorAPIDetails("myapi", "v3.1.0")
 	c.CollectProperties("GET", "/accounts", string(tdata1), 200)
 	c.OutputJSON()
@@ -20,7 +21,7 @@ func TestCollectReturnedJSONFields(t *testing.T) {
 
 func TestTransactionsJSONFields(t *testing.T) {
 	logrus.SetLevel(logrus.TraceLevel)
-	c := GetPropertyCollector()
+	c := MakeCollector()
 	c.SetCollectorAPIDetails("myapi", "v3.1.0")
 	c.CollectProperties("GET", "https://myserver/open-banking/3.1/aisp/accounts/1234567853/transactions", string(atransaction), 200)
 	c.SetCollectorAPIDetails("yourapi", "v3.1.1")
@@ -31,7 +32,7 @@ func TestTransactionsJSONFields(t *testing.T) {
 
 func TestAccountsJSONFields(t *testing.T) {
 	logrus.SetLevel(logrus.TraceLevel)
-	c := GetPropertyCollector()
+	c := MakeCollector()
 	c.SetCollectorAPIDetails("myapi", "v3.1.0")
 	c.CollectProperties("GET", "/open-banking/3.1/aisp/accounts", string(accounts), 200)
 	result := c.OutputJSON()
@@ -39,7 +40,7 @@ func TestAccountsJSONFields(t *testing.T) {
 }
 
 func TestAddEmptyAPI(t *testing.T) {
-	c := GetPropertyCollector()
+	c := MakeCollector()
 	c.CollectProperties("GET", "https://myserver/open-banking/3.1/aisp/accounts/1234567853/transactions", string(atransaction), 200)
 	apitype, err := FindApi("https://myserver/open-banking/3.1/aisp/accounts/1234567853/transactions")
 	assert.Equal(t, "accounts", apitype)
@@ -59,7 +60,7 @@ func TestAddEmptyAPI(t *testing.T) {
 }
 
 func TestAddUnnamedApiThenMerge(t *testing.T) {
-	c := GetPropertyCollector()
+	c := MakeCollector()
 	c.SetCollectorAPIDetails(ConsentGathering, "1")
 
 	c.CollectProperties("GET", "https://myserver/open-banking/3.1/aisp/accounts", string(accounts), 200)
@@ -76,7 +77,7 @@ func TestAddUnnamedApiThenMerge(t *testing.T) {
 
 func TestMergeUnnamedApiThenMerge(t *testing.T) {
 
-	c := GetPropertyCollector()
+	c := MakeCollector()
 	c.SetCollectorAPIDetails("ConsentGathering", "")
 	c.CollectProperties("GET", "https://myserver/open-banking/3.1/aisp/accounts", string(accountsmerge), 200)
 
@@ -90,6 +91,41 @@ func TestMergeUnnamedApiThenMerge(t *testing.T) {
 
 }
 
+func TestMakeRunCollectorCopiesConsentFields(t *testing.T) {
+	consents := MakeCollector()
+	consents.SetCollectorAPIDetails(ConsentGathering, "")
+	consents.CollectProperties("GET", "https://myserver/open-banking/3.1/aisp/accounts", string(accountsmerge), 200)
+
+	first := MakeRunCollector(consents)
+	first.SetCollectorAPIDetails("Accounts and Trasactions", "v3.1.0")
+	first.CollectProperties("GET", "https://myserver/open-banking/3.1/aisp/accounts/1234567853/transactions", string(atransaction), 200)
+	second := MakeRunCollector(consents)
+	second.SetCollectorAPIDetails("Accounts and Trasactions", "v3.1.0")
+	second.CollectProperties("GET", "https://myserver/open-banking/3.1/aisp/accounts", string(accounts), 200)
+
+	assert.Contains(t, first.OutputJSON(), "MyFatMergeField")
+	assert.Contains(t, first.OutputJSON(), "Data.Transaction.Balance.CreditDebitIndicator")
+	assert.Contains(t, second.OutputJSON(), "MyFatMergeField")
+	assert.NotContains(t, second.OutputJSON(), "Data.Transaction.Balance.CreditDebitIndicator", "runs do not share fields")
+	assert.Len(t, consents.GetProperties(), 1, "the fields gathered with consent are left unchanged")
+}
+
+func TestCollectorCollectsConcurrently(t *testing.T) {
+	c := MakeCollector()
+	c.SetCollectorAPIDetails("Accounts and Trasactions", "v3.1.0")
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.CollectProperties("GET", "https://myserver/open-banking/3.1/aisp/accounts", string(accounts), 200)
+		}()
+	}
+	wg.Wait()
+
+	assert.Contains(t, c.OutputJSON(), "Data.Account.AccountId")
+}
+
 var (
 	acctPay1  = append(accountsRegex, paymentsRegex...)
 	allregex1 = append(acctPay, cbpiiRegex...)
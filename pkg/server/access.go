@@ -0,0 +1,210 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/access"
+)
+
+const (
+	// principalKey - the authenticated user in the echo.Context
+	principalKey = "principal"
+
+	loginPath    = "/auth/login"
+	callbackPath = "/auth/callback"
+	logoutPath   = "/auth/logout"
+
+	// public - routes anyone may use
+	public access.Role = 0
+)
+
+// routeRoles - the role needed for routes that do not follow the rule in requiredRole, by method
+// and route, with the journey routes under /api
+var routeRoles = map[string]access.Role{
+	"GET /api/ping":                   public,
+	"POST /api/export":                access.Viewer,
+	"POST /api/config/global":         access.Admin,
+	"POST /api/import/rerun":          access.Admin,
+	"DELETE /api/runs/:" + runIDParam: access.Admin,
+}
+
+// requiredRole - the role needed to use `route` with `method`. Viewers may read and operators may
+// make changes, apart from the routes in routeRoles.
+func requiredRole(method, route string) access.Role {
+	if role, ok := routeRoles[method+" "+route]; ok {
+		return role
+	}
+	if method == http.MethodGet || method == http.MethodHead {
+		return access.Viewer
+	}
+	return access.Operator
+}
+
+// RequireAccess - only lets the users authenticated by `a` use the API and metrics, each as far
+// as their role allows, and records what they do in the audit log. When OIDC is configured users
+// log in at /auth/login, and are sent there when they open the web UI without a session.
+func (s *Server) RequireAccess(a access.Access) {
+	logger := s.logger.WithField("module", "access")
+	handlers := accessHandlers{access: a, logger: logger}
+	if a.OIDC != nil {
+		s.Pre(handlers.loginPageMiddleware)
+		s.GET(loginPath, handlers.loginHandler)
+		s.GET(callbackPath, handlers.callbackHandler)
+		s.POST(logoutPath, handlers.logoutHandler)
+	}
+	s.Use(handlers.middleware)
+}
+
+// StartTLSWithClientCAs - starts an HTTPS server asking clients for a certificate issued by one
+// of `clientCAs`. Clients without one can still authenticate in other ways.
+func (s *Server) StartTLSWithClientCAs(address, certFile, keyFile string, clientCAs *x509.CertPool) error {
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	s.TLSServer.Addr = address
+	s.TLSServer.TLSConfig = &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		ClientCAs:    clientCAs,
+	}
+	return s.StartServer(s.TLSServer)
+}
+
+type accessHandlers struct {
+	access access.Access
+	logger *logrus.Entry
+}
+
+// middleware - authenticates requests to the API and checks the user's role
+func (h accessHandlers) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		path := c.Path()
+		if !strings.HasPrefix(path, "/api") && path != metricsPath {
+			return next(c)
+		}
+		route := strings.Replace(path, "/api/journeys/:journey_id", "/api", 1)
+		required := requiredRole(c.Request().Method, route)
+		if required == public {
+			return next(c)
+		}
+
+		principal, err := h.access.Authenticator.Authenticate(c.Request())
+		entry := h.entry(c, principal)
+		if err != nil {
+			entry.Outcome = access.OutcomeUnauthenticated
+			entry.Status = http.StatusUnauthorized
+			h.record(entry)
+			return c.JSON(http.StatusUnauthorized, NewErrorMessageResponse("authentication required"))
+		}
+		if !principal.Role.Allows(required) {
+			entry.Outcome = access.OutcomeForbidden
+			entry.Status = http.StatusForbidden
+			h.record(entry)
+			return c.JSON(http.StatusForbidden, NewErrorMessageResponse(principal.Role.String()+" role may not "+entry.Action))
+		}
+
+		c.Set(principalKey, principal)
+		err = next(c)
+		if required == access.Viewer {
+			// reading results is not audited
+			return err
+		}
+		if err != nil {
+			c.Error(err)
+			err = nil
+		}
+		entry.Outcome = access.OutcomeAllowed
+		entry.Status = c.Response().Status
+		h.record(entry)
+		return err
+	}
+}
+
+// loginPageMiddleware - sends users opening the web UI without a session to log in
+func (h accessHandlers) loginPageMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		request := c.Request()
+		if request.Method != http.MethodGet || !isPage(request.URL.Path) {
+			return next(c)
+		}
+		if _, err := h.access.Authenticator.Authenticate(request); err == nil {
+			return next(c)
+		}
+		return c.Redirect(http.StatusFound, loginPath+"?redirect="+url.QueryEscape(request.URL.RequestURI()))
+	}
+}
+
+// isPage - whether `path` is part of the web UI
+func isPage(path string) bool {
+	for _, prefix := range []string{"/api", "/auth", "/swagger", metricsPath} {
+		if strings.HasPrefix(path, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+func (h accessHandlers) loginHandler(c echo.Context) error {
+	authorizationURL, err := h.access.OIDC.Login(c.Response(), c.QueryParam("redirect"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, NewErrorResponse(err))
+	}
+	return c.Redirect(http.StatusFound, authorizationURL)
+}
+
+func (h accessHandlers) callbackHandler(c echo.Context) error {
+	principal, redirect, err := h.access.OIDC.Callback(c.Response(), c.Request())
+	entry := h.entry(c, principal)
+	if err != nil {
+		entry.Outcome = access.OutcomeLoginFailed
+		entry.Status = http.StatusUnauthorized
+		entry.Error = err.Error()
+		h.record(entry)
+		return c.JSON(http.StatusUnauthorized, NewErrorMessageResponse("login failed"))
+	}
+	entry.Outcome = access.OutcomeLogin
+	entry.Status = http.StatusFound
+	h.record(entry)
+	return c.Redirect(http.StatusFound, redirect)
+}
+
+func (h accessHandlers) logoutHandler(c echo.Context) error {
+	principal, _ := h.access.Authenticator.Authenticate(c.Request())
+	h.access.OIDC.Logout(c.Response())
+	entry := h.entry(c, principal)
+	entry.Outcome = access.OutcomeLogout
+	entry.Status = http.StatusNoContent
+	h.record(entry)
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h accessHandlers) entry(c echo.Context, principal access.Principal) access.AuditEntry {
+	entry := access.AuditEntry{
+		Time:     time.Now().UTC(),
+		User:     principal.Name,
+		Method:   principal.Method,
+		Action:   c.Request().Method + " " + c.Path(),
+		Path:     c.Request().URL.Path,
+		Journey:  c.Param("journey_id"),
+		RemoteIP: c.RealIP(),
+	}
+	if principal.Role != 0 {
+		entry.Role = principal.Role.String()
+	}
+	return entry
+}
+
+func (h accessHandlers) record(entry access.AuditEntry) {
+	if err := h.access.Audit.Record(entry); err != nil {
+		h.logger.WithError(err).WithField("entry", entry).Error("writing audit log")
+	}
+}
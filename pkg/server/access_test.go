@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/access"
+	"github.com/OpenBankingUK/conformance-suite/pkg/test"
+)
+
+func TestRequiredRole(t *testing.T) {
+	assert := test.NewAssert(t)
+
+	assert.Equal(public, requiredRole(http.MethodGet, "/api/ping"))
+	assert.Equal(access.Viewer, requiredRole(http.MethodGet, "/api/runs/:run_id"))
+	assert.Equal(access.Viewer, requiredRole(http.MethodGet, "/api/run/ws"))
+	assert.Equal(access.Viewer, requiredRole(http.MethodPost, "/api/export"))
+	assert.Equal(access.Operator, requiredRole(http.MethodPost, "/api/run"))
+	assert.Equal(access.Operator, requiredRole(http.MethodPost, "/api/discovery-model"))
+	assert.Equal(access.Admin, requiredRole(http.MethodPost, "/api/config/global"))
+	assert.Equal(access.Admin, requiredRole(http.MethodDelete, "/api/runs/:run_id"))
+}
+
+func TestServerRequireAccess(t *testing.T) {
+	require := test.NewRequire(t)
+
+	dir, err := ioutil.TempDir("", "access")
+	require.NoError(err)
+	auditLog, err := access.NewFileAuditLog(filepath.Join(dir, "audit.log"))
+	require.NoError(err)
+	tokens, err := access.NewTokenAuthenticator([]access.Token{
+		{Name: "dashboard", Role: access.Viewer, SHA256: sha256Hex("viewer-token")},
+		{Name: "pipeline", Role: access.Operator, SHA256: sha256Hex("operator-token")},
+	})
+	require.NoError(err)
+
+	server := NewServer(journeysFor(testJourney()), nil, nullLogger(), makeVersionMock("", false))
+	defer func() {
+		require.NoError(server.Shutdown(context.TODO()))
+	}()
+	server.ServeMetrics()
+	server.RequireAccess(access.Access{Authenticator: access.Authenticators{tokens}, Audit: auditLog})
+
+	code, _ := requestWithToken(http.MethodGet, "/api/ping", "", server)
+	require.Equal(http.StatusOK, code, "ping is public")
+
+	code, body := requestWithToken(http.MethodGet, "/api/version", "", server)
+	require.Equal(http.StatusUnauthorized, code)
+	require.JSONEq(`{"error": "authentication required"}`, body)
+
+	code, _ = requestWithToken(http.MethodGet, "/metrics", "", server)
+	require.Equal(http.StatusUnauthorized, code)
+
+	code, _ = requestWithToken(http.MethodGet, "/metrics", "viewer-token", server)
+	require.Equal(http.StatusOK, code)
+
+	code, body = requestWithToken(http.MethodPost, "/api/config/global", "operator-token", server)
+	require.Equal(http.StatusForbidden, code)
+	require.JSONEq(`{"error": "operator role may not POST /api/config/global"}`, body)
+
+	code, _ = requestWithToken(http.MethodPost, "/api/journeys/missing/run", "viewer-token", server)
+	require.Equal(http.StatusForbidden, code, "journey routes need the same role as the unprefixed ones")
+
+	code, _ = requestWithToken(http.MethodPost, "/api/journeys", "operator-token", server)
+	require.Equal(http.StatusCreated, code)
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "audit.log"))
+	require.NoError(err)
+	entries := []access.AuditEntry{}
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		entry := access.AuditEntry{}
+		require.NoError(json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	require.Len(entries, 5, "reads by authenticated users are not audited")
+
+	require.Equal(access.OutcomeUnauthenticated, entries[0].Outcome)
+	require.Equal("GET /api/version", entries[0].Action)
+	require.Equal("pipeline", entries[2].User)
+	require.Equal(access.OutcomeForbidden, entries[2].Outcome)
+	require.Equal("dashboard", entries[3].User)
+	require.Equal("missing", entries[3].Journey)
+	require.Equal(access.AuditEntry{
+		Time:     entries[4].Time,
+		User:     "pipeline",
+		Role:     "operator",
+		Method:   access.MethodToken,
+		Action:   "POST /api/journeys",
+		Path:     "/api/journeys",
+		Status:   http.StatusCreated,
+		Outcome:  access.OutcomeAllowed,
+		RemoteIP: "192.0.2.1",
+	}, entries[4])
+}
+
+func requestWithToken(method, path, token string, server *Server) (int, string) {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.String()
+}
+
+func sha256Hex(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -10,15 +10,17 @@ import (
 
 	"github.com/OpenBankingUK/conformance-suite/pkg/discovery"
 	"github.com/OpenBankingUK/conformance-suite/pkg/server/models"
-	"gopkg.in/resty.v1"
 
 	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
 	"github.com/labstack/echo"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
 	"github.com/OpenBankingUK/conformance-suite/pkg/authentication"
 	"github.com/OpenBankingUK/conformance-suite/pkg/model"
+	"github.com/OpenBankingUK/conformance-suite/pkg/notify"
+	"github.com/OpenBankingUK/conformance-suite/pkg/preflight"
 )
 
 // ResponseType - Needs to be a interface{} slice, see the official test for an example
@@ -89,6 +91,14 @@ type GlobalConfiguration struct {
 	AcrValuesSupported            []string                             `json:"acr_values_supported,omitempty"`
 	ConditionalProperties         []discovery.ConditionalAPIProperties `json:"conditional_properties,omitempty"`
 	CBPIIDebtorAccount            discovery.CBPIIDebtorAccount         `json:"cbpii_debtor_account"`
+	// Dynamic Client Registration tests only run when a registration endpoint is provided
+	RegistrationEndpoint     string `json:"registration_endpoint,omitempty"`
+	SoftwareStatement        string `json:"software_statement,omitempty"`
+	ExpiredSoftwareStatement string `json:"expired_software_statement,omitempty"`
+	// JWKS the signing key is published in, only used by the pre-flight check
+	TPPJWKSURI string `json:"tpp_jwks_uri,omitempty"`
+	// Webhooks notified when a run completes
+	Notifications []notify.Hook `json:"notifications,omitempty"`
 	// Should be taken from the well-known endpoint:
 	Issuer string `json:"issuer" validate:"valid_url"`
 }
@@ -107,9 +117,23 @@ func (c GlobalConfiguration) Validate() error {
 		validation.Field(&c.RequestedExecutionDateTime, validation.By(futureDateTimeValidator)),
 		validation.Field(&c.PaymentFrequency, validation.Required),
 		validation.Field(&c.CBPIIDebtorAccount, validation.Required),
+		validation.Field(&c.RegistrationEndpoint, is.URL),
+		validation.Field(&c.SoftwareStatement, validation.By(softwareStatementValidator(c.RegistrationEndpoint))),
+		validation.Field(&c.Notifications),
 	)
 }
 
+// softwareStatementValidator - a software statement is required when DCR is to be tested
+func softwareStatementValidator(registrationEndpoint string) validation.RuleFunc {
+	return func(value interface{}) error {
+		ssa, _ := value.(string)
+		if registrationEndpoint != "" && ssa == "" {
+			return fmt.Errorf("softwareStatementValidator: `software_statement` is required when `registration_endpoint` is set")
+		}
+		return nil
+	}
+}
+
 func futureDateTimeValidator(value interface{}) error {
 	dateTimeStr, ok := value.(string)
 	if !ok {
@@ -189,9 +213,6 @@ func (h configHandlers) configGlobalPostHandler(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, NewErrorResponse(err))
 	}
 
-	// Use the transport keys for MATLS as some endpoints require this
-	resty.SetCertificates(journeyConfig.certificateTransport.TLSCert())
-
 	err = h.journey.SetConfig(journeyConfig)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, NewErrorResponse(err))
@@ -200,6 +221,33 @@ func (h configHandlers) configGlobalPostHandler(c echo.Context) error {
 	return c.JSON(http.StatusCreated, config)
 }
 
+// POST /api/config/preflight
+// Checks the certificates in a global configuration without setting it. The report is
+// returned with 200 OK whether or not the checks pass.
+func (h configHandlers) configPreflightPostHandler(c echo.Context) error {
+	config := new(GlobalConfiguration)
+	if err := c.Bind(config); err != nil {
+		return c.JSON(http.StatusBadRequest, NewErrorResponse(errors.Wrap(err, "error with Bind")))
+	}
+
+	report := preflight.Run(preflight.Config{
+		SigningPublic:    config.SigningPublic,
+		SigningPrivate:   config.SigningPrivate,
+		TransportPublic:  config.TransportPublic,
+		TransportPrivate: config.TransportPrivate,
+		SigningKID:       config.TPPSignatureKID,
+		JWKSURI:          config.TPPJWKSURI,
+		TrustAnchor:      config.TPPSignatureTAN,
+		Issuer:           config.TPPSignatureIssuer,
+		TokenEndpoint:    config.TokenEndpoint,
+	})
+	if !report.Pass {
+		h.logger.WithField("checks", report.Checks).Warn("pre-flight checks failed")
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
 // MakeJourneyConfig -
 func MakeJourneyConfig(config *GlobalConfiguration) (JourneyConfig, error) {
 	ok, message := validateConfig(config)
@@ -249,6 +297,10 @@ func MakeJourneyConfig(config *GlobalConfiguration) (JourneyConfig, error) {
 		AcrValuesSupported:            config.AcrValuesSupported,
 		conditionalProperties:         config.ConditionalProperties,
 		cbpiiDebtorAccount:            config.CBPIIDebtorAccount,
+		registrationEndpoint:          config.RegistrationEndpoint,
+		softwareStatement:             config.SoftwareStatement,
+		expiredSoftwareStatement:      config.ExpiredSoftwareStatement,
+		notifications:                 config.Notifications,
 		issuer:                        config.Issuer, // TBD: available from well-known ?
 	}, nil
 }
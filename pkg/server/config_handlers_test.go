@@ -13,6 +13,7 @@ import (
 	"github.com/OpenBankingUK/conformance-suite/pkg/discovery"
 	"github.com/OpenBankingUK/conformance-suite/pkg/generation"
 	"github.com/OpenBankingUK/conformance-suite/pkg/model"
+	"github.com/OpenBankingUK/conformance-suite/pkg/preflight"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 
@@ -240,7 +241,7 @@ func TestValidateConfigTestsEmpty(t *testing.T) {
 func TestServerConfigGlobalPostValid(t *testing.T) {
 	require := test.NewRequire(t)
 	logger := logrus.New()
-	server := NewServer(testJourney(), logger.WithField("component", "test-server"), &mocks.Version{})
+	server := NewServer(journeysFor(testJourney()), nil, logger.WithField("component", "test-server"), &mocks.Version{})
 	defer func() {
 		require.NoError(server.Shutdown(context.TODO()))
 	}()
@@ -867,7 +868,7 @@ func TestServerConfigGlobalPostInvalid(t *testing.T) {
 		t.Run(testCase.name, func(t *testing.T) {
 			require := test.NewRequire(t)
 
-			server := NewServer(testJourney(), nullLogger(), &mocks.Version{})
+			server := NewServer(journeysFor(testJourney()), nil, nullLogger(), &mocks.Version{})
 			defer func() {
 				require.NoError(server.Shutdown(context.TODO()))
 			}()
@@ -890,6 +891,41 @@ func TestServerConfigGlobalPostInvalid(t *testing.T) {
 	}
 }
 
+// TestServerConfigPreflightPost - the test certificate has expired and is only valid for serverAuth
+func TestServerConfigPreflightPost(t *testing.T) {
+	require := test.NewRequire(t)
+
+	server := NewServer(journeysFor(testJourney()), nil, nullLogger(), &mocks.Version{})
+	defer func() {
+		require.NoError(server.Shutdown(context.TODO()))
+	}()
+
+	configJSON, err := json.Marshal(&GlobalConfiguration{
+		SigningPrivate:   privateKey,
+		SigningPublic:    publicKey,
+		TransportPrivate: privateKey,
+		TransportPublic:  publicKey,
+	})
+	require.NoError(err)
+
+	code, body, headers := request(http.MethodPost, "/api/config/preflight", bytes.NewReader(configJSON), server)
+
+	require.Equal(http.StatusOK, code)
+	require.Equal(expectedJSONHeaders(), headers)
+	report := preflight.Report{}
+	require.NoError(json.Unmarshal(body.Bytes(), &report))
+	require.False(report.Pass)
+	require.Equal("O=Acme Co", report.Signing.Subject)
+	statuses := map[string]preflight.Status{}
+	for _, check := range report.Checks {
+		statuses[check.Name] = check.Status
+	}
+	require.Equal(preflight.StatusPass, statuses["signing key pair"])
+	require.Equal(preflight.StatusFail, statuses["signing expiry"])
+	require.Equal(preflight.StatusFail, statuses["transport key usage"])
+	require.Equal(preflight.StatusSkip, statuses["mTLS handshake"])
+}
+
 func testJourney() Journey {
 	logger := nullLogger()
 	validatorEngine := discovery.NewFuncValidator(model.NewConditionalityChecker())
@@ -111,6 +111,7 @@ func (d discoveryHandlers) setDiscoveryModelHandler(c echo.Context) error {
 			response.DefaultTxnToDateTime = defaultTxnTo
 			response.ResponseTypesSupported = config.ResponseTypesSupported
 			response.AcrValuesSupported = config.AcrValuesSupported
+			d.webJourney.SetJWKSURI(config.JwksURI)
 		}
 	}
 
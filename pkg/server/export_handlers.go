@@ -9,7 +9,7 @@ import (
 	"github.com/labstack/echo"
 	"github.com/sirupsen/logrus"
 
-	"github.com/OpenBankingUK/conformance-suite/pkg/model"
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors/events"
 	"github.com/OpenBankingUK/conformance-suite/pkg/report"
 	"github.com/OpenBankingUK/conformance-suite/pkg/server/models"
 )
@@ -48,6 +48,7 @@ func (h exportHandlers) postExport(c echo.Context) error {
 	results := h.journey.Results().AllResultsGrouped()
 	responseFields := h.journey.Results().ResponseFieldsJSON()
 	tokens := h.journey.Events().AllAcquiredAccessToken()
+	tokenStates := events.LatestTokenStates(h.journey.Events().AllTokenStateChanges())
 	discovery, err := h.journey.DiscoveryModel()
 
 	if err != nil {
@@ -59,10 +60,11 @@ func (h exportHandlers) postExport(c echo.Context) error {
 		HasPassed:        false,
 		Results:          results,
 		Tokens:           tokens,
+		TokenStates:      tokenStates,
 		DiscoveryModel:   discovery,
 		TLSVersionResult: h.journey.TLSVersionResult(),
 		ResponseFields:   responseFields,
-		JWSStatus:        model.JWSStatus(),
+		JWSStatus:        h.journey.JWSStatus(),
 	}
 
 	r, err := report.NewReport(exportResults, request.Environment)
@@ -34,7 +34,7 @@ func TestServerPostExport(t *testing.T) {
 	require.NoError(err)
 	require.Equal(discovery.NoValidationFailures(), failures)
 
-	server := NewServer(journey, nullLogger(), &version_mocks.Version{})
+	server := NewServer(journeysFor(journey), nil, nullLogger(), &version_mocks.Version{})
 	defer func() {
 		require.NoError(server.Shutdown(context.TODO()))
 	}()
@@ -117,7 +117,7 @@ func TestServerPostExport(t *testing.T) {
 func TestServerPostExport_InvalidRequest(t *testing.T) {
 	require := test.NewRequire(t)
 
-	server := NewServer(testJourney(), nullLogger(), &version_mocks.Version{})
+	server := NewServer(journeysFor(testJourney()), nil, nullLogger(), &version_mocks.Version{})
 	defer func() {
 		require.NoError(server.Shutdown(context.TODO()))
 	}()
@@ -16,7 +16,7 @@ import (
 func TestServerImportHandlersPostImportReview(t *testing.T) {
 	require := test.NewRequire(t)
 
-	server := NewServer(testJourney(), nullLogger(), &mocks.Version{})
+	server := NewServer(journeysFor(testJourney()), nil, nullLogger(), &mocks.Version{})
 	defer func() {
 		require.NoError(server.Shutdown(context.TODO()))
 	}()
@@ -1,24 +1,35 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/blang/semver/v4"
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"gopkg.in/resty.v1"
 
 	"github.com/OpenBankingUK/conformance-suite/pkg/authentication"
+	"github.com/OpenBankingUK/conformance-suite/pkg/dcr"
 	"github.com/OpenBankingUK/conformance-suite/pkg/discovery"
 	"github.com/OpenBankingUK/conformance-suite/pkg/executors"
 	"github.com/OpenBankingUK/conformance-suite/pkg/executors/events"
 	"github.com/OpenBankingUK/conformance-suite/pkg/generation"
 	"github.com/OpenBankingUK/conformance-suite/pkg/manifest"
 	"github.com/OpenBankingUK/conformance-suite/pkg/model"
+	"github.com/OpenBankingUK/conformance-suite/pkg/notify"
+	"github.com/OpenBankingUK/conformance-suite/pkg/runevents"
+	"github.com/OpenBankingUK/conformance-suite/pkg/runstore"
 	"github.com/OpenBankingUK/conformance-suite/pkg/schemaprops"
 	"github.com/OpenBankingUK/conformance-suite/pkg/server/models"
+	"github.com/OpenBankingUK/conformance-suite/pkg/tracing"
 )
 
 var (
@@ -43,19 +54,26 @@ var (
 type Journey interface {
 	SetDiscoveryModel(discoveryModel *discovery.Model) (discovery.ValidationFailures, error)
 	DiscoveryModel() (discovery.Model, error)
+	SetJWKSURI(jwksURI string)
 	SetFilteredManifests(manifest.Scripts)
 	FilteredManifests() (manifest.Scripts, error)
 	TestCases() (generation.SpecRun, error)
 	CollectToken(code, state, scope string) error
 	AllTokenCollected() bool
 	RunTests() error
+	ResumeTests(runID string) (ResumeStatus, error)
 	StopTestRun()
 	NewDaemonController()
 	Results() executors.DaemonController
 	SetConfig(config JourneyConfig) error
 	ConditionalProperties() []discovery.ConditionalAPIProperties
 	Events() events.Events
+	RunEvents() runevents.Stream
 	TLSVersionResult() map[string]*discovery.TLSValidationResult
+	JWSStatus() string
+	RunLoad(options LoadOptions) error
+	LoadReport() (executors.LoadReport, error)
+	StopLoad()
 }
 
 // AppJourney - application controlled by this class
@@ -79,12 +97,28 @@ type AppJourney struct {
 	tlsValidator          discovery.TLSValidator
 	conditionalProperties []discovery.ConditionalAPIProperties
 	dynamicResourceIDs    bool
+	jwksURI               string
+	runStore              runstore.RunStore
+	resuming              *resumption
+	stream                runevents.Stream
+	notifier              notify.Notifier
+	load                  *executors.LoadRunner
+	runner                *executors.TestCaseRunner // the last test run started
+	// consentTrace - holds the span of the PSU consent acquisition, the codes the PSU consents
+	// with are exchanged for tokens under it
+	consentTrace context.Context
+	// client - sends the journey's requests to the ASPSP, with its transport certificate
+	client *resty.Client
+	// consentProperties - the response fields collected while gathering consent, each test run
+	// starts with a copy of them
+	consentProperties schemaprops.PropertyCollector
 }
 
 // NewJourney creates an instance for a user journey
 func NewJourney(logger *logrus.Entry, generator generation.Generator,
 	validator discovery.Validator, tlsValidator discovery.TLSValidator,
 	dynamicResourceIDs bool) *AppJourney {
+	stream := runevents.NewStream(nil, logger)
 	return &AppJourney{
 		generator:             generator,
 		validator:             validator,
@@ -94,11 +128,15 @@ func NewJourney(logger *logrus.Entry, generator generation.Generator,
 		testCasesRunGenerated: false,
 		context:               model.Context{},
 		log:                   logger.WithField("module", "journey"),
-		events:                events.NewEvents(),
+		events:                newStreamingEvents(stream),
 		permissions:           make(map[string][]manifest.RequiredTokens),
 		manifests:             make([]manifest.Scripts, 0),
 		tlsValidator:          tlsValidator,
 		dynamicResourceIDs:    dynamicResourceIDs,
+		stream:                stream,
+		notifier:              notify.NewNotifier(&http.Client{Timeout: notifyTimeout}, logger),
+		client:                resty.New(),
+		consentProperties:     schemaprops.MakeCollector(),
 	}
 }
 
@@ -112,7 +150,7 @@ func (wj *AppJourney) NewDaemonController() {
 	wj.journeyLock.Lock()
 	defer wj.journeyLock.Unlock()
 	wj.daemonController = executors.NewBufferedDaemonController()
-	wj.events = events.NewEvents()
+	wj.events = newStreamingEvents(wj.stream)
 }
 
 // SetDiscoveryModel -
@@ -149,6 +187,14 @@ func (wj *AppJourney) SetDiscoveryModel(discoveryModel *discovery.Model) (discov
 	return discovery.NoValidationFailures(), nil
 }
 
+// SetJWKSURI - the jwks_uri from the ASPSP's openid-configuration, kept per journey as journeys
+// can be testing different ASPSPs
+func (wj *AppJourney) SetJWKSURI(jwksURI string) {
+	wj.journeyLock.Lock()
+	defer wj.journeyLock.Unlock()
+	wj.jwksURI = jwksURI
+}
+
 // DiscoveryModel -
 func (wj *AppJourney) DiscoveryModel() (discovery.Model, error) {
 	wj.journeyLock.Lock()
@@ -227,7 +273,10 @@ func (wj *AppJourney) TestCases() (generation.SpecRun, error) {
 		return generation.SpecRun{}, errTestCasesGenerated
 	}
 
-	jwksURI := authentication.GetJWKSUri()
+	jwksURI := wj.jwksURI
+	if jwksURI == "" {
+		jwksURI = authentication.GetJWKSUri()
+	}
 	if jwksURI != "" { // STORE jwks_uri from well known endpoint in journey context
 		wj.context.PutString("jwks_uri", jwksURI)
 	} else {
@@ -274,11 +323,7 @@ func (wj *AppJourney) TestCases() (generation.SpecRun, error) {
 	logrus.Tracef("conditionalProperties from journey config: %#v", wj.config.conditionalProperties)
 	wj.specRun, wj.filteredManifests, wj.permissions = wj.generator.GenerateManifestTests(wj.log, config, discovery, &wj.context, wj.config.conditionalProperties)
 
-	tests := 0
-	for _, sp := range wj.specRun.SpecTestCases {
-		tests += len(sp.TestCases)
-	}
-	if tests == 0 { // no tests to run
+	if testCount(wj.specRun) == 0 { // no tests to run
 		logrus.Warn("No TestCases Generated!!!")
 		return generation.SpecRun{}, errNoTestCases
 	}
@@ -292,8 +337,8 @@ func (wj *AppJourney) TestCases() (generation.SpecRun, error) {
 		}
 	}
 
-	collector := schemaprops.GetPropertyCollector()
-	collector.SetCollectorAPIDetails(schemaprops.ConsentGathering, "")
+	wj.consentProperties = schemaprops.MakeCollector()
+	wj.consentProperties.SetCollectorAPIDetails(schemaprops.ConsentGathering, "")
 
 	if discovery.TokenAcquisition == "psu" || discovery.TokenAcquisition == "mobile" { // Handle  PSU Consent
 		logger.WithFields(logrus.Fields{
@@ -301,40 +346,15 @@ func (wj *AppJourney) TestCases() (generation.SpecRun, error) {
 		}).Debug("AcquirePSUTokens ...")
 		definition := wj.makeRunDefinition()
 
-		consentIds, tokenMap, err := executors.GetPsuConsent(definition, &wj.context, &wj.specRun, wj.permissions)
+		consentIds, tokenMap, err := wj.getPsuConsent(definition, wj.permissions)
 		if err != nil {
 			logger.WithFields(logrus.Fields{
 				"err": err,
 			}).Error("Error on executors.GetPsuConsent ...")
-			return generation.SpecRun{}, errors.WithMessage(errConsentIDAcquisitionFailed, err.Error())
+			return generation.SpecRun{}, publishError(wj.stream, errors.WithMessage(errConsentIDAcquisitionFailed, err.Error()))
 		}
 
-		for k := range wj.permissions {
-			if k == "payments" {
-				paymentpermissions := wj.permissions["payments"]
-				if len(paymentpermissions) > 0 {
-					for _, spec := range wj.specRun.SpecTestCases {
-						manifest.MapTokensToPaymentTestCases(paymentpermissions, spec.TestCases, &wj.context)
-					}
-				}
-			}
-			if k == "vrps" {
-				vrpspermissions := wj.permissions["vrps"]
-				if len(vrpspermissions) > 0 {
-					for _, spec := range wj.specRun.SpecTestCases {
-						manifest.MapTokensToPaymentTestCases(vrpspermissions, spec.TestCases, &wj.context)
-					}
-				}
-			}
-			if k == "cbpii" {
-				cbpiiPerms := wj.permissions["cbpii"]
-				if len(cbpiiPerms) > 0 {
-					for _, spec := range wj.specRun.SpecTestCases {
-						manifest.MapTokensToCBPIITestCases(cbpiiPerms, spec.TestCases, &wj.context)
-					}
-				}
-			}
-		}
+		wj.mapPSUConsentTokens(wj.permissions)
 
 		for k, v := range tokenMap {
 			wj.context.PutString(k, v)
@@ -342,6 +362,7 @@ func (wj *AppJourney) TestCases() (generation.SpecRun, error) {
 		}
 
 		wj.createTokenCollector(consentIds)
+		publishConsentRequired(wj.stream, consentIds)
 
 	} else { // Handle headless token acquistion
 
@@ -355,7 +376,7 @@ func (wj *AppJourney) TestCases() (generation.SpecRun, error) {
 			logger.WithFields(logrus.Fields{
 				"err": err,
 			}).Error("Error on executors.AcquireHeadlessTokens ...")
-			return generation.SpecRun{}, errConsentIDAcquisitionFailed
+			return generation.SpecRun{}, publishError(wj.stream, errConsentIDAcquisitionFailed)
 		}
 
 		tokenMap := map[string]string{} // Put access tokens into context
@@ -411,6 +432,16 @@ func (wj *AppJourney) tlsValidCtxKey(discoveryItemName string) string {
 	return fmt.Sprintf("tlsIsValidForDiscoveryItem-%s", strings.ReplaceAll(discoveryItemName, " ", "-"))
 }
 
+// getPsuConsent - starts the PSU consent acquisition for `permissions`, in a span the codes the
+// PSU then consents with are exchanged under
+func (wj *AppJourney) getPsuConsent(definition executors.RunDefinition, permissions map[string][]manifest.RequiredTokens) (executors.TokenConsentIDs, map[string]string, error) {
+	traceCtx, span := tracing.Start(context.Background(), "consent acquisition", attribute.String("fcs.consent_type", "psu"))
+	consentIds, tokenMap, err := executors.GetPsuConsent(traceCtx, definition, &wj.context, &wj.specRun, permissions)
+	tracing.End(span, err)
+	wj.consentTrace = traceCtx
+	return consentIds, tokenMap, err
+}
+
 // CollectToken -
 func (wj *AppJourney) CollectToken(code, state, scope string) error {
 	wj.journeyLock.Lock()
@@ -431,20 +462,25 @@ func (wj *AppJourney) CollectToken(code, state, scope string) error {
 		return errTestCasesNotGenerated
 	}
 
-	accessToken, err := executors.ExchangeCodeForAccessToken(state, code, &wj.context)
+	traceCtx := wj.consentTrace
+	if traceCtx == nil {
+		traceCtx = context.Background()
+	}
+	tokenName, _ := executors.SplitConsentState(state)
+	token, err := executors.ExchangeCodeForAccessToken(traceCtx, wj.client, tokenName, code, &wj.context)
 	if err != nil {
 		logger.WithFields(logrus.Fields{
-			"err":         err,
-			"code":        code,
-			"state":       state,
-			"scope":       scope,
-			"accessToken": accessToken,
+			"err":   err,
+			"code":  code,
+			"state": state,
+			"scope": scope,
 		}).Error("Error collecting token due to error in executors.ExchangeCodeForAccessToken")
-		return err
+		return publishError(wj.stream, err)
 	}
+	accessToken := token.AccessToken
 
-	wj.context.PutString(state, accessToken)
-	if state == "Token001" {
+	wj.context.PutString(tokenName, accessToken)
+	if tokenName == "Token001" {
 		logger.WithFields(logrus.Fields{
 			"err":         err,
 			"code":        code,
@@ -456,7 +492,7 @@ func (wj *AppJourney) CollectToken(code, state, scope string) error {
 	}
 
 	if wj.config.useDynamicResourceID {
-		err := executors.GetDynamicResourceIds(state, accessToken, &wj.context, wj.permissions["accounts"])
+		err := executors.GetDynamicResourceIds(wj.client, tokenName, accessToken, &wj.context, wj.permissions["accounts"])
 		if err != nil {
 			logger.WithFields(logrus.Fields{
 				"err": err,
@@ -469,7 +505,7 @@ func (wj *AppJourney) CollectToken(code, state, scope string) error {
 		logger.Tracef("journey perms: %#v", v)
 	}
 
-	return wj.collector.Collect(state, accessToken)
+	return wj.collector.Collect(tokenName, token)
 }
 
 // AllTokenCollected -
@@ -485,6 +521,8 @@ func (wj *AppJourney) doneCollectionCallback() {
 
 // RunTests -
 func (wj *AppJourney) RunTests() error {
+	wj.journeyLock.Lock()
+	defer wj.journeyLock.Unlock()
 	logger := wj.log.WithField("function", "RunTests")
 
 	if !wj.testCasesRunGenerated {
@@ -501,6 +539,31 @@ func (wj *AppJourney) RunTests() error {
 		return errNotFinishedCollectingTokens
 	}
 
+	if wj.loadRunning() {
+		logger.WithFields(logrus.Fields{
+			"err": errLoadRunning,
+		}).Error("Error on starting run")
+		return errLoadRunning
+	}
+
+	wj.mapTokensAndResources()
+
+	runID := uuid.New().String()
+	wj.stream.StartRun(runID, runevents.Run{Tests: testCount(wj.specRun)})
+	runDefinition := wj.makeRunDefinition()
+	runDefinition.RunID = runID
+	runDefinition.Properties = schemaprops.MakeRunCollector(wj.consentProperties)
+	controller := newStreamingDaemonController(wj.recordRun(runID), wj.stream, wj.specRun, nil)
+	runner := executors.NewTestCaseRunner(wj.log, runDefinition, controller)
+	wj.runner = runner
+	wj.context.PutString(CtxPhase, "run")
+	err := runner.RunTestCases(&wj.context)
+	return publishError(wj.stream, err)
+}
+
+// mapTokensAndResources - puts the consented account ids, when resource ids are dynamic, and the
+// access tokens each test case sends into the generated test cases
+func (wj *AppJourney) mapTokensAndResources() {
 	if wj.config.useDynamicResourceID {
 		for _, accountPermissions := range wj.permissions["accounts"] {
 			// cycle over all test case ids for this account permission/token set
@@ -536,12 +599,6 @@ func (wj *AppJourney) RunTests() error {
 		manifest.MapTokensToTestCases(requiredTokens[specType], wj.specRun.SpecTestCases[k].TestCases)
 		wj.dumpJSON(wj.specRun.SpecTestCases[k].TestCases)
 	}
-
-	runDefinition := wj.makeRunDefinition()
-	runner := executors.NewTestCaseRunner(wj.log, runDefinition, wj.daemonController)
-	wj.context.PutString(CtxPhase, "run")
-	err := runner.RunTestCases(&wj.context)
-	return err
 }
 
 // Results -
@@ -554,9 +611,39 @@ func (wj *AppJourney) StopTestRun() {
 	wj.daemonController.Stop()
 }
 
+// mapPSUConsentTokens - puts the consent ids for payment, vrp and cbpii `permissions` into their test cases
+func (wj *AppJourney) mapPSUConsentTokens(permissions map[string][]manifest.RequiredTokens) {
+	for k := range permissions {
+		if k == "payments" {
+			paymentpermissions := permissions["payments"]
+			if len(paymentpermissions) > 0 {
+				for _, spec := range wj.specRun.SpecTestCases {
+					manifest.MapTokensToPaymentTestCases(paymentpermissions, spec.TestCases, &wj.context)
+				}
+			}
+		}
+		if k == "vrps" {
+			vrpspermissions := permissions["vrps"]
+			if len(vrpspermissions) > 0 {
+				for _, spec := range wj.specRun.SpecTestCases {
+					manifest.MapTokensToPaymentTestCases(vrpspermissions, spec.TestCases, &wj.context)
+				}
+			}
+		}
+		if k == "cbpii" {
+			cbpiiPerms := permissions["cbpii"]
+			if len(cbpiiPerms) > 0 {
+				for _, spec := range wj.specRun.SpecTestCases {
+					manifest.MapTokensToCBPIITestCases(cbpiiPerms, spec.TestCases, &wj.context)
+				}
+			}
+		}
+	}
+}
+
 func (wj *AppJourney) createTokenCollector(consentIds executors.TokenConsentIDs) {
 	if len(consentIds) > 0 {
-		wj.collector = executors.NewTokenCollector(wj.log, consentIds, wj.doneCollectionCallback, wj.events)
+		wj.collector = executors.NewTokenCollector(wj.log, wj.client, consentIds, wj.doneCollectionCallback, wj.events)
 		consentIdsToTestCaseRun(wj.log, consentIds, &wj.specRun)
 
 		wj.allCollected = false
@@ -591,6 +678,30 @@ func (wj *AppJourney) makeRunDefinition() executors.RunDefinition {
 		SpecRun:       wj.specRun,
 		SigningCert:   wj.config.certificateSigning,
 		TransportCert: wj.config.certificateTransport,
+		DCR:           wj.makeDCRConfig(),
+		Tokens:        wj.collector,
+		Client:        wj.client,
+		Properties:    wj.consentProperties,
+	}
+}
+
+// makeDCRConfig - returns nil when Dynamic Client Registration is not being tested
+func (wj *AppJourney) makeDCRConfig() *dcr.Config {
+	if wj.config.registrationEndpoint == "" {
+		return nil
+	}
+	return &dcr.Config{
+		RegistrationEndpoint:     wj.config.registrationEndpoint,
+		SoftwareStatement:        wj.config.softwareStatement,
+		ExpiredSoftwareStatement: wj.config.expiredSoftwareStatement,
+		Issuer:                   wj.config.issuer,
+		RedirectURIs:             []string{wj.config.redirectURL},
+		TokenEndpoint:            wj.config.tokenEndpoint,
+		TokenEndpointAuthMethod:  wj.config.tokenEndpointAuthMethod,
+		SigningAlg:               wj.config.requestObjectSigningAlgorithm,
+		KID:                      wj.config.tppSignatureKID,
+		SigningCert:              wj.config.certificateSigning,
+		TransportCert:            wj.config.certificateTransport,
 	}
 }
 
@@ -630,6 +741,10 @@ type JourneyConfig struct {
 	conditionalProperties         []discovery.ConditionalAPIProperties
 	cbpiiDebtorAccount            discovery.CBPIIDebtorAccount
 	issuer                        string
+	registrationEndpoint          string
+	softwareStatement             string
+	expiredSoftwareStatement      string
+	notifications                 []notify.Hook
 }
 
 // SetConfig -
@@ -644,6 +759,10 @@ func (wj *AppJourney) SetConfig(config JourneyConfig) error {
 		return err
 	}
 
+	// Use the transport keys for MATLS as some endpoints require this
+	wj.client.SetCertificates(config.certificateTransport.TLSCert())
+	wj.client.SetHeader("User-Agent", userAgent(config))
+
 	wj.customTestParametersToJourneyContext()
 	return nil
 }
@@ -659,6 +778,19 @@ func (wj *AppJourney) Events() events.Events {
 	return wj.events
 }
 
+// RunEvents - the progress of this journey's runs, see package runevents
+func (wj *AppJourney) RunEvents() runevents.Stream {
+	return wj.stream
+}
+
+// SetEventLog - keeps the events of every test run started by this journey in `log`
+func (wj *AppJourney) SetEventLog(log runevents.Log) {
+	wj.journeyLock.Lock()
+	defer wj.journeyLock.Unlock()
+	wj.stream = runevents.NewStream(log, wj.log)
+	wj.events = newStreamingEvents(wj.stream)
+}
+
 func (wj *AppJourney) customTestParametersToJourneyContext() {
 	if wj.validDiscoveryModel == nil {
 		return
@@ -703,11 +835,78 @@ func (wj *AppJourney) dumpJSON(i interface{}) {
 	wj.log.Traceln(string(model))
 }
 
+// SetRunStore - keeps every test run started by this journey in `store`
+func (wj *AppJourney) SetRunStore(store runstore.RunStore) {
+	wj.runStore = store
+}
+
+// SetHTTPClient - sends this journey's requests to the ASPSP with `client`, which must not be
+// shared with another journey as the configuration sets its transport certificate
+func (wj *AppJourney) SetHTTPClient(client *resty.Client) {
+	wj.client = client
+}
+
+// SetID - names the journey in the OAuth state of its consent URLs, so the consent callback
+// reaches it. The default journey has no ID.
+func (wj *AppJourney) SetID(id string) {
+	wj.context.PutString(CtxJourneyID, id)
+}
+
+// DisableJWS - stops the journey sending and checking x-jws-signature, other journeys are not
+// changed
+func (wj *AppJourney) DisableJWS() {
+	wj.journeyLock.Lock()
+	defer wj.journeyLock.Unlock()
+	model.DisableJWS(&wj.context)
+}
+
+// JWSStatus - whether the journey sends x-jws-signature, for the report
+func (wj *AppJourney) JWSStatus() string {
+	wj.journeyLock.Lock()
+	defer wj.journeyLock.Unlock()
+	return model.JWSStatus(&wj.context)
+}
+
+// recordRun - the daemon controller for a new test run, recording the run if there is a run store
+func (wj *AppJourney) recordRun(runID string) executors.DaemonController {
+	if wj.runStore == nil {
+		return wj.notifyRun(runID, time.Now())
+	}
+	run := runstore.Run{
+		ID:      runID,
+		Started: time.Now(),
+		Config:  historyConfig(wj.config),
+		SpecRun: wj.specRun,
+	}
+	if wj.validDiscoveryModel != nil {
+		run.DiscoveryModel = *wj.validDiscoveryModel
+	}
+	return wj.recorder(run)
+}
+
+// recorder - starts recording `run` with checkpoints of this journey's tokens
+func (wj *AppJourney) recorder(run runstore.Run) *recordingDaemonController {
+	checkpoint := runstore.Checkpoint{Permissions: wj.permissions}
+	recorder := newRecordingDaemonController(wj.notifyRun(run.ID, run.Started), wj.runStore, run, checkpoint, wj.log)
+	recorder.tokens = wj.collector
+	recorder.start()
+	return recorder
+}
+
 // EnableDynamicResourceIDs is triggered by and environment variable dynids=true
 func (wj *AppJourney) EnableDynamicResourceIDs() {
 	wj.dynamicResourceIDs = true
 }
 
+// testCount - the number of test cases in `specRun`
+func testCount(specRun generation.SpecRun) int {
+	tests := 0
+	for _, spec := range specRun.SpecTestCases {
+		tests += len(spec.TestCases)
+	}
+	return tests
+}
+
 // DetermineAPIVersions -
 func DetermineAPIVersions(apis []discovery.ModelDiscoveryItem) []string {
 	apiversions := []string{}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors"
+)
+
+const (
+	journeyIDParam     = "journey_id"
+	journeyHandlersKey = "journeyHandlers"
+)
+
+// journeyHandlers - the handlers for every route that acts on a journey, created once per journey
+type journeyHandlers struct {
+	importHandlers    importHandlers
+	configHandlers    configHandlers
+	discoveryHandlers discoveryHandlers
+	testCaseHandlers  testCaseHandlers
+	runHandlers       runHandlers
+	redirectHandlers  redirectHandlers
+	exportHandlers    exportHandlers
+}
+
+func newJourneyHandlers(journey Journey, logger *logrus.Entry) journeyHandlers {
+	return journeyHandlers{
+		importHandlers:    newImportHandlers(journey, logger),
+		configHandlers:    newConfigHandlers(journey, logger),
+		discoveryHandlers: newDiscoveryHandlers(journey, logger),
+		testCaseHandlers:  newTestCaseHandlers(journey, NewWebSocketUpgrader(), logger),
+		runHandlers:       newRunHandlers(journey, NewWebSocketUpgrader(), logger),
+		redirectHandlers:  newRedirectHandlers(journey, logger),
+		exportHandlers:    newExportHandlers(journey, logger),
+	}
+}
+
+// handlersFor - the handlers of the journey selected by defaultJourneyMiddleware, journeyMiddleware
+// or consentJourneyMiddleware
+func handlersFor(c echo.Context) journeyHandlers {
+	return c.Get(journeyHandlersKey).(journeyHandlers)
+}
+
+type journeysHandlers struct {
+	journeys *Journeys
+	logger   *logrus.Entry
+}
+
+func newJourneysHandlers(journeys *Journeys, logger *logrus.Entry) journeysHandlers {
+	return journeysHandlers{
+		journeys: journeys,
+		logger:   logger.WithField("handler", "journeysHandlers"),
+	}
+}
+
+// createJourneyHandler - POST /api/journeys
+func (h journeysHandlers) createJourneyHandler(c echo.Context) error {
+	return c.JSON(http.StatusCreated, h.journeys.Create())
+}
+
+// listJourneysHandler - GET /api/journeys
+func (h journeysHandlers) listJourneysHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.journeys.List())
+}
+
+// deleteJourneyHandler - DELETE /api/journeys/:journey_id
+func (h journeysHandlers) deleteJourneyHandler(c echo.Context) error {
+	if !h.journeys.Delete(c.Param(journeyIDParam)) {
+		return c.JSON(http.StatusNotFound, NewErrorResponse(errJourneyNotFound))
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// defaultJourneyMiddleware - selects the default journey for the unscoped `/api` routes
+func (h journeysHandlers) defaultJourneyMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Set(journeyHandlersKey, h.journeys.defaultJourney.handlers)
+		return next(c)
+	}
+}
+
+// journeyMiddleware - selects the journey in the `journey_id` path parameter, keeping it from
+// expiring until the request, or results websocket, is finished
+func (h journeysHandlers) journeyMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		session, ok := h.journeys.acquire(c.Param(journeyIDParam))
+		if !ok {
+			return c.JSON(http.StatusNotFound, NewErrorResponse(errJourneyNotFound))
+		}
+		defer h.journeys.release(session)
+
+		c.Set(journeyHandlersKey, session.handlers)
+		return next(c)
+	}
+}
+
+// consentJourneyMiddleware - selects the journey named in the OAuth `state` of a consent callback
+// posted to the unscoped `/api/redirect` routes, so the consent reaches the journey that asked for
+// it whichever tab or window the PSU completes it in. A state naming no journey selects the
+// default journey.
+func (h journeysHandlers) consentJourneyMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		state, err := consentState(c)
+		if err != nil {
+			return err
+		}
+		_, journeyID := executors.SplitConsentState(state)
+		if journeyID == "" {
+			return h.defaultJourneyMiddleware(next)(c)
+		}
+
+		session, ok := h.journeys.acquire(journeyID)
+		if !ok {
+			return c.JSON(http.StatusNotFound, NewErrorResponse(errJourneyNotFound))
+		}
+		defer h.journeys.release(session)
+
+		c.Set(journeyHandlersKey, session.handlers)
+		return next(c)
+	}
+}
+
+// consentState - the `state` posted to a consent callback route, the request body is kept for
+// the handler to bind
+func consentState(c echo.Context) (string, error) {
+	body, err := ioutil.ReadAll(c.Request().Body)
+	if err != nil {
+		return "", err
+	}
+	c.Request().Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	callback := struct {
+		State string `json:"state" form:"state" query:"state"`
+	}{}
+	if err := c.Bind(&callback); err != nil {
+		return "", err
+	}
+	c.Request().Body = ioutil.NopCloser(bytes.NewReader(body))
+	return callback.State, nil
+}
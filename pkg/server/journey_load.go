@@ -0,0 +1,122 @@
+package server
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors"
+	"github.com/OpenBankingUK/conformance-suite/pkg/model"
+)
+
+var (
+	errLoadRunning    = errors.New("error load run already running")
+	errNoLoadRun      = errors.New("error no load run started")
+	errTestRunRunning = errors.New("error test run running, start the load run once it has finished")
+)
+
+// LoadOptions - body of POST /api/load
+type LoadOptions struct {
+	// TestCases - regular expression matching the ids of the read-only test cases to send, all of
+	// them when empty
+	TestCases string  `json:"test_cases" form:"test_cases"`
+	RPS       float64 `json:"rps" form:"rps"`
+	Duration  string  `json:"duration" form:"duration"` // such as "30s" or "5m"
+}
+
+// RunLoad - starts sending the generated read-only test cases matching `options` at the rate and
+// for the duration it sets. Needs the test cases to be generated and all tokens collected, as for
+// RunTests, and no test run to be running. Test cases whose request cannot be prepared are left
+// out. The run goes on in the background, LoadReport returns what it has measured.
+func (wj *AppJourney) RunLoad(options LoadOptions) error {
+	wj.journeyLock.Lock()
+	defer wj.journeyLock.Unlock()
+	logger := wj.log.WithField("function", "RunLoad")
+
+	if !wj.testCasesRunGenerated {
+		return errTestCasesNotGenerated
+	}
+	if !wj.allCollected {
+		return errNotFinishedCollectingTokens
+	}
+	if wj.loadRunning() {
+		return errLoadRunning
+	}
+	if wj.runner != nil && wj.runner.Running() {
+		return errTestRunRunning
+	}
+
+	duration, err := time.ParseDuration(options.Duration)
+	if err != nil {
+		return errors.Wrap(err, "load: duration")
+	}
+	pattern, err := regexp.Compile(options.TestCases)
+	if err != nil {
+		return errors.Wrap(err, "load: test_cases")
+	}
+
+	wj.mapTokensAndResources()
+	testCases := []model.TestCase{}
+	for _, spec := range wj.specRun.SpecTestCases {
+		for _, tc := range spec.TestCases {
+			if executors.IsReadOnly(tc) && pattern.MatchString(tc.ID) {
+				testCases = append(testCases, tc)
+			}
+		}
+	}
+
+	runner, err := executors.NewLoadRunner(wj.log, executors.LoadDefinition{
+		TestCases:     testCases,
+		RPS:           options.RPS,
+		Duration:      duration,
+		SigningCert:   wj.config.certificateSigning,
+		TransportCert: wj.config.certificateTransport,
+		Tokens:        wj.collector,
+		Client:        wj.client,
+	})
+	if err != nil {
+		return err
+	}
+	ctx := &model.Context{}
+	ctx.PutContext(&wj.context)
+	ctx.PutString(CtxPhase, "run") // fields missing from the context fail a request, as in a test run
+	if err := runner.Prepare(ctx); err != nil {
+		return err
+	}
+	logger.WithFields(logrus.Fields{
+		"testCases": len(testCases),
+		"rps":       options.RPS,
+		"duration":  duration,
+	}).Info("starting load run")
+
+	wj.load = runner
+	runner.Start(ctx)
+	return nil
+}
+
+// loadRunning - whether the last load run started is still running, called with the journey lock
+// held. Test runs wait for it to finish, as a load run would skew their response times.
+func (wj *AppJourney) loadRunning() bool {
+	return wj.load != nil && wj.load.Running()
+}
+
+// LoadReport - what the last load run started has measured so far
+func (wj *AppJourney) LoadReport() (executors.LoadReport, error) {
+	wj.journeyLock.Lock()
+	defer wj.journeyLock.Unlock()
+	if wj.load == nil {
+		return executors.LoadReport{}, errNoLoadRun
+	}
+	return wj.load.Report(), nil
+}
+
+// StopLoad - ends the load run, if one is running
+func (wj *AppJourney) StopLoad() {
+	wj.journeyLock.Lock()
+	defer wj.journeyLock.Unlock()
+	if wj.load != nil {
+		wj.load.Stop()
+	}
+}
@@ -0,0 +1,193 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/authentication"
+	"github.com/OpenBankingUK/conformance-suite/pkg/discovery"
+	"github.com/OpenBankingUK/conformance-suite/pkg/discovery/mocks"
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors"
+	executormocks "github.com/OpenBankingUK/conformance-suite/pkg/executors/mocks"
+	"github.com/OpenBankingUK/conformance-suite/pkg/generation"
+	"github.com/OpenBankingUK/conformance-suite/pkg/model"
+	"github.com/OpenBankingUK/conformance-suite/pkg/test"
+	versionmock "github.com/OpenBankingUK/conformance-suite/pkg/version/mocks"
+)
+
+// loadJourney - a journey with its test cases generated and tokens collected
+func loadJourney(testCases ...model.TestCase) *AppJourney {
+	journey := NewJourney(nullLogger(), &generation.MockGenerator{}, &mocks.Validator{}, discovery.NewNullTLSValidator(), false)
+	journey.specRun = generation.SpecRun{SpecTestCases: []generation.SpecificationTestCases{{TestCases: testCases}}}
+	journey.testCasesRunGenerated = true
+	journey.allCollected = true
+	return journey
+}
+
+func TestJourneyRunLoadNeedsTestCases(t *testing.T) {
+	require := test.NewRequire(t)
+	journey := NewJourney(nullLogger(), &generation.MockGenerator{}, &mocks.Validator{}, discovery.NewNullTLSValidator(), false)
+
+	err := journey.RunLoad(LoadOptions{RPS: 1, Duration: "1s"})
+	require.Equal(errTestCasesNotGenerated, err)
+
+	_, err = journey.LoadReport()
+	require.Equal(errNoLoadRun, err)
+}
+
+func TestJourneyRunLoadSelectsReadOnlyTestCases(t *testing.T) {
+	require := test.NewRequire(t)
+	get := model.MakeTestCase()
+	get.ID = "OB-301-ACC-100100"
+	get.Input.Method = http.MethodGet
+	post := model.MakeTestCase()
+	post.ID = "OB-301-DOP-100100"
+	post.Input.Method = http.MethodPost
+	journey := loadJourney(get, post)
+
+	err := journey.RunLoad(LoadOptions{TestCases: "DOP", RPS: 1, Duration: "1s"})
+	require.EqualError(err, "load: no test cases selected")
+
+	err = journey.RunLoad(LoadOptions{TestCases: "(", RPS: 1, Duration: "1s"})
+	require.EqualError(err, "load: test_cases: error parsing regexp: missing closing ): `(`")
+
+	err = journey.RunLoad(LoadOptions{RPS: 1, Duration: "soon"})
+	require.EqualError(err, `load: duration: time: invalid duration "soon"`)
+
+	err = journey.RunLoad(LoadOptions{RPS: 0, Duration: "1s"})
+	require.EqualError(err, "load: rps must be more than 0")
+
+	err = journey.RunLoad(LoadOptions{RPS: 1001, Duration: "1s"})
+	require.EqualError(err, "load: rps must be at most 1000")
+}
+
+func TestJourneyRunLoadLeavesOutTestCasesNotPrepared(t *testing.T) {
+	require := test.NewRequire(t)
+	account := model.MakeTestCase()
+	account.ID = "OB-301-ACC-811741"
+	account.Input.Method = http.MethodGet
+	account.Input.Endpoint = "/accounts/$consentedAccountId"
+	journey := loadJourney(account)
+
+	err := journey.RunLoad(LoadOptions{RPS: 1, Duration: "1s"})
+
+	require.EqualError(err, "load: no selected test case can be prepared")
+	require.Nil(journey.load)
+}
+
+func TestJourneyRunLoadWaitsForTestRun(t *testing.T) {
+	require := test.NewRequire(t)
+	get := model.MakeTestCase()
+	get.ID = "OB-301-ACC-100100"
+	get.Input.Method = http.MethodGet
+	get.Input.Endpoint = "/accounts"
+	journey := loadJourney(get)
+	finish := make(chan time.Time)
+	controller := &executormocks.DaemonController{}
+	controller.On("AddResponseFields", mock.Anything).WaitUntil(finish)
+	controller.On("SetCompleted")
+	certificate, err := authentication.NewCertificate(publicKey, privateKey)
+	require.NoError(err)
+	journey.config = JourneyConfig{certificateSigning: certificate, certificateTransport: certificate}
+	definition := executors.RunDefinition{SigningCert: certificate, TransportCert: certificate}
+	journey.runner = executors.NewTestCaseRunner(nullLogger(), definition, controller)
+	require.NoError(journey.runner.RunTestCases(&model.Context{}))
+
+	err = journey.RunLoad(LoadOptions{RPS: 1, Duration: "1s"})
+	require.Equal(errTestRunRunning, err)
+	require.Nil(journey.load)
+
+	close(finish)
+	require.Eventually(func() bool { return !journey.runner.Running() }, time.Second, 10*time.Millisecond)
+	require.NoError(journey.RunLoad(LoadOptions{RPS: 1, Duration: "1s"}))
+	journey.StopLoad()
+}
+
+func TestJourneyTestRunWaitsForLoadRun(t *testing.T) {
+	require := test.NewRequire(t)
+	get := model.MakeTestCase()
+	get.ID = "OB-301-ACC-100100"
+	get.Input.Method = http.MethodGet
+	get.Input.Endpoint = "/accounts"
+	journey := loadJourney(get)
+	certificate, err := authentication.NewCertificate(publicKey, privateKey)
+	require.NoError(err)
+	journey.config = JourneyConfig{certificateSigning: certificate, certificateTransport: certificate}
+	require.NoError(journey.RunLoad(LoadOptions{RPS: 1, Duration: "1m"}))
+	require.True(journey.load.Running(), "running once RunLoad returns")
+
+	require.Equal(errLoadRunning, journey.RunTests())
+	_, err = journey.ResumeTests("run-1")
+	require.Equal(errLoadRunning, err)
+	require.Nil(journey.runner)
+
+	journey.StopLoad()
+	require.Eventually(func() bool { return !journey.load.Running() }, time.Second, 10*time.Millisecond)
+}
+
+func TestServerRunWaitsForLoadRun(t *testing.T) {
+	require := test.NewRequire(t)
+
+	journey := &MockJourney{}
+	journey.On("RunTests").Return(errLoadRunning)
+	journey.On("ResumeTests", "run-1").Return(ResumeStatus{}, errLoadRunning)
+	server := NewServer(journeysFor(journey), nil, nullLogger(), &versionmock.Version{})
+	defer func() {
+		require.NoError(server.Shutdown(context.TODO()))
+	}()
+
+	code, response, _ := request(http.MethodPost, "/api/run", nil, server)
+	require.Equal(http.StatusConflict, code)
+	require.JSONEq(`{"error": "error load run already running"}`, response.String())
+
+	code, response, _ = request(http.MethodPost, "/api/run/resume", strings.NewReader(`{"run_id": "run-1"}`), server)
+	require.Equal(http.StatusConflict, code)
+	require.JSONEq(`{"error": "error load run already running"}`, response.String())
+	journey.AssertExpectations(t)
+}
+
+func TestServerLoad(t *testing.T) {
+	require := test.NewRequire(t)
+
+	journey := &MockJourney{}
+	journey.On("RunLoad", LoadOptions{TestCases: "ACC", RPS: 5, Duration: "1m"}).Return(nil).Once()
+	journey.On("RunLoad", LoadOptions{TestCases: "ACC", RPS: 5, Duration: "1m"}).Return(errLoadRunning).Once()
+	journey.On("RunLoad", LoadOptions{TestCases: "ACC", RPS: 5, Duration: "1m"}).Return(errTestRunRunning).Once()
+	journey.On("LoadReport").Return(executors.LoadReport{Running: true, TargetRPS: 5, Requests: 3}, nil).Once()
+	journey.On("LoadReport").Return(executors.LoadReport{}, errNoLoadRun).Once()
+	journey.On("StopLoad").Return()
+	server := NewServer(journeysFor(journey), nil, nullLogger(), &versionmock.Version{})
+	defer func() {
+		require.NoError(server.Shutdown(context.TODO()))
+	}()
+
+	body := `{"test_cases": "ACC", "rps": 5, "duration": "1m"}`
+	code, _, _ := request(http.MethodPost, "/api/load", strings.NewReader(body), server)
+	require.Equal(http.StatusCreated, code)
+
+	code, response, _ := request(http.MethodPost, "/api/load", strings.NewReader(body), server)
+	require.Equal(http.StatusConflict, code)
+	require.JSONEq(`{"error": "error load run already running"}`, response.String())
+
+	code, response, _ = request(http.MethodPost, "/api/load", strings.NewReader(body), server)
+	require.Equal(http.StatusConflict, code)
+	require.JSONEq(`{"error": "error test run running, start the load run once it has finished"}`, response.String())
+
+	code, response, _ = request(http.MethodGet, "/api/load", nil, server)
+	require.Equal(http.StatusOK, code)
+	require.Contains(response.String(), `"running":true`)
+	require.Contains(response.String(), `"requests":3`)
+
+	code, _, _ = request(http.MethodDelete, "/api/load", nil, server)
+	require.Equal(http.StatusNoContent, code)
+
+	code, response, _ = request(http.MethodGet, "/api/load", nil, server)
+	require.Equal(http.StatusNotFound, code)
+	require.JSONEq(`{"error": "error no load run started"}`, response.String())
+	journey.AssertExpectations(t)
+}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors"
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors/events"
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors/results"
+	"github.com/OpenBankingUK/conformance-suite/pkg/manifest"
+	"github.com/OpenBankingUK/conformance-suite/pkg/runevents"
+	"github.com/OpenBankingUK/conformance-suite/pkg/runstore"
+	"github.com/OpenBankingUK/conformance-suite/pkg/schemaprops"
+)
+
+// tokenExpiryMargin - tokens expiring within this long are acquired again when a run is resumed,
+// so they do not expire part way through the remaining test cases
+const tokenExpiryMargin = 5 * time.Minute
+
+var (
+	errRunHistoryDisabled = errors.New("run history is disabled, start the server with --run_store")
+	errConfigNotSet       = errors.New("error config not set")
+)
+
+// ResumeStatus - the outcome of resuming a run. Tokens lists the access tokens that expired since
+// the run stopped. Once the PSU has consented to each of them, resuming the run again continues it.
+type ResumeStatus struct {
+	RunID  string        `json:"run_id"`
+	Tokens []ResumeToken `json:"tokens,omitempty"`
+}
+
+// ResumeToken - an access token that needs PSU consent before a run can be resumed
+type ResumeToken struct {
+	Name       string `json:"name"`
+	ConsentURL string `json:"consent_url"`
+}
+
+// resumption - a run from the run history this journey is resuming
+type resumption struct {
+	run        runstore.Run
+	checkpoint runstore.Checkpoint
+}
+
+// ResumeTests - continues a run from the run history at its first unfinished test case. Needs the
+// discovery model and configuration to be set, the test cases and tokens are taken from the run's
+// checkpoint. Expired tokens are refreshed, only those that cannot be are acquired again.
+func (wj *AppJourney) ResumeTests(runID string) (ResumeStatus, error) {
+	wj.journeyLock.Lock()
+	defer wj.journeyLock.Unlock()
+	if wj.loadRunning() {
+		return ResumeStatus{}, errLoadRunning
+	}
+	status, err := wj.prepareResume(runID)
+	if err != nil || len(status.Tokens) > 0 {
+		return status, err
+	}
+
+	return status, wj.runResumed()
+}
+
+func (wj *AppJourney) prepareResume(runID string) (ResumeStatus, error) {
+	logger := wj.log.WithFields(logrus.Fields{
+		"function": "ResumeTests",
+		"run_id":   runID,
+	})
+
+	if wj.runStore == nil {
+		return ResumeStatus{}, errRunHistoryDisabled
+	}
+	if wj.validDiscoveryModel == nil {
+		return ResumeStatus{}, errDiscoveryModelNotSet
+	}
+	if wj.config.certificateSigning == nil {
+		return ResumeStatus{}, errConfigNotSet
+	}
+
+	if wj.resuming != nil && wj.resuming.run.ID == runID {
+		return ResumeStatus{RunID: runID, Tokens: wj.pendingTokens()}, nil
+	}
+
+	run, err := wj.runStore.Get(runID)
+	if err != nil {
+		return ResumeStatus{}, err
+	}
+	checkpoint, err := wj.runStore.LoadCheckpoint(runID)
+	if err != nil {
+		return ResumeStatus{}, err
+	}
+
+	wj.specRun = run.SpecRun
+	wj.permissions = checkpoint.Permissions
+	for key, value := range checkpoint.Context {
+		wj.context.Put(key, value)
+	}
+	wj.testCasesRunGenerated = true
+	wj.resuming = &resumption{run: run, checkpoint: checkpoint}
+
+	tokens, expired := wj.resumedTokens(checkpoint, time.Now().Add(tokenExpiryMargin), logger)
+	logger.WithField("expired", expired).Info("resuming run")
+	if len(expired) == 0 {
+		wj.collector = executors.NewTokenCollector(wj.log, wj.client, tokens, wj.doneCollectionCallback, wj.events)
+		wj.allCollected = true
+		return ResumeStatus{RunID: runID}, nil
+	}
+
+	permissions := expiredPermissions(checkpoint.Permissions, expired)
+	consentIds, _, err := wj.getPsuConsent(wj.makeRunDefinition(), permissions)
+	if err != nil {
+		logger.WithError(err).Error("acquiring consent for expired tokens")
+		wj.resuming = nil
+		return ResumeStatus{}, publishError(wj.stream, errors.WithMessage(errConsentIDAcquisitionFailed, err.Error()))
+	}
+	wj.mapPSUConsentTokens(permissions)
+	consentIdsToTestCaseRun(wj.log, consentIds, &wj.specRun)
+	publishConsentRequired(wj.stream, consentIds)
+	wj.collector = executors.NewTokenCollector(wj.log, wj.client, append(tokens, consentIds...), wj.doneCollectionCallback, wj.events)
+	wj.allCollected = len(consentIds) == 0
+
+	return ResumeStatus{RunID: runID, Tokens: wj.pendingTokens()}, nil
+}
+
+// resumedTokens - the tokens in `checkpoint` still usable by the resumed run. Tokens expiring before
+// `at` are refreshed when they have a refresh token, the names of those that cannot be refreshed
+// are returned as they need PSU consent again.
+func (wj *AppJourney) resumedTokens(checkpoint runstore.Checkpoint, at time.Time, logger *logrus.Entry) (executors.TokenConsentIDs, []string) {
+	isExpired := map[string]bool{}
+	for _, name := range checkpoint.ExpiredTokens(at) {
+		isExpired[name] = true
+	}
+
+	tokens := executors.TokenConsentIDs{}
+	expired := []string{}
+	for _, token := range checkpoint.Tokens {
+		item := executors.TokenConsentIDItem{
+			TokenName:    token.Name,
+			RefreshToken: token.RefreshToken,
+			Expires:      token.Expires,
+			State:        events.TokenValid,
+		}
+		item.AccessToken, _ = wj.context.GetString(token.Name)
+
+		if isExpired[token.Name] {
+			if token.RefreshToken == "" {
+				expired = append(expired, token.Name)
+				continue
+			}
+			refreshed, err := executors.RefreshAccessToken(context.Background(), wj.client, token.RefreshToken, &wj.context)
+			if err != nil {
+				logger.WithError(err).WithField("token", token.Name).Warn("refreshing expired token")
+				expired = append(expired, token.Name)
+				continue
+			}
+			item.AccessToken = refreshed.AccessToken
+			item.Expires = refreshed.Expires
+			if refreshed.RefreshToken != "" {
+				item.RefreshToken = refreshed.RefreshToken
+			}
+			item.State = events.TokenRefreshed
+			wj.context.PutString(token.Name, item.AccessToken)
+			if token.Name == "Token001" {
+				wj.context.PutString("access_token", item.AccessToken)
+			}
+		}
+		tokens = append(tokens, item)
+	}
+	return tokens, expired
+}
+
+// pendingTokens - tokens still waiting for PSU consent
+func (wj *AppJourney) pendingTokens() []ResumeToken {
+	if wj.allCollected || wj.collector == nil {
+		return nil
+	}
+	pending := []ResumeToken{}
+	for _, token := range wj.collector.Tokens() {
+		if token.AccessToken == "" {
+			pending = append(pending, ResumeToken{Name: token.TokenName, ConsentURL: token.ConsentURL})
+		}
+	}
+	return pending
+}
+
+// runResumed - runs the test cases of the resumed run that have no result yet, called with the
+// journey lock held
+func (wj *AppJourney) runResumed() error {
+	resuming := wj.resuming
+	wj.resuming = nil
+	if resuming == nil {
+		return errTestCasesNotGenerated
+	}
+
+	runDefinition := wj.makeRunDefinition()
+	runDefinition.RunID = resuming.run.ID
+	runDefinition.Properties = schemaprops.MakeRunCollector(wj.consentProperties)
+	runDefinition.Idempotent = resuming.checkpoint.Idempotent
+	runDefinition.Completed = make([]results.TestCase, 0, len(resuming.run.Results))
+	for _, result := range resuming.run.Results {
+		runDefinition.Completed = append(runDefinition.Completed, result.TestCase())
+	}
+
+	wj.stream.StartRun(resuming.run.ID, runevents.Run{Tests: testCount(wj.specRun), Resumed: true})
+	recorder := wj.recorder(resuming.run)
+	recorder.checkpoint.Idempotent = resuming.checkpoint.Idempotent // kept for the next resume
+	controller := newStreamingDaemonController(recorder, wj.stream, wj.specRun, runDefinition.Completed)
+	runner := executors.NewTestCaseRunner(wj.log, runDefinition, controller)
+	wj.runner = runner
+	wj.context.PutString(CtxPhase, "run")
+	return publishError(wj.stream, runner.RunTestCases(&wj.context))
+}
+
+// expiredPermissions - the required tokens in `permissions` named in `expired`
+func expiredPermissions(permissions map[string][]manifest.RequiredTokens, expired []string) map[string][]manifest.RequiredTokens {
+	isExpired := map[string]bool{}
+	for _, name := range expired {
+		isExpired[name] = true
+	}
+
+	filtered := map[string][]manifest.RequiredTokens{}
+	for specType, requiredTokens := range permissions {
+		for _, requiredToken := range requiredTokens {
+			if isExpired[requiredToken.Name] {
+				filtered[specType] = append(filtered[specType], requiredToken)
+			}
+		}
+	}
+	return filtered
+}
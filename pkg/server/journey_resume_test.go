@@ -0,0 +1,144 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/authentication"
+	"github.com/OpenBankingUK/conformance-suite/pkg/discovery"
+	"github.com/OpenBankingUK/conformance-suite/pkg/discovery/mocks"
+	gmocks "github.com/OpenBankingUK/conformance-suite/pkg/generation"
+	"github.com/OpenBankingUK/conformance-suite/pkg/runstore"
+	"github.com/OpenBankingUK/conformance-suite/pkg/test"
+	versionmock "github.com/OpenBankingUK/conformance-suite/pkg/version/mocks"
+)
+
+// resumableJourney - a journey with a discovery model and configuration, and `store` as its run store
+func resumableJourney(t *testing.T, store runstore.RunStore) *AppJourney {
+	require := test.NewRequire(t)
+
+	discoveryModel := &discovery.Model{}
+	validator := &mocks.Validator{}
+	validator.On("Validate", discoveryModel).Return(discovery.NoValidationFailures(), nil)
+	journey := NewJourney(nullLogger(), &gmocks.MockGenerator{}, validator, discovery.NewNullTLSValidator(), false)
+	journey.SetRunStore(store)
+	_, err := journey.SetDiscoveryModel(discoveryModel)
+	require.NoError(err)
+
+	certificate, err := authentication.NewCertificate(publicCertValid, privateCertValid)
+	require.NoError(err)
+	journey.config = JourneyConfig{certificateSigning: certificate, certificateTransport: certificate}
+	return journey
+}
+
+func TestJourneyResumeTestsNeedsRunHistory(t *testing.T) {
+	journey := NewJourney(nullLogger(), &gmocks.MockGenerator{}, &mocks.Validator{}, discovery.NewNullTLSValidator(), false)
+
+	_, err := journey.ResumeTests("run-1")
+
+	test.NewAssert(t).Equal(errRunHistoryDisabled, err)
+}
+
+func TestJourneyResumeTestsUnknownRun(t *testing.T) {
+	store := runstore.NewMemoryStore()
+	journey := resumableJourney(t, store)
+
+	_, err := journey.ResumeTests("run-1")
+	test.NewAssert(t).Equal(runstore.ErrNotFound, err)
+
+	test.NewRequire(t).NoError(store.Save(runstore.Run{ID: "run-1", Status: runstore.StatusCompleted}))
+	_, err = journey.ResumeTests("run-1")
+	test.NewAssert(t).Equal(runstore.ErrNoCheckpoint, err)
+}
+
+func TestJourneyPrepareResumeRestoresCheckpoint(t *testing.T) {
+	require := test.NewRequire(t)
+
+	store := runstore.NewMemoryStore()
+	run := runstore.Run{ID: "run-1", Status: runstore.StatusStopped, SpecRun: testSpecRun("OB-301-ACC-120382", "OB-301-ACC-811741")}
+	require.NoError(store.Save(run))
+	checkpoint := runstore.Checkpoint{
+		Context: map[string]interface{}{"Token001": "a5c1f1fa", CtxConsentedAccountID: "500000000000000000000001"},
+		Tokens:  []runstore.Token{{Name: "Token001", Expires: time.Now().Add(time.Hour)}},
+	}
+	require.NoError(store.Checkpoint("run-1", runstore.Result{ID: "OB-301-ACC-120382", Pass: true}, checkpoint))
+	journey := resumableJourney(t, store)
+
+	status, err := journey.prepareResume("run-1")
+
+	require.NoError(err)
+	require.Equal(ResumeStatus{RunID: "run-1"}, status)
+	require.True(journey.allCollected)
+	require.True(journey.testCasesRunGenerated)
+	require.Equal(run.SpecRun, journey.specRun)
+	token, err := journey.context.GetString("Token001")
+	require.NoError(err)
+	require.Equal("a5c1f1fa", token)
+	require.Len(journey.resuming.run.Results, 1)
+}
+
+func TestServerRunResume(t *testing.T) {
+	require := test.NewRequire(t)
+
+	journey := &MockJourney{}
+	journey.On("ResumeTests", "run-1").Return(ResumeStatus{RunID: "run-1"}, nil)
+	journey.On("ResumeTests", "run-2").Return(ResumeStatus{RunID: "run-2", Tokens: []ResumeToken{{Name: "Token001", ConsentURL: "https://aspsp.example.com/auth"}}}, nil)
+	journey.On("ResumeTests", "run-3").Return(ResumeStatus{}, runstore.ErrNotFound)
+	server := NewServer(journeysFor(journey), nil, nullLogger(), &versionmock.Version{})
+	defer func() {
+		require.NoError(server.Shutdown(context.TODO()))
+	}()
+
+	code, body, _ := request(http.MethodPost, "/api/run/resume", strings.NewReader(`{"run_id": "run-1"}`), server)
+	require.Equal(http.StatusCreated, code)
+	require.JSONEq(`{"run_id": "run-1"}`, body.String())
+
+	code, body, _ = request(http.MethodPost, "/api/run/resume", strings.NewReader(`{"run_id": "run-2"}`), server)
+	require.Equal(http.StatusAccepted, code)
+	require.JSONEq(`{"run_id": "run-2", "tokens": [{"name": "Token001", "consent_url": "https://aspsp.example.com/auth"}]}`, body.String())
+
+	code, _, _ = request(http.MethodPost, "/api/run/resume", strings.NewReader(`{"run_id": "run-3"}`), server)
+	require.Equal(http.StatusNotFound, code)
+
+	code, body, _ = request(http.MethodPost, "/api/run/resume", strings.NewReader(`{}`), server)
+	require.Equal(http.StatusBadRequest, code)
+	require.JSONEq(`{"error": "run_id is required"}`, body.String())
+}
+
+func TestJourneyResumeTestsStartsRunnerWithJourneyLocked(t *testing.T) {
+	require := test.NewRequire(t)
+
+	store := runstore.NewMemoryStore()
+	run := runstore.Run{ID: "run-1", Status: runstore.StatusStopped, SpecRun: testSpecRun("OB-301-ACC-120382")}
+	require.NoError(store.Save(run))
+	require.NoError(store.Checkpoint("run-1", runstore.Result{ID: "OB-301-ACC-120382", Pass: true}, runstore.Checkpoint{}))
+	journey := resumableJourney(t, store)
+	certificate, err := authentication.NewCertificate(publicKey, privateKey)
+	require.NoError(err)
+	journey.config.certificateSigning = certificate
+	journey.config.certificateTransport = certificate
+
+	loadErrs := make(chan error)
+	go func() {
+		// races with the resume when the runner is started without the journey lock
+		loadErrs <- journey.RunLoad(LoadOptions{Duration: "1s", RPS: 1})
+	}()
+	status, err := journey.ResumeTests("run-1")
+	require.NoError(err)
+	require.Equal(ResumeStatus{RunID: "run-1"}, status)
+	if err := <-loadErrs; err == nil {
+		journey.StopLoad()
+	}
+
+	journey.journeyLock.Lock()
+	defer journey.journeyLock.Unlock()
+	require.NotNil(journey.runner)
+	require.Nil(journey.resuming)
+	phase, err := journey.context.GetString(CtxPhase)
+	require.NoError(err)
+	require.Equal("run", phase)
+	require.Eventually(func() bool { return !journey.runner.Running() }, time.Second, 10*time.Millisecond)
+}
@@ -58,6 +58,17 @@ func TestJourneySetDiscoveryModelValidatesModel(t *testing.T) {
 	generator.AssertExpectations(t)
 }
 
+func TestJourneyDisableJWSLeavesOtherJourneys(t *testing.T) {
+	assert := test.NewAssert(t)
+	disabled := NewJourney(nullLogger(), &gmocks.MockGenerator{}, &mocks.Validator{}, discovery.NewNullTLSValidator(), false)
+	other := NewJourney(nullLogger(), &gmocks.MockGenerator{}, &mocks.Validator{}, discovery.NewNullTLSValidator(), false)
+
+	disabled.DisableJWS()
+
+	assert.Equal("disabled", disabled.JWSStatus())
+	assert.Equal("false", other.JWSStatus())
+}
+
 func TestJourneySetDiscoveryModelHandlesErrorFromValidator(t *testing.T) {
 	assert := test.NewAssert(t)
 
@@ -0,0 +1,191 @@
+package server
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+var errJourneyNotFound = errors.New("journey not found, it may have expired - create a new one with POST /api/journeys")
+
+// defaultJourneyID - the ID of the journey used by the routes not scoped to a journey
+const defaultJourneyID = "default"
+
+// JourneyFactory - creates the Journey for a new session with `id`, which is empty for the
+// default journey. The journey puts `id` in the OAuth state of its consent URLs, so the consent
+// callback reaches it, see consentJourneyMiddleware.
+type JourneyFactory func(id string) Journey
+
+// Journeys - the journeys served by one fcs_server, so several people can use the same
+// instance without overwriting each other's discovery model, configuration and tokens.
+//
+// The routes under `/api` use the default journey, which never expires. Journeys created
+// with POST `/api/journeys` are served under `/api/journeys/:journey_id` and are removed
+// once they have not been used for the idle timeout.
+type Journeys struct {
+	factory        JourneyFactory
+	idleTimeout    time.Duration
+	defaultJourney *journeySession
+	logger         *logrus.Entry
+
+	lock     sync.Mutex
+	sessions map[string]*journeySession
+	now      func() time.Time
+}
+
+type journeySession struct {
+	id       string
+	journey  Journey
+	handlers journeyHandlers
+	created  time.Time
+	lastUsed time.Time
+	// active - requests in progress, a results websocket stays active for the whole run
+	active int
+}
+
+// JourneyInfo - a journey as listed by GET `/api/journeys`
+type JourneyInfo struct {
+	ID       string    `json:"id"`
+	Created  time.Time `json:"created"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// NewJourneys - `factory` creates the default journey and one per session. A zero `idleTimeout`
+// keeps journeys until they are deleted.
+func NewJourneys(factory JourneyFactory, idleTimeout time.Duration, logger *logrus.Entry) *Journeys {
+	journeys := &Journeys{
+		factory:     factory,
+		idleTimeout: idleTimeout,
+		logger:      logger.WithField("module", "journeys"),
+		sessions:    map[string]*journeySession{},
+		now:         time.Now,
+	}
+	journeys.defaultJourney = journeys.newSession(defaultJourneyID)
+	return journeys
+}
+
+// Default - the journey used by the routes that are not scoped to a journey ID
+func (j *Journeys) Default() Journey {
+	return j.defaultJourney.journey
+}
+
+// Create - starts a new journey, returning its ID
+func (j *Journeys) Create() JourneyInfo {
+	session := j.newSession(uuid.New().String())
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.expire()
+	j.sessions[session.id] = session
+	j.logger.WithField("journey_id", session.id).Info("journey created")
+	return session.info()
+}
+
+// List - the journeys that have not expired
+func (j *Journeys) List() []JourneyInfo {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.expire()
+
+	journeys := make([]JourneyInfo, 0, len(j.sessions))
+	for _, session := range j.sessions {
+		journeys = append(journeys, session.info())
+	}
+	return journeys
+}
+
+// Get - the journey with `id`, or false if it does not exist or has expired
+func (j *Journeys) Get(id string) (Journey, bool) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.expire()
+
+	session, ok := j.sessions[id]
+	if !ok {
+		return nil, false
+	}
+	session.lastUsed = j.now()
+	return session.journey, true
+}
+
+// Delete - stops any test run and removes the journey
+func (j *Journeys) Delete(id string) bool {
+	j.lock.Lock()
+	session, ok := j.sessions[id]
+	delete(j.sessions, id)
+	j.lock.Unlock()
+
+	if ok {
+		session.journey.StopTestRun()
+		j.logger.WithField("journey_id", id).Info("journey deleted")
+	}
+	return ok
+}
+
+func (j *Journeys) newSession(id string) *journeySession {
+	journeyID := id
+	if id == defaultJourneyID {
+		journeyID = ""
+	}
+	journey := j.factory(journeyID)
+	now := j.now()
+	return &journeySession{
+		id:       id,
+		journey:  journey,
+		handlers: newJourneyHandlers(journey, j.logger),
+		created:  now,
+		lastUsed: now,
+	}
+}
+
+// acquire - marks the session as in use for the duration of a request, see release
+func (j *Journeys) acquire(id string) (*journeySession, bool) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.expire()
+
+	session, ok := j.sessions[id]
+	if !ok {
+		return nil, false
+	}
+	session.active++
+	session.lastUsed = j.now()
+	return session, true
+}
+
+func (j *Journeys) release(session *journeySession) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	session.active--
+	session.lastUsed = j.now()
+}
+
+// expire - removes idle sessions, the caller holds the lock
+func (j *Journeys) expire() {
+	if j.idleTimeout <= 0 {
+		return
+	}
+	now := j.now()
+	for id, session := range j.sessions {
+		if session.active > 0 || now.Sub(session.lastUsed) < j.idleTimeout {
+			continue
+		}
+		delete(j.sessions, id)
+		session.journey.StopTestRun()
+		j.logger.WithFields(logrus.Fields{
+			"journey_id": id,
+			"last_used":  session.lastUsed,
+		}).Info("journey expired")
+	}
+}
+
+func (s *journeySession) info() JourneyInfo {
+	return JourneyInfo{
+		ID:       s.id,
+		Created:  s.created,
+		LastUsed: s.lastUsed,
+	}
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/test"
+	versionmock "github.com/OpenBankingUK/conformance-suite/pkg/version/mocks"
+)
+
+// mockJourneys - a Journeys whose factory hands out `journeys` in order
+func mockJourneys(idleTimeout time.Duration, journeys ...*MockJourney) *Journeys {
+	next := 0
+	return NewJourneys(func(string) Journey {
+		journey := journeys[next]
+		next++
+		return journey
+	}, idleTimeout, nullLogger())
+}
+
+func createJourney(t *testing.T, server *Server) string {
+	code, body, _ := request(http.MethodPost, "/api/journeys", nil, server)
+	test.NewRequire(t).Equal(http.StatusCreated, code)
+
+	info := JourneyInfo{}
+	test.NewRequire(t).NoError(json.Unmarshal(body.Bytes(), &info))
+	return info.ID
+}
+
+func TestServerJourneysAreIndependent(t *testing.T) {
+	require := test.NewRequire(t)
+
+	defaultJourney, first, second := &MockJourney{}, &MockJourney{}, &MockJourney{}
+	first.On("RunTests").Return(nil)
+	second.On("RunTests").Return(errors.New("error not finished collecting tokens"))
+	server := NewServer(mockJourneys(0, defaultJourney, first, second), nil, nullLogger(), &versionmock.Version{})
+	defer func() {
+		require.NoError(server.Shutdown(context.TODO()))
+	}()
+
+	firstID := createJourney(t, server)
+	secondID := createJourney(t, server)
+	require.NotEqual(firstID, secondID)
+
+	code, _, _ := request(http.MethodPost, "/api/journeys/"+firstID+"/run", nil, server)
+	require.Equal(http.StatusCreated, code)
+
+	code, body, _ := request(http.MethodPost, "/api/journeys/"+secondID+"/run", nil, server)
+	require.Equal(http.StatusBadRequest, code)
+	require.JSONEq(`{"error": "error not finished collecting tokens"}`, body.String())
+
+	first.AssertNumberOfCalls(t, "RunTests", 1)
+	second.AssertNumberOfCalls(t, "RunTests", 1)
+	defaultJourney.AssertNotCalled(t, "RunTests")
+}
+
+func TestServerJourneysUnknownID(t *testing.T) {
+	require := test.NewRequire(t)
+
+	server := NewServer(mockJourneys(0, &MockJourney{}), nil, nullLogger(), &versionmock.Version{})
+	defer func() {
+		require.NoError(server.Shutdown(context.TODO()))
+	}()
+
+	code, body, _ := request(http.MethodPost, "/api/journeys/unknown/run", nil, server)
+	require.Equal(http.StatusNotFound, code)
+	require.JSONEq(`{"error": "journey not found, it may have expired - create a new one with POST /api/journeys"}`, body.String())
+
+	code, _, _ = request(http.MethodDelete, "/api/journeys/unknown", nil, server)
+	require.Equal(http.StatusNotFound, code)
+}
+
+func TestServerConsentCallbackReachesJourneyInState(t *testing.T) {
+	require := test.NewRequire(t)
+
+	defaultJourney, first, second := &MockJourney{}, &MockJourney{}, &MockJourney{}
+	server := NewServer(mockJourneys(0, defaultJourney, first, second), nil, nullLogger(), &versionmock.Version{})
+	defer func() {
+		require.NoError(server.Shutdown(context.TODO()))
+	}()
+	firstID := createJourney(t, server)
+	secondID := createJourney(t, server)
+	require.NotEqual(firstID, secondID)
+	second.On("CollectToken", "a052c795", "Token001."+secondID, "openid accounts").Return(nil)
+	defaultJourney.On("CollectToken", "80bf17a3", "Token001", "openid accounts").Return(nil)
+
+	// the callback window has not started a journey, so it posts to the unscoped route
+	code, _, _ := request(http.MethodPost, "/api/redirect/fragment/ok",
+		strings.NewReader(`{"code": "a052c795", "scope": "openid accounts", "state": "Token001.`+secondID+`"}`), server)
+	require.Equal(http.StatusOK, code)
+	code, _, _ = request(http.MethodPost, "/api/redirect/query/ok",
+		strings.NewReader(`{"code": "80bf17a3", "scope": "openid accounts", "state": "Token001"}`), server)
+	require.Equal(http.StatusOK, code)
+
+	second.AssertNumberOfCalls(t, "CollectToken", 1)
+	defaultJourney.AssertNumberOfCalls(t, "CollectToken", 1)
+	first.AssertNumberOfCalls(t, "CollectToken", 0)
+
+	code, body, _ := request(http.MethodPost, "/api/redirect/fragment/ok",
+		strings.NewReader(`{"code": "a052c795", "state": "Token001.6c3b4ed5"}`), server)
+	require.Equal(http.StatusNotFound, code)
+	require.JSONEq(`{"error": "journey not found, it may have expired - create a new one with POST /api/journeys"}`, body.String())
+}
+
+func TestServerJourneysDelete(t *testing.T) {
+	require := test.NewRequire(t)
+
+	journey := &MockJourney{}
+	journey.On("StopTestRun").Return()
+	server := NewServer(mockJourneys(0, &MockJourney{}, journey), nil, nullLogger(), &versionmock.Version{})
+	defer func() {
+		require.NoError(server.Shutdown(context.TODO()))
+	}()
+
+	id := createJourney(t, server)
+	code, body, _ := request(http.MethodGet, "/api/journeys", nil, server)
+	require.Equal(http.StatusOK, code)
+	require.Contains(body.String(), id)
+
+	code, _, _ = request(http.MethodDelete, "/api/journeys/"+id, nil, server)
+	require.Equal(http.StatusNoContent, code)
+	journey.AssertCalled(t, "StopTestRun")
+
+	code, body, _ = request(http.MethodGet, "/api/journeys", nil, server)
+	require.Equal(http.StatusOK, code)
+	require.JSONEq(`[]`, body.String())
+}
+
+func TestJourneysIdleExpiry(t *testing.T) {
+	require := test.NewRequire(t)
+
+	idle, busy := &MockJourney{}, &MockJourney{}
+	idle.On("StopTestRun").Return()
+	busy.On("StopTestRun").Return()
+	journeys := mockJourneys(time.Hour, &MockJourney{}, idle, busy)
+	now := time.Date(2020, 10, 1, 9, 0, 0, 0, time.UTC)
+	journeys.now = func() time.Time { return now }
+
+	idleID := journeys.Create().ID
+	busyID := journeys.Create().ID
+	session, ok := journeys.acquire(busyID)
+	require.True(ok)
+
+	now = now.Add(time.Hour)
+	_, ok = journeys.Get(idleID)
+	require.False(ok, "idle journey expires")
+	_, ok = journeys.Get(busyID)
+	require.True(ok, "journey with a request in progress does not expire")
+	idle.AssertCalled(t, "StopTestRun")
+	busy.AssertNotCalled(t, "StopTestRun")
+
+	journeys.release(session)
+	now = now.Add(2 * time.Hour)
+	require.Empty(journeys.List())
+	busy.AssertCalled(t, "StopTestRun")
+	require.NotNil(journeys.Default(), "default journey never expires")
+}
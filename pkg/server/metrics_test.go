@@ -0,0 +1,29 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/test"
+)
+
+func TestServerMetrics(t *testing.T) {
+	assert := test.NewAssert(t)
+
+	server := NewServer(journeysFor(testJourney()), nil, nullLogger(), makeVersionMock("", false))
+	defer func() {
+		require.NoError(t, server.Shutdown(context.TODO()))
+	}()
+
+	code, _, _ := request(http.MethodGet, "/metrics", nil, server)
+	assert.NotEqual(http.StatusOK, code, "metrics are only served once enabled")
+
+	server.ServeMetrics()
+	code, body, headers := request(http.MethodGet, "/metrics", nil, server)
+	assert.Equal(http.StatusOK, code)
+	assert.Contains(headers.Get("Content-Type"), "text/plain")
+	assert.Contains(body.String(), "# TYPE fcs_consent_wait_seconds histogram")
+}
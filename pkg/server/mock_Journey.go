@@ -8,6 +8,7 @@ import executors "github.com/OpenBankingUK/conformance-suite/pkg/executors"
 import generation "github.com/OpenBankingUK/conformance-suite/pkg/generation"
 import manifest "github.com/OpenBankingUK/conformance-suite/pkg/manifest"
 import mock "github.com/stretchr/testify/mock"
+import runevents "github.com/OpenBankingUK/conformance-suite/pkg/runevents"
 
 // MockJourney is an autogenerated mock type for the Journey type
 type MockJourney struct {
@@ -116,6 +117,41 @@ func (_m *MockJourney) FilteredManifests() (manifest.Scripts, error) {
 	return r0, r1
 }
 
+// JWSStatus provides a mock function with given fields:
+func (_m *MockJourney) JWSStatus() string {
+	ret := _m.Called()
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// LoadReport provides a mock function with given fields:
+func (_m *MockJourney) LoadReport() (executors.LoadReport, error) {
+	ret := _m.Called()
+
+	var r0 executors.LoadReport
+	if rf, ok := ret.Get(0).(func() executors.LoadReport); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(executors.LoadReport)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewDaemonController provides a mock function with given fields:
 func (_m *MockJourney) NewDaemonController() {
 	_m.Called()
@@ -137,6 +173,20 @@ func (_m *MockJourney) Results() executors.DaemonController {
 	return r0
 }
 
+// RunLoad provides a mock function with given fields: options
+func (_m *MockJourney) RunLoad(options LoadOptions) error {
+	ret := _m.Called(options)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(LoadOptions) error); ok {
+		r0 = rf(options)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // RunTests provides a mock function with given fields:
 func (_m *MockJourney) RunTests() error {
 	ret := _m.Called()
@@ -151,6 +201,43 @@ func (_m *MockJourney) RunTests() error {
 	return r0
 }
 
+// ResumeTests provides a mock function with given fields: runID
+func (_m *MockJourney) ResumeTests(runID string) (ResumeStatus, error) {
+	ret := _m.Called(runID)
+
+	var r0 ResumeStatus
+	if rf, ok := ret.Get(0).(func(string) ResumeStatus); ok {
+		r0 = rf(runID)
+	} else {
+		r0 = ret.Get(0).(ResumeStatus)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(runID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RunEvents provides a mock function with given fields:
+func (_m *MockJourney) RunEvents() runevents.Stream {
+	ret := _m.Called()
+
+	var r0 runevents.Stream
+	if rf, ok := ret.Get(0).(func() runevents.Stream); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(runevents.Stream)
+		}
+	}
+
+	return r0
+}
+
 // SetConfig provides a mock function with given fields: config
 func (_m *MockJourney) SetConfig(config JourneyConfig) error {
 	ret := _m.Called(config)
@@ -188,11 +275,21 @@ func (_m *MockJourney) SetDiscoveryModel(discoveryModel *discovery.Model) (disco
 	return r0, r1
 }
 
+// SetJWKSURI provides a mock function with given fields: jwksURI
+func (_m *MockJourney) SetJWKSURI(jwksURI string) {
+	_m.Called(jwksURI)
+}
+
 // SetFilteredManifests provides a mock function with given fields: _a0
 func (_m *MockJourney) SetFilteredManifests(_a0 manifest.Scripts) {
 	_m.Called(_a0)
 }
 
+// StopLoad provides a mock function with given fields:
+func (_m *MockJourney) StopLoad() {
+	_m.Called()
+}
+
 // StopTestRun provides a mock function with given fields:
 func (_m *MockJourney) StopTestRun() {
 	_m.Called()
@@ -5,7 +5,6 @@ import (
 	"github.com/OpenBankingUK/conformance-suite/pkg/model"
 	"github.com/OpenBankingUK/conformance-suite/pkg/version"
 	"github.com/sirupsen/logrus"
-	"gopkg.in/resty.v1"
 )
 
 // Context Variables
@@ -51,6 +50,7 @@ const (
 	CtxPhase                               = "phase"
 	CtxDynamicResourceIDs                  = "dynamicResourceIDs"
 	CtxAcrValuesSupported                  = "acrValuesSupported"
+	CtxJourneyID                           = "journey_id" // CtxJourneyID - names the journey in the OAuth state of its consent URLs
 )
 
 // PutParametersToJourneyContext populates a JourneyContext with values from the config screen
@@ -109,13 +109,16 @@ func PutParametersToJourneyContext(config JourneyConfig, context model.Context)
 		context.Delete(CtxStatementID)
 	}
 
+	logrus.Tracef("TokenEndpoint auth method %s", config.tokenEndpointAuthMethod)
+	return nil
+}
+
+// userAgent - the User-Agent header sent to the ASPSP, naming the organisation and software
+// statement in the transport certificate of `config` when it has them
+func userAgent(config JourneyConfig) string {
 	_, ou, cn, err := config.certificateTransport.DN()
 	if err == nil && cn != "" && ou != "" {
-		resty.SetHeader("User-Agent", "OpenBankingFCS/"+version.NewGitHub("").GetHumanVersion()+"/"+ou+"/"+cn)
-	} else {
-		resty.SetHeader("User-Agent", "OpenBankingFCS/"+version.NewGitHub("").GetHumanVersion())
+		return "OpenBankingFCS/" + version.NewGitHub("").GetHumanVersion() + "/" + ou + "/" + cn
 	}
-
-	logrus.Tracef("TokenEndpoint auth method %s", config.tokenEndpointAuthMethod)
-	return nil
+	return "OpenBankingFCS/" + version.NewGitHub("").GetHumanVersion()
 }
@@ -86,6 +86,7 @@ type ExportResults struct {
 	HasPassed        bool                                      `json:"has_passed"`
 	Results          map[results.ResultKey][]results.TestCase  `json:"results"`
 	Tokens           []events.AcquiredAccessToken              `json:"tokens"`
+	TokenStates      []events.TokenStateChanged                `json:"token_states"` // last state of each token
 	DiscoveryModel   discovery.Model                           `json:"discovery_model"`
 	ResponseFields   string                                    `json:"-"`
 	TLSVersionResult map[string]*discovery.TLSValidationResult `json:"-"`
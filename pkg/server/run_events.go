@@ -0,0 +1,162 @@
+package server
+
+import (
+	"time"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors"
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors/events"
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors/results"
+	"github.com/OpenBankingUK/conformance-suite/pkg/generation"
+	"github.com/OpenBankingUK/conformance-suite/pkg/model"
+	"github.com/OpenBankingUK/conformance-suite/pkg/runevents"
+	"github.com/OpenBankingUK/conformance-suite/pkg/runstore"
+)
+
+// streamingDaemonController - publishes the progress of a run to the journey's event stream. All
+// calls go on to the wrapped DaemonController.
+type streamingDaemonController struct {
+	executors.DaemonController
+	stream  runevents.Stream
+	specRun generation.SpecRun
+	// replayed - test cases of a resumed run that finished before it stopped, their results are
+	// reported again but were published when they first ran
+	replayed map[string]bool
+}
+
+func newStreamingDaemonController(daemonController executors.DaemonController, stream runevents.Stream, specRun generation.SpecRun, completed []results.TestCase) *streamingDaemonController {
+	replayed := map[string]bool{}
+	for _, result := range completed {
+		replayed[result.Id] = true
+	}
+	return &streamingDaemonController{
+		DaemonController: daemonController,
+		stream:           stream,
+		specRun:          specRun,
+		replayed:         replayed,
+	}
+}
+
+// Checkpoint - passed on when the wrapped DaemonController records checkpoints
+func (sc *streamingDaemonController) Checkpoint(result results.TestCase, ctx *model.Context) {
+	if checkpointer, ok := sc.DaemonController.(executors.Checkpointer); ok {
+		checkpointer.Checkpoint(result, ctx)
+	}
+}
+
+// RecordIdempotentRequest - passed on when the wrapped DaemonController keeps idempotent requests
+func (sc *streamingDaemonController) RecordIdempotentRequest(testCaseID string, request executors.IdempotentRequest) {
+	if recorder, ok := sc.DaemonController.(executors.IdempotencyRecorder); ok {
+		recorder.RecordIdempotentRequest(testCaseID, request)
+	}
+}
+
+// TestCaseStarted - publishes test_started
+func (sc *streamingDaemonController) TestCaseStarted(tc model.TestCase) {
+	sc.stream.Publish(runevents.TestStarted, runevents.Test{
+		ID:         tc.ID,
+		Name:       tc.Name,
+		Method:     tc.Input.Method,
+		Endpoint:   tc.Input.Endpoint,
+		API:        tc.APIName,
+		APIVersion: tc.APIVersion,
+	})
+}
+
+// AddResult - publishes test_finished
+func (sc *streamingDaemonController) AddResult(result results.TestCase) {
+	sc.DaemonController.AddResult(result)
+	if sc.replayed[result.Id] {
+		return
+	}
+	sc.stream.Publish(runevents.TestFinished, runevents.TestResult{
+		ID:           result.Id,
+		Pass:         result.Pass,
+		Fail:         result.Fail,
+		HTTPStatus:   result.HttpStatus,
+		ResponseTime: float64(result.Metrics.ResponseTime) / float64(time.Millisecond),
+	})
+}
+
+// SetCompleted - publishes run_completed once the wrapped DaemonController has completed
+func (sc *streamingDaemonController) SetCompleted() {
+	sc.DaemonController.SetCompleted()
+
+	testResults := sc.DaemonController.AllResults()
+	completed := runevents.RunResult{
+		Status: string(runStatus(sc.specRun, testResults)),
+		Tests:  len(testResults),
+	}
+	for _, result := range testResults {
+		if result.Pass {
+			completed.Passed++
+		} else {
+			completed.Failed++
+		}
+	}
+	sc.stream.Publish(runevents.RunCompleted, completed)
+}
+
+// runStatus - completed, or stopped when some test cases in `specRun` have no result
+func runStatus(specRun generation.SpecRun, testResults []results.TestCase) runstore.Status {
+	ran := map[string]bool{}
+	for _, testResult := range testResults {
+		ran[testResult.Id] = true
+	}
+	for _, spec := range specRun.SpecTestCases {
+		for _, testCase := range spec.TestCases {
+			if !ran[testCase.ID] {
+				return runstore.StatusStopped
+			}
+		}
+	}
+	return runstore.StatusCompleted
+}
+
+// streamingEvents - publishes consent_granted when an access token is acquired, and
+// token_refreshed or token_expired as the tokens change state
+type streamingEvents struct {
+	events.Events
+	stream runevents.Stream
+}
+
+func newStreamingEvents(stream runevents.Stream) events.Events {
+	return streamingEvents{
+		Events: events.NewEvents(),
+		stream: stream,
+	}
+}
+
+func (e streamingEvents) AddAcquiredAccessToken(acquiredAccessToken events.AcquiredAccessToken) {
+	e.Events.AddAcquiredAccessToken(acquiredAccessToken)
+	e.stream.Publish(runevents.ConsentGranted, runevents.Consent{TokenName: acquiredAccessToken.TokenName})
+}
+
+func (e streamingEvents) AddTokenStateChanged(tokenStateChanged events.TokenStateChanged) {
+	e.Events.AddTokenStateChanged(tokenStateChanged)
+
+	token := runevents.Token{TokenName: tokenStateChanged.TokenName, Expires: tokenStateChanged.Expires}
+	switch tokenStateChanged.State {
+	case events.TokenRefreshed:
+		e.stream.Publish(runevents.TokenRefreshed, token)
+	case events.TokenExpired:
+		e.stream.Publish(runevents.TokenExpired, token)
+	}
+}
+
+// publishConsentRequired - publishes consent_required for each token in `tokens` still waiting for consent
+func publishConsentRequired(stream runevents.Stream, tokens executors.TokenConsentIDs) {
+	for _, token := range tokens {
+		if token.AccessToken != "" {
+			continue
+		}
+		stream.Publish(runevents.ConsentRequired, runevents.Consent{TokenName: token.TokenName, ConsentURL: token.ConsentURL})
+	}
+}
+
+// publishError - publishes error with `err`, returning it
+func publishError(stream runevents.Stream, err error) error {
+	if err != nil {
+		stream.Publish(runevents.Error, runevents.Failure{Message: err.Error()})
+	}
+	return err
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors"
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors/events"
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors/results"
+	"github.com/OpenBankingUK/conformance-suite/pkg/model"
+	"github.com/OpenBankingUK/conformance-suite/pkg/runevents"
+	"github.com/OpenBankingUK/conformance-suite/pkg/test"
+)
+
+// streamed - the type and data of each event `stream` has published
+func streamed(stream runevents.Stream) []string {
+	subscription, unsubscribe := stream.Subscribe(0)
+	unsubscribe()
+	published := []string{}
+	for event := range subscription {
+		published = append(published, string(event.Type)+" "+string(event.Data))
+	}
+	return published
+}
+
+func TestStreamingDaemonControllerPublishesRun(t *testing.T) {
+	require := test.NewRequire(t)
+
+	stream := runevents.NewStream(nil, nullLogger())
+	completed := []results.TestCase{{Id: "OB-301-ACC-120382", Pass: true}}
+	controller := newStreamingDaemonController(executors.NewBufferedDaemonController(), stream,
+		testSpecRun("OB-301-ACC-120382", "OB-301-ACC-811741", "OB-301-ACC-650922"), completed)
+
+	controller.AddResult(completed[0])
+	controller.TestCaseStarted(model.TestCase{ID: "OB-301-ACC-811741", Input: model.Input{Method: http.MethodGet, Endpoint: "/accounts"}})
+	controller.AddResult(results.TestCase{Id: "OB-301-ACC-811741", Fail: []string{"status code"}, HttpStatus: "401"})
+	controller.SetCompleted()
+
+	require.Equal([]string{
+		`test_started {"id":"OB-301-ACC-811741","name":"","method":"GET","endpoint":"/accounts","api":"","api_version":""}`,
+		`test_finished {"id":"OB-301-ACC-811741","pass":false,"fail":["status code"],"http_status":"401","response_time_ms":0}`,
+		`run_completed {"status":"stopped","tests":2,"passed":1,"failed":1}`,
+	}, streamed(stream))
+}
+
+func TestStreamingEventsPublishesTokens(t *testing.T) {
+	require := test.NewRequire(t)
+
+	stream := runevents.NewStream(nil, nullLogger())
+	tokenEvents := newStreamingEvents(stream)
+	tokenEvents.AddAcquiredAccessToken(events.NewAcquiredAccessToken("Token001"))
+	tokenEvents.AddTokenStateChanged(events.TokenStateChanged{TokenName: "Token001", State: events.TokenValid})
+	tokenEvents.AddTokenStateChanged(events.TokenStateChanged{TokenName: "Token001", State: events.TokenRefreshed, Expires: "2020-05-04T11:00:00Z"})
+	tokenEvents.AddTokenStateChanged(events.TokenStateChanged{TokenName: "Token001", State: events.TokenExpired})
+
+	require.Equal([]string{
+		`consent_granted {"token_name":"Token001"}`,
+		`token_refreshed {"token_name":"Token001","expires":"2020-05-04T11:00:00Z"}`,
+		`token_expired {"token_name":"Token001"}`,
+	}, streamed(stream))
+	require.Len(tokenEvents.AllTokenStateChanges(), 3)
+}
+
+func TestRunEventsHandler(t *testing.T) {
+	cases := map[string]struct {
+		accept string
+		body   []string
+	}{
+		"server-sent events": {
+			accept: "text/event-stream",
+			body:   []string{"id: 2", "event: run_completed", `data: {"version":1,"seq":2,"type":"run_completed"`},
+		},
+		"ndjson": {
+			accept: "application/x-ndjson",
+			body:   []string{`{"version":1,"seq":2,"type":"run_completed"`},
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			require := test.NewRequire(t)
+
+			stream := runevents.NewStream(nil, nullLogger())
+			stream.StartRun("4c5f1d2e-4a43-4e8e-9d0a-3b5b3a4bb0e1", runevents.Run{Tests: 1})
+			stream.Publish(runevents.RunCompleted, runevents.RunResult{Status: "completed", Tests: 1, Passed: 1})
+			journey := &MockJourney{}
+			journey.On("RunEvents").Return(stream)
+			handlers := newRunHandlers(journey, NewWebSocketUpgrader(), nullLogger())
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			request := httptest.NewRequest(http.MethodGet, "/api/run/events", nil).WithContext(ctx)
+			request.Header.Set(echo.HeaderAccept, c.accept)
+			request.Header.Set("Last-Event-ID", "1")
+			recorder := httptest.NewRecorder()
+
+			require.NoError(handlers.runEventsHandler(echo.New().NewContext(request, recorder)))
+
+			require.Equal(http.StatusOK, recorder.Code)
+			require.Equal(c.accept, recorder.Header().Get(echo.HeaderContentType))
+			body := recorder.Body.String()
+			for _, line := range c.body {
+				require.Contains(body, line)
+			}
+			require.NotContains(body, "run_started")
+			if c.accept == mimeNDJSON {
+				event := runevents.Event{}
+				require.NoError(json.Unmarshal([]byte(strings.TrimSpace(body)), &event))
+				require.Equal(runevents.RunCompleted, event.Type)
+			}
+		})
+	}
+}
+
+func TestRunEventsHandlerInvalidLastEventID(t *testing.T) {
+	require := test.NewRequire(t)
+
+	handlers := newRunHandlers(&MockJourney{}, NewWebSocketUpgrader(), nullLogger())
+	request := httptest.NewRequest(http.MethodGet, "/api/run/events?after=last", nil)
+	recorder := httptest.NewRecorder()
+
+	require.NoError(handlers.runEventsHandler(echo.New().NewContext(request, recorder)))
+	require.Equal(http.StatusBadRequest, recorder.Code)
+}
@@ -1,9 +1,12 @@
 package server
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
-
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -14,6 +17,9 @@ import (
 	"github.com/OpenBankingUK/conformance-suite/pkg/executors"
 	"github.com/OpenBankingUK/conformance-suite/pkg/executors/events"
 	"github.com/OpenBankingUK/conformance-suite/pkg/executors/results"
+	"github.com/OpenBankingUK/conformance-suite/pkg/redact"
+	"github.com/OpenBankingUK/conformance-suite/pkg/runevents"
+	"github.com/OpenBankingUK/conformance-suite/pkg/runstore"
 )
 
 const (
@@ -21,6 +27,11 @@ const (
 	pingFrequency = time.Hour * 24
 	// Deadline for write to timeout
 	writeTimeout = time.Hour * 24
+	// How often to send a comment on the run event stream, so proxies keep the connection open
+	eventStreamKeepAlive = time.Second * 15
+
+	mimeEventStream = "text/event-stream"
+	mimeNDJSON      = "application/x-ndjson"
 )
 
 type runHandlers struct {
@@ -37,15 +48,117 @@ func newRunHandlers(journey Journey, upgrader *websocket.Upgrader, logger *logru
 	}
 }
 
-// runStartPostHandler creates a new test run
+// runStartPostHandler creates a new test run, 409 while a load run is running
 func (h runHandlers) runStartPostHandler(c echo.Context) error {
 	err := h.journey.RunTests()
+	if err == errLoadRunning {
+		return c.JSON(http.StatusConflict, NewErrorResponse(err))
+	}
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, NewErrorResponse(err))
 	}
 	return c.NoContent(http.StatusCreated)
 }
 
+// ResumeRunRequest - body of POST /api/run/resume
+type ResumeRunRequest struct {
+	RunID string `json:"run_id" form:"run_id"`
+}
+
+// runResumePostHandler - continues a run from the run history. Returns 202 with the tokens needing
+// PSU consent when some have expired, 201 once the run continues and 409 while a load run is running.
+func (h runHandlers) runResumePostHandler(c echo.Context) error {
+	request := ResumeRunRequest{}
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, NewErrorResponse(errors.Wrap(err, "error with Bind")))
+	}
+	if request.RunID == "" {
+		return c.JSON(http.StatusBadRequest, NewErrorResponse(errors.New("run_id is required")))
+	}
+
+	status, err := h.journey.ResumeTests(request.RunID)
+	if errors.Cause(err) == runstore.ErrNotFound {
+		return c.JSON(http.StatusNotFound, NewErrorResponse(err))
+	}
+	if err == errLoadRunning {
+		return c.JSON(http.StatusConflict, NewErrorResponse(err))
+	}
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, NewErrorResponse(err))
+	}
+	if len(status.Tokens) > 0 {
+		return c.JSON(http.StatusAccepted, status)
+	}
+	return c.JSON(http.StatusCreated, status)
+}
+
+// runEventsHandler - GET /api/run/events
+// streams the run events of the journey as Server-Sent Events, or as NDJSON when the client
+// accepts `application/x-ndjson`. Events after the `Last-Event-ID` header or `after` query
+// parameter are sent first, while they are kept.
+func (h runHandlers) runEventsHandler(c echo.Context) error {
+	after := c.Request().Header.Get("Last-Event-ID")
+	if after == "" {
+		after = c.QueryParam("after")
+	}
+	seq, err := strconv.ParseUint(after, 10, 64)
+	if after != "" && err != nil {
+		return c.JSON(http.StatusBadRequest, NewErrorResponse(errors.Wrap(err, "invalid Last-Event-ID")))
+	}
+	ndjson := strings.Contains(c.Request().Header.Get(echo.HeaderAccept), mimeNDJSON)
+
+	subscription, unsubscribe := h.journey.RunEvents().Subscribe(seq)
+	defer unsubscribe()
+
+	response := c.Response()
+	if ndjson {
+		response.Header().Set(echo.HeaderContentType, mimeNDJSON)
+	} else {
+		response.Header().Set(echo.HeaderContentType, mimeEventStream)
+	}
+	response.Header().Set("Cache-Control", "no-cache")
+	response.WriteHeader(http.StatusOK)
+	response.Flush()
+
+	keepAlive := time.NewTicker(eventStreamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case <-keepAlive.C:
+			if ndjson {
+				continue
+			}
+			if _, err := response.Write([]byte(": keep-alive\n\n")); err != nil {
+				return nil
+			}
+		case event, ok := <-subscription:
+			if !ok {
+				return nil // fell behind, the client reconnects with Last-Event-ID
+			}
+			if err := writeRunEvent(response, event, ndjson); err != nil {
+				h.logger.WithError(err).Debug("writing run event")
+				return nil
+			}
+		}
+		response.Flush()
+	}
+}
+
+func writeRunEvent(w io.Writer, event runevents.Event, ndjson bool) error {
+	encoded, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if ndjson {
+		_, err = fmt.Fprintf(w, "%s\n", encoded)
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Type, encoded)
+	return err
+}
+
 // listenResultWebSocket - /api/run/ws
 // creates a socket connection to listen for test run results.
 //
@@ -98,6 +211,10 @@ func (h runHandlers) listenResultWebSocket(c echo.Context) error {
 			if err := h.processAcquiredAllAccessTokensEvent(ws, logger, event, ok); err != nil {
 				break
 			}
+		case event, ok := <-events.TokenStatesChannel():
+			if err := h.processTokenStateChangedEvent(ws, logger, event, ok); err != nil {
+				break
+			}
 		}
 	}
 
@@ -109,7 +226,7 @@ func (h runHandlers) shouldStop(daemon executors.DaemonController, ws *websocket
 	if daemon.ShouldStop() {
 		daemon.Stopped()
 		logger.Info("sending stop event")
-		if err := ws.WriteJSON(newStoppedEvent()); err != nil {
+		if err := writeRedactedJSON(ws, newStoppedEvent()); err != nil {
 			logger.WithError(err).Error("writing StoppedEvent")
 			return true
 		}
@@ -157,7 +274,7 @@ func (h runHandlers) processTestCasesCompleted(ws *websocket.Conn, logger *logru
 		"wsEvent.Type": wsEvent.Type,
 		"isCompleted":  isCompleted,
 	}).Info("sending event")
-	if err := ws.WriteJSON(wsEvent); err != nil {
+	if err := writeRedactedJSON(ws, wsEvent); err != nil {
 		logger.WithError(err).Error("[processTestCasesCompleted] writing json to websocket")
 		return err
 	}
@@ -177,7 +294,7 @@ func (h runHandlers) processTestCaseResult(ws *websocket.Conn, logger *logrus.En
 		"wsEvent.Type": wsEvent.Type,
 		"result.Id":    result.Id,
 	}).Info("sending event")
-	if err := ws.WriteJSON(wsEvent); err != nil {
+	if err := writeRedactedJSON(ws, wsEvent); err != nil {
 		logger.WithError(err).Error("[processTestCaseResult] writing json to websocket")
 		return err
 	}
@@ -197,7 +314,7 @@ func (h runHandlers) processAcquiredAccessTokenEvent(ws *websocket.Conn, logger
 		"wsEvent.Type":    wsEvent.Type,
 		"event.TokenName": event.TokenName,
 	}).Info("sending event")
-	if err := ws.WriteJSON(wsEvent); err != nil {
+	if err := writeRedactedJSON(ws, wsEvent); err != nil {
 		logger.WithError(err).Error("[processAcquiredAccessTokenEvent] writing json to websocket")
 		return err
 	}
@@ -217,7 +334,7 @@ func (h runHandlers) processAcquiredAllAccessTokensEvent(ws *websocket.Conn, log
 		"wsEvent.Type":     wsEvent.Type,
 		"event.TokenNames": event.TokenNames,
 	}).Info("sending event")
-	if err := ws.WriteJSON(wsEvent); err != nil {
+	if err := writeRedactedJSON(ws, wsEvent); err != nil {
 		logger.WithError(err).Error("[processAcquiredAllAccessTokensEvent] writing json to websocket")
 		return err
 	}
@@ -225,6 +342,36 @@ func (h runHandlers) processAcquiredAllAccessTokensEvent(ws *websocket.Conn, log
 	return nil
 }
 
+func (h runHandlers) processTokenStateChangedEvent(ws *websocket.Conn, logger *logrus.Entry, event events.TokenStateChanged, ok bool) error {
+	if !ok {
+		err := errors.New("error reading from events.TokenStates channel")
+		logger.Error(err)
+		return err
+	}
+
+	wsEvent := newTokenStateChangedWebSocketEvent(event)
+	logger.WithFields(logrus.Fields{
+		"wsEvent.Type":    wsEvent.Type,
+		"event.TokenName": event.TokenName,
+		"event.State":     event.State,
+	}).Info("sending event")
+	if err := writeRedactedJSON(ws, wsEvent); err != nil {
+		logger.WithError(err).Error("[processTokenStateChangedEvent] writing json to websocket")
+		return err
+	}
+
+	return nil
+}
+
+// writeRedactedJSON - writes `event` to `ws` as JSON, with sensitive values redacted
+func writeRedactedJSON(ws *websocket.Conn, event interface{}) error {
+	encoded, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return ws.WriteMessage(websocket.TextMessage, []byte(redact.String(string(encoded))))
+}
+
 // StoppedEvent -
 type StoppedEvent struct {
 	Stopped bool `json:"stopped"`
@@ -287,3 +434,50 @@ func newAcquiredAllAccessTokensWebSocketEvent(event events.AcquiredAllAccessToke
 		Value: event,
 	}
 }
+
+// TokenStateChangedWebSocketEvent -
+type TokenStateChangedWebSocketEvent struct {
+	Type  string                   `json:"type"`
+	Value events.TokenStateChanged `json:"value"`
+}
+
+func newTokenStateChangedWebSocketEvent(event events.TokenStateChanged) TokenStateChangedWebSocketEvent {
+	return TokenStateChangedWebSocketEvent{
+		Type:  "ResultType_TokenStateChanged",
+		Value: event,
+	}
+}
+
+// loadStartPostHandler - POST /api/load
+// starts a load run of the read-only test cases. Returns 409 while one is running.
+func (h runHandlers) loadStartPostHandler(c echo.Context) error {
+	options := LoadOptions{}
+	if err := c.Bind(&options); err != nil {
+		return c.JSON(http.StatusBadRequest, NewErrorResponse(errors.Wrap(err, "error with Bind")))
+	}
+
+	err := h.journey.RunLoad(options)
+	if err == errLoadRunning || err == errTestRunRunning {
+		return c.JSON(http.StatusConflict, NewErrorResponse(err))
+	}
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, NewErrorResponse(err))
+	}
+	return c.NoContent(http.StatusCreated)
+}
+
+// loadReportHandler - GET /api/load
+// returns what the load run has measured so far, 404 when none was started
+func (h runHandlers) loadReportHandler(c echo.Context) error {
+	report, err := h.journey.LoadReport()
+	if err != nil {
+		return c.JSON(http.StatusNotFound, NewErrorResponse(err))
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+// stopLoadHandler - DELETE /api/load
+func (h runHandlers) stopLoadHandler(c echo.Context) error {
+	h.journey.StopLoad()
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors"
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors/results"
+	"github.com/OpenBankingUK/conformance-suite/pkg/model"
+	"github.com/OpenBankingUK/conformance-suite/pkg/runstore"
+)
+
+// recordingDaemonController - saves the run to the run store once the runner completes, and a
+// checkpoint after each test case so the run can be resumed. All other calls go to the wrapped
+// DaemonController.
+type recordingDaemonController struct {
+	executors.DaemonController
+	store      runstore.RunStore
+	run        runstore.Run
+	checkpoint runstore.Checkpoint
+	tokens     executors.TokenCollector // optional, the checkpoint keeps its tokens as they are refreshed
+	logger     *logrus.Entry
+	now        func() time.Time
+}
+
+func newRecordingDaemonController(daemonController executors.DaemonController, store runstore.RunStore, run runstore.Run, checkpoint runstore.Checkpoint, logger *logrus.Entry) *recordingDaemonController {
+	return &recordingDaemonController{
+		DaemonController: daemonController,
+		store:            store,
+		run:              run,
+		checkpoint:       checkpoint,
+		logger:           logger.WithField("run_id", run.ID),
+		now:              time.Now,
+	}
+}
+
+// start - saves the run as running, so a run interrupted by a restart is still listed
+func (rc *recordingDaemonController) start() {
+	rc.run.Status = runstore.StatusRunning
+	rc.run.Finished = time.Time{}
+	rc.save()
+}
+
+// Checkpoint - adds `result` to the saved run, with the context after the test case ran
+func (rc *recordingDaemonController) Checkpoint(result results.TestCase, ctx *model.Context) {
+	checkpoint := rc.checkpoint
+	checkpoint.Context = checkpointContext(*ctx)
+	if rc.tokens != nil {
+		checkpoint.Tokens = checkpointTokens(rc.tokens.Tokens())
+	}
+	if err := rc.store.Checkpoint(rc.run.ID, runstore.NewResult(result), checkpoint); err != nil {
+		rc.logger.WithError(err).Error("saving run checkpoint")
+	}
+}
+
+// RecordIdempotentRequest - keeps `request` in the checkpoints saved from now on
+func (rc *recordingDaemonController) RecordIdempotentRequest(testCaseID string, request executors.IdempotentRequest) {
+	idempotent := make(map[string]executors.IdempotentRequest, len(rc.checkpoint.Idempotent)+1)
+	for id, sent := range rc.checkpoint.Idempotent {
+		idempotent[id] = sent
+	}
+	idempotent[testCaseID] = request
+	rc.checkpoint.Idempotent = idempotent
+}
+
+// SetCompleted - saves the results of the run, then marks it as completed. The run is saved as
+// stopped when some of its test cases did not run.
+func (rc *recordingDaemonController) SetCompleted() {
+	testResults := rc.DaemonController.AllResults()
+	rc.run.Results = make([]runstore.Result, 0, len(testResults))
+	for _, testResult := range testResults {
+		rc.run.Results = append(rc.run.Results, runstore.NewResult(testResult))
+	}
+	rc.run.Finished = rc.now()
+	rc.run.Status = runStatus(rc.run.SpecRun, testResults)
+	rc.save()
+
+	rc.DaemonController.SetCompleted()
+}
+
+func (rc *recordingDaemonController) save() {
+	if err := rc.store.Save(rc.run); err != nil {
+		rc.logger.WithError(err).Error("saving run history")
+	}
+}
+
+// checkpointContext - `ctx` without the secrets and keys from the configuration, they are
+// taken from the configuration again when the run is resumed. The journey ID and whether JWS is
+// disabled are left out too, as the run may be resumed by another journey.
+func checkpointContext(ctx model.Context) map[string]interface{} {
+	checkpoint := map[string]interface{}{}
+	for key, value := range ctx {
+		switch key {
+		case CtxConstClientSecret, CtxConstBasicAuthentication, CtxSigningPrivate, CtxSigningPublic, CtxJourneyID, model.CtxDisableJWS:
+			continue
+		}
+		checkpoint[key] = value
+	}
+	return checkpoint
+}
+
+// checkpointTokens - the expiry and refresh token of each of `tokens`
+func checkpointTokens(tokens executors.TokenConsentIDs) []runstore.Token {
+	checkpointed := make([]runstore.Token, 0, len(tokens))
+	for _, token := range tokens {
+		checkpointed = append(checkpointed, runstore.Token{
+			Name:         token.TokenName,
+			Expires:      token.Expires,
+			RefreshToken: token.RefreshToken,
+		})
+	}
+	return checkpointed
+}
+
+// historyConfig - the configuration of a run as kept in the run history.
+// Certificates, keys, secrets and software statements are left out.
+func historyConfig(config JourneyConfig) map[string]interface{} {
+	return map[string]interface{}{
+		"client_id":                     config.clientID,
+		"token_endpoint":                config.tokenEndpoint,
+		"token_endpoint_auth_method":    config.tokenEndpointAuthMethod,
+		"authorization_endpoint":        config.authorizationEndpoint,
+		"resource_base_url":             config.resourceBaseURL,
+		"issuer":                        config.issuer,
+		"registration_endpoint":         config.registrationEndpoint,
+		"redirect_url":                  config.redirectURL,
+		"response_type":                 config.ResponseType,
+		"request_object_signing_alg":    config.requestObjectSigningAlgorithm,
+		"x_fapi_financial_id":           config.xXFAPIFinancialID,
+		"api_version":                   config.apiVersion,
+		"transaction_from_date":         config.transactionFromDate,
+		"transaction_to_date":           config.transactionToDate,
+		"use_dynamic_resource_id":       config.useDynamicResourceID,
+		"resource_ids":                  config.resourceIDs,
+		"conditional_properties":        config.conditionalProperties,
+		"acr_values_supported":          config.AcrValuesSupported,
+		"tpp_signature_kid":             config.tppSignatureKID,
+		"tpp_signature_issuer":          config.tppSignatureIssuer,
+		"tpp_signature_tan":             config.tppSignatureTAN,
+		"currency_of_transfer":          config.currencyOfTransfer,
+		"first_payment_date_time":       config.firstPaymentDateTime,
+		"requested_execution_date_time": config.requestedExecutionDateTime,
+		"x_fapi_customer_ip_address":    config.xXFAPICustomerIPAddress,
+	}
+}
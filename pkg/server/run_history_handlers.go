@@ -0,0 +1,86 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/runevents"
+	"github.com/OpenBankingUK/conformance-suite/pkg/runstore"
+)
+
+const runIDParam = "run_id"
+
+type runHistoryHandlers struct {
+	runs     runstore.RunStore
+	eventLog runevents.Log
+	logger   *logrus.Entry
+}
+
+func newRunHistoryHandlers(runs runstore.RunStore, logger *logrus.Entry) runHistoryHandlers {
+	return runHistoryHandlers{
+		runs:   runs,
+		logger: logger.WithField("handler", "runHistoryHandlers"),
+	}
+}
+
+// listRunsHandler - GET /api/runs
+func (h runHistoryHandlers) listRunsHandler(c echo.Context) error {
+	if h.runs == nil {
+		return c.JSON(http.StatusNotFound, NewErrorResponse(errRunHistoryDisabled))
+	}
+	summaries, err := h.runs.List()
+	if err != nil {
+		h.logger.WithError(err).Error("listing runs")
+		return c.JSON(http.StatusInternalServerError, NewErrorResponse(err))
+	}
+	return c.JSON(http.StatusOK, summaries)
+}
+
+// getRunHandler - GET /api/runs/:run_id
+func (h runHistoryHandlers) getRunHandler(c echo.Context) error {
+	if h.runs == nil {
+		return c.JSON(http.StatusNotFound, NewErrorResponse(errRunHistoryDisabled))
+	}
+	run, err := h.runs.Get(c.Param(runIDParam))
+	if err != nil {
+		return h.storeError(c, err)
+	}
+	return c.JSON(http.StatusOK, run)
+}
+
+// deleteRunHandler - DELETE /api/runs/:run_id
+func (h runHistoryHandlers) deleteRunHandler(c echo.Context) error {
+	if h.runs == nil {
+		return c.JSON(http.StatusNotFound, NewErrorResponse(errRunHistoryDisabled))
+	}
+	if err := h.runs.Delete(c.Param(runIDParam)); err != nil {
+		return h.storeError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// getRunEventsHandler - GET /api/runs/:run_id/events
+// the run's events as NDJSON
+func (h runHistoryHandlers) getRunEventsHandler(c echo.Context) error {
+	events, err := h.eventLog.Open(c.Param(runIDParam))
+	if errors.Is(err, runevents.ErrNoLog) {
+		return c.JSON(http.StatusNotFound, NewErrorResponse(err))
+	}
+	if err != nil {
+		h.logger.WithError(err).Error("reading run event log")
+		return c.JSON(http.StatusInternalServerError, NewErrorResponse(err))
+	}
+	defer events.Close()
+	return c.Stream(http.StatusOK, mimeNDJSON, events)
+}
+
+func (h runHistoryHandlers) storeError(c echo.Context, err error) error {
+	if errors.Is(err, runstore.ErrNotFound) {
+		return c.JSON(http.StatusNotFound, NewErrorResponse(err))
+	}
+	h.logger.WithError(err).Error("reading run history")
+	return c.JSON(http.StatusInternalServerError, NewErrorResponse(err))
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors"
+	"github.com/OpenBankingUK/conformance-suite/pkg/generation"
+	"github.com/OpenBankingUK/conformance-suite/pkg/notify"
+)
+
+// notifyTimeout - how long a webhook has to answer each post
+const notifyTimeout = 30 * time.Second
+
+// notifyingDaemonController - notifies the journey's webhooks once the run completes. All calls
+// go on to the wrapped DaemonController.
+type notifyingDaemonController struct {
+	executors.DaemonController
+	notifier notify.Notifier
+	hooks    []notify.Hook
+	summary  notify.Summary
+	specRun  generation.SpecRun
+	previous map[string]bool // whether each test case passed in the previous run, nil when there was none
+	now      func() time.Time
+	// notified - closed once the hooks have been notified
+	notified chan struct{}
+}
+
+func newNotifyingDaemonController(daemonController executors.DaemonController, notifier notify.Notifier, hooks []notify.Hook, summary notify.Summary, specRun generation.SpecRun, previous map[string]bool) *notifyingDaemonController {
+	return &notifyingDaemonController{
+		DaemonController: daemonController,
+		notifier:         notifier,
+		hooks:            hooks,
+		summary:          summary,
+		specRun:          specRun,
+		previous:         previous,
+		now:              time.Now,
+		notified:         make(chan struct{}),
+	}
+}
+
+// SetCompleted - completes the run, then notifies the hooks in the background so slow or
+// unreachable webhooks do not hold up the results
+func (nc *notifyingDaemonController) SetCompleted() {
+	nc.DaemonController.SetCompleted()
+
+	summary := nc.summary
+	summary.Finished = nc.now()
+	summary.Status = string(runStatus(nc.specRun, nc.DaemonController.AllResults()))
+	summary.AddResults(nc.DaemonController.AllResultsGrouped(), nc.previous)
+	go func() {
+		defer close(nc.notified)
+		nc.notifier.Notify(nc.hooks, summary)
+	}()
+}
+
+// notifyRun - the daemon controller for run `runID`, notifying the configured webhooks when it completes
+func (wj *AppJourney) notifyRun(runID string, started time.Time) executors.DaemonController {
+	if len(wj.config.notifications) == 0 {
+		return wj.daemonController
+	}
+	summary := notify.Summary{RunID: runID, Started: started}
+	if wj.validDiscoveryModel != nil {
+		summary.Name = wj.validDiscoveryModel.DiscoveryModel.Name
+	}
+	previous := wj.previousResults(runID, summary.Name)
+	return newNotifyingDaemonController(wj.daemonController, wj.notifier, wj.config.notifications, summary, wj.specRun, previous)
+}
+
+// previousResults - whether each test case passed in the most recent earlier run of the discovery
+// model `name`, nil when the run history is disabled or has no such run
+func (wj *AppJourney) previousResults(runID, name string) map[string]bool {
+	if wj.runStore == nil {
+		return nil
+	}
+	logger := wj.log.WithFields(logrus.Fields{"function": "previousResults", "run_id": runID})
+	summaries, err := wj.runStore.List()
+	if err != nil {
+		logger.WithError(err).Warn("listing runs, regressions will not be reported")
+		return nil
+	}
+	for _, summary := range summaries {
+		if summary.ID == runID || summary.Name != name || summary.Tests == 0 {
+			continue
+		}
+		run, err := wj.runStore.Get(summary.ID)
+		if err != nil {
+			logger.WithError(err).Warn("reading previous run, regressions will not be reported")
+			return nil
+		}
+		passed := map[string]bool{}
+		for _, result := range run.Results {
+			passed[result.ID] = result.Pass
+		}
+		return passed
+	}
+	return nil
+}
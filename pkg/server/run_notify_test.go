@@ -0,0 +1,83 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/discovery"
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors"
+	"github.com/OpenBankingUK/conformance-suite/pkg/executors/results"
+	"github.com/OpenBankingUK/conformance-suite/pkg/notify"
+	"github.com/OpenBankingUK/conformance-suite/pkg/runstore"
+	"github.com/OpenBankingUK/conformance-suite/pkg/test"
+)
+
+// recordingNotifier - a notify.Notifier keeping the summaries it is asked to post
+type recordingNotifier struct {
+	hooks     []notify.Hook
+	summaries []notify.Summary
+}
+
+func (n *recordingNotifier) Notify(hooks []notify.Hook, summary notify.Summary) {
+	n.hooks = hooks
+	n.summaries = append(n.summaries, summary)
+}
+
+func TestNotifyingDaemonControllerNotifiesOnCompletion(t *testing.T) {
+	require := test.NewRequire(t)
+
+	notifier := &recordingNotifier{}
+	hooks := []notify.Hook{{URL: "https://hooks.example.com", On: []notify.Condition{notify.OnFailure}}}
+	started := time.Date(2020, 5, 4, 10, 0, 0, 0, time.UTC)
+	controller := newNotifyingDaemonController(executors.NewBufferedDaemonController(), notifier, hooks,
+		notify.Summary{RunID: "run-2", Name: "ob-v3.1-ozone", Started: started},
+		testSpecRun("OB-301-ACC-120382", "OB-301-ACC-811741"), map[string]bool{"OB-301-ACC-811741": true})
+	controller.now = func() time.Time { return started.Add(time.Minute) }
+
+	controller.AddResult(results.TestCase{Id: "OB-301-ACC-120382", Pass: true})
+	controller.AddResult(results.TestCase{Id: "OB-301-ACC-811741", Fail: []string{"status code"}})
+	controller.SetCompleted()
+	<-controller.notified
+
+	require.Equal(hooks, notifier.hooks)
+	require.Len(notifier.summaries, 1)
+	summary := notifier.summaries[0]
+	require.Equal("completed", summary.Status)
+	require.Equal(started.Add(time.Minute), summary.Finished)
+	require.Equal(2, summary.Tests)
+	require.Equal(1, summary.Failed)
+	require.Equal([]string{"OB-301-ACC-811741"}, summary.Regressions)
+}
+
+func TestJourneyNotifyRunWithoutHooks(t *testing.T) {
+	journey := resumableJourney(t, nil)
+
+	test.NewRequire(t).Equal(journey.daemonController, journey.notifyRun("run-1", time.Now()))
+}
+
+func TestJourneyPreviousResults(t *testing.T) {
+	require := test.NewRequire(t)
+
+	store := runstore.NewMemoryStore()
+	journey := resumableJourney(t, store)
+	started := time.Date(2020, 5, 4, 10, 0, 0, 0, time.UTC)
+	require.NoError(store.Save(runstore.Run{
+		ID: "run-0", Status: runstore.StatusCompleted, Started: started,
+		DiscoveryModel: discovery.Model{DiscoveryModel: discovery.ModelDiscovery{Name: "ob-v3.1-ozone"}},
+		Results:        []runstore.Result{{ID: "OB-301-ACC-811741", Pass: false}},
+	}))
+	require.NoError(store.Save(runstore.Run{
+		ID: "run-1", Status: runstore.StatusCompleted, Started: started.Add(time.Hour),
+		DiscoveryModel: discovery.Model{DiscoveryModel: discovery.ModelDiscovery{Name: "ob-v3.1-ozone"}},
+		Results:        []runstore.Result{{ID: "OB-301-ACC-811741", Pass: true}},
+	}))
+	require.NoError(store.Save(runstore.Run{
+		ID: "run-2", Status: runstore.StatusCompleted, Started: started.Add(2 * time.Hour),
+		DiscoveryModel: discovery.Model{DiscoveryModel: discovery.ModelDiscovery{Name: "another-bank"}},
+		Results:        []runstore.Result{{ID: "OB-301-ACC-811741", Pass: false}},
+	}))
+
+	require.Equal(map[string]bool{"OB-301-ACC-811741": true}, journey.previousResults("run-3", "ob-v3.1-ozone"))
+	require.Equal(map[string]bool{"OB-301-ACC-811741": false}, journey.previousResults("run-1", "ob-v3.1-ozone"))
+	require.Nil(journey.previousResults("run-3", "unknown"))
+}
@@ -5,6 +5,9 @@ import (
 
 	"math"
 
+	"github.com/OpenBankingUK/conformance-suite/pkg/metrics"
+	"github.com/OpenBankingUK/conformance-suite/pkg/runevents"
+	"github.com/OpenBankingUK/conformance-suite/pkg/runstore"
 	"github.com/OpenBankingUK/conformance-suite/pkg/version"
 
 	"github.com/gorilla/websocket"
@@ -16,6 +19,9 @@ import (
 // ListenHost defines the name/address by which the service can be accessed.
 const ListenHost = "0.0.0.0"
 
+// metricsPath - where Prometheus scrapes the suite's metrics
+const metricsPath = "/metrics"
+
 // Server - wraps *echo.Echo.
 type Server struct {
 	*echo.Echo // Wrap (using composition) *echo.Echo, allows us to pretend Server is echo.Echo.
@@ -23,8 +29,8 @@ type Server struct {
 	version    version.Checker
 }
 
-// NewServer returns new echo.Echo server.
-func NewServer(journey Journey, logger *logrus.Entry, version version.Checker) *Server {
+// NewServer returns new echo.Echo server. `runs` keeps the run history, nil disables it.
+func NewServer(journeys *Journeys, runs runstore.RunStore, logger *logrus.Entry, version version.Checker) *Server {
 	server := &Server{
 		Echo:    echo.New(),
 		logger:  logger,
@@ -59,12 +65,26 @@ func NewServer(journey Journey, logger *logrus.Entry, version version.Checker) *
 		Browse:  false,
 	}))
 
-	registerRoutes(journey, server, logger, version)
+	registerRoutes(journeys, runs, server, logger, version)
 
 	return server
 }
 
-func registerRoutes(journey Journey, server *Server, logger *logrus.Entry, version version.Checker) {
+// ServeRunEventLogs - serves the event log of each run kept in `log` at GET `/api/runs/:run_id/events`
+func (s *Server) ServeRunEventLogs(log runevents.Log) {
+	handlers := runHistoryHandlers{
+		eventLog: log,
+		logger:   s.logger.WithField("handler", "runHistoryHandlers"),
+	}
+	s.GET("/api/runs/:"+runIDParam+"/events", handlers.getRunEventsHandler)
+}
+
+// ServeMetrics - serves the suite's Prometheus metrics at GET `/metrics`
+func (s *Server) ServeMetrics() {
+	s.GET(metricsPath, echo.WrapHandler(metrics.Handler()))
+}
+
+func registerRoutes(journeys *Journeys, runs runstore.RunStore, server *Server, logger *logrus.Entry, version version.Checker) {
 	// swagger ui endpoints
 	for path, handler := range swaggerHandlers(logger) {
 		server.GET(path, handler)
@@ -75,42 +95,62 @@ func registerRoutes(journey Journey, server *Server, logger *logrus.Entry, versi
 
 	api.GET("/ping", func(c echo.Context) error { return nil })
 
-	importHandlers := newImportHandlers(journey, logger)
-	api.POST("/import/review", importHandlers.postImportReview)
-	api.POST("/import/rerun", importHandlers.postImportRerun)
+	// endpoints for journeys, each has its own discovery model, configuration, tokens and results
+	journeysHandlers := newJourneysHandlers(journeys, logger)
+	api.POST("/journeys", journeysHandlers.createJourneyHandler)
+	api.GET("/journeys", journeysHandlers.listJourneysHandler)
+	api.DELETE("/journeys/:journey_id", journeysHandlers.deleteJourneyHandler)
+
+	registerJourneyRoutes(api.Group("/journeys/:journey_id"), journeysHandlers.journeyMiddleware, journeysHandlers.journeyMiddleware)
+	registerJourneyRoutes(api, journeysHandlers.defaultJourneyMiddleware, journeysHandlers.consentJourneyMiddleware)
+
+	// endpoints for the history of test runs
+	runHistoryHandlers := newRunHistoryHandlers(runs, logger)
+	api.GET("/runs", runHistoryHandlers.listRunsHandler)
+	api.GET("/runs/:run_id", runHistoryHandlers.getRunHandler)
+	api.DELETE("/runs/:run_id", runHistoryHandlers.deleteRunHandler)
+
+	// endpoints for utility function such as version/update checking.
+	utilityEndpoints := newUtilityEndpoints(version)
+	api.GET("/version", utilityEndpoints.versionCheck)
+}
+
+// registerJourneyRoutes - the routes that act on a journey, `selectJourney` puts the handlers
+// for the journey in the echo.Context. `selectConsentJourney` does so for the consent callbacks.
+func registerJourneyRoutes(group *echo.Group, selectJourney, selectConsentJourney echo.MiddlewareFunc) {
+	group.POST("/import/review", func(c echo.Context) error { return handlersFor(c).importHandlers.postImportReview(c) }, selectJourney)
+	group.POST("/import/rerun", func(c echo.Context) error { return handlersFor(c).importHandlers.postImportRerun(c) }, selectJourney)
 
-	configHandlers := newConfigHandlers(journey, logger)
 	// endpoint to post global configuration
-	api.POST("/config/global", configHandlers.configGlobalPostHandler)
-	api.GET("/config/conditional-property", configHandlers.configConditionalPropertyHandler)
+	group.POST("/config/global", func(c echo.Context) error { return handlersFor(c).configHandlers.configGlobalPostHandler(c) }, selectJourney)
+	group.POST("/config/preflight", func(c echo.Context) error { return handlersFor(c).configHandlers.configPreflightPostHandler(c) }, selectJourney)
+	group.GET("/config/conditional-property", func(c echo.Context) error { return handlersFor(c).configHandlers.configConditionalPropertyHandler(c) }, selectJourney)
 
 	// endpoints for discovery model
-	discoveryHandlers := newDiscoveryHandlers(journey, logger)
-	api.POST("/discovery-model", discoveryHandlers.setDiscoveryModelHandler)
+	group.POST("/discovery-model", func(c echo.Context) error { return handlersFor(c).discoveryHandlers.setDiscoveryModelHandler(c) }, selectJourney)
 
 	// endpoints for test cases
-	testCaseHandlers := newTestCaseHandlers(journey, NewWebSocketUpgrader(), logger)
-	api.GET("/test-cases", testCaseHandlers.testCasesHandler)
+	group.GET("/test-cases", func(c echo.Context) error { return handlersFor(c).testCaseHandlers.testCasesHandler(c) }, selectJourney)
 
 	// endpoints for test runner
-	runHandlers := newRunHandlers(journey, NewWebSocketUpgrader(), logger)
-	api.POST("/run", runHandlers.runStartPostHandler)
-	api.GET("/run/ws", runHandlers.listenResultWebSocket)
-	api.DELETE("/run", runHandlers.stopRunHandler)
+	group.POST("/run", func(c echo.Context) error { return handlersFor(c).runHandlers.runStartPostHandler(c) }, selectJourney)
+	group.GET("/run/ws", func(c echo.Context) error { return handlersFor(c).runHandlers.listenResultWebSocket(c) }, selectJourney)
+	group.GET("/run/events", func(c echo.Context) error { return handlersFor(c).runHandlers.runEventsHandler(c) }, selectJourney)
+	group.DELETE("/run", func(c echo.Context) error { return handlersFor(c).runHandlers.stopRunHandler(c) }, selectJourney)
+	group.POST("/run/resume", func(c echo.Context) error { return handlersFor(c).runHandlers.runResumePostHandler(c) }, selectJourney)
+
+	// endpoints for load runs of the read-only test cases
+	group.POST("/load", func(c echo.Context) error { return handlersFor(c).runHandlers.loadStartPostHandler(c) }, selectJourney)
+	group.GET("/load", func(c echo.Context) error { return handlersFor(c).runHandlers.loadReportHandler(c) }, selectJourney)
+	group.DELETE("/load", func(c echo.Context) error { return handlersFor(c).runHandlers.stopLoadHandler(c) }, selectJourney)
 
 	// endpoints for validating and storing the token retrieved in `/conformancesuite/callback`
 	// `pkg/server/assets/main.js` calls into this endpoint.
-	redirectHandlers := newRedirectHandlers(journey, logger)
-	api.POST("/redirect/fragment/ok", redirectHandlers.postFragmentOKHandler)
-	api.POST("/redirect/query/ok", redirectHandlers.postQueryOKHandler)
-	api.POST("/redirect/error", redirectHandlers.postErrorHandler)
+	group.POST("/redirect/fragment/ok", func(c echo.Context) error { return handlersFor(c).redirectHandlers.postFragmentOKHandler(c) }, selectConsentJourney)
+	group.POST("/redirect/query/ok", func(c echo.Context) error { return handlersFor(c).redirectHandlers.postQueryOKHandler(c) }, selectConsentJourney)
+	group.POST("/redirect/error", func(c echo.Context) error { return handlersFor(c).redirectHandlers.postErrorHandler(c) }, selectConsentJourney)
 
-	exportHandlers := newExportHandlers(journey, logger)
-	api.POST("/export", exportHandlers.postExport)
-
-	// endpoints for utility function such as version/update checking.
-	utilityEndpoints := newUtilityEndpoints(version)
-	api.GET("/version", utilityEndpoints.versionCheck)
+	group.POST("/export", func(c echo.Context) error { return handlersFor(c).exportHandlers.postExport(c) }, selectJourney)
 }
 
 // skipperSwagger - ensures that all requests not prefixed with any string in `pathsToSkip` is skipped.
@@ -119,6 +159,7 @@ func skipperSwagger(c echo.Context) bool {
 	pathsToSkip := []string{
 		"/api",
 		"/swagger",
+		metricsPath,
 	}
 
 	path := c.Path()
@@ -131,15 +172,18 @@ func skipperSwagger(c echo.Context) bool {
 	return false
 }
 
-// skipperGzip - ensures that gzip compression is not turned on for the `/api/export` and `/api/import` paths.
-// I.e., don't run the Gzip middleware for certain paths.
+// skipperGzip - ensures that gzip compression is not turned on for the `/api/export`, `/api/import` and
+// `/api/run/events` paths, including the ones scoped to a journey. I.e., don't run the Gzip middleware for certain paths.
+// `/metrics` is compressed by the Prometheus handler itself.
 func skipperGzip(c echo.Context) bool {
 	pathsToSkip := []string{
 		"/api/export",
 		"/api/import",
+		"/api/run/events",
+		metricsPath,
 	}
 
-	path := c.Path()
+	path := strings.Replace(c.Path(), "/api/journeys/:journey_id", "/api", 1)
 	for _, pathToSkip := range pathsToSkip {
 		if strings.HasPrefix(path, pathToSkip) {
 			return true
@@ -40,3 +40,8 @@ func nullLogger() *logrus.Entry {
 	logger.Out = ioutil.Discard
 	return logger.WithField("app", "test")
 }
+
+// journeysFor - serves `journey` as the default journey, and for any journey created.
+func journeysFor(journey Journey) *Journeys {
+	return NewJourneys(func(string) Journey { return journey }, 0, nullLogger())
+}
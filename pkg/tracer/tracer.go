@@ -19,6 +19,8 @@ import (
 	"fmt"
 
 	"github.com/sirupsen/logrus"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/redact"
 )
 
 // Tracer info capture interface - capturing testcase execution info - expects to be extended/improved over time
@@ -59,7 +61,7 @@ func AppMsg(objtype, msg, objdump string) {
 	if Silent {
 		return
 	}
-	appmsg := fmt.Sprintf("%s[%s] %s", "" /*indent()*/, objtype, msg)
+	appmsg := redact.String(fmt.Sprintf("%s[%s] %s", "" /*indent()*/, objtype, msg))
 	logrus.StandardLogger().Debugln(appmsg)
 	applicationTrace <- appmsg
 }
@@ -69,7 +71,7 @@ func AppErr(objtype, msg, objdump string) {
 	if Silent {
 		return
 	}
-	errmsg := fmt.Sprintf("%s[%s] %s", "" /*indent()*/, objtype, msg)
+	errmsg := redact.String(fmt.Sprintf("%s[%s] %s", "" /*indent()*/, objtype, msg))
 	logrus.StandardLogger().Error(errmsg)
 	applicationTrace <- errmsg
 }
@@ -0,0 +1,129 @@
+// Package tracing records OpenTelemetry spans for test runs: the run, each specification, each
+// test case and the HTTP requests they make, consent acquisition and access token requests. Spans
+// are exported over OTLP/HTTP to a collector. Until Setup is called spans are not recorded.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+	"gopkg.in/resty.v1"
+
+	"github.com/OpenBankingUK/conformance-suite/pkg/redact"
+)
+
+const (
+	instrumentationName = "github.com/OpenBankingUK/conformance-suite"
+	serviceName         = "fcs_server"
+	// InteractionIDHeader - correlates a request with the ASPSP's own logs and traces
+	InteractionIDHeader = "x-fapi-interaction-id"
+)
+
+// Attribute keys
+const (
+	RunIDKey         = attribute.Key("fcs.run_id")
+	TestCaseIDKey    = attribute.Key("fcs.test_case.id")
+	TokenNameKey     = attribute.Key("fcs.token_name")
+	InteractionIDKey = attribute.Key("fapi.interaction_id")
+)
+
+// Config - where spans are exported to
+type Config struct {
+	Endpoint string // host:port of an OTLP/HTTP collector, e.g. localhost:4318
+	Insecure bool   // export over plain HTTP, as to a collector on the same host
+	// Propagate - send the W3C `traceparent` header to the ASPSP, so its spans join the suite's traces
+	Propagate      bool
+	ServiceVersion string
+}
+
+// propagator - injects the trace context into requests to the ASPSP, nil unless propagation is on
+var propagator propagation.TextMapPropagator
+
+// Setup - exports spans as set out in `config`. The returned function flushes the spans not yet
+// exported and stops exporting.
+func Setup(config Config) (func(context.Context) error, error) {
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(config.Endpoint)}
+	if config.Insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(context.Background(), options...)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String(serviceName),
+			semconv.ServiceVersionKey.String(config.ServiceVersion),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	if config.Propagate {
+		propagator = propagation.TraceContext{}
+	}
+	return provider.Shutdown, nil
+}
+
+// Start - starts a span named `name`, a child of any span in `ctx`
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// End - ends `span`, marking it failed with `err` when it is not nil
+func End(span trace.Span, err error) {
+	if err != nil {
+		message := redact.String(err.Error())
+		span.RecordError(errors.New(message))
+		span.SetStatus(codes.Error, message)
+	}
+	span.End()
+}
+
+// Inject - adds the `traceparent` header of the span in `ctx` to `header`, if propagation is on
+func Inject(ctx context.Context, header http.Header) {
+	if propagator == nil {
+		return
+	}
+	propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// StartHTTP - starts the span of request `r`, a child of any span in `ctx`, sending its trace
+// context to the ASPSP if propagation is on. `r` must have its method and URL set.
+func StartHTTP(ctx context.Context, r *resty.Request) trace.Span {
+	ctx, span := Start(ctx, "HTTP "+r.Method,
+		semconv.HTTPMethodKey.String(r.Method),
+		semconv.HTTPURLKey.String(redact.String(r.URL)),
+	)
+	if interactionID := r.Header.Get(InteractionIDHeader); interactionID != "" {
+		span.SetAttributes(InteractionIDKey.String(interactionID))
+	}
+	Inject(ctx, r.Header)
+	r.SetContext(ctx)
+	return span
+}
+
+// EndHTTP - records the response, and any interaction ID the ASPSP sent back, on `span`
+func EndHTTP(span trace.Span, resp *resty.Response, err error) {
+	if resp != nil && resp.RawResponse != nil {
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(resp.StatusCode()))
+		if interactionID := resp.Header().Get(InteractionIDHeader); interactionID != "" {
+			span.SetAttributes(InteractionIDKey.String(interactionID))
+		}
+		if resp.StatusCode() >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, resp.Status())
+		}
+	}
+	End(span, err)
+}
@@ -0,0 +1,58 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans - records the spans started from now on
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestStartAndEnd(t *testing.T) {
+	recorder := recordSpans(t)
+
+	ctx, run := Start(context.Background(), "run", RunIDKey.String("run-1"))
+	_, testCase := Start(ctx, "test case", TestCaseIDKey.String("OB-301-ACC-120382"))
+	End(testCase, errors.New("token endpoint said: Bearer a5c1f1fa is invalid"))
+	End(run, nil)
+
+	ended := recorder.Ended()
+	require.Len(t, ended, 2)
+	assert.Equal(t, "test case", ended[0].Name())
+	assert.Equal(t, ended[1].SpanContext().SpanID(), ended[0].Parent().SpanID())
+	assert.Equal(t, codes.Error, ended[0].Status().Code)
+	assert.Equal(t, "token endpoint said: Bearer [redacted] is invalid", ended[0].Status().Description)
+	assert.Equal(t, codes.Unset, ended[1].Status().Code)
+}
+
+func TestInject(t *testing.T) {
+	recordSpans(t)
+	ctx, span := Start(context.Background(), "HTTP GET")
+	defer span.End()
+
+	header := http.Header{}
+	Inject(ctx, header)
+	assert.Empty(t, header.Get("traceparent"), "propagation is off by default")
+
+	propagator = propagation.TraceContext{}
+	defer func() { propagator = nil }()
+	Inject(ctx, header)
+	assert.Contains(t, header.Get("traceparent"), span.SpanContext().TraceID().String())
+}
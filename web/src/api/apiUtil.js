@@ -1,3 +1,4 @@
+import journey from './journey';
 
 const fetchWithTimeout = (url, timeout, options) => Promise.race([
   fetch(url, options),
@@ -11,7 +12,7 @@ const doRequest = async (path, setShowLoading, method) => {
     setShowLoading(true);
   }
   try {
-    const response = await fetchWithTimeout(path, FETCH_TIMEOUT, {
+    const response = await fetchWithTimeout(journey.path(path), FETCH_TIMEOUT, {
       method,
       headers: {
         Accept: 'application/json; charset=UTF-8',
@@ -51,7 +52,7 @@ export default {
       setShowLoading(true);
     }
     try {
-      const response = await fetchWithTimeout(path, FETCH_TIMEOUT, {
+      const response = await fetchWithTimeout(journey.path(path), FETCH_TIMEOUT, {
         method: 'POST',
         headers,
         body: obj ? JSON.stringify(obj) : null,
@@ -0,0 +1,50 @@
+const JOURNEYS_URL = '/api/journeys';
+const STORAGE_KEY = 'fcsJourneyId';
+// Routes that are not part of a journey. The consent callbacks find their journey
+// from the OAuth state, as the PSU may complete the consent in any tab or window.
+const UNSCOPED_PATHS = [JOURNEYS_URL, '/api/version', '/api/ping', '/api/redirect'];
+
+let journeyId = null;
+
+export default {
+  /**
+   * Calls POST `/api/journeys` so this tab gets its own discovery model, configuration
+   * and tokens on the server. The ID is kept in session storage, so it is not shared
+   * with other tabs but survives a reload.
+   */
+  async start() {
+    const response = await fetch(JOURNEYS_URL, {
+      method: 'POST',
+      headers: {
+        Accept: 'application/json; charset=UTF-8',
+      },
+    });
+    if (response.status !== 201) {
+      throw new Error(`Failed to start a journey. Got ${response.status} from server.`);
+    }
+    const { id } = await response.json();
+    journeyId = id;
+    window.sessionStorage.setItem(STORAGE_KEY, id);
+    return id;
+  },
+  /**
+   * The journey started in this tab.
+   */
+  id() {
+    if (!journeyId) {
+      journeyId = window.sessionStorage.getItem(STORAGE_KEY);
+    }
+    return journeyId;
+  },
+  /**
+   * Scopes an `/api` path to the current journey, e.g. `/api/run` becomes
+   * `/api/journeys/{id}/run`. Paths are unchanged when no journey has been started.
+   */
+  path(path) {
+    const id = this.id();
+    if (!id || !path.startsWith('/api/') || UNSCOPED_PATHS.some(unscoped => path.startsWith(unscoped))) {
+      return path;
+    }
+    return `${JOURNEYS_URL}/${id}${path.substring('/api'.length)}`;
+  },
+};
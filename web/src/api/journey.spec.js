@@ -0,0 +1,33 @@
+import journey from './journey';
+
+describe('journey', () => {
+  beforeEach(() => {
+    fetch.resetMocks();
+    window.sessionStorage.clear();
+  });
+
+  it('leaves paths unchanged before a journey is started', () => {
+    expect(journey.path('/api/run')).toEqual('/api/run');
+  });
+
+  it('start creates a journey and scopes api paths to it', async () => {
+    fetch.mockResponseOnce(JSON.stringify({ id: '6c3b4ed5' }), { status: 201 });
+
+    expect(await journey.start()).toEqual('6c3b4ed5');
+
+    expect(fetch.mock.calls[0][0]).toEqual('/api/journeys');
+    expect(fetch.mock.calls[0][1].method).toEqual('POST');
+    expect(window.sessionStorage.getItem('fcsJourneyId')).toEqual('6c3b4ed5');
+    expect(journey.path('/api/config/global')).toEqual('/api/journeys/6c3b4ed5/config/global');
+    expect(journey.path('/api/run/ws')).toEqual('/api/journeys/6c3b4ed5/run/ws');
+    expect(journey.path('/api/version')).toEqual('/api/version');
+    expect(journey.path('/api/journeys')).toEqual('/api/journeys');
+    expect(journey.path('/api/redirect/fragment/ok')).toEqual('/api/redirect/fragment/ok');
+  });
+
+  it('start throws when the server does not create a journey', async () => {
+    fetch.mockResponseOnce('', { status: 500 });
+
+    await expect(journey.start()).rejects.toThrow('Failed to start a journey. Got 500 from server.');
+  });
+});
@@ -3,6 +3,7 @@ import * as types from './mutation-types';
 import constants from '../config/constants';
 
 import api from '../../../api';
+import journey from '../../../api/journey';
 
 /**
  * Setup WebSocket connection to the backend to retrieve results.
@@ -16,7 +17,7 @@ const createWebSocketConnection = () => {
     const isSecure = protocol === 'https:';
     const scheme = isSecure ? 'wss' : 'ws';
 
-    const url = `${scheme}://${host}/api/run/ws`;
+    const url = `${scheme}://${host}${journey.path('/api/run/ws')}`;
     return url;
   };
 
@@ -107,6 +108,8 @@ export default {
           commit(types.ADD_TOKEN_ACQUIRED, update);
         } else if (_.has(update, 'type') && update.type === 'ResultType_AcquiredAllAccessTokens') {
           commit(types.SET_ALL_TOKENS_ACQUIRED);
+        } else if (_.has(update, 'type') && update.type === 'ResultType_TokenStateChanged') {
+          commit(types.SET_TOKEN_STATE, update.value);
         } else if (_.has(update, 'stopped') && update.stopped) {
           // do nothing
         } else {
@@ -12,3 +12,4 @@ export const TOGGLE_ROW_DETAILS = 'TOGGLE_ROW_DETAILS';
 
 export const ADD_TOKEN_ACQUIRED = 'ADD_TOKEN_ACQUIRED';
 export const SET_ALL_TOKENS_ACQUIRED = 'SET_ALL_TOKENS_ACQUIRED';
+export const SET_TOKEN_STATE = 'SET_TOKEN_STATE';
@@ -34,7 +34,7 @@ export default {
     }
 
     const {
-      id, pass, metrics, fail, detail, refURI,
+      id, pass, metrics, fail, detail, refURI, signature,
     } = update.test;
 
     testCase.id = id;
@@ -45,6 +45,7 @@ export default {
     testCase.error = fail;
     testCase.detail = detail;
     testCase.refURI = refURI;
+    testCase.signature = signature;
 
     if (fail) {
       // Set the row variant, for alternate styling.
@@ -90,4 +91,10 @@ export default {
   [types.SET_ALL_TOKENS_ACQUIRED](state) {
     state.tokens.all_acquired = true;
   },
+  [types.SET_TOKEN_STATE](state, value) {
+    state.tokens.states = {
+      ...state.tokens.states,
+      [value.token_name]: value,
+    };
+  },
 };
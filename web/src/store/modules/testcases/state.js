@@ -10,5 +10,7 @@ export default {
   tokens: {
     acquired: [],
     all_acquired: false,
+    // token_name -> { token_name, state, expires }, state is valid, expiring, refreshed or expired
+    states: {},
   },
 };